@@ -1,7 +1,11 @@
 module github.com/chrisranney/gopas
 
-go 1.21
+go 1.23
 
 require (
 	golang.org/x/oauth2 v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
+	software.sslmate.com/src/go-pkcs12 v0.5.0
 )
+
+require golang.org/x/crypto v0.11.0 // indirect
@@ -27,6 +27,15 @@
 //		SafeName: "MySafe",
 //	})
 //
+// Iterate over every account, fetching pages as needed:
+//
+//	for acct, err := range gopas.AllAccounts(ctx, sess, gopas.ListAccountsOptions{}, gopas.PageOptions{}) {
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		fmt.Println(acct.Name)
+//	}
+//
 // # Authentication
 //
 // goPAS supports multiple authentication methods:
@@ -63,12 +72,17 @@ package gopas
 
 import (
 	"context"
+	"iter"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/accounts"
 	"github.com/chrisranney/gopas/pkg/authentication"
 	"github.com/chrisranney/gopas/pkg/ccp"
 	"github.com/chrisranney/gopas/pkg/safes"
+	"github.com/chrisranney/gopas/pkg/tlsconfig"
+	"github.com/chrisranney/gopas/pkg/types"
 )
 
 // Version is the current version of the goPAS SDK.
@@ -85,6 +99,10 @@ type Credentials = authentication.Credentials
 // SessionOptions holds options for creating a session.
 type SessionOptions = authentication.SessionOptions
 
+// TLSConfig holds TLS settings: private CAs, client certificates, pinning
+// and the minimum TLS version.
+type TLSConfig = tlsconfig.Config
+
 // AuthMethod represents an authentication method.
 type AuthMethod = authentication.AuthMethod
 
@@ -94,8 +112,125 @@ const (
 	AuthMethodLDAP     = authentication.AuthMethodLDAP
 	AuthMethodRADIUS   = authentication.AuthMethodRADIUS
 	AuthMethodWindows  = authentication.AuthMethodWindows
+	AuthMethodPKI      = authentication.AuthMethodPKI
+)
+
+// OTPMode selects how a RADIUS one-time password is sent.
+type OTPMode = authentication.OTPMode
+
+// OTP mode constants
+const (
+	OTPModeAppend    = authentication.OTPModeAppend
+	OTPModeChallenge = authentication.OTPModeChallenge
+)
+
+// Challenge is a request for more input during a multi-step logon.
+type Challenge = authentication.Challenge
+
+// ChallengeHandler answers RADIUS challenges during logon.
+type ChallengeHandler = authentication.ChallengeHandler
+
+// ChallengeHandlerFunc adapts a function to the ChallengeHandler interface.
+type ChallengeHandlerFunc = authentication.ChallengeHandlerFunc
+
+// RateLimit throttles API requests and caps their concurrency.
+type RateLimit = client.RateLimit
+
+// Middleware wraps the transport of every API request attempt.
+type Middleware = client.Middleware
+
+// MiddlewareFunc adapts a function to the Middleware interface.
+type MiddlewareFunc = client.MiddlewareFunc
+
+// RoundTripperFunc adapts a function to the http.RoundTripper interface.
+type RoundTripperFunc = client.RoundTripperFunc
+
+// LoggingOptions configures NewLoggingMiddleware.
+type LoggingOptions = client.LoggingOptions
+
+// NewLoggingMiddleware returns a middleware that logs requests with secrets redacted.
+func NewLoggingMiddleware(opts LoggingOptions) Middleware {
+	return client.NewLoggingMiddleware(opts)
+}
+
+// TimingFunc receives the duration and outcome of each request attempt.
+type TimingFunc = client.TimingFunc
+
+// NewTimingMiddleware returns a middleware that reports request durations.
+func NewTimingMiddleware(observe TimingFunc) Middleware {
+	return client.NewTimingMiddleware(observe)
+}
+
+// Instrumentation observes every API call, for tracing and metrics.
+// See the otelgopas module for an OpenTelemetry implementation.
+type Instrumentation = client.Instrumentation
+
+// CallInfo describes a single API call observed by Instrumentation.
+type CallInfo = client.CallInfo
+
+// RouteTemplate replaces the identifiers in an API path with {id}.
+func RouteTemplate(path string) string {
+	return client.RouteTemplate(path)
+}
+
+// APIError represents an error response from the CyberArk API.
+type APIError = client.APIError
+
+// ErrorCategory classifies CyberArk error codes.
+type ErrorCategory = client.ErrorCategory
+
+// Error categories reported by APIError.Category
+const (
+	CategoryUnknown        = client.CategoryUnknown
+	CategoryAuthentication = client.CategoryAuthentication
+	CategorySession        = client.CategorySession
+	CategoryPermission     = client.CategoryPermission
+	CategoryNotFound       = client.CategoryNotFound
+	CategoryConflict       = client.CategoryConflict
+	CategoryValidation     = client.CategoryValidation
+	CategoryChallenge      = client.CategoryChallenge
+	CategoryServer         = client.CategoryServer
 )
 
+// Sentinel errors for use with errors.Is
+var (
+	ErrNotFound           = client.ErrNotFound
+	ErrUnauthorized       = client.ErrUnauthorized
+	ErrForbidden          = client.ErrForbidden
+	ErrConflict           = client.ErrConflict
+	ErrSessionInvalid     = client.ErrSessionInvalid
+	ErrVersionUnsupported = client.ErrVersionUnsupported
+
+	ErrSecondFactorRequired = authentication.ErrSecondFactorRequired
+)
+
+// VersionError reports that an operation is not supported by the connected
+// vault version or deployment type. It matches ErrVersionUnsupported.
+type VersionError = helpers.VersionError
+
+// AsAPIError finds the first APIError in the error's chain.
+func AsAPIError(err error) (*APIError, bool) {
+	return client.AsAPIError(err)
+}
+
+// CredentialProvider supplies credentials on demand, for example from CCP.
+type CredentialProvider = authentication.CredentialProvider
+
+// CCPCredentialProvider returns a CredentialProvider that fetches the logon
+// credentials from the Central Credential Provider on every call.
+func CCPCredentialProvider(ccpClient *CCPClient, req CCPCredentialRequest) CredentialProvider {
+	return authentication.CCPCredentialProvider(ccpClient, req)
+}
+
+// RetryPolicy controls automatic retries of failed API requests.
+type RetryPolicy = client.RetryPolicy
+
+// DefaultRetryPolicy returns a retry policy suitable for most workloads.
+// POST and PATCH requests are not retried unless RetryNonIdempotent is set.
+func DefaultRetryPolicy() *RetryPolicy {
+	return client.DefaultRetryPolicy()
+}
+
 // Account represents a CyberArk privileged account.
 type Account = accounts.Account
 
@@ -133,6 +268,14 @@ func ListAccounts(ctx context.Context, sess *Session, opts ListAccountsOptions)
 	return accounts.List(ctx, sess, opts)
 }
 
+// PageOptions controls how list iterators walk through paged results.
+type PageOptions = types.PageOptions
+
+// AllAccounts returns an iterator over every account matching opts.
+func AllAccounts(ctx context.Context, sess *Session, opts ListAccountsOptions, pageOpts PageOptions) iter.Seq2[Account, error] {
+	return accounts.All(ctx, sess, opts, pageOpts)
+}
+
 // GetAccount retrieves a specific account by ID.
 func GetAccount(ctx context.Context, sess *Session, accountID string) (*Account, error) {
 	return accounts.Get(ctx, sess, accountID)
@@ -164,6 +307,11 @@ func ListSafes(ctx context.Context, sess *Session, opts ListSafesOptions) (*safe
 	return safes.List(ctx, sess, opts)
 }
 
+// AllSafes returns an iterator over every safe matching opts.
+func AllSafes(ctx context.Context, sess *Session, opts ListSafesOptions, pageOpts PageOptions) iter.Seq2[Safe, error] {
+	return safes.All(ctx, sess, opts, pageOpts)
+}
+
 // GetSafe retrieves a specific safe by name.
 func GetSafe(ctx context.Context, sess *Session, safeName string) (*Safe, error) {
 	return safes.Get(ctx, sess, safeName)
@@ -195,6 +343,9 @@ type CCPClient = ccp.Client
 // CCPClientConfig holds configuration for creating a CCP client.
 type CCPClientConfig = ccp.ClientConfig
 
+// CCPCacheConfig configures credential caching on a CCP client.
+type CCPCacheConfig = ccp.CacheConfig
+
 // CCPCredentialRequest represents a request to retrieve credentials from CCP.
 type CCPCredentialRequest = ccp.CredentialRequest
 
@@ -6,22 +6,33 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/chrisranney/gopas/pkg/tlsconfig"
 )
 
 // Client represents an HTTP client for CyberArk API communication.
 type Client struct {
+	mu          sync.RWMutex
 	httpClient  *http.Client
 	baseURL     string
 	apiURL      string
 	authToken   string
 	contentType string
 	timeout     time.Duration
+	retryPolicy *RetryPolicy
+	refresher   TokenRefresher
+	refreshing  *refreshCall
+
+	instrumentation Instrumentation
+	limiter         *limiter
 }
 
 // Config holds the client configuration options.
@@ -30,6 +41,32 @@ type Config struct {
 	Timeout          time.Duration
 	SkipTLSVerify    bool
 	CustomHTTPClient *http.Client
+
+	// TLS configures client certificates, private CAs and certificate
+	// pinning (optional). It is ignored when CustomHTTPClient is set.
+	TLS *tlsconfig.Config
+
+	// Proxy is an http, https or socks5 proxy URL (optional). When empty
+	// the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables are
+	// used. It is ignored when CustomHTTPClient is set.
+	Proxy string
+
+	// NoProxy is a comma-separated list of hosts, domains, IP addresses and
+	// CIDR ranges to reach without the proxy (optional)
+	NoProxy string
+
+	// RetryPolicy enables automatic retries of failed requests (optional)
+	RetryPolicy *RetryPolicy
+
+	// Middlewares wrap the transport of every request attempt (optional).
+	// The first middleware is the outermost.
+	Middlewares []Middleware
+
+	// Instrumentation observes every API call, for tracing and metrics (optional)
+	Instrumentation Instrumentation
+
+	// RateLimit throttles requests and caps concurrency (optional)
+	RateLimit *RateLimit
 }
 
 // NewClient creates a new HTTP client for CyberArk API communication.
@@ -48,27 +85,47 @@ func NewClient(cfg Config) (*Client, error) {
 
 	httpClient := cfg.CustomHTTPClient
 	if httpClient == nil {
+		transport, err := newTransport(cfg)
+		if err != nil {
+			return nil, err
+		}
 		httpClient = &http.Client{
-			Timeout: timeout,
+			Transport: transport,
+			Timeout:   timeout,
 		}
 	}
 
+	if len(cfg.Middlewares) > 0 {
+		// Copy the client so a caller-supplied one is left untouched
+		wrapped := *httpClient
+		wrapped.Transport = chainMiddleware(httpClient.Transport, cfg.Middlewares)
+		httpClient = &wrapped
+	}
+
 	return &Client{
 		httpClient:  httpClient,
 		baseURL:     cfg.BaseURL,
 		apiURL:      cfg.BaseURL + "/PasswordVault/API",
 		contentType: "application/json",
 		timeout:     timeout,
+		retryPolicy: cfg.RetryPolicy,
+
+		instrumentation: cfg.Instrumentation,
+		limiter:         newLimiter(cfg.RateLimit),
 	}, nil
 }
 
 // SetAuthToken sets the authentication token for subsequent requests.
 func (c *Client) SetAuthToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.authToken = token
 }
 
 // GetAuthToken returns the current authentication token.
 func (c *Client) GetAuthToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.authToken
 }
 
@@ -99,7 +156,35 @@ type Response struct {
 }
 
 // Do executes an HTTP request to the CyberArk API.
+// When a retry policy is configured, transport errors and retryable status
+// codes are retried with backoff until the policy or the context gives up.
+// When a token refresher is configured, a 401 response triggers a single
+// re-authentication after which the request is replayed once.
 func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
+	if c.instrumentation != nil {
+		return c.instrumentedDo(ctx, req)
+	}
+	return c.do(ctx, req)
+}
+
+// do executes a request with retries and re-authentication.
+func (c *Client) do(ctx context.Context, req Request) (*Response, error) {
+	token := c.GetAuthToken()
+
+	resp, err := c.doWithRetry(ctx, req)
+	if !c.shouldReauthenticate(ctx, err) {
+		return resp, err
+	}
+
+	if refreshErr := c.refreshToken(ctx, token); refreshErr != nil {
+		return resp, fmt.Errorf("%w (re-authentication failed: %w)", err, refreshErr)
+	}
+
+	return c.doWithRetry(ctx, req)
+}
+
+// doWithRetry executes a request, retrying according to the retry policy.
+func (c *Client) doWithRetry(ctx context.Context, req Request) (*Response, error) {
 	// Build the full URL
 	fullURL := c.apiURL + req.Path
 	if len(req.QueryParams) > 0 {
@@ -107,12 +192,44 @@ func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
 	}
 
 	// Serialize body if present
-	var bodyReader io.Reader
+	var bodyBytes []byte
 	if req.Body != nil {
-		bodyBytes, err := json.Marshal(req.Body)
+		var err error
+		bodyBytes, err = json.Marshal(req.Body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
+	}
+
+	attempts := 1
+	if c.retryPolicy != nil && c.retryPolicy.allowsMethod(req.Method) {
+		attempts = c.retryPolicy.maxAttempts()
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.doOnce(ctx, req, fullURL, bodyBytes)
+		if err == nil || attempt >= attempts || !c.shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		if sleepErr := sleepContext(ctx, c.retryPolicy.backoff(attempt, resp)); sleepErr != nil {
+			return resp, fmt.Errorf("%w (retry aborted: %w)", err, sleepErr)
+		}
+	}
+}
+
+// doOnce performs a single attempt of an API request.
+func (c *Client) doOnce(ctx context.Context, req Request, fullURL string, bodyBytes []byte) (*Response, error) {
+	if c.limiter != nil {
+		release, err := c.limiter.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	var bodyReader io.Reader
+	if bodyBytes != nil {
 		bodyReader = bytes.NewReader(bodyBytes)
 	}
 
@@ -124,8 +241,8 @@ func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
 
 	// Set default headers
 	httpReq.Header.Set("Content-Type", c.contentType)
-	if c.authToken != "" {
-		httpReq.Header.Set("Authorization", c.authToken)
+	if token := c.GetAuthToken(); token != "" {
+		httpReq.Header.Set("Authorization", token)
 	}
 
 	// Set custom headers
@@ -160,6 +277,18 @@ func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
 	return resp, nil
 }
 
+// shouldRetry reports whether a failed attempt is worth repeating.
+// A nil response means the request never completed (a transport error).
+func (c *Client) shouldRetry(ctx context.Context, resp *Response, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if resp == nil {
+		return true
+	}
+	return c.retryPolicy.isRetryableStatus(resp.StatusCode)
+}
+
 // Get performs a GET request.
 func (c *Client) Get(ctx context.Context, path string, queryParams url.Values) (*Response, error) {
 	return c.Do(ctx, Request{
@@ -4,12 +4,15 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"encoding/pem"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
+
+	"github.com/chrisranney/gopas/pkg/tlsconfig"
 )
 
 func TestNewClient(t *testing.T) {
@@ -445,3 +448,43 @@ func TestClient_InvalidBodyMarshal(t *testing.T) {
 		t.Error("Post() expected error for invalid body marshal")
 	}
 }
+
+func TestNewClient_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "untrusted server", cfg: Config{}, wantErr: true},
+		{name: "private CA", cfg: Config{TLS: &tlsconfig.Config{CAPEM: caPEM}}},
+		{name: "skip verify", cfg: Config{SkipTLSVerify: true}},
+		{name: "pinned key", cfg: Config{TLS: &tlsconfig.Config{
+			CAPEM:            caPEM,
+			PinnedSPKIHashes: []string{tlsconfig.SPKIHash(server.Certificate())},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.BaseURL = server.URL
+			client, err := NewClient(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			_, err = client.Get(context.Background(), "/Server", nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewClient(Config{BaseURL: server.URL, TLS: &tlsconfig.Config{CAFile: "/nonexistent/ca.pem"}}); err == nil {
+		t.Error("NewClient() with a missing CA file succeeded")
+	}
+}
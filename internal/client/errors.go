@@ -3,9 +3,103 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/chrisranney/gopas/internal/helpers"
+)
+
+// Sentinel errors matched with errors.Is. An *APIError matches the sentinel
+// for its category, so callers do not need to inspect status codes directly:
+//
+//	if errors.Is(err, client.ErrNotFound) { ... }
+var (
+	// ErrNotFound indicates the requested object does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrUnauthorized indicates the request was rejected for missing or bad credentials
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden indicates the authenticated user lacks the required permissions
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrConflict indicates the object already exists or is in a conflicting state
+	ErrConflict = errors.New("conflict")
+
+	// ErrSessionInvalid indicates there is no usable session, either because none
+	// was supplied or because the Vault has expired the session token
+	ErrSessionInvalid = errors.New("valid session is required")
+
+	// ErrVersionUnsupported indicates the operation is not available on the
+	// connected CyberArk version or deployment type
+	ErrVersionUnsupported = helpers.ErrVersionUnsupported
 )
 
+// ErrorCategory classifies a CyberArk error so callers can branch on its
+// meaning rather than on individual error codes.
+type ErrorCategory string
+
+// Error categories
+const (
+	CategoryUnknown        ErrorCategory = ""
+	CategoryAuthentication ErrorCategory = "Authentication"
+	CategorySession        ErrorCategory = "Session"
+	CategoryPermission     ErrorCategory = "Permission"
+	CategoryNotFound       ErrorCategory = "NotFound"
+	CategoryConflict       ErrorCategory = "Conflict"
+	CategoryValidation     ErrorCategory = "Validation"
+	CategoryChallenge      ErrorCategory = "Challenge"
+	CategoryServer         ErrorCategory = "Server"
+)
+
+// errorCodeCategories maps known CyberArk error codes to their category.
+// PASWS codes come from the PVWA web services, SFWS codes from the safes
+// API, APPAP codes from the applications API, ITATS codes from the Vault and
+// CAWS codes from the Gen2 web services.
+var errorCodeCategories = map[string]ErrorCategory{
+	// Authentication failures and suspended users
+	"PASWS013E": CategoryAuthentication,
+	"ITATS004E": CategoryAuthentication,
+	"ITATS203E": CategoryAuthentication,
+
+	// Expired session tokens
+	"PASWS006E": CategorySession,
+
+	// RADIUS and other multi-step logons waiting for a response
+	"ITATS542I": CategoryChallenge,
+
+	// Request validation
+	"PASWS167E": CategoryValidation,
+
+	// Missing objects
+	"CAWS00001E": CategoryNotFound,
+	"PASWS143E":  CategoryNotFound,
+	"PASWS148E":  CategoryNotFound,
+	"PASWS164E":  CategoryNotFound,
+	"PASWS182E":  CategoryNotFound,
+	"PASWS186E":  CategoryNotFound,
+	"SFWS0007":   CategoryNotFound,
+	"SFWS0011E":  CategoryNotFound,
+	"APPAP004E":  CategoryNotFound,
+	"APPAP007E":  CategoryNotFound,
+
+	// Objects that already exist
+	"PASWS027E": CategoryConflict,
+	"PASWS120E": CategoryConflict,
+	"PASWS130E": CategoryConflict,
+	"PASWS183E": CategoryConflict,
+	"SFWS0002":  CategoryConflict,
+	"APPAP008E": CategoryConflict,
+	"APPAP010E": CategoryConflict,
+}
+
+// CategoryForErrorCode returns the category of a CyberArk error code, or
+// CategoryUnknown if the code is not recognized.
+func CategoryForErrorCode(code string) ErrorCategory {
+	return errorCodeCategories[strings.ToUpper(strings.TrimSpace(code))]
+}
+
 // APIError represents a CyberArk API error response.
 type APIError struct {
 	StatusCode int    `json:"-"`
@@ -22,6 +116,60 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("CyberArk API error [%d]: %s", e.StatusCode, e.ErrorMsg)
 }
 
+// Category returns the category of the error. A recognized ErrorCode takes
+// precedence; otherwise the message and then the HTTP status code are used,
+// since the Vault reports many missing or duplicate objects as a 400 or 500.
+func (e *APIError) Category() ErrorCategory {
+	if category := CategoryForErrorCode(e.ErrorCode); category != CategoryUnknown {
+		return category
+	}
+
+	msg := strings.ToLower(e.ErrorMsg)
+	switch {
+	case strings.Contains(msg, "already exists"):
+		return CategoryConflict
+	case strings.Contains(msg, "does not exist"), strings.Contains(msg, "was not found"):
+		return CategoryNotFound
+	}
+
+	switch {
+	case e.StatusCode == 400:
+		return CategoryValidation
+	case e.StatusCode == 401:
+		return CategoryAuthentication
+	case e.StatusCode == 403:
+		return CategoryPermission
+	case e.StatusCode == 404:
+		return CategoryNotFound
+	case e.StatusCode == 409:
+		return CategoryConflict
+	case e.StatusCode >= 500:
+		return CategoryServer
+	default:
+		return CategoryUnknown
+	}
+}
+
+// Is reports whether the error matches one of the package sentinels,
+// allowing errors.Is(err, ErrNotFound) and friends to see through wrapping.
+func (e *APIError) Is(target error) bool {
+	category := e.Category()
+	switch target {
+	case ErrNotFound:
+		return category == CategoryNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == 401 || category == CategoryAuthentication || category == CategorySession
+	case ErrForbidden:
+		return category == CategoryPermission
+	case ErrConflict:
+		return category == CategoryConflict
+	case ErrSessionInvalid:
+		return category == CategorySession
+	default:
+		return false
+	}
+}
+
 // IsNotFound returns true if the error is a 404 Not Found error.
 func (e *APIError) IsNotFound() bool {
 	return e.StatusCode == 404
@@ -85,14 +233,17 @@ func parseAPIError(resp *Response) error {
 	return apiErr
 }
 
-// IsAPIError returns true if the error is an APIError.
+// IsAPIError returns true if the error, or any error it wraps, is an APIError.
 func IsAPIError(err error) bool {
-	_, ok := err.(*APIError)
+	_, ok := AsAPIError(err)
 	return ok
 }
 
-// AsAPIError attempts to convert an error to an APIError.
+// AsAPIError finds the first APIError in the error's chain.
 func AsAPIError(err error) (*APIError, bool) {
-	apiErr, ok := err.(*APIError)
-	return apiErr, ok
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
 }
@@ -2,6 +2,8 @@
 package client
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -252,6 +254,11 @@ func TestIsAPIError(t *testing.T) {
 			err:      &APIError{StatusCode: 404, ErrorMsg: "Not found"},
 			expected: true,
 		},
+		{
+			name:     "wrapped APIError",
+			err:      fmt.Errorf("failed to get account: %w", &APIError{StatusCode: 404}),
+			expected: true,
+		},
 		{
 			name:     "other error type",
 			err:      &testError{msg: "test error"},
@@ -287,6 +294,12 @@ func TestAsAPIError(t *testing.T) {
 			wantOk:   true,
 			wantCode: 404,
 		},
+		{
+			name:     "doubly wrapped APIError",
+			err:      fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", &APIError{StatusCode: 409})),
+			wantOk:   true,
+			wantCode: 409,
+		},
 		{
 			name:   "other error type",
 			err:    &testError{msg: "test error"},
@@ -312,6 +325,108 @@ func TestAsAPIError(t *testing.T) {
 	}
 }
 
+func TestAPIError_Category(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiErr *APIError
+		want   ErrorCategory
+	}{
+		{name: "session expired code", apiErr: &APIError{StatusCode: 401, ErrorCode: "PASWS006E"}, want: CategorySession},
+		{name: "authentication failure code", apiErr: &APIError{StatusCode: 500, ErrorCode: "ITATS004E"}, want: CategoryAuthentication},
+		{name: "radius challenge code", apiErr: &APIError{StatusCode: 500, ErrorCode: "ITATS542I"}, want: CategoryChallenge},
+		{name: "lowercase code", apiErr: &APIError{StatusCode: 400, ErrorCode: "pasws167e"}, want: CategoryValidation},
+		{name: "safe already exists code", apiErr: &APIError{StatusCode: 409, ErrorCode: "SFWS0002"}, want: CategoryConflict},
+		{name: "duplicate by message", apiErr: &APIError{StatusCode: 400, ErrorCode: "PASWS999E", ErrorMsg: "Account already exists"}, want: CategoryConflict},
+		{name: "gen2 not found code", apiErr: &APIError{StatusCode: 400, ErrorCode: "CAWS00001E"}, want: CategoryNotFound},
+		{name: "account not found code", apiErr: &APIError{StatusCode: 500, ErrorCode: "PASWS164E"}, want: CategoryNotFound},
+		{name: "user already exists code", apiErr: &APIError{StatusCode: 400, ErrorCode: "PASWS120E"}, want: CategoryConflict},
+		{name: "account already exists code", apiErr: &APIError{StatusCode: 400, ErrorCode: "PASWS027E"}, want: CategoryConflict},
+		{name: "suspended user code", apiErr: &APIError{StatusCode: 403, ErrorCode: "ITATS203E"}, want: CategoryAuthentication},
+		{name: "missing by message", apiErr: &APIError{StatusCode: 500, ErrorMsg: "Safe Linux does not exist"}, want: CategoryNotFound},
+		{name: "status 403", apiErr: &APIError{StatusCode: 403}, want: CategoryPermission},
+		{name: "status 404", apiErr: &APIError{StatusCode: 404}, want: CategoryNotFound},
+		{name: "status 503", apiErr: &APIError{StatusCode: 503}, want: CategoryServer},
+		{name: "status 418", apiErr: &APIError{StatusCode: 418}, want: CategoryUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.apiErr.Category(); got != tt.want {
+				t.Errorf("Category() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIError_Is(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		matches []error
+		misses  []error
+	}{
+		{
+			name:    "not found",
+			err:     fmt.Errorf("failed to get safe: %w", &APIError{StatusCode: 404}),
+			matches: []error{ErrNotFound},
+			misses:  []error{ErrUnauthorized, ErrConflict, ErrSessionInvalid},
+		},
+		{
+			name:    "expired session",
+			err:     fmt.Errorf("failed to list accounts: %w", &APIError{StatusCode: 401, ErrorCode: "PASWS006E"}),
+			matches: []error{ErrUnauthorized, ErrSessionInvalid},
+			misses:  []error{ErrNotFound},
+		},
+		{
+			name:    "plain unauthorized",
+			err:     &APIError{StatusCode: 401},
+			matches: []error{ErrUnauthorized},
+			misses:  []error{ErrSessionInvalid},
+		},
+		{
+			name:    "conflict",
+			err:     fmt.Errorf("failed to add safe: %w", &APIError{StatusCode: 409}),
+			matches: []error{ErrConflict},
+			misses:  []error{ErrForbidden},
+		},
+		{
+			name:    "forbidden",
+			err:     &APIError{StatusCode: 403},
+			matches: []error{ErrForbidden},
+			misses:  []error{ErrUnauthorized},
+		},
+		{
+			name:   "server error",
+			err:    &APIError{StatusCode: 500},
+			misses: []error{ErrNotFound, ErrUnauthorized, ErrForbidden, ErrConflict, ErrSessionInvalid, ErrVersionUnsupported},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, target := range tt.matches {
+				if !errors.Is(tt.err, target) {
+					t.Errorf("errors.Is(%v, %v) = false, want true", tt.err, target)
+				}
+			}
+			for _, target := range tt.misses {
+				if errors.Is(tt.err, target) {
+					t.Errorf("errors.Is(%v, %v) = true, want false", tt.err, target)
+				}
+			}
+		})
+	}
+}
+
+func TestCategoryForErrorCode(t *testing.T) {
+	if got := CategoryForErrorCode(" PASWS013E "); got != CategoryAuthentication {
+		t.Errorf("CategoryForErrorCode(PASWS013E) = %q, want %q", got, CategoryAuthentication)
+	}
+	if got := CategoryForErrorCode("XYZ123"); got != CategoryUnknown {
+		t.Errorf("CategoryForErrorCode(XYZ123) = %q, want unknown", got)
+	}
+}
+
 // testError is a helper error type for testing
 type testError struct {
 	msg string
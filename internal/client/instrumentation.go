@@ -0,0 +1,140 @@
+// Package client provides call-level instrumentation hooks for tracing and metrics.
+package client
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// CallInfo describes a single Client.Do call. A call spans every attempt
+// made for the request, including retries and the replay after
+// re-authentication.
+type CallInfo struct {
+	// Method is the HTTP method
+	Method string
+
+	// Path is the request path relative to the API URL
+	Path string
+
+	// Route is the path with identifiers replaced by {id}, for example
+	// /Accounts/{id}/Password/Retrieve, suitable as a low-cardinality label
+	Route string
+
+	// StatusCode is the final HTTP status code, or zero if no response was received
+	StatusCode int
+
+	// ErrorCode is the CyberArk error code of a failed call, if any
+	ErrorCode string
+
+	// Err is the error returned to the caller, if any
+	Err error
+
+	// Duration is the total time spent in the call
+	Duration time.Duration
+}
+
+// Instrumentation observes every Client.Do call, for example to record
+// tracing spans and metrics. StartCall runs before the first attempt and may
+// return a derived context, which is used for the HTTP requests. The returned
+// function runs once the call completes, with the outcome filled in.
+type Instrumentation interface {
+	StartCall(ctx context.Context, call CallInfo) (context.Context, func(call CallInfo))
+}
+
+// instrumentedDo wraps do with the configured instrumentation.
+func (c *Client) instrumentedDo(ctx context.Context, req Request) (*Response, error) {
+	call := CallInfo{
+		Method: req.Method,
+		Path:   req.Path,
+		Route:  RouteTemplate(req.Path),
+	}
+
+	ctx, end := c.instrumentation.StartCall(ctx, call)
+	start := time.Now()
+
+	resp, err := c.do(ctx, req)
+
+	call.Duration = time.Since(start)
+	call.Err = err
+	if resp != nil {
+		call.StatusCode = resp.StatusCode
+	}
+	if apiErr, ok := AsAPIError(err); ok {
+		call.StatusCode = apiErr.StatusCode
+		call.ErrorCode = apiErr.ErrorCode
+	}
+	if end != nil {
+		end(call)
+	}
+
+	return resp, err
+}
+
+// routeCollections lists the path segments that are followed by an
+// identifier, such as /Accounts/{id} or /Safes/{id}/Members/{id}.
+var routeCollections = map[string]bool{
+	"account":                     true,
+	"accountgroups":               true,
+	"accounts":                    true,
+	"applications":                true,
+	"authenticationmethods":       true,
+	"authentications":             true,
+	"automaticonboardingrules":    true,
+	"cache":                       true,
+	"componentsmonitoringdetails": true,
+	"directories":                 true,
+	"discoveredaccounts":          true,
+	"events":                      true,
+	"incomingrequests":            true,
+	"linkaccount":                 true,
+	"livesessions":                true,
+	"mappings":                    true,
+	"members":                     true,
+	"myrequests":                  true,
+	"platforms":                   true,
+	"policy":                      true,
+	"privilegedcommands":          true,
+	"privilegedgroups":            true,
+	"privilegedusers":             true,
+	"recordings":                  true,
+	"reports":                     true,
+	"riskyactivities":             true,
+	"safes":                       true,
+	"schedules":                   true,
+	"sshkeys":                     true,
+	"usergroups":                  true,
+	"users":                       true,
+}
+
+// routeKeywords lists fixed segments that may follow a collection but are
+// not identifiers, such as /Reports/Schedules or /Platforms/import.
+var routeKeywords = map[string]bool{
+	"adhocconnect": true,
+	"cache":        true,
+	"clearcache":   true,
+	"import":       true,
+	"schedules":    true,
+	"targets":      true,
+	"userlicense":  true,
+}
+
+// RouteTemplate replaces the identifiers in an API path with {id}, so that
+// /Accounts/12_34/Password/Retrieve becomes /Accounts/{id}/Password/Retrieve.
+// Any query string is dropped.
+func RouteTemplate(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		prev := strings.ToLower(segments[i-1])
+		if segments[i] == "" || !routeCollections[prev] || routeKeywords[strings.ToLower(segments[i])] {
+			continue
+		}
+		segments[i] = "{id}"
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,102 @@
+// Package client provides tests for call-level instrumentation.
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+type ctxKey string
+
+// recordingInstrumentation captures the calls it observes.
+type recordingInstrumentation struct {
+	started []CallInfo
+	ended   []CallInfo
+}
+
+func (r *recordingInstrumentation) StartCall(ctx context.Context, call CallInfo) (context.Context, func(CallInfo)) {
+	r.started = append(r.started, call)
+	return context.WithValue(ctx, ctxKey("span"), "span-1"), func(call CallInfo) {
+		r.ended = append(r.ended, call)
+	}
+}
+
+func TestClient_Instrumentation(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"ErrorCode":"PASWS164E","ErrorMessage":"Account was not found"}`))
+	}))
+	defer server.Close()
+
+	inst := &recordingInstrumentation{}
+	var sawSpanContext bool
+	spanCheck := MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sawSpanContext = req.Context().Value(ctxKey("span")) == "span-1"
+			return next.RoundTrip(req)
+		})
+	})
+
+	c := newMiddlewareTestClient(t, server.URL, Config{
+		RetryPolicy:     fastRetryPolicy(2),
+		Middlewares:     []Middleware{spanCheck},
+		Instrumentation: inst,
+	})
+
+	if _, err := c.Get(context.Background(), "/Accounts/12_34/Password/Retrieve", nil); err == nil {
+		t.Fatal("Get() expected error")
+	}
+
+	if len(inst.started) != 1 || len(inst.ended) != 1 {
+		t.Fatalf("observed %d starts and %d ends, want one call covering both attempts", len(inst.started), len(inst.ended))
+	}
+	if !sawSpanContext {
+		t.Error("HTTP request did not carry the context returned by StartCall")
+	}
+
+	start, end := inst.started[0], inst.ended[0]
+	if start.Method != http.MethodGet || start.Route != "/Accounts/{id}/Password/Retrieve" {
+		t.Errorf("StartCall() call = %+v", start)
+	}
+	if end.StatusCode != http.StatusNotFound || end.ErrorCode != "PASWS164E" || end.Err == nil {
+		t.Errorf("end call = %+v, want 404 PASWS164E with error", end)
+	}
+	if end.Duration <= 0 {
+		t.Errorf("Duration = %v, want positive", end.Duration)
+	}
+}
+
+func TestRouteTemplate(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/Accounts", want: "/Accounts"},
+		{path: "/Accounts/12_34", want: "/Accounts/{id}"},
+		{path: "/Accounts/12_34/Password/Retrieve", want: "/Accounts/{id}/Password/Retrieve"},
+		{path: "/Accounts/AdHocConnect", want: "/Accounts/AdHocConnect"},
+		{path: "/Safes/Linux%20Admins/Members/jdoe", want: "/Safes/{id}/Members/{id}"},
+		{path: "/Safes/Users", want: "/Safes/{id}"},
+		{path: "/Users/42/Secret/SSHKeys/Cache/key-1", want: "/Users/{id}/Secret/SSHKeys/Cache/{id}"},
+		{path: "/Reports/Schedules/7", want: "/Reports/Schedules/{id}"},
+		{path: "/BulkActions/Accounts/job-1", want: "/BulkActions/Accounts/{id}"},
+		{path: "/Configuration/LDAP/Directories/corp.local/Mappings/5", want: "/Configuration/LDAP/Directories/{id}/Mappings/{id}"},
+		{path: "/WebServices/PIMServices.svc/Applications/App1/Authentications/3", want: "/WebServices/PIMServices.svc/Applications/{id}/Authentications/{id}"},
+		{path: "/Auth/CyberArk/Logon", want: "/Auth/CyberArk/Logon"},
+		{path: "/Accounts?search=root", want: "/Accounts"},
+	}
+
+	for _, tt := range tests {
+		if got := RouteTemplate(tt.path); got != tt.want {
+			t.Errorf("RouteTemplate(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
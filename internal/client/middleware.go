@@ -0,0 +1,235 @@
+// Package client provides the request middleware chain and built-in middlewares.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisranney/gopas/internal/helpers"
+)
+
+// Middleware wraps the transport used to send each request attempt, in the
+// same way an http.RoundTripper decorates another. Middlewares see every
+// attempt, including retries and the replay after re-authentication.
+//
+// Implementations must not modify the request they receive; clone it with
+// req.Clone before changing headers, as required by http.RoundTripper.
+type Middleware interface {
+	Wrap(next http.RoundTripper) http.RoundTripper
+}
+
+// MiddlewareFunc adapts an ordinary function to the Middleware interface.
+type MiddlewareFunc func(next http.RoundTripper) http.RoundTripper
+
+// Wrap calls f(next).
+func (f MiddlewareFunc) Wrap(next http.RoundTripper) http.RoundTripper {
+	return f(next)
+}
+
+// RoundTripperFunc adapts an ordinary function to the http.RoundTripper interface.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// chainMiddleware wraps the transport with the middlewares. The first
+// middleware is the outermost, so it sees the request first and the
+// response last.
+func chainMiddleware(transport http.RoundTripper, middlewares []Middleware) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			transport = middlewares[i].Wrap(transport)
+		}
+	}
+	return transport
+}
+
+// LoggingOptions configures the logging middleware.
+type LoggingOptions struct {
+	// Logger receives the log records (default: slog.Default())
+	Logger *slog.Logger
+
+	// Level is the level requests are logged at (default: slog.LevelInfo)
+	Level slog.Level
+
+	// LogBodies includes the request and response bodies, with secret
+	// fields redacted. Bodies that are not JSON are logged by size only.
+	LogBodies bool
+}
+
+// NewLoggingMiddleware returns a middleware that logs each request and its
+// outcome. Authorization and cookie headers, and any JSON field whose name
+// contains "password", "secret", "credential" or "token", are masked with
+// helpers.HideSecretValue before being logged.
+func NewLoggingMiddleware(opts LoggingOptions) Middleware {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			if !logger.Enabled(ctx, opts.Level) {
+				return next.RoundTrip(req)
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.Redacted()),
+				slog.Any("headers", redactHeaders(req.Header)),
+			}
+			if opts.LogBodies && req.Body != nil && req.GetBody != nil {
+				if body, err := req.GetBody(); err == nil {
+					data, _ := io.ReadAll(body)
+					body.Close()
+					attrs = append(attrs, slog.String("body", redactBody(data)))
+				}
+			}
+			logger.LogAttrs(ctx, opts.Level, "CyberArk API request", attrs...)
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			attrs = []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.Redacted()),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+				logger.LogAttrs(ctx, opts.Level, "CyberArk API request failed", attrs...)
+				return resp, err
+			}
+
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			if opts.LogBodies && resp.Body != nil {
+				data, readErr := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				resp.Body = io.NopCloser(bytes.NewReader(data))
+				if readErr != nil {
+					return resp, readErr
+				}
+				attrs = append(attrs, slog.String("body", redactBody(data)))
+			}
+			logger.LogAttrs(ctx, opts.Level, "CyberArk API response", attrs...)
+
+			return resp, nil
+		})
+	})
+}
+
+// TimingFunc receives the outcome of a request attempt. The status is zero
+// when the request failed before a response was received.
+type TimingFunc func(req *http.Request, status int, duration time.Duration, err error)
+
+// NewTimingMiddleware returns a middleware that measures how long each
+// request attempt takes and reports it to observe.
+func NewTimingMiddleware(observe TimingFunc) Middleware {
+	return MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			if observe != nil {
+				observe(req, status, time.Since(start), err)
+			}
+			return resp, err
+		})
+	})
+}
+
+// isSensitiveHeader reports whether a header carries credentials.
+func isSensitiveHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization":
+		return true
+	default:
+		return false
+	}
+}
+
+// isSensitiveField reports whether a JSON field name looks like it holds a secret.
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range []string{"password", "secret", "credential", "token", "authorization"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHeaders returns a copy of the headers with credentials masked.
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		value := strings.Join(values, ", ")
+		if isSensitiveHeader(name) {
+			value = helpers.HideSecretValue(value)
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// redactBody returns the body with secret fields masked. A body that is a
+// bare JSON string, such as the token returned by a logon, is masked whole.
+func redactBody(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "[" + http.DetectContentType(data) + ", " + strconv.Itoa(len(data)) + " bytes]"
+	}
+
+	if s, ok := v.(string); ok {
+		v = helpers.HideSecretValue(s)
+	} else {
+		v = redactValue(v)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "[" + strconv.Itoa(len(data)) + " bytes]"
+	}
+	return string(out)
+}
+
+// redactValue walks a decoded JSON value and masks secret fields.
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, field := range val {
+			if s, ok := field.(string); ok && isSensitiveField(key) {
+				val[key] = helpers.HideSecretValue(s)
+				continue
+			}
+			val[key] = redactValue(field)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactValue(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
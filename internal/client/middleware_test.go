@@ -0,0 +1,261 @@
+// Package client provides tests for the request middleware chain.
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordingMiddleware appends its name to order on the way in and out.
+func recordingMiddleware(name string, order *[]string) Middleware {
+	return MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*order = append(*order, name+":request")
+			resp, err := next.RoundTrip(req)
+			*order = append(*order, name+":response")
+			return resp, err
+		})
+	})
+}
+
+func newMiddlewareTestClient(t *testing.T, serverURL string, cfg Config) *Client {
+	t.Helper()
+	cfg.BaseURL = serverURL
+	c, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	c.apiURL = serverURL
+	return c
+}
+
+func TestClient_Middlewares_Order(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Trace-Id"); got != "trace-1" {
+			t.Errorf("X-Trace-Id = %q, want trace-1", got)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var order []string
+	tracing := MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Set("X-Trace-Id", "trace-1")
+			return next.RoundTrip(req)
+		})
+	})
+
+	c := newMiddlewareTestClient(t, server.URL, Config{
+		Middlewares: []Middleware{recordingMiddleware("outer", &order), tracing, recordingMiddleware("inner", &order)},
+	})
+
+	if _, err := c.Get(context.Background(), "/test", nil); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+
+	want := []string{"outer:request", "inner:request", "inner:response", "outer:response"}
+	if strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("middleware order = %v, want %v", order, want)
+	}
+}
+
+func TestClient_Middlewares_SeeEveryAttempt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Fail the first attempt before it reaches the server
+	var attempts int32
+	faults := MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       io.NopCloser(strings.NewReader("")),
+					Header:     http.Header{},
+					Request:    req,
+				}, nil
+			}
+			return next.RoundTrip(req)
+		})
+	})
+
+	c := newMiddlewareTestClient(t, server.URL, Config{
+		RetryPolicy: fastRetryPolicy(2),
+		Middlewares: []Middleware{faults},
+	})
+
+	if _, err := c.Get(context.Background(), "/test", nil); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("middleware saw %d attempts, want 2", got)
+	}
+}
+
+func TestClient_Middlewares_CustomHTTPClientUntouched(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	custom := &http.Client{Timeout: 5 * time.Second}
+	var calls int32
+	c := newMiddlewareTestClient(t, server.URL, Config{
+		CustomHTTPClient: custom,
+		Middlewares: []Middleware{NewTimingMiddleware(func(*http.Request, int, time.Duration, error) {
+			atomic.AddInt32(&calls, 1)
+		})},
+	})
+
+	if _, err := c.Get(context.Background(), "/test", nil); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if custom.Transport != nil {
+		t.Error("NewClient() modified the caller's http.Client")
+	}
+	if c.httpClient.Timeout != 5*time.Second {
+		t.Errorf("Timeout = %v, want the custom client's 5s", c.httpClient.Timeout)
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("timing middleware called %d times, want 1", calls)
+	}
+}
+
+func TestNewTimingMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	var gotStatus int
+	var gotDuration time.Duration
+	var gotPath string
+	c := newMiddlewareTestClient(t, server.URL, Config{
+		Middlewares: []Middleware{NewTimingMiddleware(func(req *http.Request, status int, d time.Duration, err error) {
+			gotPath, gotStatus, gotDuration = req.URL.Path, status, d
+		})},
+	})
+
+	_, _ = c.Get(context.Background(), "/Accounts", nil)
+	if gotStatus != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", gotStatus)
+	}
+	if gotDuration < 5*time.Millisecond {
+		t.Errorf("duration = %v, want at least 5ms", gotDuration)
+	}
+	if gotPath != "/Accounts" {
+		t.Errorf("path = %q, want /Accounts", gotPath)
+	}
+
+	// Transport errors are reported with a zero status
+	failing := NewTimingMiddleware(func(req *http.Request, status int, d time.Duration, err error) {
+		gotStatus = status
+		if err == nil {
+			t.Error("expected transport error")
+		}
+	}).Wrap(RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}))
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	_, _ = failing.RoundTrip(req)
+	if gotStatus != 0 {
+		t.Errorf("status = %d, want 0 for transport error", gotStatus)
+	}
+}
+
+func TestNewLoggingMiddleware_Redacts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "SuperSecret123") {
+			t.Errorf("server received redacted body: %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`"session-token-abcdef"`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	c := newMiddlewareTestClient(t, server.URL, Config{
+		Middlewares: []Middleware{NewLoggingMiddleware(LoggingOptions{Logger: logger, Level: slog.LevelDebug, LogBodies: true})},
+	})
+	c.SetAuthToken("auth-token-123456")
+
+	resp, err := c.Post(context.Background(), "/Auth/CyberArk/Logon", map[string]interface{}{
+		"username": "admin",
+		"password": "SuperSecret123",
+		"nested":   map[string]string{"clientSecret": "TopSecretValue"},
+	})
+	if err != nil {
+		t.Fatalf("Post() unexpected error: %v", err)
+	}
+	if string(resp.Body) != `"session-token-abcdef"` {
+		t.Errorf("response body = %s, middleware must leave it readable", resp.Body)
+	}
+
+	logged := buf.String()
+	for _, secret := range []string{"SuperSecret123", "TopSecretValue", "auth-token-123456", "session-token-abcdef"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("log output contains secret %q:\n%s", secret, logged)
+		}
+	}
+	for _, want := range []string{"admin", "/Auth/CyberArk/Logon", "status=200", "Su****23"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("log output missing %q:\n%s", want, logged)
+		}
+	}
+}
+
+func TestNewLoggingMiddleware_Disabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	rt := NewLoggingMiddleware(LoggingOptions{Logger: logger}).Wrap(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip() unexpected error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("records logged below the handler level: %s", buf.String())
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "empty", in: "", want: ""},
+		{name: "secret fields", in: `{"Password":"abcdefgh","UserName":"bob"}`, want: `{"Password":"ab****gh","UserName":"bob"}`},
+		{name: "array of objects", in: `[{"secretValue":"abcdefgh"}]`, want: `[{"secretValue":"ab****gh"}]`},
+		{name: "bare token", in: `"tok"`, want: `"****"`},
+		{name: "not json", in: `password=hunter2`, want: `[text/plain; charset=utf-8, 16 bytes]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactBody([]byte(tt.in)); got != tt.want {
+				t.Errorf("redactBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Package client provides client-side rate limiting for CyberArk API requests.
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimit throttles requests on the client side so bulk jobs do not
+// overwhelm PVWA. The limits apply to every request attempt made through the
+// client, and so to every session and clone sharing it.
+type RateLimit struct {
+	// RequestsPerSecond is the sustained request rate (0 disables rate limiting)
+	RequestsPerSecond float64
+
+	// Burst is the number of requests that may be sent at once before the
+	// rate applies (default: 1)
+	Burst int
+
+	// MaxInFlight caps the number of concurrent requests (0 means unlimited)
+	MaxInFlight int
+}
+
+// limiter enforces a RateLimit with a token bucket and a semaphore.
+type limiter struct {
+	bucket   *tokenBucket
+	inFlight chan struct{}
+}
+
+// newLimiter returns a limiter for the configuration, or nil if it imposes no limits.
+func newLimiter(cfg *RateLimit) *limiter {
+	if cfg == nil || (cfg.RequestsPerSecond <= 0 && cfg.MaxInFlight <= 0) {
+		return nil
+	}
+
+	l := &limiter{}
+	if cfg.RequestsPerSecond > 0 {
+		l.bucket = newTokenBucket(cfg.RequestsPerSecond, cfg.Burst)
+	}
+	if cfg.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, cfg.MaxInFlight)
+	}
+	return l
+}
+
+// acquire blocks until a request may be sent and returns the function that
+// releases its in-flight slot. It gives up as soon as the context is done.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+			release = func() { <-l.inFlight }
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for a request slot: %w", ctx.Err())
+		}
+	}
+
+	if l.bucket != nil {
+		if err := l.bucket.wait(ctx); err != nil {
+			release()
+			return nil, fmt.Errorf("waiting for rate limit: %w", err)
+		}
+	}
+
+	return release, nil
+}
+
+// tokenBucket is a token bucket refilled continuously at rate tokens per second.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// reserve takes a token, possibly going into debt, and returns how long the
+// caller must wait before the token is actually available.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token that was not used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// wait blocks until a token is available. If the context's deadline would
+// pass before then, it fails immediately rather than sleeping in vain.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	delay := b.reserve()
+	if delay == 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && b.now().Add(delay).After(deadline) {
+		b.cancel()
+		return context.DeadlineExceeded
+	}
+
+	if err := sleepContext(ctx, delay); err != nil {
+		b.cancel()
+		return err
+	}
+	return nil
+}
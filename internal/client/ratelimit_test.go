@@ -0,0 +1,197 @@
+// Package client provides tests for client-side rate limiting.
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewLimiter_Disabled(t *testing.T) {
+	if newLimiter(nil) != nil {
+		t.Error("newLimiter(nil) should disable limiting")
+	}
+	if newLimiter(&RateLimit{}) != nil {
+		t.Error("newLimiter() with zero limits should disable limiting")
+	}
+}
+
+func TestTokenBucket_Reserve(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newTokenBucket(10, 2)
+	b.last = now
+	b.now = func() time.Time { return now }
+
+	// The burst is available immediately
+	if d := b.reserve(); d != 0 {
+		t.Errorf("first reserve() = %v, want 0", d)
+	}
+	if d := b.reserve(); d != 0 {
+		t.Errorf("second reserve() = %v, want 0", d)
+	}
+
+	// Then each token costs 1/rate
+	if d := b.reserve(); d != 100*time.Millisecond {
+		t.Errorf("third reserve() = %v, want 100ms", d)
+	}
+	if d := b.reserve(); d != 200*time.Millisecond {
+		t.Errorf("fourth reserve() = %v, want 200ms", d)
+	}
+
+	// Tokens refill over time, up to the burst
+	now = now.Add(time.Second)
+	b.tokens = -2
+	if d := b.reserve(); d != 0 {
+		t.Errorf("reserve() after refill = %v, want 0", d)
+	}
+	if b.tokens > b.burst {
+		t.Errorf("tokens = %v, exceeds burst %v", b.tokens, b.burst)
+	}
+}
+
+func TestTokenBucket_WaitDeadline(t *testing.T) {
+	b := newTokenBucket(1, 1)
+	b.reserve()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := b.wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait() error = %v, want DeadlineExceeded", err)
+	}
+	if time.Since(start) > 5*time.Millisecond {
+		t.Error("wait() slept although the deadline could not be met")
+	}
+	if b.tokens < -0.01 {
+		t.Errorf("tokens = %v, reservation was not returned", b.tokens)
+	}
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newMiddlewareTestClient(t, server.URL, Config{
+		RateLimit: &RateLimit{RequestsPerSecond: 50, Burst: 1},
+	})
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := c.Get(context.Background(), "/test", nil); err != nil {
+			t.Fatalf("Get() unexpected error: %v", err)
+		}
+	}
+
+	// One request from the burst, then four at 20ms intervals
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("5 requests took %v, want at least 70ms at 50 req/s", elapsed)
+	}
+	if got := atomic.LoadInt32(&calls); got != 5 {
+		t.Errorf("server calls = %d, want 5", got)
+	}
+}
+
+func TestClient_RateLimit_RespectsContext(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newMiddlewareTestClient(t, server.URL, Config{
+		RateLimit:   &RateLimit{RequestsPerSecond: 0.1},
+		RetryPolicy: fastRetryPolicy(3),
+	})
+
+	if _, err := c.Get(context.Background(), "/test", nil); err != nil {
+		t.Fatalf("first Get() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Get(ctx, "/test", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get() error = %v, want DeadlineExceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Get() waited past the context deadline")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("server calls = %d, want 1", got)
+	}
+}
+
+func TestClient_MaxInFlight(t *testing.T) {
+	var current, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newMiddlewareTestClient(t, server.URL, Config{
+		RateLimit: &RateLimit{MaxInFlight: 2},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get(context.Background(), "/test", nil); err != nil {
+				t.Errorf("Get() unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&peak); got > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", got)
+	}
+}
+
+func TestClient_MaxInFlight_RespectsContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := newMiddlewareTestClient(t, server.URL, Config{
+		RateLimit: &RateLimit{MaxInFlight: 1},
+	})
+
+	go c.Get(context.Background(), "/slow", nil)
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Get(ctx, "/test", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want DeadlineExceeded while the slot is taken", err)
+	}
+}
@@ -0,0 +1,87 @@
+// Package client provides transparent re-authentication for expired sessions.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// TokenRefresher logs in again and returns a new authentication token.
+// It is invoked when a request fails with 401 Unauthorized.
+type TokenRefresher func(ctx context.Context) (string, error)
+
+// refreshCall tracks an in-flight re-authentication shared by concurrent callers.
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+type reauthContextKey struct{}
+
+// SetTokenRefresher configures the function used to obtain a new token after
+// a 401 response. Passing nil disables re-authentication.
+func (c *Client) SetTokenRefresher(refresher TokenRefresher) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refresher = refresher
+}
+
+// shouldReauthenticate reports whether a failed request should trigger a
+// re-authentication. Requests issued by the refresher itself never do, so a
+// rejected logon cannot recurse.
+func (c *Client) shouldReauthenticate(ctx context.Context, err error) bool {
+	if err == nil || ctx.Value(reauthContextKey{}) != nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.IsUnauthorized() {
+		return false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.refresher != nil
+}
+
+// refreshToken obtains a new token unless one newer than staleToken is
+// already in place. Concurrent callers share a single in-flight refresh.
+func (c *Client) refreshToken(ctx context.Context, staleToken string) error {
+	c.mu.Lock()
+	if c.authToken != staleToken {
+		// Another caller already refreshed the token
+		c.mu.Unlock()
+		return nil
+	}
+	if call := c.refreshing; call != nil {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	call := &refreshCall{done: make(chan struct{})}
+	c.refreshing = call
+	refresher := c.refresher
+	c.mu.Unlock()
+
+	token, err := refresher(context.WithValue(ctx, reauthContextKey{}, true))
+	if err == nil && token == "" {
+		err = fmt.Errorf("no authentication token received")
+	}
+
+	c.mu.Lock()
+	if err == nil {
+		c.authToken = token
+	}
+	c.refreshing = nil
+	c.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+	return err
+}
@@ -0,0 +1,187 @@
+// Package client provides tests for transparent re-authentication.
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer accepts only requests carrying the current valid token.
+func tokenServer(validToken *atomic.Value, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if r.Header.Get("Authorization") != validToken.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ErrorCode":"PASWS013E","ErrorMessage":"Session expired"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func TestClient_Do_ReauthenticatesOn401(t *testing.T) {
+	var validToken atomic.Value
+	validToken.Store("fresh-token")
+	var hits int32
+
+	server := tokenServer(&validToken, &hits)
+	defer server.Close()
+
+	c, _ := NewClient(Config{BaseURL: server.URL})
+	c.apiURL = server.URL
+	c.SetAuthToken("expired-token")
+
+	var refreshes int32
+	c.SetTokenRefresher(func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&refreshes, 1)
+		return "fresh-token", nil
+	})
+
+	resp, err := c.Get(context.Background(), "/Accounts", nil)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want 200", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(&refreshes); got != 1 {
+		t.Errorf("refresher called %d times, want 1", got)
+	}
+	if got := c.GetAuthToken(); got != "fresh-token" {
+		t.Errorf("GetAuthToken() = %q, want fresh-token", got)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("server hits = %d, want 2", got)
+	}
+}
+
+func TestClient_Do_NoRefresherReturns401(t *testing.T) {
+	var validToken atomic.Value
+	validToken.Store("fresh-token")
+	var hits int32
+
+	server := tokenServer(&validToken, &hits)
+	defer server.Close()
+
+	c, _ := NewClient(Config{BaseURL: server.URL})
+	c.apiURL = server.URL
+	c.SetAuthToken("expired-token")
+
+	_, err := c.Get(context.Background(), "/Accounts", nil)
+	apiErr, ok := AsAPIError(err)
+	if !ok || !apiErr.IsUnauthorized() {
+		t.Fatalf("expected 401 APIError, got %v", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("server hits = %d, want 1", got)
+	}
+}
+
+func TestClient_Do_RefresherFailure(t *testing.T) {
+	var validToken atomic.Value
+	validToken.Store("fresh-token")
+	var hits int32
+
+	server := tokenServer(&validToken, &hits)
+	defer server.Close()
+
+	c, _ := NewClient(Config{BaseURL: server.URL})
+	c.apiURL = server.URL
+	c.SetAuthToken("expired-token")
+
+	refreshErr := errors.New("vault unreachable")
+	c.SetTokenRefresher(func(ctx context.Context) (string, error) {
+		return "", refreshErr
+	})
+
+	_, err := c.Get(context.Background(), "/Accounts", nil)
+	if !errors.Is(err, refreshErr) {
+		t.Errorf("expected refresher error in chain, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.IsUnauthorized() {
+		t.Errorf("expected 401 APIError in chain, got %v", err)
+	}
+	if got := c.GetAuthToken(); got != "expired-token" {
+		t.Errorf("token should be unchanged after failed refresh, got %q", got)
+	}
+}
+
+func TestClient_Do_RefresherDoesNotRecurse(t *testing.T) {
+	var validToken atomic.Value
+	validToken.Store("never-valid")
+	var hits int32
+
+	server := tokenServer(&validToken, &hits)
+	defer server.Close()
+
+	c, _ := NewClient(Config{BaseURL: server.URL})
+	c.apiURL = server.URL
+	c.SetAuthToken("expired-token")
+
+	var refreshes int32
+	c.SetTokenRefresher(func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&refreshes, 1)
+		// A logon rejected with 401 must not trigger another refresh
+		if _, err := c.Post(ctx, "/Auth/CyberArk/Logon", nil); err != nil {
+			return "", err
+		}
+		return "new-token", nil
+	})
+
+	if _, err := c.Get(context.Background(), "/Accounts", nil); err == nil {
+		t.Fatal("Get() expected error")
+	}
+	if got := atomic.LoadInt32(&refreshes); got != 1 {
+		t.Errorf("refresher called %d times, want 1", got)
+	}
+}
+
+func TestClient_Do_ConcurrentRefreshShared(t *testing.T) {
+	var validToken atomic.Value
+	validToken.Store("fresh-token")
+	var hits int32
+
+	server := tokenServer(&validToken, &hits)
+	defer server.Close()
+
+	c, _ := NewClient(Config{BaseURL: server.URL})
+	c.apiURL = server.URL
+	c.SetAuthToken("expired-token")
+
+	var refreshes int32
+	c.SetTokenRefresher(func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&refreshes, 1)
+		time.Sleep(50 * time.Millisecond)
+		return "fresh-token", nil
+	})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get(context.Background(), "/Accounts", nil); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Get() unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&refreshes); got != 1 {
+		t.Errorf("refresher called %d times, want 1", got)
+	}
+}
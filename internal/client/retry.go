@@ -0,0 +1,167 @@
+// Package client provides retry support for CyberArk API requests.
+package client
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+// A nil policy, or one with MaxAttempts of 1 or less, disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry (default: 500ms)
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts, including delays requested
+	// with Retry-After (default: 30s)
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction (0-1) of each delay that is randomized
+	Jitter float64
+
+	// RetryableStatusCodes lists the HTTP status codes that trigger a retry
+	// (default: 429, 502, 503, 504)
+	RetryableStatusCodes []int
+
+	// RetryNonIdempotent allows POST and PATCH requests to be retried.
+	// Leave disabled unless replaying the operation is safe, since requests
+	// such as an immediate credential change may already have been accepted.
+	RetryNonIdempotent bool
+
+	// IgnoreRetryAfter disables honoring the Retry-After response header
+	IgnoreRetryAfter bool
+}
+
+// DefaultRetryPolicy returns a retry policy suitable for most workloads.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       500 * time.Millisecond,
+		MaxBackoff:           30 * time.Second,
+		Jitter:               0.2,
+		RetryableStatusCodes: defaultRetryableStatusCodes(),
+	}
+}
+
+func defaultRetryableStatusCodes() []int {
+	return []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+}
+
+// maxAttempts returns the effective number of attempts for the policy.
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// allowsMethod reports whether requests with the given method may be retried.
+func (p *RetryPolicy) allowsMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPatch:
+		return p.RetryNonIdempotent
+	default:
+		return true
+	}
+}
+
+// isRetryableStatus reports whether the status code should trigger a retry.
+func (p *RetryPolicy) isRetryableStatus(statusCode int) bool {
+	codes := p.RetryableStatusCodes
+	if len(codes) == 0 {
+		codes = defaultRetryableStatusCodes()
+	}
+	for _, code := range codes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry (1 for the first retry).
+// A Retry-After header on the previous response takes precedence, capped at
+// MaxBackoff so a server cannot stall the call indefinitely.
+func (p *RetryPolicy) backoff(retry int, resp *Response) time.Duration {
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+
+	if !p.IgnoreRetryAfter && resp != nil {
+		if d, ok := parseRetryAfter(resp.Headers.Get("Retry-After"), time.Now()); ok {
+			return min(d, maxBackoff)
+		}
+	}
+
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	jitter := p.Jitter
+	if jitter < 0 || jitter > 1 {
+		jitter = 0.2
+	}
+
+	delay := float64(initial) * math.Pow(2, float64(retry-1))
+	if delay > float64(maxBackoff) {
+		delay = float64(maxBackoff)
+	}
+	delay -= delay * jitter * rand.Float64()
+
+	return time.Duration(delay)
+}
+
+// parseRetryAfter parses a Retry-After header given either as a number of
+// seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// sleepContext waits for the given duration or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
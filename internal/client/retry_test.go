@@ -0,0 +1,305 @@
+// Package client provides tests for request retry handling.
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRetryTestClient creates a client with a fast retry policy pointed at the server.
+func newRetryTestClient(t *testing.T, serverURL string, policy *RetryPolicy) *Client {
+	t.Helper()
+	c, err := NewClient(Config{BaseURL: serverURL, RetryPolicy: policy})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	c.apiURL = serverURL
+	return c
+}
+
+func fastRetryPolicy(maxAttempts int) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}
+}
+
+func TestClient_Do_RetriesRetryableStatus(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	c := newRetryTestClient(t, server.URL, fastRetryPolicy(3))
+
+	resp, err := c.Get(context.Background(), "/test", nil)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("server calls = %d, want 3", got)
+	}
+}
+
+func TestClient_Do_RetryExhausted(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	c := newRetryTestClient(t, server.URL, fastRetryPolicy(2))
+
+	_, err := c.Get(context.Background(), "/test", nil)
+	apiErr, ok := AsAPIError(err)
+	if !ok {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, http.StatusBadGateway)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("server calls = %d, want 2", got)
+	}
+}
+
+func TestClient_Do_NoRetryForNonRetryableStatus(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := newRetryTestClient(t, server.URL, fastRetryPolicy(3))
+
+	if _, err := c.Get(context.Background(), "/test", nil); err == nil {
+		t.Fatal("Get() expected error")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("server calls = %d, want 1", got)
+	}
+}
+
+func TestClient_Do_NonIdempotentRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		optIn     bool
+		wantCalls int32
+	}{
+		{name: "POST not retried by default", optIn: false, wantCalls: 1},
+		{name: "POST retried when opted in", optIn: true, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			policy := fastRetryPolicy(3)
+			policy.RetryNonIdempotent = tt.optIn
+			c := newRetryTestClient(t, server.URL, policy)
+
+			if _, err := c.Post(context.Background(), "/Accounts/1/Change", map[string]bool{}); err == nil {
+				t.Fatal("Post() expected error")
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("server calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClient_Do_RetryResendsBody(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 64)
+		n, _ := r.Body.Read(buf)
+		if string(buf[:n]) != `{"key":"value"}` {
+			t.Errorf("attempt %d body = %q", atomic.LoadInt32(&calls)+1, string(buf[:n]))
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newRetryTestClient(t, server.URL, fastRetryPolicy(2))
+
+	if _, err := c.Put(context.Background(), "/test", map[string]string{"key": "value"}); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+}
+
+func TestClient_Do_RetryStopsOnContextCancel(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// Honor the full Retry-After so the wait outlasts the context
+	policy := fastRetryPolicy(5)
+	policy.MaxBackoff = time.Minute
+	c := newRetryTestClient(t, server.URL, policy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Get(ctx, "/test", nil)
+	if err == nil {
+		t.Fatal("Get() expected error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded in error chain, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Errorf("expected APIError in error chain, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("retry did not stop when the context was cancelled")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("server calls = %d, want 1", got)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     300 * time.Millisecond,
+		Jitter:         0,
+	}
+
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{retry: 1, want: 100 * time.Millisecond},
+		{retry: 2, want: 200 * time.Millisecond},
+		{retry: 3, want: 300 * time.Millisecond},
+		{retry: 10, want: 300 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := policy.backoff(tt.retry, nil); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.retry, got, tt.want)
+		}
+	}
+
+	resp := &Response{Headers: http.Header{"Retry-After": {"2"}}}
+	if got := (&RetryPolicy{MaxBackoff: 5 * time.Second}).backoff(1, resp); got != 2*time.Second {
+		t.Errorf("backoff with Retry-After = %v, want 2s", got)
+	}
+
+	// A long Retry-After is capped at MaxBackoff
+	if got := policy.backoff(1, resp); got != 300*time.Millisecond {
+		t.Errorf("backoff with Retry-After above MaxBackoff = %v, want 300ms", got)
+	}
+	long := &Response{Headers: http.Header{"Retry-After": {"86400"}}}
+	if got := (&RetryPolicy{}).backoff(1, long); got != 30*time.Second {
+		t.Errorf("backoff with Retry-After of a day = %v, want the 30s default cap", got)
+	}
+
+	policy.IgnoreRetryAfter = true
+	if got := policy.backoff(1, resp); got != 100*time.Millisecond {
+		t.Errorf("backoff ignoring Retry-After = %v, want 100ms", got)
+	}
+}
+
+func TestRetryPolicy_BackoffJitter(t *testing.T) {
+	policy := &RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Jitter:         0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		got := policy.backoff(1, nil)
+		if got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("backoff() = %v, want between 50ms and 100ms", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "empty", value: "", wantOK: false},
+		{name: "seconds", value: "5", want: 5 * time.Second, wantOK: true},
+		{name: "negative seconds", value: "-1", wantOK: false},
+		{name: "http date", value: "Mon, 01 Jan 2024 12:00:10 GMT", want: 10 * time.Second, wantOK: true},
+		{name: "date in past", value: "Mon, 01 Jan 2024 11:00:00 GMT", want: 0, wantOK: true},
+		{name: "garbage", value: "soon", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if ok != tt.wantOK {
+				t.Fatalf("parseRetryAfter() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Errorf("parseRetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Defaults(t *testing.T) {
+	var nilPolicy *RetryPolicy
+	if nilPolicy.maxAttempts() != 1 {
+		t.Errorf("nil policy maxAttempts() = %d, want 1", nilPolicy.maxAttempts())
+	}
+
+	policy := DefaultRetryPolicy()
+	if policy.maxAttempts() != 3 {
+		t.Errorf("DefaultRetryPolicy().maxAttempts() = %d, want 3", policy.maxAttempts())
+	}
+	if policy.allowsMethod(http.MethodPost) {
+		t.Error("DefaultRetryPolicy() should not retry POST")
+	}
+	if !policy.allowsMethod(http.MethodGet) || !policy.allowsMethod(http.MethodDelete) {
+		t.Error("DefaultRetryPolicy() should retry GET and DELETE")
+	}
+	for _, code := range []int{429, 502, 503, 504} {
+		if !policy.isRetryableStatus(code) {
+			t.Errorf("status %d should be retryable", code)
+		}
+	}
+	if policy.isRetryableStatus(500) {
+		t.Error("status 500 should not be retryable by default")
+	}
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chrisranney/gopas/pkg/tlsconfig"
+)
+
+// newTransport returns an HTTP transport using the configured TLS and
+// proxy settings.
+func newTransport(cfg Config) (*http.Transport, error) {
+	tlsOptions := tlsconfig.Config{}
+	if cfg.TLS != nil {
+		tlsOptions = *cfg.TLS
+	}
+	if cfg.SkipTLSVerify {
+		tlsOptions.InsecureSkipVerify = true
+	}
+	tlsConfig, err := tlsOptions.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyURL, err := parseProxyURL(cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	if cfg.NoProxy != "" {
+		proxy = bypassProxy(proxy, parseNoProxy(cfg.NoProxy))
+	}
+	transport.Proxy = proxy
+
+	return transport, nil
+}
+
+// parseProxyURL validates a proxy URL. A URL without a scheme is treated
+// as an http proxy.
+func parseProxyURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q: use http, https or socks5", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", raw)
+	}
+	return u, nil
+}
+
+// noProxyRule is one entry of a NO_PROXY list.
+type noProxyRule struct {
+	all    bool
+	ipNet  *net.IPNet
+	ip     net.IP
+	domain string // lower case, without a leading dot
+	exact  bool   // a leading dot matches subdomains only
+	port   string
+}
+
+// parseNoProxy parses a NO_PROXY list such as
+// "localhost,.internal.example.com,10.0.0.0/8,vault.example.com:8443".
+func parseNoProxy(list string) []noProxyRule {
+	var rules []noProxyRule
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return []noProxyRule{{all: true}}
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			rules = append(rules, noProxyRule{ipNet: ipNet})
+			continue
+		}
+
+		host, port := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			host, port = h, p
+		}
+		if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+			rules = append(rules, noProxyRule{ip: ip, port: port})
+			continue
+		}
+
+		host = strings.TrimPrefix(host, "*")
+		rule := noProxyRule{
+			port:   port,
+			domain: strings.TrimPrefix(host, "."),
+			exact:  !strings.HasPrefix(host, "."),
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// matches reports whether a request to host and port bypasses the proxy.
+// A bare domain matches the host itself and its subdomains; a domain with a
+// leading dot matches subdomains only.
+func (r noProxyRule) matches(host, port string) bool {
+	if r.all {
+		return true
+	}
+	if r.port != "" && r.port != port {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if r.ipNet != nil {
+			return r.ipNet.Contains(ip)
+		}
+		return r.ip != nil && r.ip.Equal(ip)
+	}
+	if r.domain == "" {
+		return false
+	}
+	if strings.HasSuffix(host, "."+r.domain) {
+		return true
+	}
+	return r.exact && host == r.domain
+}
+
+// bypassProxy wraps a proxy function so that hosts matching the NO_PROXY
+// rules are reached directly.
+func bypassProxy(proxy func(*http.Request) (*url.URL, error), rules []noProxyRule) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		host := strings.ToLower(req.URL.Hostname())
+		port := req.URL.Port()
+		if port == "" {
+			port = "80"
+			if req.URL.Scheme == "https" {
+				port = "443"
+			}
+		}
+		for _, rule := range rules {
+			if rule.matches(host, port) {
+				return nil, nil
+			}
+		}
+		return proxy(req)
+	}
+}
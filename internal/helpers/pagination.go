@@ -0,0 +1,111 @@
+// Package helpers provides pagination utilities for list APIs.
+// This is equivalent to the nextLink handling in Invoke-PASRestMethod in psPAS.
+package helpers
+
+import (
+	"context"
+	"iter"
+
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+// Page holds a single page of results returned by a list API.
+type Page[T any] struct {
+	Items    []T
+	Total    int
+	NextLink string
+}
+
+// PageFetcher retrieves the page of results starting at the given offset.
+type PageFetcher[T any] func(ctx context.Context, offset int) (*Page[T], error)
+
+// Paginate returns an iterator over every item of a paged list API, starting
+// at the given offset. The next page is located from the page's nextLink when
+// present, otherwise from its total count. Iteration stops at the first error,
+// which is yielded alongside the zero value of T.
+func Paginate[T any](ctx context.Context, fetch PageFetcher[T], offset int, opts types.PageOptions) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		yielded := 0
+		next := fetchPage(ctx, fetch, offset, false)
+
+		for {
+			page, err := next()
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			nextOffset, more := nextPageOffset(offset, page)
+			if more && opts.MaxItems > 0 && yielded+len(page.Items) >= opts.MaxItems {
+				more = false
+			}
+			if more {
+				next = fetchPage(ctx, fetch, nextOffset, opts.Prefetch)
+			}
+
+			for _, item := range page.Items {
+				if opts.MaxItems > 0 && yielded >= opts.MaxItems {
+					return
+				}
+				if !yield(item, nil) {
+					return
+				}
+				yielded++
+			}
+
+			if !more {
+				return
+			}
+			offset = nextOffset
+		}
+	}
+}
+
+// fetchPage returns a function that yields the page at the given offset.
+// When async is true the request is started immediately in the background.
+func fetchPage[T any](ctx context.Context, fetch PageFetcher[T], offset int, async bool) func() (*Page[T], error) {
+	if !async {
+		return func() (*Page[T], error) {
+			return fetch(ctx, offset)
+		}
+	}
+
+	type result struct {
+		page *Page[T]
+		err  error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		page, err := fetch(ctx, offset)
+		ch <- result{page: page, err: err}
+	}()
+
+	return func() (*Page[T], error) {
+		r := <-ch
+		return r.page, r.err
+	}
+}
+
+// nextPageOffset determines the offset of the page following the given one.
+func nextPageOffset[T any](offset int, page *Page[T]) (int, bool) {
+	if page == nil || len(page.Items) == 0 {
+		return 0, false
+	}
+
+	if page.NextLink != "" {
+		if next, err := ParseNextLink(page.NextLink); err == nil && next > offset {
+			return next, true
+		}
+	}
+
+	if page.Total > 0 && offset+len(page.Items) < page.Total {
+		return offset + len(page.Items), true
+	}
+
+	return 0, false
+}
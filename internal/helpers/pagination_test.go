@@ -0,0 +1,165 @@
+// Package helpers provides tests for pagination utilities.
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+// pagedSource serves a fixed list of items in pages of the given size.
+type pagedSource struct {
+	items    []int
+	pageSize int
+	useLink  bool
+	calls    int32
+	failAt   int
+}
+
+func (s *pagedSource) fetch(ctx context.Context, offset int) (*Page[int], error) {
+	atomic.AddInt32(&s.calls, 1)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.failAt > 0 && offset >= s.failAt {
+		return nil, errors.New("page failed")
+	}
+
+	end := offset + s.pageSize
+	if end > len(s.items) {
+		end = len(s.items)
+	}
+	page := &Page[int]{Items: s.items[offset:end]}
+	if s.useLink {
+		if end < len(s.items) {
+			page.NextLink = fmt.Sprintf("api/Items?offset=%d&limit=%d", end, s.pageSize)
+		}
+	} else {
+		page.Total = len(s.items)
+	}
+	return page, nil
+}
+
+func newPagedSource(n, pageSize int, useLink bool) *pagedSource {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	return &pagedSource{items: items, pageSize: pageSize, useLink: useLink}
+}
+
+func collect(t *testing.T, seq func(func(int, error) bool)) ([]int, error) {
+	t.Helper()
+	var got []int
+	for item, err := range seq {
+		if err != nil {
+			return got, err
+		}
+		got = append(got, item)
+	}
+	return got, nil
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     int
+		pageSize  int
+		useLink   bool
+		offset    int
+		opts      types.PageOptions
+		wantCount int
+		wantFirst int
+		wantCalls int32
+	}{
+		{name: "follows next link", items: 25, pageSize: 10, useLink: true, wantCount: 25, wantCalls: 3},
+		{name: "uses total count", items: 25, pageSize: 10, wantCount: 25, wantCalls: 3},
+		{name: "single page", items: 5, pageSize: 10, wantCount: 5, wantCalls: 1},
+		{name: "empty result", items: 0, pageSize: 10, wantCount: 0, wantCalls: 1},
+		{name: "starting offset", items: 25, pageSize: 10, offset: 20, wantCount: 5, wantFirst: 20, wantCalls: 1},
+		{name: "max items stops early", items: 100, pageSize: 10, opts: types.PageOptions{MaxItems: 15}, wantCount: 15, wantCalls: 2},
+		{name: "max items on page boundary", items: 100, pageSize: 10, opts: types.PageOptions{MaxItems: 20}, wantCount: 20, wantCalls: 2},
+		{name: "prefetch", items: 25, pageSize: 10, useLink: true, opts: types.PageOptions{Prefetch: true}, wantCount: 25, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newPagedSource(tt.items, tt.pageSize, tt.useLink)
+
+			got, err := collect(t, Paginate(context.Background(), src.fetch, tt.offset, tt.opts))
+			if err != nil {
+				t.Fatalf("Paginate() unexpected error: %v", err)
+			}
+			if len(got) != tt.wantCount {
+				t.Errorf("Paginate() yielded %d items, want %d", len(got), tt.wantCount)
+			}
+			if len(got) > 0 && got[0] != tt.wantFirst {
+				t.Errorf("first item = %d, want %d", got[0], tt.wantFirst)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i] != got[i-1]+1 {
+					t.Fatalf("items out of order at %d: %v", i, got)
+				}
+			}
+			if calls := atomic.LoadInt32(&src.calls); calls != tt.wantCalls {
+				t.Errorf("fetch called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPaginate_Error(t *testing.T) {
+	src := newPagedSource(30, 10, false)
+	src.failAt = 10
+
+	got, err := collect(t, Paginate(context.Background(), src.fetch, 0, types.PageOptions{}))
+	if err == nil {
+		t.Fatal("Paginate() expected error")
+	}
+	if len(got) != 10 {
+		t.Errorf("yielded %d items before error, want 10", len(got))
+	}
+}
+
+func TestPaginate_EarlyBreak(t *testing.T) {
+	src := newPagedSource(100, 10, true)
+
+	count := 0
+	for _, err := range Paginate(context.Background(), src.fetch, 0, types.PageOptions{Prefetch: true}) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		count++
+		if count == 5 {
+			break
+		}
+	}
+
+	if count != 5 {
+		t.Errorf("count = %d, want 5", count)
+	}
+	// The first page plus at most one prefetched page
+	if calls := atomic.LoadInt32(&src.calls); calls > 2 {
+		t.Errorf("fetch called %d times after early break, want at most 2", calls)
+	}
+}
+
+func TestPaginate_NonAdvancingNextLink(t *testing.T) {
+	calls := 0
+	fetch := func(ctx context.Context, offset int) (*Page[int], error) {
+		calls++
+		return &Page[int]{Items: []int{1, 2}, NextLink: "api/Items?offset=0"}, nil
+	}
+
+	got, err := collect(t, Paginate(context.Background(), fetch, 0, types.PageOptions{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || calls != 1 {
+		t.Errorf("got %d items in %d calls, want 2 items in 1 call", len(got), calls)
+	}
+}
@@ -3,11 +3,17 @@
 package helpers
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 )
 
+// ErrVersionUnsupported indicates the operation is not available on the
+// connected CyberArk version or deployment type. It is re-exported as
+// client.ErrVersionUnsupported alongside the other sentinel errors.
+var ErrVersionUnsupported = errors.New("unsupported CyberArk version")
+
 // Version represents a semantic version.
 type Version struct {
 	Major int
@@ -137,15 +143,86 @@ func (r *VersionRequirement) IsSatisfied(version *Version) bool {
 	return true
 }
 
-// AssertVersionRequirement checks if the current version meets the requirement.
-// This is equivalent to Assert-VersionRequirement in psPAS.
-func AssertVersionRequirement(currentVersion string, minVersion string, maxVersion string, privilegeCloudRequired bool, selfHostedRequired bool, isPrivilegeCloud bool) error {
-	if privilegeCloudRequired && !isPrivilegeCloud {
-		return fmt.Errorf("this operation requires Privilege Cloud")
+// VersionError reports that an operation is not supported by the connected
+// vault. It matches ErrVersionUnsupported with errors.Is.
+type VersionError struct {
+	// Operation is the SDK function that was called, if known
+	Operation string
+
+	// CurrentVersion is the version of the connected vault
+	CurrentVersion string
+
+	// MinVersion and MaxVersion are the supported version bounds
+	MinVersion string
+	MaxVersion string
+
+	// PrivilegeCloudRequired is set when the operation only exists in Privilege Cloud
+	PrivilegeCloudRequired bool
+
+	// SelfHostedRequired is set when the operation is not available in Privilege Cloud
+	SelfHostedRequired bool
+}
+
+// Error implements the error interface.
+func (e *VersionError) Error() string {
+	subject := "this operation"
+	if e.Operation != "" {
+		subject = e.Operation
+	}
+
+	switch {
+	case e.PrivilegeCloudRequired:
+		return fmt.Sprintf("%s requires Privilege Cloud", subject)
+	case e.SelfHostedRequired:
+		return fmt.Sprintf("%s requires Self-Hosted (not supported in Privilege Cloud)", subject)
+	case e.MinVersion != "" && e.MaxVersion != "":
+		return fmt.Sprintf("%s requires CyberArk version between %s and %s (current: %s)", subject, e.MinVersion, e.MaxVersion, e.CurrentVersion)
+	case e.MinVersion != "":
+		return fmt.Sprintf("%s requires CyberArk version %s or higher (current: %s)", subject, e.MinVersion, e.CurrentVersion)
+	default:
+		return fmt.Sprintf("%s requires CyberArk version %s or lower (current: %s)", subject, e.MaxVersion, e.CurrentVersion)
+	}
+}
+
+// Unwrap returns ErrVersionUnsupported so callers can match with errors.Is.
+func (e *VersionError) Unwrap() error {
+	return ErrVersionUnsupported
+}
+
+// Requirement declares the vault versions and deployment types an SDK
+// function supports. Empty bounds are unrestricted.
+type Requirement struct {
+	Operation          string
+	MinVersion         string
+	MaxVersion         string
+	PrivilegeCloudOnly bool
+	SelfHostedOnly     bool
+}
+
+// Check verifies the requirement against the connected vault. The version
+// bounds are skipped when the vault version is unknown or unparsable, and a
+// Privilege Cloud requirement is only enforced when the vault is known to be
+// Self-Hosted, so a session is never blocked on a guess. A Privilege Cloud
+// tenant on a custom domain is not detected, for example.
+func (r Requirement) Check(currentVersion string, isPrivilegeCloud, isSelfHosted bool) error {
+	if _, err := ParseVersion(currentVersion); err != nil {
+		r.MinVersion, r.MaxVersion = "", ""
+		currentVersion = "0"
+	}
+	if !isPrivilegeCloud && !isSelfHosted {
+		r.PrivilegeCloudOnly = false
+	}
+	return r.assert(currentVersion, isPrivilegeCloud)
+}
+
+// assert verifies the requirement, failing if currentVersion cannot be parsed.
+func (r Requirement) assert(currentVersion string, isPrivilegeCloud bool) error {
+	if r.PrivilegeCloudOnly && !isPrivilegeCloud {
+		return &VersionError{Operation: r.Operation, CurrentVersion: currentVersion, PrivilegeCloudRequired: true}
 	}
 
-	if selfHostedRequired && isPrivilegeCloud {
-		return fmt.Errorf("this operation requires Self-Hosted (not supported in Privilege Cloud)")
+	if r.SelfHostedOnly && isPrivilegeCloud {
+		return &VersionError{Operation: r.Operation, CurrentVersion: currentVersion, SelfHostedRequired: true}
 	}
 
 	current, err := ParseVersion(currentVersion)
@@ -153,22 +230,30 @@ func AssertVersionRequirement(currentVersion string, minVersion string, maxVersi
 		return fmt.Errorf("failed to parse current version: %w", err)
 	}
 
-	req, err := NewVersionRequirement(minVersion, maxVersion)
+	req, err := NewVersionRequirement(r.MinVersion, r.MaxVersion)
 	if err != nil {
 		return fmt.Errorf("failed to create version requirement: %w", err)
 	}
 
 	if !req.IsSatisfied(current) {
-		if minVersion != "" && maxVersion != "" {
-			return fmt.Errorf("this operation requires CyberArk version between %s and %s (current: %s)", minVersion, maxVersion, currentVersion)
-		}
-		if minVersion != "" {
-			return fmt.Errorf("this operation requires CyberArk version %s or higher (current: %s)", minVersion, currentVersion)
-		}
-		if maxVersion != "" {
-			return fmt.Errorf("this operation requires CyberArk version %s or lower (current: %s)", maxVersion, currentVersion)
+		return &VersionError{
+			Operation:      r.Operation,
+			CurrentVersion: currentVersion,
+			MinVersion:     r.MinVersion,
+			MaxVersion:     r.MaxVersion,
 		}
 	}
 
 	return nil
 }
+
+// AssertVersionRequirement checks if the current version meets the requirement.
+// This is equivalent to Assert-VersionRequirement in psPAS.
+func AssertVersionRequirement(currentVersion string, minVersion string, maxVersion string, privilegeCloudRequired bool, selfHostedRequired bool, isPrivilegeCloud bool) error {
+	return Requirement{
+		MinVersion:         minVersion,
+		MaxVersion:         maxVersion,
+		PrivilegeCloudOnly: privilegeCloudRequired,
+		SelfHostedOnly:     selfHostedRequired,
+	}.assert(currentVersion, isPrivilegeCloud)
+}
@@ -2,6 +2,7 @@
 package helpers
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -539,6 +540,58 @@ func TestAssertVersionRequirement(t *testing.T) {
 	}
 }
 
+func TestAssertVersionRequirement_Unsupported(t *testing.T) {
+	if err := AssertVersionRequirement("11.0.0", "12.0.0", "", false, false, false); !errors.Is(err, ErrVersionUnsupported) {
+		t.Errorf("version too low: error = %v, want ErrVersionUnsupported", err)
+	}
+	if err := AssertVersionRequirement("14.0.0", "", "", true, false, false); !errors.Is(err, ErrVersionUnsupported) {
+		t.Errorf("Privilege Cloud required: error = %v, want ErrVersionUnsupported", err)
+	}
+	if err := AssertVersionRequirement("bad", "12.0.0", "", false, false, false); errors.Is(err, ErrVersionUnsupported) {
+		t.Error("parse failure should not report ErrVersionUnsupported")
+	}
+}
+
+func TestRequirement_Check(t *testing.T) {
+	tests := []struct {
+		name           string
+		req            Requirement
+		currentVersion string
+		isCloud        bool
+		isSelfHosted   bool
+		wantErr        string
+	}{
+		{name: "satisfied", req: Requirement{MinVersion: "12.0"}, currentVersion: "14.0.0"},
+		{name: "too old", req: Requirement{Operation: "jitaccess.RequestJITAccess", MinVersion: "12.0"}, currentVersion: "11.7", wantErr: "jitaccess.RequestJITAccess requires CyberArk version 12.0 or higher (current: 11.7)"},
+		{name: "too new", req: Requirement{MaxVersion: "12.0"}, currentVersion: "13.0", wantErr: "this operation requires CyberArk version 12.0 or lower (current: 13.0)"},
+		{name: "unknown version skips bounds", req: Requirement{MinVersion: "12.0"}, currentVersion: ""},
+		{name: "unparsable version skips bounds", req: Requirement{MinVersion: "12.0"}, currentVersion: "Cloud"},
+		{name: "cloud only on self-hosted", req: Requirement{Operation: "ipallowlist.List", PrivilegeCloudOnly: true}, isSelfHosted: true, wantErr: "ipallowlist.List requires Privilege Cloud"},
+		{name: "cloud only on unknown deployment", req: Requirement{PrivilegeCloudOnly: true}, currentVersion: "14.0"},
+		{name: "cloud only on cloud", req: Requirement{PrivilegeCloudOnly: true}, isCloud: true},
+		{name: "self-hosted only on cloud", req: Requirement{SelfHostedOnly: true}, currentVersion: "14.0", isCloud: true, wantErr: "this operation requires Self-Hosted (not supported in Privilege Cloud)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Check(tt.currentVersion, tt.isCloud, tt.isSelfHosted)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+			var versionErr *VersionError
+			if !errors.As(err, &versionErr) {
+				t.Errorf("Check() error type = %T, want *VersionError", err)
+			}
+		})
+	}
+}
+
 func containsSubstring(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
 		(len(s) > 0 && len(substr) > 0 && contains(s, substr)))
@@ -3,10 +3,13 @@
 package session
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 )
 
 // Session represents an authenticated session with CyberArk.
@@ -54,14 +57,25 @@ type Session struct {
 
 	// PrivilegeCloud indicates if connected to Privilege Cloud (ISPSS)
 	PrivilegeCloud bool
+
+	// SelfHosted indicates the vault is known to be Self-Hosted. When neither
+	// it nor PrivilegeCloud is set, the deployment type is unknown.
+	SelfHosted bool
+
+	// SkipVersionEnforcement disables the per-call version requirement checks
+	SkipVersionEnforcement bool
 }
 
 // NewSession creates a new unauthenticated session.
 func NewSession(baseURI string) (*Session, error) {
-	cfg := client.Config{
+	return NewSessionWithConfig(client.Config{
 		BaseURL: baseURI,
-	}
+	})
+}
 
+// NewSessionWithConfig creates a new unauthenticated session using the given
+// client configuration.
+func NewSessionWithConfig(cfg client.Config) (*Session, error) {
 	c, err := client.NewClient(cfg)
 	if err != nil {
 		return nil, err
@@ -69,7 +83,7 @@ func NewSession(baseURI string) (*Session, error) {
 
 	return &Session{
 		Client:    c,
-		BaseURI:   baseURI,
+		BaseURI:   cfg.BaseURL,
 		APIURI:    c.GetAPIURL(),
 		StartTime: time.Now(),
 	}, nil
@@ -87,6 +101,44 @@ func (s *Session) SetAuthenticated(user, token, authMethod string) {
 	s.Client.SetAuthToken(token)
 }
 
+// CredentialSource logs in again and returns a new session token.
+// It is used to transparently re-authenticate when the token expires.
+type CredentialSource func(ctx context.Context) (string, error)
+
+// SetCredentialSource enables transparent re-authentication. When a request
+// fails with 401 Unauthorized, the source is called once to obtain a new
+// token, which replaces the session token before the request is replayed.
+// Passing nil disables re-authentication.
+func (s *Session) SetCredentialSource(src CredentialSource) {
+	if src == nil {
+		s.Client.SetTokenRefresher(nil)
+		return
+	}
+
+	s.Client.SetTokenRefresher(func(ctx context.Context) (string, error) {
+		token, err := src(ctx)
+		if err != nil {
+			return "", err
+		}
+		if token == "" {
+			return "", fmt.Errorf("no authentication token received")
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.SessionToken = token
+		s.Client.SetAuthToken(token)
+		return token, nil
+	})
+}
+
+// GetSessionToken returns the current session token.
+func (s *Session) GetSessionToken() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.SessionToken
+}
+
 // SetVersion sets the CyberArk version for the session.
 func (s *Session) SetVersion(version string) {
 	s.mu.Lock()
@@ -101,6 +153,34 @@ func (s *Session) SetPrivilegeCloud(isCloud bool) {
 	s.PrivilegeCloud = isCloud
 }
 
+// SetSelfHosted marks the session as a Self-Hosted connection.
+func (s *Session) SetSelfHosted(isSelfHosted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.SelfHosted = isSelfHosted
+}
+
+// SetSkipVersionEnforcement enables or disables the per-call version checks.
+func (s *Session) SetSkipVersionEnforcement(skip bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.SkipVersionEnforcement = skip
+}
+
+// CheckRequirement returns a *helpers.VersionError if the connected vault does
+// not support the operation. SDK functions that declare a Requirement call it
+// before sending a request.
+func (s *Session) CheckRequirement(req helpers.Requirement) error {
+	s.mu.RLock()
+	version, isCloud, isSelfHosted, skip := s.ExternalVersion, s.PrivilegeCloud, s.SelfHosted, s.SkipVersionEnforcement
+	s.mu.RUnlock()
+
+	if skip {
+		return nil
+	}
+	return req.Check(version, isCloud, isSelfHosted)
+}
+
 // UpdateLastCommand updates the last command tracking.
 func (s *Session) UpdateLastCommand(cmd string) {
 	s.mu.Lock()
@@ -149,6 +229,9 @@ func (s *Session) Clone() *Session {
 		AuthMethod:      s.AuthMethod,
 		SessionToken:    s.SessionToken,
 		PrivilegeCloud:  s.PrivilegeCloud,
+		SelfHosted:      s.SelfHosted,
+
+		SkipVersionEnforcement: s.SkipVersionEnforcement,
 	}
 }
 
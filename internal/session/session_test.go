@@ -2,9 +2,16 @@
 package session
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 )
 
 func TestNewSession(t *testing.T) {
@@ -376,6 +383,126 @@ func TestSession_ThreadSafety(t *testing.T) {
 	// If we get here without panic/race, test passes
 }
 
+func TestNewSessionWithConfig(t *testing.T) {
+	sess, err := NewSessionWithConfig(client.Config{
+		BaseURL:     "https://cyberark.example.com",
+		RetryPolicy: client.DefaultRetryPolicy(),
+	})
+	if err != nil {
+		t.Fatalf("NewSessionWithConfig() error: %v", err)
+	}
+	if sess.BaseURI != "https://cyberark.example.com" {
+		t.Errorf("BaseURI = %v, want https://cyberark.example.com", sess.BaseURI)
+	}
+	if sess.APIURI != "https://cyberark.example.com/PasswordVault/API" {
+		t.Errorf("APIURI = %v", sess.APIURI)
+	}
+
+	if _, err := NewSessionWithConfig(client.Config{}); err == nil {
+		t.Error("NewSessionWithConfig() expected error for empty base URL")
+	}
+}
+
+func TestSession_SetCredentialSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "new-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sess, err := NewSession(server.URL)
+	if err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+	sess.SetAuthenticated("user", "old-token", "CyberArk")
+
+	calls := 0
+	sess.SetCredentialSource(func(ctx context.Context) (string, error) {
+		calls++
+		return "new-token", nil
+	})
+
+	if _, err := sess.Client.Get(context.Background(), "/Accounts", nil); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("credential source called %d times, want 1", calls)
+	}
+	if got := sess.GetSessionToken(); got != "new-token" {
+		t.Errorf("GetSessionToken() = %q, want new-token", got)
+	}
+
+	// Disabling re-authentication surfaces the 401 again
+	sess.SetCredentialSource(nil)
+	sess.Client.SetAuthToken("old-token")
+	if _, err := sess.Client.Get(context.Background(), "/Accounts", nil); err == nil {
+		t.Error("Get() expected error after disabling credential source")
+	}
+}
+
+func TestSession_SetCredentialSource_EmptyToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	sess, _ := NewSession(server.URL)
+	sess.SetAuthenticated("user", "old-token", "CyberArk")
+	sess.SetCredentialSource(func(ctx context.Context) (string, error) {
+		return "", nil
+	})
+
+	if _, err := sess.Client.Get(context.Background(), "/Accounts", nil); err == nil {
+		t.Fatal("Get() expected error")
+	}
+	if got := sess.GetSessionToken(); got != "old-token" {
+		t.Errorf("GetSessionToken() = %q, want old-token", got)
+	}
+}
+
+func TestSession_CheckRequirement(t *testing.T) {
+	sess, _ := NewSession("https://cyberark.example.com")
+	sess.SetVersion("11.0")
+
+	req := helpers.Requirement{Operation: "authmethods.ListAuthenticationMethods", MinVersion: "11.1"}
+	if err := sess.CheckRequirement(req); !errors.Is(err, client.ErrVersionUnsupported) {
+		t.Errorf("CheckRequirement() error = %v, want ErrVersionUnsupported", err)
+	}
+
+	clone := sess.Clone()
+	sess.SetSkipVersionEnforcement(true)
+	if err := sess.CheckRequirement(req); err != nil {
+		t.Errorf("CheckRequirement() with enforcement skipped error = %v", err)
+	}
+	if err := clone.CheckRequirement(req); err == nil {
+		t.Error("clone taken before skipping should still enforce requirements")
+	}
+	if !sess.Clone().SkipVersionEnforcement {
+		t.Error("Clone() did not copy SkipVersionEnforcement")
+	}
+}
+
+func TestSession_CheckRequirement_Deployment(t *testing.T) {
+	sess, _ := NewSession("https://pam.example.com")
+	req := helpers.Requirement{Operation: "ipallowlist.List", PrivilegeCloudOnly: true}
+
+	// A Privilege Cloud tenant on a custom domain is not detected
+	if err := sess.CheckRequirement(req); err != nil {
+		t.Errorf("CheckRequirement() with unknown deployment error = %v", err)
+	}
+
+	sess.SetSelfHosted(true)
+	if err := sess.CheckRequirement(req); !errors.Is(err, client.ErrVersionUnsupported) {
+		t.Errorf("CheckRequirement() on Self-Hosted error = %v, want ErrVersionUnsupported", err)
+	}
+	if !sess.Clone().SelfHosted {
+		t.Error("Clone() did not copy SelfHosted")
+	}
+}
+
 // testError is a helper error type for testing
 type testError struct {
 	msg string
@@ -0,0 +1,166 @@
+// Package otelgopas provides OpenTelemetry tracing and metrics for goPAS.
+//
+// It lives in its own module so that users of the core SDK do not pull in
+// the OpenTelemetry dependencies. Every API call is recorded as a client span
+// named after its HTTP method and templated path, and counted in request,
+// error and latency instruments:
+//
+//	inst, err := otelgopas.New(otelgopas.Config{})
+//	if err != nil {
+//		return err
+//	}
+//
+//	sess, err := gopas.NewSession(ctx, gopas.SessionOptions{
+//		BaseURL:         "https://cyberark.example.com",
+//		Credentials:     creds,
+//		Instrumentation: inst,
+//		Middlewares:     []gopas.Middleware{inst.Middleware()},
+//	})
+package otelgopas
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/chrisranney/gopas"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope used for spans and metrics.
+const ScopeName = "github.com/chrisranney/gopas/otelgopas"
+
+// Attribute keys recorded on spans and metrics
+const (
+	AttrHTTPMethod     = attribute.Key("http.request.method")
+	AttrURLTemplate    = attribute.Key("url.template")
+	AttrHTTPStatusCode = attribute.Key("http.response.status_code")
+	AttrErrorCode      = attribute.Key("cyberark.error_code")
+)
+
+// Config configures the instrumentation. Zero values use the global
+// OpenTelemetry providers.
+type Config struct {
+	// TracerProvider creates the tracer (default: otel.GetTracerProvider())
+	TracerProvider trace.TracerProvider
+
+	// MeterProvider creates the meter (default: otel.GetMeterProvider())
+	MeterProvider metric.MeterProvider
+
+	// Propagator injects trace context into outgoing requests
+	// (default: otel.GetTextMapPropagator())
+	Propagator propagation.TextMapPropagator
+}
+
+// Instrumentation records OpenTelemetry spans and metrics for goPAS calls.
+// It implements gopas.Instrumentation.
+type Instrumentation struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	requests metric.Int64Counter
+	failures metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// New creates the instrumentation and registers its metric instruments.
+func New(cfg Config) (*Instrumentation, error) {
+	tp := cfg.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	mp := cfg.MeterProvider
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	propagator := cfg.Propagator
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+
+	meter := mp.Meter(ScopeName, metric.WithInstrumentationVersion(gopas.Version))
+
+	requests, err := meter.Int64Counter("gopas.client.requests",
+		metric.WithDescription("Number of CyberArk API calls"),
+		metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request counter: %w", err)
+	}
+
+	failures, err := meter.Int64Counter("gopas.client.errors",
+		metric.WithDescription("Number of CyberArk API calls that returned an error"),
+		metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create error counter: %w", err)
+	}
+
+	duration, err := meter.Float64Histogram("gopas.client.request.duration",
+		metric.WithDescription("Duration of CyberArk API calls, including retries"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create duration histogram: %w", err)
+	}
+
+	return &Instrumentation{
+		tracer:     tp.Tracer(ScopeName, trace.WithInstrumentationVersion(gopas.Version)),
+		propagator: propagator,
+		requests:   requests,
+		failures:   failures,
+		duration:   duration,
+	}, nil
+}
+
+// StartCall starts a client span for the call and returns a function that
+// ends it and records the call's metrics.
+func (i *Instrumentation) StartCall(ctx context.Context, call gopas.CallInfo) (context.Context, func(gopas.CallInfo)) {
+	ctx, span := i.tracer.Start(ctx, call.Method+" "+call.Route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			AttrHTTPMethod.String(call.Method),
+			AttrURLTemplate.String(call.Route),
+		))
+
+	return ctx, func(call gopas.CallInfo) {
+		attrs := []attribute.KeyValue{
+			AttrHTTPMethod.String(call.Method),
+			AttrURLTemplate.String(call.Route),
+		}
+		if call.StatusCode != 0 {
+			attrs = append(attrs, AttrHTTPStatusCode.Int(call.StatusCode))
+		}
+		if call.ErrorCode != "" {
+			attrs = append(attrs, AttrErrorCode.String(call.ErrorCode))
+		}
+
+		span.SetAttributes(attrs...)
+		if call.Err != nil {
+			span.RecordError(call.Err)
+			span.SetStatus(codes.Error, call.Err.Error())
+		}
+		span.End()
+
+		set := metric.WithAttributes(attrs...)
+		i.requests.Add(ctx, 1, set)
+		if call.Err != nil {
+			i.failures.Add(ctx, 1, set)
+		}
+		i.duration.Record(ctx, call.Duration.Seconds(), set)
+	}
+}
+
+// Middleware returns a middleware that injects the current trace context
+// into outgoing requests, so PVWA-side logs can be correlated with the span.
+func (i *Instrumentation) Middleware() gopas.Middleware {
+	return gopas.MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return gopas.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			i.propagator.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+			return next.RoundTrip(req)
+		})
+	})
+}
@@ -0,0 +1,204 @@
+// Package otelgopas provides tests for the OpenTelemetry instrumentation.
+package otelgopas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chrisranney/gopas"
+	"github.com/chrisranney/gopas/pkg/accounts"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestSession creates a session against a fake PVWA that issues a token
+// and fails password retrieval for unknown accounts.
+func newTestSession(t *testing.T, inst *Instrumentation) (*gopas.Session, *httptest.Server, *string) {
+	t.Helper()
+
+	traceparent := new(string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/Logon"):
+			w.Write([]byte(`{"CyberArkLogonResult":"token-123"}`))
+		case strings.HasSuffix(r.URL.Path, "/Password/Retrieve"):
+			*traceparent = r.Header.Get("Traceparent")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"ErrorCode":"PASWS164E","ErrorMessage":"Account was not found"}`))
+		default:
+			w.Write([]byte(`{"id":"12_34","name":"root"}`))
+		}
+	}))
+
+	sess, err := gopas.NewSession(context.Background(), gopas.SessionOptions{
+		BaseURL:          server.URL,
+		Credentials:      gopas.Credentials{Username: "admin", Password: "password"},
+		SkipVersionCheck: true,
+		Instrumentation:  inst,
+		Middlewares:      []gopas.Middleware{inst.Middleware()},
+	})
+	if err != nil {
+		server.Close()
+		t.Fatalf("NewSession() error: %v", err)
+	}
+
+	return sess, server, traceparent
+}
+
+func attrValue(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestInstrumentation_Spans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+
+	inst, err := New(Config{
+		TracerProvider: tp,
+		MeterProvider:  sdkmetric.NewMeterProvider(),
+		Propagator:     propagation.TraceContext{},
+	})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	sess, server, traceparent := newTestSession(t, inst)
+	defer server.Close()
+
+	if _, err := accounts.GetPassword(context.Background(), sess, "12_34", "audit"); err == nil {
+		t.Fatal("GetPassword() expected error")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want logon and password retrieval", len(spans))
+	}
+
+	logon := spans[0]
+	if logon.Name != "POST /Auth/CyberArk/Logon" {
+		t.Errorf("logon span name = %q", logon.Name)
+	}
+	if logon.Status.Code == codes.Error {
+		t.Errorf("logon span status = %v, want unset", logon.Status)
+	}
+
+	span := spans[1]
+	if span.Name != "POST /Accounts/{id}/Password/Retrieve" {
+		t.Errorf("span name = %q, want templated route", span.Name)
+	}
+	if span.SpanKind != trace.SpanKindClient {
+		t.Errorf("span kind = %v, want client", span.SpanKind)
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("span status = %v, want error", span.Status)
+	}
+
+	want := map[attribute.Key]attribute.Value{
+		AttrHTTPMethod:     attribute.StringValue(http.MethodPost),
+		AttrURLTemplate:    attribute.StringValue("/Accounts/{id}/Password/Retrieve"),
+		AttrHTTPStatusCode: attribute.IntValue(http.StatusNotFound),
+		AttrErrorCode:      attribute.StringValue("PASWS164E"),
+	}
+	for key, value := range want {
+		got, ok := attrValue(span.Attributes, key)
+		if !ok || got != value {
+			t.Errorf("attribute %s = %v, want %v", key, got.Emit(), value.Emit())
+		}
+	}
+
+	if !strings.Contains(*traceparent, span.SpanContext.TraceID().String()) {
+		t.Errorf("traceparent header %q does not carry trace %s", *traceparent, span.SpanContext.TraceID())
+	}
+}
+
+func TestInstrumentation_Metrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(context.Background())
+
+	inst, err := New(Config{
+		TracerProvider: sdktrace.NewTracerProvider(),
+		MeterProvider:  mp,
+	})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	sess, server, _ := newTestSession(t, inst)
+	defer server.Close()
+
+	if _, err := accounts.Get(context.Background(), sess, "12_34"); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if _, err := accounts.GetPassword(context.Background(), sess, "12_34", "audit"); err == nil {
+		t.Fatal("GetPassword() expected error")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error: %v", err)
+	}
+
+	metrics := map[string]metricdata.Metrics{}
+	for _, sm := range rm.ScopeMetrics {
+		if sm.Scope.Name != ScopeName {
+			continue
+		}
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+
+	sumOf := func(name string) int64 {
+		m, ok := metrics[name]
+		if !ok {
+			t.Fatalf("metric %s not recorded", name)
+		}
+		var total int64
+		for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+			total += dp.Value
+		}
+		return total
+	}
+
+	// Logon, account lookup and password retrieval
+	if got := sumOf("gopas.client.requests"); got != 3 {
+		t.Errorf("gopas.client.requests = %d, want 3", got)
+	}
+	if got := sumOf("gopas.client.errors"); got != 1 {
+		t.Errorf("gopas.client.errors = %d, want 1", got)
+	}
+
+	errorPoints := metrics["gopas.client.errors"].Data.(metricdata.Sum[int64]).DataPoints
+	if code, ok := errorPoints[0].Attributes.Value(AttrErrorCode); !ok || code.AsString() != "PASWS164E" {
+		t.Errorf("error data point ErrorCode = %v, want PASWS164E", code.Emit())
+	}
+
+	histogram, ok := metrics["gopas.client.request.duration"]
+	if !ok {
+		t.Fatal("gopas.client.request.duration not recorded")
+	}
+	var count uint64
+	for _, dp := range histogram.Data.(metricdata.Histogram[float64]).DataPoints {
+		count += dp.Count
+	}
+	if count != 3 {
+		t.Errorf("duration histogram count = %d, want 3", count)
+	}
+}
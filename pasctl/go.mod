@@ -1,6 +1,6 @@
 module pasctl
 
-go 1.21
+go 1.23
 
 require (
 	github.com/chrisranney/gopas v0.0.0
@@ -15,7 +15,9 @@ require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-runewidth v0.0.9 // indirect
+	golang.org/x/crypto v0.11.0 // indirect
 	golang.org/x/sys v0.15.0 // indirect
+	software.sslmate.com/src/go-pkcs12 v0.5.0 // indirect
 )
 
 replace github.com/chrisranney/gopas => ../
@@ -0,0 +1,584 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chrisranney/gopas"
+	"github.com/chrisranney/gopas/pkg/applications"
+	"github.com/chrisranney/gopas/pkg/safemembers"
+
+	"pasctl/internal/output"
+)
+
+// authTypeAliases maps the names accepted by --type to the authentication
+// method types of the Credential Provider.
+var authTypeAliases = map[string]string{
+	"machine":                 applications.AuthTypeMachineAddress,
+	"machine-address":         applications.AuthTypeMachineAddress,
+	"machineaddress":          applications.AuthTypeMachineAddress,
+	"os-user":                 applications.AuthTypeOSUser,
+	"osuser":                  applications.AuthTypeOSUser,
+	"path":                    applications.AuthTypePath,
+	"hash":                    applications.AuthTypeHash,
+	"cert-serial":             applications.AuthTypeCertificateSerialNumber,
+	"certificateserialnumber": applications.AuthTypeCertificateSerialNumber,
+	"cert-attr":               applications.AuthTypeCertificateAttribute,
+	"certificateattr":         applications.AuthTypeCertificateAttribute,
+}
+
+// ApplicationsCommand handles Credential Provider application operations.
+type ApplicationsCommand struct{}
+
+func (c *ApplicationsCommand) Name() string {
+	return "applications"
+}
+
+func (c *ApplicationsCommand) Description() string {
+	return "Manage Credential Provider applications"
+}
+
+func (c *ApplicationsCommand) Usage() string {
+	return `applications <subcommand> [options]
+
+Subcommands:
+  list                            List applications
+  get <app-id>                    Get application details
+  create <app-id>                 Create an application
+  delete <app-id>                 Delete an application
+  auth list <app-id>              List authentication methods
+  auth add <app-id>               Add an authentication method
+  auth remove <app-id> <auth-id>  Remove an authentication method
+  provision <app-id>              Create an application, its authentication
+                                  methods and safe memberships in one step
+
+Options for 'list':
+  --location=PATH       Only applications in this location
+  --sublocations        Include applications in sublocations
+
+Options for 'create' and 'provision':
+  --description=DESC    Application description
+  --location=PATH       Vault location (default: \)
+  --owner-first=NAME    Business owner first name
+  --owner-last=NAME     Business owner last name
+  --owner-email=EMAIL   Business owner email
+  --owner-phone=PHONE   Business owner phone
+
+Options for 'delete':
+  --yes                 Skip the confirmation prompt
+
+Options for 'auth add':
+  --type=TYPE           machine, os-user, path, hash, cert-serial or cert-attr
+  --value=VALUE         Value to match (not used by cert-attr)
+  --folder              For path, the value is a folder
+  --allow-internal-scripts
+                        For path, allow scripts run by the application
+  --comment=TEXT        Comment
+  --subject=ATTR        For cert-attr, a subject attribute such as CN=app
+                        (repeatable)
+  --issuer=ATTR         For cert-attr, an issuer attribute (repeatable)
+  --san=NAME            For cert-attr, a subject alternative name such as
+                        DNS Name=app.example.com (repeatable)
+
+Options for 'provision':
+  --safes=A,B           Safes the application retrieves credentials from
+  --providers=P1,P2     Credential Provider users serving the application
+  --machine=ADDR        Allowed machine address (repeatable)
+  --os-user=USER        Allowed OS user (repeatable)
+  --path=PATH           Allowed executable path (repeatable)
+  --hash=HASH           Allowed executable hash (repeatable)
+  --cert-serial=SERIAL  Allowed client certificate serial number (repeatable)
+
+Provisioning grants the application List and Retrieve accounts, and each
+provider List and Retrieve accounts and View safe members, on every safe.
+Objects that already exist are left as they are, and existing safe members
+are granted any of these permissions they lack, so provisioning can be run
+again to add safes or authentication methods.
+
+Examples:
+  applications list --location=\Applications --sublocations
+  applications create Billing --description="Billing service"
+  applications auth add Billing --type=os-user --value=svc_billing
+  applications auth add Billing --type=cert-attr --subject=CN=billing --issuer="CN=Example CA"
+  applications auth remove Billing 12
+  applications provision Billing --safes=Billing-Prod,Billing-DR --providers=Prov_app01 --machine=10.0.0.5 --os-user=svc_billing
+  applications delete Billing
+`
+}
+
+func (c *ApplicationsCommand) Subcommands() []string {
+	return []string{"list", "get", "create", "delete", "auth", "provision"}
+}
+
+func (c *ApplicationsCommand) Execute(execCtx *ExecutionContext, args []string) error {
+	if err := RequireSession(execCtx); err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		fmt.Println(c.Usage())
+		return nil
+	}
+
+	switch args[0] {
+	case "list":
+		return c.list(execCtx, args[1:])
+	case "get":
+		return c.get(execCtx, args[1:])
+	case "create":
+		return c.create(execCtx, args[1:])
+	case "delete":
+		return c.delete(execCtx, args[1:])
+	case "auth":
+		return c.auth(execCtx, args[1:])
+	case "provision":
+		return c.provision(execCtx, args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+}
+
+// stringsFlag is a flag that may be repeated, collecting every value.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// splitList splits a comma separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseAuthType returns the authentication method type for a --type value.
+func parseAuthType(value string) (string, error) {
+	authType, ok := authTypeAliases[strings.ToLower(value)]
+	if !ok {
+		return "", fmt.Errorf("invalid authentication type: %s (use machine, os-user, path, hash, cert-serial or cert-attr)", value)
+	}
+	return authType, nil
+}
+
+// applicationFlags registers the flags shared by create and provision.
+func applicationFlags(fs *flag.FlagSet) func(appID string) applications.CreateOptions {
+	description := fs.String("description", "", "Application description")
+	location := fs.String("location", "", "Vault location")
+	ownerFirst := fs.String("owner-first", "", "Business owner first name")
+	ownerLast := fs.String("owner-last", "", "Business owner last name")
+	ownerEmail := fs.String("owner-email", "", "Business owner email")
+	ownerPhone := fs.String("owner-phone", "", "Business owner phone")
+
+	return func(appID string) applications.CreateOptions {
+		return applications.CreateOptions{
+			AppID:              appID,
+			Description:        *description,
+			Location:           *location,
+			BusinessOwnerFName: *ownerFirst,
+			BusinessOwnerLName: *ownerLast,
+			BusinessOwnerEmail: *ownerEmail,
+			BusinessOwnerPhone: *ownerPhone,
+		}
+	}
+}
+
+func (c *ApplicationsCommand) list(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("applications list", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	location := fs.String("location", "", "Only applications in this location")
+	sublocations := fs.Bool("sublocations", false, "Include applications in sublocations")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	apps, err := applications.List(execCtx.Ctx, execCtx.Session, applications.ListOptions{
+		Location:     *location,
+		SubLocations: *sublocations,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(apps) == 0 {
+		output.PrintInfo("No applications found")
+		return nil
+	}
+
+	if execCtx.Formatter.GetFormat() == output.FormatTable {
+		table := output.NewTable("APP ID", "LOCATION", "DISABLED", "OWNER", "DESCRIPTION")
+		for _, app := range apps {
+			table.AddRow(
+				app.AppID,
+				app.Location,
+				boolToStr(app.Disabled),
+				strings.TrimSpace(app.BusinessOwnerFName+" "+app.BusinessOwnerLName),
+				app.Description,
+			)
+		}
+		table.Render()
+		fmt.Printf("\nTotal: %d applications\n", len(apps))
+	} else {
+		return execCtx.Formatter.Format(apps)
+	}
+
+	return nil
+}
+
+func (c *ApplicationsCommand) get(execCtx *ExecutionContext, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("application ID required")
+	}
+
+	app, err := applications.Get(execCtx.Ctx, execCtx.Session, args[0])
+	if err != nil {
+		return err
+	}
+
+	return execCtx.Formatter.Format(app)
+}
+
+func (c *ApplicationsCommand) create(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("applications create", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	createOptions := applicationFlags(fs)
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("application ID required")
+	}
+
+	if err := applications.Create(execCtx.Ctx, execCtx.Session, createOptions(positional[0])); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Application '%s' created", positional[0])
+	return nil
+}
+
+func (c *ApplicationsCommand) delete(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("applications delete", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	yes := fs.Bool("yes", false, "Skip the confirmation prompt")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("application ID required")
+	}
+	appID := positional[0]
+
+	if !*yes {
+		fmt.Printf("Are you sure you want to delete application %s? [y/N]: ", appID)
+		var confirm string
+		fmt.Scanln(&confirm)
+		if strings.ToLower(confirm) != "y" && strings.ToLower(confirm) != "yes" {
+			output.PrintInfo("Deletion cancelled")
+			return nil
+		}
+	}
+
+	if err := applications.Delete(execCtx.Ctx, execCtx.Session, appID); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Application '%s' deleted", appID)
+	return nil
+}
+
+func (c *ApplicationsCommand) auth(execCtx *ExecutionContext, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("auth subcommand required: list, add or remove")
+	}
+
+	switch args[0] {
+	case "list":
+		return c.authList(execCtx, args[1:])
+	case "add":
+		return c.authAdd(execCtx, args[1:])
+	case "remove":
+		return c.authRemove(execCtx, args[1:])
+	default:
+		return fmt.Errorf("unknown auth subcommand: %s", args[0])
+	}
+}
+
+func (c *ApplicationsCommand) authList(execCtx *ExecutionContext, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("application ID required")
+	}
+
+	methods, err := applications.ListAuthMethods(execCtx.Ctx, execCtx.Session, args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(methods) == 0 {
+		output.PrintInfo("No authentication methods for application %s", args[0])
+		return nil
+	}
+
+	if execCtx.Formatter.GetFormat() == output.FormatTable {
+		table := output.NewTable("AUTH ID", "TYPE", "VALUE", "COMMENT")
+		for _, m := range methods {
+			table.AddRow(m.AuthID.String(), m.AuthType, authMethodValue(m), m.Comment)
+		}
+		table.Render()
+	} else {
+		return execCtx.Formatter.Format(methods)
+	}
+
+	return nil
+}
+
+// authMethodValue describes what an authentication method matches.
+func authMethodValue(m applications.AuthMethod) string {
+	if !strings.EqualFold(m.AuthType, applications.AuthTypeCertificateAttribute) {
+		value := m.AuthValue
+		if m.IsFolder {
+			value += " (folder)"
+		}
+		return value
+	}
+
+	var parts []string
+	if len(m.Subject) > 0 {
+		parts = append(parts, "Subject: "+strings.Join(m.Subject, ", "))
+	}
+	if len(m.Issuer) > 0 {
+		parts = append(parts, "Issuer: "+strings.Join(m.Issuer, ", "))
+	}
+	if len(m.SubjectAlternativeName) > 0 {
+		parts = append(parts, "SAN: "+strings.Join(m.SubjectAlternativeName, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (c *ApplicationsCommand) authAdd(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("applications auth add", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	authTypeFlag := fs.String("type", "", "Authentication type")
+	value := fs.String("value", "", "Value to match")
+	folder := fs.Bool("folder", false, "For path, the value is a folder")
+	allowScripts := fs.Bool("allow-internal-scripts", false, "For path, allow scripts run by the application")
+	comment := fs.String("comment", "", "Comment")
+	var subject, issuer, san stringsFlag
+	fs.Var(&subject, "subject", "For cert-attr, a subject attribute (repeatable)")
+	fs.Var(&issuer, "issuer", "For cert-attr, an issuer attribute (repeatable)")
+	fs.Var(&san, "san", "For cert-attr, a subject alternative name (repeatable)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("application ID required")
+	}
+	appID := positional[0]
+
+	if *authTypeFlag == "" {
+		return fmt.Errorf("--type is required")
+	}
+	authType, err := parseAuthType(*authTypeFlag)
+	if err != nil {
+		return err
+	}
+
+	opts := applications.AddAuthMethodOptions{
+		AuthType:  authType,
+		AuthValue: *value,
+		Comment:   *comment,
+	}
+	switch authType {
+	case applications.AuthTypeCertificateAttribute:
+		if *value != "" {
+			return fmt.Errorf("--value is not used by cert-attr, use --subject, --issuer or --san")
+		}
+		if len(subject) == 0 && len(issuer) == 0 && len(san) == 0 {
+			return fmt.Errorf("cert-attr requires --subject, --issuer or --san")
+		}
+		opts.Subject = subject
+		opts.Issuer = issuer
+		opts.SubjectAlternativeName = san
+	default:
+		if *value == "" {
+			return fmt.Errorf("--value is required")
+		}
+		if len(subject) > 0 || len(issuer) > 0 || len(san) > 0 {
+			return fmt.Errorf("--subject, --issuer and --san require --type=cert-attr")
+		}
+	}
+	if *folder || *allowScripts {
+		if authType != applications.AuthTypePath {
+			return fmt.Errorf("--folder and --allow-internal-scripts require --type=path")
+		}
+		opts.IsFolder = *folder
+		opts.AllowInternalScripts = *allowScripts
+	}
+
+	if err := applications.AddAuthMethod(execCtx.Ctx, execCtx.Session, appID, opts); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Added %s authentication to application '%s'", authType, appID)
+	return nil
+}
+
+func (c *ApplicationsCommand) authRemove(execCtx *ExecutionContext, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("application ID and authentication ID required")
+	}
+
+	if err := applications.RemoveAuthMethod(execCtx.Ctx, execCtx.Session, args[0], args[1]); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Removed authentication %s from application '%s'", args[1], args[0])
+	return nil
+}
+
+func (c *ApplicationsCommand) provision(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("applications provision", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	createOptions := applicationFlags(fs)
+	safesFlag := fs.String("safes", "", "Safes the application retrieves credentials from")
+	providersFlag := fs.String("providers", "", "Credential Provider users serving the application")
+	var machines, osUsers, paths, hashes, serials stringsFlag
+	fs.Var(&machines, "machine", "Allowed machine address (repeatable)")
+	fs.Var(&osUsers, "os-user", "Allowed OS user (repeatable)")
+	fs.Var(&paths, "path", "Allowed executable path (repeatable)")
+	fs.Var(&hashes, "hash", "Allowed executable hash (repeatable)")
+	fs.Var(&serials, "cert-serial", "Allowed client certificate serial number (repeatable)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("application ID required")
+	}
+	appID := positional[0]
+
+	safeNames := splitList(*safesFlag)
+	providers := splitList(*providersFlag)
+	if len(providers) > 0 && len(safeNames) == 0 {
+		return fmt.Errorf("--providers requires --safes")
+	}
+
+	var methods []applications.AddAuthMethodOptions
+	for _, group := range []struct {
+		authType string
+		values   []string
+	}{
+		{applications.AuthTypeMachineAddress, machines},
+		{applications.AuthTypeOSUser, osUsers},
+		{applications.AuthTypePath, paths},
+		{applications.AuthTypeHash, hashes},
+		{applications.AuthTypeCertificateSerialNumber, serials},
+	} {
+		for _, value := range group.values {
+			methods = append(methods, applications.AddAuthMethodOptions{AuthType: group.authType, AuthValue: value})
+		}
+	}
+
+	// Each step tolerates objects that already exist, so a partly
+	// provisioned application can be completed by running this again.
+	err = applications.Create(execCtx.Ctx, execCtx.Session, createOptions(appID))
+	if err := provisionStep(err, "created", "Application '%s'", appID); err != nil {
+		return err
+	}
+
+	for _, m := range methods {
+		err := applications.AddAuthMethod(execCtx.Ctx, execCtx.Session, appID, m)
+		if err := provisionStep(err, "added", "Authentication %s %s", m.AuthType, m.AuthValue); err != nil {
+			return err
+		}
+	}
+
+	for _, safeName := range safeNames {
+		if err := ensureMember(execCtx, safeName, appID, safemembers.DefaultApplicationPermissions()); err != nil {
+			return err
+		}
+		for _, provider := range providers {
+			if err := ensureMember(execCtx, safeName, provider, safemembers.DefaultProviderPermissions()); err != nil {
+				return err
+			}
+		}
+	}
+
+	output.PrintSuccess("Application '%s' provisioned", appID)
+	return nil
+}
+
+// ensureMember grants memberName the required permissions on safeName,
+// keeping any other permissions it already has, and reports what changed.
+func ensureMember(execCtx *ExecutionContext, safeName, memberName string, required *safemembers.Permissions) error {
+	step := fmt.Sprintf("Safe '%s' member '%s'", safeName, memberName)
+
+	desired := required
+	current, err := safemembers.Get(execCtx.Ctx, execCtx.Session, safeName, memberName)
+	switch {
+	case err == nil:
+		desired = safemembers.MergePermissions(current.Permissions, required)
+	case !errors.Is(err, gopas.ErrNotFound):
+		return fmt.Errorf("%s: %w", step, err)
+	}
+
+	result, err := safemembers.Ensure(execCtx.Ctx, execCtx.Session, safeName, memberName, desired)
+	if err != nil {
+		return fmt.Errorf("%s: %w", step, err)
+	}
+
+	switch result.Action {
+	case safemembers.EnsureUpdated:
+		changes := make([]string, len(result.Changes))
+		for i, change := range result.Changes {
+			changes[i] = change.String()
+		}
+		output.PrintSuccess("%s: %s (%s)", step, result.Action, strings.Join(changes, ", "))
+	case safemembers.EnsureUnchanged:
+		output.PrintInfo("%s: %s", step, result.Action)
+	default:
+		output.PrintSuccess("%s: %s", step, result.Action)
+	}
+	return nil
+}
+
+// provisionStep reports the outcome of one provisioning step. Objects that
+// already exist are reported and skipped; any other error stops the run.
+func provisionStep(err error, done string, format string, args ...interface{}) error {
+	step := fmt.Sprintf(format, args...)
+	switch {
+	case err == nil:
+		output.PrintSuccess("%s: %s", step, done)
+		return nil
+	case errors.Is(err, gopas.ErrConflict):
+		output.PrintInfo("%s: already exists", step)
+		return nil
+	default:
+		return fmt.Errorf("%s: %w", step, err)
+	}
+}
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chrisranney/gopas"
+	"github.com/chrisranney/gopas/pkg/applications"
+	"github.com/chrisranney/gopas/pkg/pvwatest"
+	"github.com/chrisranney/gopas/pkg/safemembers"
+	"github.com/chrisranney/gopas/pkg/safes"
+	"github.com/chrisranney/gopas/pkg/users"
+)
+
+// newApplicationsTestServer returns a server with two safes and a Credential
+// Provider user, and an execution context logged on as the administrator.
+func newApplicationsTestServer(t *testing.T) (*pvwatest.Server, *ExecutionContext) {
+	t.Helper()
+	srv := pvwatest.NewServer()
+	t.Cleanup(srv.Close)
+
+	srv.AddSafe(safes.CreateOptions{SafeName: "Billing-Prod"})
+	srv.AddSafe(safes.CreateOptions{SafeName: "Billing-DR"})
+	srv.AddUser(users.CreateOptions{Username: "Prov_app01", UserType: "AppProvider"})
+
+	sess, err := gopas.NewSession(context.Background(), gopas.SessionOptions{
+		BaseURL:          srv.URL,
+		Credentials:      gopas.Credentials{Username: pvwatest.DefaultUsername, Password: pvwatest.DefaultPassword},
+		SkipVersionCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	execCtx := createTestExecutionContext(t)
+	execCtx.Session = sess
+	return srv, execCtx
+}
+
+func memberPermissions(t *testing.T, execCtx *ExecutionContext, safeName, member string) *safemembers.Permissions {
+	t.Helper()
+	m, err := safemembers.Get(execCtx.Ctx, execCtx.Session, safeName, member)
+	if err != nil {
+		t.Fatalf("safemembers.Get(%s, %s) error = %v", safeName, member, err)
+	}
+	return m.Permissions
+}
+
+func TestApplicationsCommand_Provision(t *testing.T) {
+	_, execCtx := newApplicationsTestServer(t)
+	cmd := &ApplicationsCommand{}
+
+	args := []string{"provision", "Billing", "--safes=Billing-Prod,Billing-DR", "--providers=Prov_app01",
+		"--machine=10.0.0.5", "--machine=10.0.0.6", "--os-user=svc_billing", "--description=Billing service"}
+	if err := cmd.Execute(execCtx, args); err != nil {
+		t.Fatalf("provision error = %v", err)
+	}
+
+	app, err := applications.Get(execCtx.Ctx, execCtx.Session, "Billing")
+	if err != nil || app.Description != "Billing service" {
+		t.Fatalf("Get() = %+v, %v", app, err)
+	}
+	auths, err := applications.ListAuthMethods(execCtx.Ctx, execCtx.Session, "Billing")
+	if err != nil || len(auths) != 3 {
+		t.Fatalf("ListAuthMethods() = %+v, %v, want 3 methods", auths, err)
+	}
+
+	for _, safeName := range []string{"Billing-Prod", "Billing-DR"} {
+		if got := memberPermissions(t, execCtx, safeName, "Billing"); *got != *safemembers.DefaultApplicationPermissions() {
+			t.Errorf("%s application permissions = %+v", safeName, got)
+		}
+		if got := memberPermissions(t, execCtx, safeName, "Prov_app01"); *got != *safemembers.DefaultProviderPermissions() {
+			t.Errorf("%s provider permissions = %+v", safeName, got)
+		}
+	}
+
+	// A provider that lost a permission, and gained another, on one safe
+	_, err = safemembers.Update(execCtx.Ctx, execCtx.Session, "Billing-DR", "Prov_app01", safemembers.UpdateOptions{
+		Permissions: &safemembers.Permissions{ListAccounts: true, UseAccounts: true},
+	})
+	if err != nil {
+		t.Fatalf("safemembers.Update() error = %v", err)
+	}
+
+	// Running again skips what exists, adds the new method and restores
+	// the missing permission without revoking the extra one
+	if err := cmd.Execute(execCtx, append(args, "--hash=4d3c2b1a")); err != nil {
+		t.Fatalf("second provision error = %v", err)
+	}
+	if auths, _ := applications.ListAuthMethods(execCtx.Ctx, execCtx.Session, "Billing"); len(auths) != 4 {
+		t.Errorf("methods after second provision = %d, want 4", len(auths))
+	}
+	want := *safemembers.DefaultProviderPermissions()
+	want.UseAccounts = true
+	if got := memberPermissions(t, execCtx, "Billing-DR", "Prov_app01"); *got != want {
+		t.Errorf("provider permissions after second provision = %+v, want %+v", got, want)
+	}
+}
+
+func TestApplicationsCommand_ProvisionStopsOnError(t *testing.T) {
+	_, execCtx := newApplicationsTestServer(t)
+
+	err := (&ApplicationsCommand{}).Execute(execCtx, []string{"provision", "Billing", "--safes=Missing,Billing-Prod"})
+	if err == nil || !strings.Contains(err.Error(), "Safe 'Missing' member 'Billing'") {
+		t.Fatalf("provision error = %v, want the failing step", err)
+	}
+	if _, err := safemembers.Get(execCtx.Ctx, execCtx.Session, "Billing-Prod", "Billing"); err == nil {
+		t.Error("provision continued after a failed step")
+	}
+}
+
+func TestApplicationsCommand_Auth(t *testing.T) {
+	srv, execCtx := newApplicationsTestServer(t)
+	srv.AddApplication(applications.CreateOptions{AppID: "Reports"})
+	cmd := &ApplicationsCommand{}
+
+	err := cmd.Execute(execCtx, []string{"auth", "add", "Reports", "--type=cert-attr", "--subject=CN=reports", "--subject=O=Example", "--issuer=CN=Example CA"})
+	if err != nil {
+		t.Fatalf("auth add cert-attr error = %v", err)
+	}
+	if err := cmd.Execute(execCtx, []string{"auth", "add", "Reports", "--type=path", "--value=/opt/reports", "--folder"}); err != nil {
+		t.Fatalf("auth add path error = %v", err)
+	}
+
+	auths, err := applications.ListAuthMethods(execCtx.Ctx, execCtx.Session, "Reports")
+	if err != nil || len(auths) != 2 {
+		t.Fatalf("ListAuthMethods() = %+v, %v", auths, err)
+	}
+	if auths[0].AuthType != applications.AuthTypeCertificateAttribute || len(auths[0].Subject) != 2 || len(auths[0].Issuer) != 1 {
+		t.Errorf("cert-attr method = %+v", auths[0])
+	}
+	if !auths[1].IsFolder {
+		t.Errorf("path method = %+v, want a folder", auths[1])
+	}
+	if got := authMethodValue(auths[0]); got != "Subject: CN=reports, O=Example; Issuer: CN=Example CA" {
+		t.Errorf("authMethodValue() = %q", got)
+	}
+
+	if err := cmd.Execute(execCtx, []string{"auth", "remove", "Reports", auths[1].AuthID.String()}); err != nil {
+		t.Fatalf("auth remove error = %v", err)
+	}
+	if auths, _ := applications.ListAuthMethods(execCtx.Ctx, execCtx.Session, "Reports"); len(auths) != 1 {
+		t.Errorf("methods after remove = %d, want 1", len(auths))
+	}
+}
+
+func TestApplicationsCommand_ArgumentErrors(t *testing.T) {
+	_, execCtx := newApplicationsTestServer(t)
+	cmd := &ApplicationsCommand{}
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"get"}, "application ID required"},
+		{[]string{"create"}, "application ID required"},
+		{[]string{"auth"}, "auth subcommand required"},
+		{[]string{"auth", "add", "App"}, "--type is required"},
+		{[]string{"auth", "add", "App", "--type=ldap", "--value=x"}, "invalid authentication type"},
+		{[]string{"auth", "add", "App", "--type=os-user"}, "--value is required"},
+		{[]string{"auth", "add", "App", "--type=cert-attr"}, "cert-attr requires"},
+		{[]string{"auth", "add", "App", "--type=cert-attr", "--value=x"}, "--value is not used by cert-attr"},
+		{[]string{"auth", "add", "App", "--type=hash", "--value=x", "--subject=CN=x"}, "require --type=cert-attr"},
+		{[]string{"auth", "add", "App", "--type=hash", "--value=x", "--folder"}, "require --type=path"},
+		{[]string{"auth", "remove", "App"}, "authentication ID required"},
+		{[]string{"provision", "App", "--providers=Prov_app01"}, "--providers requires --safes"},
+		{[]string{"bogus"}, "unknown subcommand"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			err := cmd.Execute(execCtx, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Execute() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
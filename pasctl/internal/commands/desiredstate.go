@@ -0,0 +1,304 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/chrisranney/gopas/pkg/desiredstate"
+
+	"pasctl/internal/output"
+)
+
+// ErrDrift is returned by plan when the Vault differs from the desired state.
+var ErrDrift = errors.New("vault differs from the desired state")
+
+// PlanCommand shows the changes needed to reach a desired state document.
+type PlanCommand struct{}
+
+func (c *PlanCommand) Name() string {
+	return "plan"
+}
+
+func (c *PlanCommand) Description() string {
+	return "Show changes needed to match a desired state file"
+}
+
+func (c *PlanCommand) Usage() string {
+	return `plan -f FILE [options]
+
+Compares a YAML or JSON desired state document with the Vault and shows
+the safes, safe members, accounts, platforms and applications that would
+change. Nothing is modified.
+
+In single command and script mode, pasctl exits with status 2 when the
+Vault differs from the document, so plan can detect drift in CI. Safes and
+applications marked absent that still exist are drift even without
+--allow-deletes.
+
+Options:
+  -f, --file=FILE         Desired state document (required)
+  --allow-deletes         Include deletions of objects missing from the document
+  --ignore-members=LIST   Comma-separated safe members that are never deleted
+
+Examples:
+  plan -f vault.yaml
+  plan -f vault.yaml --allow-deletes
+  plan -f vault.yaml --ignore-members=Administrator,Auditors
+`
+}
+
+func (c *PlanCommand) Execute(execCtx *ExecutionContext, args []string) error {
+	if err := RequireSession(execCtx); err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	opts := addPlanFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	plan, err := opts.build(execCtx)
+	if err != nil {
+		return err
+	}
+
+	if execCtx.Formatter.GetFormat() == output.FormatTable {
+		renderPlan(os.Stdout, plan)
+	} else if err := execCtx.Formatter.Format(plan); err != nil {
+		return err
+	}
+
+	if plan.HasDrift() {
+		return &ExitError{Code: ExitCodeDrift, Err: ErrDrift}
+	}
+	return nil
+}
+
+// ApplyCommand reconciles the Vault with a desired state document.
+type ApplyCommand struct{}
+
+func (c *ApplyCommand) Name() string {
+	return "apply"
+}
+
+func (c *ApplyCommand) Description() string {
+	return "Apply a desired state file to the Vault"
+}
+
+func (c *ApplyCommand) Usage() string {
+	return `apply -f FILE [options]
+
+Shows the plan for a desired state document and, once confirmed, makes
+the changes. Actions run in dependency order and stop at the first failure.
+
+Options:
+  -f, --file=FILE         Desired state document (required)
+  --allow-deletes         Delete objects missing from the document
+  --ignore-members=LIST   Comma-separated safe members that are never deleted
+  --continue-on-error     Keep applying after an action fails
+  --yes                   Skip the confirmation prompt
+
+Examples:
+  apply -f vault.yaml
+  apply -f vault.yaml --allow-deletes --yes
+`
+}
+
+func (c *ApplyCommand) Execute(execCtx *ExecutionContext, args []string) error {
+	if err := RequireSession(execCtx); err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	opts := addPlanFlags(fs)
+	continueOnError := fs.Bool("continue-on-error", false, "Keep applying after an action fails")
+	yes := fs.Bool("yes", false, "Skip the confirmation prompt")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	plan, err := opts.build(execCtx)
+	if err != nil {
+		return err
+	}
+
+	tableFormat := execCtx.Formatter.GetFormat() == output.FormatTable
+	if tableFormat {
+		renderPlan(os.Stdout, plan)
+	}
+
+	if !plan.HasChanges() {
+		if tableFormat {
+			return nil
+		}
+		return execCtx.Formatter.Format(&desiredstate.ApplyResult{Results: []desiredstate.ActionResult{}})
+	}
+
+	// The prompt goes to stderr so it does not corrupt JSON or YAML output
+	if !*yes {
+		fmt.Fprintf(os.Stderr, "Apply %d change(s) to the Vault? [y/N]: ", len(plan.Actions))
+		var confirm string
+		fmt.Scanln(&confirm)
+		if strings.ToLower(confirm) != "y" && strings.ToLower(confirm) != "yes" {
+			if tableFormat {
+				output.PrintInfo("Apply cancelled")
+			} else {
+				fmt.Fprintln(os.Stderr, "Apply cancelled")
+			}
+			return nil
+		}
+	}
+
+	result, applyErr := desiredstate.Apply(execCtx.Ctx, execCtx.Session, plan, desiredstate.ApplyOptions{
+		ContinueOnError: *continueOnError,
+	})
+	if result == nil {
+		return applyErr
+	}
+
+	if !tableFormat {
+		if err := execCtx.Formatter.Format(result); err != nil {
+			return err
+		}
+		return applyErr
+	}
+
+	fmt.Println()
+	for _, r := range result.Results {
+		if r.Applied {
+			output.PrintSuccess("%s", r.Action)
+		} else {
+			output.PrintError("%s: %s", r.Action, r.Error)
+		}
+	}
+	if notRun := len(plan.Actions) - len(result.Results); notRun > 0 {
+		output.PrintWarning("%d action(s) not attempted", notRun)
+	}
+
+	fmt.Println()
+	if result.Failed > 0 {
+		return fmt.Errorf("apply failed: %d applied, %d failed", result.Applied, result.Failed)
+	}
+	output.PrintSuccess("Apply complete: %d applied", result.Applied)
+	return nil
+}
+
+// planFlags holds the options shared by plan and apply.
+type planFlags struct {
+	file          *string
+	allowDeletes  *bool
+	ignoreMembers *string
+}
+
+func addPlanFlags(fs *flag.FlagSet) *planFlags {
+	f := &planFlags{
+		file:          fs.String("file", "", "Desired state document"),
+		allowDeletes:  fs.Bool("allow-deletes", false, "Include deletions"),
+		ignoreMembers: fs.String("ignore-members", "", "Safe members that are never deleted"),
+	}
+	fs.StringVar(f.file, "f", "", "Desired state document (shorthand)")
+	return f
+}
+
+func (f *planFlags) build(execCtx *ExecutionContext) (*desiredstate.Plan, error) {
+	if *f.file == "" {
+		return nil, fmt.Errorf("desired state file required (-f FILE)")
+	}
+
+	doc, err := desiredstate.LoadFile(*f.file)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := desiredstate.PlanOptions{AllowDeletes: *f.allowDeletes}
+	for _, name := range strings.Split(*f.ignoreMembers, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			opts.IgnoreMembers = append(opts.IgnoreMembers, name)
+		}
+	}
+
+	return desiredstate.BuildPlan(execCtx.Ctx, execCtx.Session, doc, opts)
+}
+
+// renderPlan writes a plan as a colored diff.
+func renderPlan(w io.Writer, plan *desiredstate.Plan) {
+	if !plan.HasChanges() {
+		if plan.HasDrift() {
+			fmt.Fprintf(w, "%s No changes without --allow-deletes. Objects marked absent still exist.\n", output.Warning("!"))
+		} else {
+			fmt.Fprintf(w, "%s No changes. The Vault matches the desired state.\n", output.Success("✓"))
+		}
+		renderSkippedDeletes(w, plan)
+		return
+	}
+
+	fmt.Fprintln(w)
+	for _, action := range plan.Actions {
+		symbol, color := actionStyle(action.Action)
+		fmt.Fprintf(w, "  %s %s\n", color(symbol), output.Bold(action.Address()))
+		for _, change := range action.Changes {
+			if action.Action == desiredstate.ActionCreate {
+				fmt.Fprintf(w, "      %s: %s\n", change.Field, formatPlanValue(change.To))
+				continue
+			}
+			fmt.Fprintf(w, "      %s: %s → %s\n", change.Field,
+				output.Dim(formatPlanValue(change.From)), formatPlanValue(change.To))
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Plan: %s to create, %s to update, %s to delete.\n",
+		output.Success(plan.Summary.Create),
+		output.Warning(plan.Summary.Update),
+		output.Error(plan.Summary.Delete))
+	renderSkippedDeletes(w, plan)
+}
+
+func renderSkippedDeletes(w io.Writer, plan *desiredstate.Plan) {
+	if len(plan.SkippedDeletes) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, output.Dim(fmt.Sprintf("%d object(s) would be deleted with --allow-deletes:", len(plan.SkippedDeletes))))
+	for _, action := range plan.SkippedDeletes {
+		fmt.Fprintln(w, output.Dim("  - "+action.Address()))
+	}
+}
+
+func actionStyle(action desiredstate.ActionType) (string, func(a ...interface{}) string) {
+	switch action {
+	case desiredstate.ActionCreate:
+		return "+", output.Success
+	case desiredstate.ActionDelete:
+		return "-", output.Error
+	default:
+		return "~", output.Warning
+	}
+}
+
+func formatPlanValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "(none)"
+	case string:
+		return fmt.Sprintf("%q", val)
+	case bool, int, int64, float64:
+		return fmt.Sprint(val)
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,205 @@
+// Package commands provides tests for the plan and apply commands.
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrisranney/gopas"
+	"github.com/chrisranney/gopas/pkg/desiredstate"
+
+	"pasctl/internal/output"
+)
+
+func TestPlanCommand_Name(t *testing.T) {
+	if (&PlanCommand{}).Name() != "plan" {
+		t.Error("Name() should be plan")
+	}
+	if (&ApplyCommand{}).Name() != "apply" {
+		t.Error("Name() should be apply")
+	}
+}
+
+func TestPlanCommand_Usage(t *testing.T) {
+	for _, content := range []string{"-f", "--allow-deletes", "--ignore-members", "status 2"} {
+		if !strings.Contains((&PlanCommand{}).Usage(), content) {
+			t.Errorf("plan Usage() should contain %q", content)
+		}
+	}
+	for _, content := range []string{"--yes", "--continue-on-error"} {
+		if !strings.Contains((&ApplyCommand{}).Usage(), content) {
+			t.Errorf("apply Usage() should contain %q", content)
+		}
+	}
+}
+
+func TestPlanCommand_RequiresSession(t *testing.T) {
+	execCtx := createTestExecutionContext(t)
+
+	for _, cmd := range []Command{&PlanCommand{}, &ApplyCommand{}} {
+		err := cmd.Execute(execCtx, []string{"-f", "vault.yaml"})
+		if err == nil || !strings.Contains(err.Error(), "not connected") {
+			t.Errorf("%s: error = %v, want not connected", cmd.Name(), err)
+		}
+	}
+}
+
+// newDesiredStateTestServer serves a logon and an empty list of safes.
+func newDesiredStateTestServer(t *testing.T) *gopas.Session {
+	t.Helper()
+	return newDesiredStateTestServerWithSafes(t, `[]`)
+}
+
+// newDesiredStateTestServerWithSafes serves a logon and the given JSON
+// array of safes.
+func newDesiredStateTestServerWithSafes(t *testing.T, safes string) *gopas.Session {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/Logon"):
+			w.Write([]byte(`"test-token"`))
+		case strings.HasSuffix(r.URL.Path, "/Safes") && r.Method == http.MethodGet:
+			w.Write([]byte(`{"value":` + safes + `}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sess, err := gopas.NewSession(context.Background(), gopas.SessionOptions{
+		BaseURL:          server.URL,
+		Credentials:      gopas.Credentials{Username: "admin", Password: "secret"},
+		SkipVersionCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+	return sess
+}
+
+func writeDocument(t *testing.T, document string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vault.yaml")
+	if err := os.WriteFile(path, []byte(document), 0600); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	return path
+}
+
+func TestPlanCommand_Execute_Drift(t *testing.T) {
+	execCtx := createTestExecutionContext(t)
+	execCtx.Session = newDesiredStateTestServer(t)
+
+	path := writeDocument(t, "safes:\n  - safeName: Linux\n")
+	err := (&PlanCommand{}).Execute(execCtx, []string{"-f", path})
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitCodeDrift {
+		t.Fatalf("Execute() error = %v, want exit code %d", err, ExitCodeDrift)
+	}
+	if !errors.Is(err, ErrDrift) {
+		t.Error("error should wrap ErrDrift")
+	}
+}
+
+func TestPlanCommand_Execute_NoDrift(t *testing.T) {
+	execCtx := createTestExecutionContext(t)
+	execCtx.Session = newDesiredStateTestServer(t)
+
+	path := writeDocument(t, "safes: []\n")
+	if err := (&PlanCommand{}).Execute(execCtx, []string{"--file=" + path}); err != nil {
+		t.Errorf("Execute() error = %v, want nil", err)
+	}
+}
+
+func TestPlanCommand_Execute_AbsentSafeIsDrift(t *testing.T) {
+	execCtx := createTestExecutionContext(t)
+	execCtx.Session = newDesiredStateTestServerWithSafes(t, `[{"safeName":"Legacy"}]`)
+
+	// Without --allow-deletes the delete is skipped, but the Vault still
+	// does not match the document
+	path := writeDocument(t, "safes:\n  - safeName: Legacy\n    absent: true\n")
+	err := (&PlanCommand{}).Execute(execCtx, []string{"-f", path})
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitCodeDrift {
+		t.Fatalf("Execute() error = %v, want exit code %d", err, ExitCodeDrift)
+	}
+}
+
+func TestPlanCommand_Execute_MissingFile(t *testing.T) {
+	execCtx := createTestExecutionContext(t)
+	execCtx.Session = newDesiredStateTestServer(t)
+
+	err := (&PlanCommand{}).Execute(execCtx, []string{})
+	if err == nil || !strings.Contains(err.Error(), "-f FILE") {
+		t.Errorf("Execute() error = %v, want file required", err)
+	}
+}
+
+func TestApplyCommand_Execute_PromptOnStderr(t *testing.T) {
+	execCtx := createTestExecutionContext(t)
+	execCtx.Session = newDesiredStateTestServer(t)
+	execCtx.Formatter.SetFormat(output.FormatJSON)
+	path := writeDocument(t, "safes:\n  - safeName: Linux\n")
+
+	stdin, answer, _ := os.Pipe()
+	answer.WriteString("n\n")
+	answer.Close()
+	stdoutReader, stdout, _ := os.Pipe()
+	origStdin, origStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdin, stdout
+	err := (&ApplyCommand{}).Execute(execCtx, []string{"-f", path})
+	os.Stdin, os.Stdout = origStdin, origStdout
+	stdout.Close()
+
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	var got bytes.Buffer
+	got.ReadFrom(stdoutReader)
+	if got.Len() != 0 {
+		t.Errorf("stdout = %q, want nothing for a cancelled JSON apply", got.String())
+	}
+}
+
+func TestRenderPlan(t *testing.T) {
+	plan := &desiredstate.Plan{
+		Actions: []desiredstate.Action{
+			{Action: desiredstate.ActionCreate, Resource: desiredstate.ResourceSafe, Safe: "Windows",
+				Changes: []desiredstate.Change{{Field: "managingCPM", To: "PasswordManager"}}},
+			{Action: desiredstate.ActionUpdate, Resource: desiredstate.ResourcePlatform, Name: "UnixSSH",
+				Changes: []desiredstate.Change{{Field: "active", From: false, To: true}}},
+		},
+		Summary: desiredstate.PlanSummary{Create: 1, Update: 1},
+		SkippedDeletes: []desiredstate.Action{
+			{Action: desiredstate.ActionDelete, Resource: desiredstate.ResourceMember, Safe: "Linux", Name: "stale"},
+		},
+	}
+
+	var buf bytes.Buffer
+	renderPlan(&buf, plan)
+	got := buf.String()
+
+	for _, want := range []string{
+		"+ safe/Windows",
+		`managingCPM: "PasswordManager"`,
+		"~ platform/UnixSSH",
+		"active: false → true",
+		"Plan: 1 to create, 1 to update, 0 to delete.",
+		"--allow-deletes",
+		"- member/Linux/stale",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("renderPlan() output missing %q:\n%s", want, got)
+		}
+	}
+}
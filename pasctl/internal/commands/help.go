@@ -56,14 +56,16 @@ func (c *HelpCommand) showAllHelp() error {
 	categories := map[string][]string{
 		"Session": {"connect", "disconnect", "status"},
 		"Resources": {
-			"accounts", "safes", "users", "platforms",
+			"accounts", "safes", "users", "platforms", "requests", "applications",
+			"onboarding",
 		},
-		"Monitoring": {"psm", "health"},
-		"Settings":   {"set", "config"},
-		"Other":      {"help", "history", "clear", "exit"},
+		"Configuration": {"plan", "apply", "snapshot"},
+		"Monitoring":    {"psm", "health", "pta"},
+		"Settings":      {"set", "config"},
+		"Other":         {"help", "history", "clear", "exit"},
 	}
 
-	for _, cat := range []string{"Session", "Resources", "Monitoring", "Settings", "Other"} {
+	for _, cat := range []string{"Session", "Resources", "Configuration", "Monitoring", "Settings", "Other"} {
 		cmds := categories[cat]
 		fmt.Printf("  %s:\n", output.InfoBold(cat))
 		for _, name := range cmds {
@@ -0,0 +1,625 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chrisranney/gopas/pkg/onboardingrules"
+	"github.com/chrisranney/gopas/pkg/types"
+
+	"pasctl/internal/output"
+)
+
+// Values accepted by the onboarding rule filter flags.
+var (
+	onboardingMethods = []string{
+		onboardingrules.MethodEquals, onboardingrules.MethodBegins, onboardingrules.MethodEnds, onboardingrules.MethodContains,
+	}
+	onboardingSystemTypes = []string{
+		onboardingrules.SystemTypeWindows, onboardingrules.SystemTypeUnix,
+	}
+	onboardingMachineTypes = []string{
+		onboardingrules.MachineTypeAny, onboardingrules.MachineTypeWorkstation, onboardingrules.MachineTypeServer,
+	}
+	onboardingAccountCategories = []string{
+		onboardingrules.AccountCategoryAny, onboardingrules.AccountCategoryPrivileged, onboardingrules.AccountCategoryNonPrivileged,
+	}
+)
+
+// OnboardingCommand handles automatic onboarding rules and discovered
+// accounts.
+type OnboardingCommand struct{}
+
+func (c *OnboardingCommand) Name() string {
+	return "onboarding"
+}
+
+func (c *OnboardingCommand) Description() string {
+	return "Manage onboarding rules and discovered accounts"
+}
+
+func (c *OnboardingCommand) Usage() string {
+	return `onboarding <subcommand> [options]
+
+Subcommands:
+  rules [list]                    List onboarding rules in precedence order
+  rules create <name>             Create a rule
+  rules update <rule-id>          Update a rule
+  rules delete <rule-id>          Delete a rule
+  discovered [list]               List discovered accounts
+  publish <account-id>            Onboard a discovered account
+  simulate                        Show which rule would onboard each
+                                  discovered account
+
+Options for 'rules create' and 'rules update':
+  --safe=NAME           Target safe (required for create)
+  --platform=ID         Target platform (required for create)
+  --description=DESC    Rule description
+  --system-type=TYPE    Windows or Unix
+  --machine-type=TYPE   Any, Workstation or Server
+  --account-category=C  Any, Privileged or NonPrivileged
+  --admin-only          Only accounts with an administrator ID
+  --user-filter=TEXT    User name to match
+  --user-method=METHOD  Equals, Begins, Ends or Contains (default: Equals)
+  --address-filter=TEXT Address to match
+  --address-method=M    Equals, Begins, Ends or Contains (default: Equals)
+  --precedence=N        Rule precedence, 1 is evaluated first
+  --reconcile-account=ID
+                        Reconcile account for onboarded accounts
+
+Options for 'rules delete':
+  --yes                 Skip the confirmation prompt
+
+Options for 'discovered list' and 'simulate':
+  --search=TERM         Search term
+  --platform-type=TYPE  Only accounts of this platform type, such as
+                        "Windows Server Local"
+  --privileged          Only privileged accounts
+  --enabled             Only enabled accounts
+  --limit=N             Maximum accounts (default: 25, 0 for all)
+
+Options for 'publish':
+  --safe=NAME           Safe to onboard the account to (required)
+  --platform=ID         Platform to assign (required)
+  --manual-reason=TEXT  Disable automatic management, giving this reason
+
+Options for 'simulate':
+  --unmatched           Only show accounts no rule would onboard
+  --explain             Explain why each rule did or did not match
+
+Examples:
+  onboarding rules list
+  onboarding rules create "Windows admins" --safe=Win-Local --platform=WinServerLocal --system-type=Windows --machine-type=Server --user-filter=adm_ --user-method=Begins
+  onboarding rules update 4 --precedence=1
+  onboarding discovered list --platform-type="Windows Server Local" --privileged
+  onboarding simulate --unmatched
+  onboarding simulate --search=adm_ --explain
+  onboarding publish 8a4b2c --safe=Win-Local --platform=WinServerLocal
+`
+}
+
+func (c *OnboardingCommand) Subcommands() []string {
+	return []string{"rules", "discovered", "publish", "simulate"}
+}
+
+func (c *OnboardingCommand) Execute(execCtx *ExecutionContext, args []string) error {
+	if err := RequireSession(execCtx); err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		fmt.Println(c.Usage())
+		return nil
+	}
+
+	switch args[0] {
+	case "rules":
+		return c.rules(execCtx, args[1:])
+	case "discovered":
+		return c.discovered(execCtx, args[1:])
+	case "publish":
+		return c.publish(execCtx, args[1:])
+	case "simulate":
+		return c.simulate(execCtx, args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+}
+
+// oneOf returns the entry of allowed that matches value, ignoring case.
+func oneOf(flagName, value string, allowed []string) (string, error) {
+	for _, a := range allowed {
+		if strings.EqualFold(a, value) {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --%s %q: use %s", flagName, value, strings.Join(allowed, ", "))
+}
+
+// parseRuleID parses an onboarding rule ID argument.
+func parseRuleID(value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rule ID: %s", value)
+	}
+	return id, nil
+}
+
+// ruleFilterText describes a rule's text filter for display.
+func ruleFilterText(method, filter string) string {
+	if filter == "" {
+		return "any"
+	}
+	if method == "" {
+		method = onboardingrules.MethodEquals
+	}
+	return method + " " + filter
+}
+
+func (c *OnboardingCommand) rules(execCtx *ExecutionContext, args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		return c.rulesList(execCtx)
+	}
+
+	switch args[0] {
+	case "create":
+		return c.rulesCreate(execCtx, args[1:])
+	case "update":
+		return c.rulesUpdate(execCtx, args[1:])
+	case "delete":
+		return c.rulesDelete(execCtx, args[1:])
+	default:
+		return fmt.Errorf("unknown rules subcommand: %s", args[0])
+	}
+}
+
+func (c *OnboardingCommand) rulesList(execCtx *ExecutionContext) error {
+	rules, err := onboardingrules.List(execCtx.Ctx, execCtx.Session)
+	if err != nil {
+		return err
+	}
+
+	if len(rules) == 0 {
+		output.PrintInfo("No onboarding rules")
+		return nil
+	}
+	onboardingrules.SortByPrecedence(rules)
+
+	if execCtx.Formatter.GetFormat() == output.FormatTable {
+		table := output.NewTable("ID", "PRECEDENCE", "NAME", "SYSTEM", "MACHINE", "USER", "ADDRESS", "SAFE", "PLATFORM")
+		for _, rule := range rules {
+			table.AddRow(
+				strconv.Itoa(rule.RuleID),
+				strconv.Itoa(rule.RulePrecedence),
+				rule.RuleName,
+				rule.SystemTypeFilter,
+				rule.MachineTypeFilter,
+				ruleFilterText(rule.UserNameMethod, rule.UserNameFilter),
+				ruleFilterText(rule.AddressMethod, rule.AddressFilter),
+				rule.TargetSafeName,
+				rule.TargetPlatformID.String(),
+			)
+		}
+		table.Render()
+	} else {
+		return execCtx.Formatter.Format(rules)
+	}
+
+	return nil
+}
+
+// ruleFlags holds the flags shared by rules create and rules update.
+type ruleFlags struct {
+	fs               *flag.FlagSet
+	safe             *string
+	platform         *string
+	description      *string
+	systemType       *string
+	machineType      *string
+	accountCategory  *string
+	adminOnly        *bool
+	userFilter       *string
+	userMethod       *string
+	addressFilter    *string
+	addressMethod    *string
+	precedence       *int
+	reconcileAccount *string
+}
+
+func newRuleFlags(name string) *ruleFlags {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return &ruleFlags{
+		fs:               fs,
+		safe:             fs.String("safe", "", "Target safe"),
+		platform:         fs.String("platform", "", "Target platform"),
+		description:      fs.String("description", "", "Rule description"),
+		systemType:       fs.String("system-type", "", "Windows or Unix"),
+		machineType:      fs.String("machine-type", "", "Any, Workstation or Server"),
+		accountCategory:  fs.String("account-category", "", "Any, Privileged or NonPrivileged"),
+		adminOnly:        fs.Bool("admin-only", false, "Only accounts with an administrator ID"),
+		userFilter:       fs.String("user-filter", "", "User name to match"),
+		userMethod:       fs.String("user-method", "", "Equals, Begins, Ends or Contains"),
+		addressFilter:    fs.String("address-filter", "", "Address to match"),
+		addressMethod:    fs.String("address-method", "", "Equals, Begins, Ends or Contains"),
+		precedence:       fs.Int("precedence", 0, "Rule precedence"),
+		reconcileAccount: fs.String("reconcile-account", "", "Reconcile account ID"),
+	}
+}
+
+// normalize validates the enumerated flags that were given and rewrites
+// them in the case the API expects.
+func (f *ruleFlags) normalize() error {
+	for _, check := range []struct {
+		name    string
+		value   *string
+		allowed []string
+	}{
+		{"system-type", f.systemType, onboardingSystemTypes},
+		{"machine-type", f.machineType, onboardingMachineTypes},
+		{"account-category", f.accountCategory, onboardingAccountCategories},
+		{"user-method", f.userMethod, onboardingMethods},
+		{"address-method", f.addressMethod, onboardingMethods},
+	} {
+		if *check.value == "" {
+			continue
+		}
+		value, err := oneOf(check.name, *check.value, check.allowed)
+		if err != nil {
+			return err
+		}
+		*check.value = value
+	}
+	if *f.precedence < 0 {
+		return fmt.Errorf("--precedence must be positive")
+	}
+	return nil
+}
+
+func (c *OnboardingCommand) rulesCreate(execCtx *ExecutionContext, args []string) error {
+	f := newRuleFlags("onboarding rules create")
+	positional, err := parseFlags(f.fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("rule name required")
+	}
+	if *f.safe == "" || *f.platform == "" {
+		return fmt.Errorf("--safe and --platform are required")
+	}
+	if err := f.normalize(); err != nil {
+		return err
+	}
+
+	rule, err := onboardingrules.Create(execCtx.Ctx, execCtx.Session, onboardingrules.CreateOptions{
+		RuleName:              positional[0],
+		RuleDescription:       *f.description,
+		TargetPlatformID:      types.FlexibleID(*f.platform),
+		TargetSafeName:        *f.safe,
+		IsAdminIDFilter:       *f.adminOnly,
+		MachineTypeFilter:     *f.machineType,
+		SystemTypeFilter:      *f.systemType,
+		UserNameFilter:        *f.userFilter,
+		UserNameMethod:        *f.userMethod,
+		AddressFilter:         *f.addressFilter,
+		AddressMethod:         *f.addressMethod,
+		AccountCategoryFilter: *f.accountCategory,
+		RulePrecedence:        *f.precedence,
+		ReconcileAccountID:    types.FlexibleID(*f.reconcileAccount),
+	})
+	if err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Onboarding rule '%s' created with ID: %d", rule.RuleName, rule.RuleID)
+	return nil
+}
+
+func (c *OnboardingCommand) rulesUpdate(execCtx *ExecutionContext, args []string) error {
+	f := newRuleFlags("onboarding rules update")
+	positional, err := parseFlags(f.fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("rule ID required")
+	}
+	ruleID, err := parseRuleID(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := f.normalize(); err != nil {
+		return err
+	}
+
+	set := map[string]bool{}
+	f.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	if len(set) == 0 {
+		return fmt.Errorf("nothing to update")
+	}
+
+	opts := onboardingrules.UpdateOptions{
+		RuleDescription:       *f.description,
+		TargetPlatformID:      types.FlexibleID(*f.platform),
+		TargetSafeName:        *f.safe,
+		MachineTypeFilter:     *f.machineType,
+		SystemTypeFilter:      *f.systemType,
+		UserNameFilter:        *f.userFilter,
+		UserNameMethod:        *f.userMethod,
+		AddressFilter:         *f.addressFilter,
+		AddressMethod:         *f.addressMethod,
+		AccountCategoryFilter: *f.accountCategory,
+		ReconcileAccountID:    types.FlexibleID(*f.reconcileAccount),
+	}
+	if set["admin-only"] {
+		opts.IsAdminIDFilter = f.adminOnly
+	}
+	if set["precedence"] {
+		opts.RulePrecedence = f.precedence
+	}
+
+	rule, err := onboardingrules.Update(execCtx.Ctx, execCtx.Session, ruleID, opts)
+	if err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Onboarding rule %d updated", rule.RuleID)
+	return nil
+}
+
+func (c *OnboardingCommand) rulesDelete(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("onboarding rules delete", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	yes := fs.Bool("yes", false, "Skip the confirmation prompt")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("rule ID required")
+	}
+	ruleID, err := parseRuleID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	if !*yes {
+		fmt.Printf("Are you sure you want to delete onboarding rule %d? [y/N]: ", ruleID)
+		var confirm string
+		fmt.Scanln(&confirm)
+		if strings.ToLower(confirm) != "y" && strings.ToLower(confirm) != "yes" {
+			output.PrintInfo("Deletion cancelled")
+			return nil
+		}
+	}
+
+	if err := onboardingrules.Delete(execCtx.Ctx, execCtx.Session, ruleID); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Onboarding rule %d deleted", ruleID)
+	return nil
+}
+
+// discoveredFlags holds the filters shared by discovered list and simulate.
+type discoveredFlags struct {
+	search       *string
+	platformType *string
+	privileged   *bool
+	enabled      *bool
+	limit        *int
+}
+
+func newDiscoveredFlags(fs *flag.FlagSet) *discoveredFlags {
+	return &discoveredFlags{
+		search:       fs.String("search", "", "Search term"),
+		platformType: fs.String("platform-type", "", "Only accounts of this platform type"),
+		privileged:   fs.Bool("privileged", false, "Only privileged accounts"),
+		enabled:      fs.Bool("enabled", false, "Only enabled accounts"),
+		limit:        fs.Int("limit", 25, "Maximum accounts"),
+	}
+}
+
+// accounts reads the discovered accounts matching the flags.
+func (f *discoveredFlags) accounts(execCtx *ExecutionContext) ([]onboardingrules.DiscoveredAccount, error) {
+	var filters []string
+	if *f.platformType != "" {
+		filters = append(filters, "platformType eq "+*f.platformType)
+	}
+	if *f.privileged {
+		filters = append(filters, "privileged eq true")
+	}
+	if *f.enabled {
+		filters = append(filters, "accountEnabled eq true")
+	}
+
+	opts := onboardingrules.ListDiscoveredOptions{
+		Search: *f.search,
+		Filter: strings.Join(filters, " AND "),
+		Limit:  100,
+	}
+	var result []onboardingrules.DiscoveredAccount
+	for acct, err := range onboardingrules.AllDiscoveredAccounts(execCtx.Ctx, execCtx.Session, opts, types.PageOptions{MaxItems: *f.limit}) {
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, acct)
+	}
+	return result, nil
+}
+
+func (c *OnboardingCommand) discovered(execCtx *ExecutionContext, args []string) error {
+	if len(args) > 0 && args[0] == "list" {
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("onboarding discovered list", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	filters := newDiscoveredFlags(fs)
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("unknown discovered subcommand: %s", positional[0])
+	}
+
+	accounts, err := filters.accounts(execCtx)
+	if err != nil {
+		return err
+	}
+
+	if len(accounts) == 0 {
+		output.PrintInfo("No discovered accounts found")
+		return nil
+	}
+
+	if execCtx.Formatter.GetFormat() == output.FormatTable {
+		table := output.NewTable("ID", "USERNAME", "ADDRESS", "PLATFORM TYPE", "PRIVILEGED", "ENABLED", "DISCOVERED")
+		for _, acct := range accounts {
+			table.AddRow(
+				acct.ID.String(),
+				acct.UserName,
+				acct.Address,
+				acct.PlatformType,
+				boolToStr(acct.Privileged),
+				boolToStr(acct.AccountEnabled),
+				formatUnixTime(acct.DiscoveryDateTime),
+			)
+		}
+		table.Render()
+		fmt.Printf("\nShowing %d discovered accounts\n", len(accounts))
+	} else {
+		return execCtx.Formatter.Format(accounts)
+	}
+
+	return nil
+}
+
+func (c *OnboardingCommand) publish(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("onboarding publish", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	safe := fs.String("safe", "", "Safe to onboard the account to")
+	platform := fs.String("platform", "", "Platform to assign")
+	manualReason := fs.String("manual-reason", "", "Disable automatic management, giving this reason")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("discovered account ID required")
+	}
+	if *safe == "" || *platform == "" {
+		return fmt.Errorf("--safe and --platform are required")
+	}
+
+	opts := onboardingrules.PublishDiscoveredAccountOptions{
+		AccountID:  positional[0],
+		SafeName:   *safe,
+		PlatformID: types.FlexibleID(*platform),
+	}
+	if *manualReason != "" {
+		automatic := false
+		opts.AutomaticManagement = &automatic
+		opts.ManualManagementReason = *manualReason
+	}
+
+	acct, err := onboardingrules.PublishDiscoveredAccount(execCtx.Ctx, execCtx.Session, opts)
+	if err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Discovered account %s onboarded to safe '%s' as account %s", positional[0], acct.SafeName, acct.ID)
+	return nil
+}
+
+func (c *OnboardingCommand) simulate(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("onboarding simulate", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	filters := newDiscoveredFlags(fs)
+	unmatched := fs.Bool("unmatched", false, "Only show accounts no rule would onboard")
+	explain := fs.Bool("explain", false, "Explain why each rule did or did not match")
+
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	rules, err := onboardingrules.List(execCtx.Ctx, execCtx.Session)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := filters.accounts(execCtx)
+	if err != nil {
+		return err
+	}
+
+	var evals []onboardingrules.Evaluation
+	matched := 0
+	for _, acct := range accounts {
+		eval := onboardingrules.Evaluate(rules, acct)
+		if eval.Match != nil {
+			matched++
+			if *unmatched {
+				continue
+			}
+		}
+		evals = append(evals, eval)
+	}
+
+	if len(evals) == 0 {
+		if *unmatched && len(accounts) > 0 {
+			output.PrintSuccess("Every discovered account matches a rule")
+		} else {
+			output.PrintInfo("No discovered accounts found")
+		}
+		return nil
+	}
+
+	if execCtx.Formatter.GetFormat() != output.FormatTable {
+		return execCtx.Formatter.Format(evals)
+	}
+
+	table := output.NewTable("ID", "USERNAME", "ADDRESS", "PLATFORM TYPE", "RULE", "SAFE", "PLATFORM")
+	for _, eval := range evals {
+		acct := eval.Account
+		if eval.Match == nil {
+			table.AddRow(acct.ID.String(), acct.UserName, acct.Address, acct.PlatformType, output.Warning("no match"), "", "")
+			continue
+		}
+		table.AddRow(acct.ID.String(), acct.UserName, acct.Address, acct.PlatformType,
+			eval.Match.RuleName, eval.Match.TargetSafeName, eval.Match.TargetPlatformID.String())
+	}
+	table.Render()
+	fmt.Printf("\n%d of %d discovered accounts match a rule\n", matched, len(accounts))
+
+	if *explain {
+		for _, eval := range evals {
+			printEvaluation(eval)
+		}
+	}
+	return nil
+}
+
+// printEvaluation lists each rule evaluated for an account and why it was
+// or was not used.
+func printEvaluation(eval onboardingrules.Evaluation) {
+	fmt.Println()
+	fmt.Println(output.Header(fmt.Sprintf("%s %s@%s", eval.Account.ID, eval.Account.UserName, eval.Account.Address)))
+	for _, result := range eval.Results {
+		if result.Matched {
+			fmt.Printf("  %s %s\n", output.Success("✓"), result.Rule.RuleName)
+			continue
+		}
+		fmt.Printf("  %s %s: %s\n", output.Error("✗"), result.Rule.RuleName, strings.Join(result.Reasons, "; "))
+	}
+}
@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chrisranney/gopas"
+	"github.com/chrisranney/gopas/pkg/onboardingrules"
+)
+
+// fakeOnboarding serves the onboarding rule and discovered account
+// endpoints of the PVWA API from memory.
+type fakeOnboarding struct {
+	mu              sync.Mutex
+	rules           []onboardingrules.OnboardingRule
+	updates         map[string]map[string]interface{}
+	deleted         []string
+	discovered      []onboardingrules.DiscoveredAccount
+	discoveredQuery []string
+	published       map[string]onboardingrules.PublishDiscoveredAccountOptions
+}
+
+func newFakeOnboarding(t *testing.T) (*fakeOnboarding, *ExecutionContext) {
+	t.Helper()
+	f := &fakeOnboarding{
+		updates:   map[string]map[string]interface{}{},
+		published: map[string]onboardingrules.PublishDiscoveredAccountOptions{},
+	}
+	f.rules = []onboardingrules.OnboardingRule{
+		{RuleID: 1, RuleName: "Catch-all", TargetSafeName: "Discovered", TargetPlatformID: "WinServerLocal", RulePrecedence: 3},
+		{RuleID: 2, RuleName: "Server admins", TargetSafeName: "Win-Admins", TargetPlatformID: "WinServerLocal",
+			MachineTypeFilter: "Server", UserNameFilter: "adm_", UserNameMethod: "Begins", RulePrecedence: 1},
+		{RuleID: 3, RuleName: "Lab", TargetSafeName: "Lab", TargetPlatformID: "WinDesktopLocal",
+			AddressFilter: ".lab.example.com", AddressMethod: "Ends", RulePrecedence: 2},
+	}
+	f.discovered = []onboardingrules.DiscoveredAccount{
+		{ID: "a1", UserName: "ADM_backup", Address: "srv01.example.com", PlatformType: "Windows Server Local"},
+		{ID: "a2", UserName: "adm_jsmith", Address: "pc42.lab.example.com", PlatformType: "Windows Desktop Local"},
+		{ID: "a3", UserName: "svc_web", Address: "web01.example.com", PlatformType: "Windows Server Local"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /PasswordVault/API/Auth/CyberArk/Logon", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"token"`))
+	})
+	mux.HandleFunc("GET /PasswordVault/API/AutomaticOnboardingRules", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		json.NewEncoder(w).Encode(onboardingrules.OnboardingRulesResponse{AutomaticOnboardingRules: f.rules})
+	})
+	mux.HandleFunc("POST /PasswordVault/API/AutomaticOnboardingRules", func(w http.ResponseWriter, r *http.Request) {
+		var rule onboardingrules.OnboardingRule
+		json.NewDecoder(r.Body).Decode(&rule)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		rule.RuleID = len(f.rules) + 1
+		f.rules = append(f.rules, rule)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rule)
+	})
+	mux.HandleFunc("PUT /PasswordVault/API/AutomaticOnboardingRules/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.updates[r.PathValue("id")] = body
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		json.NewEncoder(w).Encode(onboardingrules.OnboardingRule{RuleID: id})
+	})
+	mux.HandleFunc("DELETE /PasswordVault/API/AutomaticOnboardingRules/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.deleted = append(f.deleted, r.PathValue("id"))
+	})
+	mux.HandleFunc("GET /PasswordVault/API/DiscoveredAccounts", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.discoveredQuery = append(f.discoveredQuery, r.URL.Query().Get("filter"))
+		json.NewEncoder(w).Encode(onboardingrules.DiscoveredAccountsResponse{Value: f.discovered, Count: len(f.discovered)})
+	})
+	mux.HandleFunc("POST /PasswordVault/API/DiscoveredAccounts/{id}/Onboard", func(w http.ResponseWriter, r *http.Request) {
+		var opts onboardingrules.PublishDiscoveredAccountOptions
+		json.NewDecoder(r.Body).Decode(&opts)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.published[r.PathValue("id")] = opts
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(onboardingrules.PublishedAccount{ID: "12_5", SafeName: opts.SafeName, PlatformID: opts.PlatformID})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	sess, err := gopas.NewSession(context.Background(), gopas.SessionOptions{
+		BaseURL:          srv.URL,
+		Credentials:      gopas.Credentials{Username: "vaultadmin", Password: "pw"},
+		SkipVersionCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	execCtx := createTestExecutionContext(t)
+	execCtx.Session = sess
+	return f, execCtx
+}
+
+func TestOnboardingCommand_RulesCreateAndUpdate(t *testing.T) {
+	f, execCtx := newFakeOnboarding(t)
+	cmd := &OnboardingCommand{}
+
+	err := cmd.Execute(execCtx, []string{"rules", "create", "Unix roots", "--safe=Unix", "--platform=UnixSSH",
+		"--system-type=unix", "--user-filter=root", "--user-method=equals", "--admin-only"})
+	if err != nil {
+		t.Fatalf("rules create error = %v", err)
+	}
+	created := f.rules[len(f.rules)-1]
+	if created.SystemTypeFilter != "Unix" || created.UserNameMethod != "Equals" || !created.IsAdminIDFilter {
+		t.Errorf("created rule = %+v", created)
+	}
+
+	if err := cmd.Execute(execCtx, []string{"rules", "update", "2", "--precedence=4"}); err != nil {
+		t.Fatalf("rules update error = %v", err)
+	}
+	// Only the flags given are sent
+	if body := f.updates["2"]; len(body) != 1 || body["RulePrecedence"] != float64(4) {
+		t.Errorf("update body = %v", body)
+	}
+
+	if err := cmd.Execute(execCtx, []string{"rules", "delete", "3", "--yes"}); err != nil {
+		t.Fatalf("rules delete error = %v", err)
+	}
+	if len(f.deleted) != 1 || f.deleted[0] != "3" {
+		t.Errorf("deleted = %v", f.deleted)
+	}
+}
+
+func TestOnboardingCommand_DiscoveredAndPublish(t *testing.T) {
+	f, execCtx := newFakeOnboarding(t)
+	cmd := &OnboardingCommand{}
+
+	if err := cmd.Execute(execCtx, []string{"discovered", "list", "--platform-type=Windows Server Local", "--privileged"}); err != nil {
+		t.Fatalf("discovered list error = %v", err)
+	}
+	if want := "platformType eq Windows Server Local AND privileged eq true"; len(f.discoveredQuery) != 1 || f.discoveredQuery[0] != want {
+		t.Errorf("filters = %q, want %q", f.discoveredQuery, want)
+	}
+
+	if err := cmd.Execute(execCtx, []string{"publish", "a3", "--safe=Web", "--platform=WinServerLocal", "--manual-reason=Managed by app team"}); err != nil {
+		t.Fatalf("publish error = %v", err)
+	}
+	got := f.published["a3"]
+	if got.SafeName != "Web" || got.AutomaticManagement == nil || *got.AutomaticManagement || got.ManualManagementReason == "" {
+		t.Errorf("published = %+v", got)
+	}
+}
+
+func TestOnboardingCommand_Simulate(t *testing.T) {
+	f, execCtx := newFakeOnboarding(t)
+	cmd := &OnboardingCommand{}
+
+	for _, args := range [][]string{{"simulate"}, {"simulate", "--explain"}, {"simulate", "--unmatched"}} {
+		if err := cmd.Execute(execCtx, args); err != nil {
+			t.Fatalf("%v error = %v", args, err)
+		}
+	}
+	if len(f.discoveredQuery) != 3 {
+		t.Errorf("discovered accounts read %d times, want 3", len(f.discoveredQuery))
+	}
+
+	// The fake's rules route each account as the simulation reports it
+	want := map[string]string{"a1": "Server admins", "a2": "Lab", "a3": "Catch-all"}
+	for _, acct := range f.discovered {
+		eval := onboardingrules.Evaluate(f.rules, acct)
+		if eval.Match == nil || eval.Match.RuleName != want[acct.ID.String()] {
+			t.Errorf("Evaluate(%s) match = %+v, want %s", acct.ID, eval.Match, want[acct.ID.String()])
+		}
+	}
+}
+
+func TestOnboardingCommand_ArgumentErrors(t *testing.T) {
+	_, execCtx := newFakeOnboarding(t)
+	cmd := &OnboardingCommand{}
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"rules", "create"}, "rule name required"},
+		{[]string{"rules", "create", "r", "--safe=S"}, "--safe and --platform are required"},
+		{[]string{"rules", "create", "r", "--safe=S", "--platform=P", "--user-method=Like"}, "invalid --user-method"},
+		{[]string{"rules", "create", "r", "--safe=S", "--platform=P", "--machine-type=Laptop"}, "invalid --machine-type"},
+		{[]string{"rules", "update", "x"}, "invalid rule ID"},
+		{[]string{"rules", "update", "2"}, "nothing to update"},
+		{[]string{"rules", "bogus"}, "unknown rules subcommand"},
+		{[]string{"discovered", "bogus"}, "unknown discovered subcommand"},
+		{[]string{"publish"}, "discovered account ID required"},
+		{[]string{"publish", "a1", "--safe=S"}, "--safe and --platform are required"},
+		{[]string{"bogus"}, "unknown subcommand"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			err := cmd.Execute(execCtx, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Execute() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
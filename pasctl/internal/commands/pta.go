@@ -0,0 +1,475 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisranney/gopas/pkg/eventsecurity"
+	"github.com/chrisranney/gopas/pkg/types"
+
+	"pasctl/internal/output"
+)
+
+// PTA event statuses accepted by SetEventStatus.
+const (
+	ptaStatusOpen   = "OPEN"
+	ptaStatusClosed = "CLOSED"
+)
+
+// Risk score thresholds used to color PTA events.
+const (
+	ptaHighScore   = 75
+	ptaMediumScore = 40
+)
+
+// PTACommand handles Privilege Threat Analytics events and settings.
+type PTACommand struct{}
+
+func (c *PTACommand) Name() string {
+	return "pta"
+}
+
+func (c *PTACommand) Description() string {
+	return "Triage Privilege Threat Analytics events"
+}
+
+func (c *PTACommand) Usage() string {
+	return `pta <subcommand> [options]
+
+Subcommands:
+  events                          List security events
+  event <event-id>                Show an event and its affected accounts
+  close <event-id>...             Close events
+  open <event-id>...              Reopen events
+  rules [list]                    List risky activity rules
+  rules enable <rule-id>          Enable a rule
+  rules disable <rule-id>         Disable a rule
+  privileged-users [list]         List privileged users
+  privileged-users add <name>     Add a privileged user
+  privileged-users remove <name>  Remove a privileged user (by name or ID)
+  privileged-groups [list]        List privileged groups
+  privileged-groups add <name>    Add a privileged group
+  privileged-groups remove <name> Remove a privileged group (by name or ID)
+
+Options for 'events':
+  --from=TIME           Events since TIME (YYYY-MM-DD HH:MM, RFC 3339, or a
+                        duration such as 24h meaning that long ago)
+  --to=TIME             Events until TIME
+  --status=STATUS       Only OPEN or CLOSED events
+  --min-score=N         Only events with a risk score of at least N
+  --account=ID          Only events affecting this account
+  --limit=N             Maximum results (default: 25)
+
+Scores of 75 and above are shown in red, 40 and above in yellow.
+
+Examples:
+  pta events --from=24h --status=open --min-score=75
+  pta event 5f3e2a
+  pta close 5f3e2a 5f3e2b
+  pta rules disable 23
+  pta privileged-users add svc_backup
+  pta privileged-groups remove "Domain Admins"
+`
+}
+
+func (c *PTACommand) Subcommands() []string {
+	return []string{"events", "event", "close", "open", "rules", "privileged-users", "privileged-groups"}
+}
+
+func (c *PTACommand) Execute(execCtx *ExecutionContext, args []string) error {
+	if err := RequireSession(execCtx); err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		fmt.Println(c.Usage())
+		return nil
+	}
+
+	switch args[0] {
+	case "events":
+		return c.events(execCtx, args[1:])
+	case "event":
+		return c.event(execCtx, args[1:])
+	case "close":
+		return c.setStatus(execCtx, args[1:], ptaStatusClosed)
+	case "open":
+		return c.setStatus(execCtx, args[1:], ptaStatusOpen)
+	case "rules":
+		return c.rules(execCtx, args[1:])
+	case "privileged-users":
+		return c.privilegedUsers(execCtx, args[1:])
+	case "privileged-groups":
+		return c.privilegedGroups(execCtx, args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+}
+
+// parseEventTime parses an event time filter into milliseconds since the
+// epoch, the unit PTA uses for event times, accepting a duration to mean
+// that long before now.
+func parseEventTime(value string) (int64, error) {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return time.Now().Add(-d).UnixMilli(), nil
+	}
+	seconds, err := parseRequestTime(value)
+	if err != nil {
+		return 0, err
+	}
+	return seconds * 1000, nil
+}
+
+// formatEventTime formats a PTA event time, which may be in seconds or
+// milliseconds since the epoch.
+func formatEventTime(ts int64) string {
+	if ts > 1e12 {
+		ts /= 1000
+	}
+	return formatUnixTime(ts)
+}
+
+// colorScore formats a risk score, colored by severity.
+func colorScore(score float64) string {
+	text := strconv.FormatFloat(score, 'f', -1, 64)
+	switch {
+	case score >= ptaHighScore:
+		return output.ErrorBold(text)
+	case score >= ptaMediumScore:
+		return output.Warning(text)
+	default:
+		return output.Success(text)
+	}
+}
+
+func (c *PTACommand) events(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("pta events", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	from := fs.String("from", "", "Events since TIME")
+	to := fs.String("to", "", "Events until TIME")
+	status := fs.String("status", "", "Only OPEN or CLOSED events")
+	minScore := fs.Float64("min-score", 0, "Minimum risk score")
+	accountID := fs.String("account", "", "Only events affecting this account")
+	limit := fs.Int("limit", 25, "Maximum results")
+
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts := eventsecurity.ListEventsOptions{
+		AccountID: *accountID,
+		Limit:     100,
+	}
+	var err error
+	if *from != "" {
+		if opts.FromDate, err = parseEventTime(*from); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if opts.ToDate, err = parseEventTime(*to); err != nil {
+			return err
+		}
+	}
+	if *status != "" {
+		opts.Status = strings.ToUpper(*status)
+		if opts.Status != ptaStatusOpen && opts.Status != ptaStatusClosed {
+			return fmt.Errorf("invalid status %q: use open or closed", *status)
+		}
+	}
+
+	// The API has no score filter, so read pages until enough events match
+	var events []eventsecurity.PTAEvent
+	for event, err := range eventsecurity.AllEvents(execCtx.Ctx, execCtx.Session, opts, types.PageOptions{}) {
+		if err != nil {
+			return err
+		}
+		if event.Score < *minScore {
+			continue
+		}
+		events = append(events, event)
+		if *limit > 0 && len(events) >= *limit {
+			break
+		}
+	}
+
+	if len(events) == 0 {
+		output.PrintInfo("No events found")
+		return nil
+	}
+
+	if execCtx.Formatter.GetFormat() == output.FormatTable {
+		table := output.NewTable("ID", "TIME", "TYPE", "SCORE", "USER", "MACHINE", "STATUS", "ACCOUNTS")
+		for _, event := range events {
+			table.AddRow(
+				event.ID.String(),
+				formatEventTime(event.EventTime),
+				event.Type,
+				colorScore(event.Score),
+				event.UserName,
+				event.MachineAddress,
+				event.Status,
+				strconv.Itoa(len(event.AffectedAccounts)),
+			)
+		}
+		table.Render()
+		fmt.Printf("\nShowing %d events\n", len(events))
+	} else {
+		return execCtx.Formatter.Format(events)
+	}
+
+	return nil
+}
+
+func (c *PTACommand) event(execCtx *ExecutionContext, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("event ID required")
+	}
+
+	event, err := eventsecurity.GetEvent(execCtx.Ctx, execCtx.Session, args[0])
+	if err != nil {
+		return err
+	}
+
+	if execCtx.Formatter.GetFormat() != output.FormatTable {
+		return execCtx.Formatter.Format(event)
+	}
+
+	fmt.Printf("Event:    %s\n", event.ID)
+	fmt.Printf("Type:     %s\n", event.Type)
+	fmt.Printf("Score:    %s\n", colorScore(event.Score))
+	fmt.Printf("Status:   %s\n", event.Status)
+	fmt.Printf("Time:     %s\n", formatEventTime(event.EventTime))
+	if event.UserName != "" {
+		fmt.Printf("User:     %s\n", event.UserName)
+	}
+	if event.MachineAddress != "" {
+		fmt.Printf("Machine:  %s\n", event.MachineAddress)
+	}
+	if event.CloudData != nil {
+		fmt.Printf("Cloud:    %s %s %s\n", event.CloudData.CloudProvider, event.CloudData.CloudService, event.CloudData.Region)
+	}
+
+	fmt.Println()
+	if len(event.AffectedAccounts) == 0 {
+		output.PrintInfo("No affected accounts")
+		return nil
+	}
+	fmt.Println(output.Header("Affected accounts:"))
+	table := output.NewTable("ACCOUNT ID", "NAME", "SAFE", "PLATFORM")
+	for _, acct := range event.AffectedAccounts {
+		table.AddRow(acct.AccountID.String(), acct.AccountName, acct.SafeName, acct.PlatformID.String())
+	}
+	table.Render()
+	return nil
+}
+
+func (c *PTACommand) setStatus(execCtx *ExecutionContext, args []string, status string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("event ID required")
+	}
+
+	failed := 0
+	for _, id := range args {
+		if err := eventsecurity.SetEventStatus(execCtx.Ctx, execCtx.Session, id, status); err != nil {
+			output.PrintError("Event %s: %v", id, err)
+			failed++
+			continue
+		}
+		output.PrintSuccess("Event %s %s", id, strings.ToLower(status))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d events could not be updated", failed, len(args))
+	}
+	return nil
+}
+
+func (c *PTACommand) rules(execCtx *ExecutionContext, args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		rules, err := eventsecurity.ListRules(execCtx.Ctx, execCtx.Session)
+		if err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			output.PrintInfo("No rules found")
+			return nil
+		}
+
+		if execCtx.Formatter.GetFormat() == output.FormatTable {
+			table := output.NewTable("ID", "NAME", "TYPE", "SCORE", "ACTIVE")
+			for _, rule := range rules {
+				table.AddRow(
+					rule.ID.String(),
+					rule.Name,
+					rule.Type,
+					colorScore(float64(rule.Score)),
+					output.BoolStatus(rule.Active),
+				)
+			}
+			table.Render()
+		} else {
+			return execCtx.Formatter.Format(rules)
+		}
+		return nil
+	}
+
+	var active bool
+	switch args[0] {
+	case "enable":
+		active = true
+	case "disable":
+		active = false
+	default:
+		return fmt.Errorf("unknown rules subcommand: %s", args[0])
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("rule ID required")
+	}
+	ruleID := args[1]
+
+	// Updating a rule replaces its score, so keep the current one
+	rules, err := eventsecurity.ListRules(execCtx.Ctx, execCtx.Session)
+	if err != nil {
+		return err
+	}
+	var rule *eventsecurity.PTARule
+	for i := range rules {
+		if rules[i].ID.String() == ruleID {
+			rule = &rules[i]
+			break
+		}
+	}
+	if rule == nil {
+		return fmt.Errorf("rule %s not found", ruleID)
+	}
+
+	if err := eventsecurity.SetRule(execCtx.Ctx, execCtx.Session, ruleID, eventsecurity.SetRuleOptions{Active: active, Score: rule.Score}); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Rule %s (%s) %sd", ruleID, rule.Name, args[0])
+	return nil
+}
+
+func (c *PTACommand) privilegedUsers(execCtx *ExecutionContext, args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		list, err := eventsecurity.GetPrivilegedUsers(execCtx.Ctx, execCtx.Session)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			output.PrintInfo("No privileged users")
+			return nil
+		}
+		if execCtx.Formatter.GetFormat() == output.FormatTable {
+			table := output.NewTable("ID", "USERNAME", "SOURCE")
+			for _, u := range list {
+				table.AddRow(u.ID.String(), u.UserName, u.Source)
+			}
+			table.Render()
+		} else {
+			return execCtx.Formatter.Format(list)
+		}
+		return nil
+	}
+
+	if len(args) < 2 {
+		return fmt.Errorf("user name required")
+	}
+	name := args[1]
+
+	switch args[0] {
+	case "add":
+		if err := eventsecurity.AddPrivilegedUser(execCtx.Ctx, execCtx.Session, name); err != nil {
+			return err
+		}
+		output.PrintSuccess("Privileged user %s added", name)
+		return nil
+	case "remove":
+		list, err := eventsecurity.GetPrivilegedUsers(execCtx.Ctx, execCtx.Session)
+		if err != nil {
+			return err
+		}
+		id := ""
+		for _, u := range list {
+			if u.ID.String() == name || strings.EqualFold(u.UserName, name) {
+				id = u.ID.String()
+				break
+			}
+		}
+		if id == "" {
+			return fmt.Errorf("privileged user %s not found", name)
+		}
+		if err := eventsecurity.RemovePrivilegedUser(execCtx.Ctx, execCtx.Session, id); err != nil {
+			return err
+		}
+		output.PrintSuccess("Privileged user %s removed", name)
+		return nil
+	default:
+		return fmt.Errorf("unknown privileged-users subcommand: %s", args[0])
+	}
+}
+
+func (c *PTACommand) privilegedGroups(execCtx *ExecutionContext, args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		list, err := eventsecurity.GetPrivilegedGroups(execCtx.Ctx, execCtx.Session)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			output.PrintInfo("No privileged groups")
+			return nil
+		}
+		if execCtx.Formatter.GetFormat() == output.FormatTable {
+			table := output.NewTable("ID", "GROUP", "SOURCE")
+			for _, g := range list {
+				table.AddRow(g.ID, g.GroupName, g.Source)
+			}
+			table.Render()
+		} else {
+			return execCtx.Formatter.Format(list)
+		}
+		return nil
+	}
+
+	if len(args) < 2 {
+		return fmt.Errorf("group name required")
+	}
+	name := args[1]
+
+	switch args[0] {
+	case "add":
+		if err := eventsecurity.AddPrivilegedGroup(execCtx.Ctx, execCtx.Session, name); err != nil {
+			return err
+		}
+		output.PrintSuccess("Privileged group %s added", name)
+		return nil
+	case "remove":
+		list, err := eventsecurity.GetPrivilegedGroups(execCtx.Ctx, execCtx.Session)
+		if err != nil {
+			return err
+		}
+		id := ""
+		for _, g := range list {
+			if g.ID == name || strings.EqualFold(g.GroupName, name) {
+				id = g.ID
+				break
+			}
+		}
+		if id == "" {
+			return fmt.Errorf("privileged group %s not found", name)
+		}
+		if err := eventsecurity.RemovePrivilegedGroup(execCtx.Ctx, execCtx.Session, id); err != nil {
+			return err
+		}
+		output.PrintSuccess("Privileged group %s removed", name)
+		return nil
+	default:
+		return fmt.Errorf("unknown privileged-groups subcommand: %s", args[0])
+	}
+}
@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chrisranney/gopas"
+	"github.com/chrisranney/gopas/pkg/eventsecurity"
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+// fakePTA serves the PTA endpoints of the PVWA API from memory.
+type fakePTA struct {
+	mu          sync.Mutex
+	events      []eventsecurity.PTAEvent
+	eventQuery  []string
+	rules       []eventsecurity.PTARule
+	ruleUpdates map[string]eventsecurity.SetRuleOptions
+	users       []eventsecurity.PrivilegedUser
+	removed     []string
+}
+
+func newFakePTA(t *testing.T) (*fakePTA, *ExecutionContext) {
+	t.Helper()
+	f := &fakePTA{ruleUpdates: map[string]eventsecurity.SetRuleOptions{}}
+	for i, score := range []float64{90, 55, 10} {
+		f.events = append(f.events, eventsecurity.PTAEvent{
+			ID:               types.FlexibleID(strconv.Itoa(i + 1)),
+			Type:             "SuspectedCredentialsTheft",
+			Score:            score,
+			EventTime:        1609459200000,
+			Status:           ptaStatusOpen,
+			AffectedAccounts: []eventsecurity.AffectedAccount{{AccountID: "12_3", AccountName: "root", SafeName: "Linux"}},
+		})
+	}
+	f.rules = []eventsecurity.PTARule{{ID: "23", Name: "Unmanaged privileged access", Active: true, Score: 80}}
+	f.users = []eventsecurity.PrivilegedUser{{ID: "7", UserName: "svc_backup"}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /PasswordVault/API/Auth/CyberArk/Logon", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"token"`))
+	})
+	mux.HandleFunc("GET /PasswordVault/API/pta/API/Events", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.eventQuery = append(f.eventQuery, r.URL.RawQuery)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		events := f.events[min(offset, len(f.events)):]
+		json.NewEncoder(w).Encode(eventsecurity.PTAEventsResponse{PTAEvents: events, Total: len(f.events)})
+	})
+	mux.HandleFunc("GET /PasswordVault/API/pta/API/Events/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, e := range f.events {
+			if e.ID.String() == r.PathValue("id") {
+				json.NewEncoder(w).Encode(e)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("PATCH /PasswordVault/API/pta/API/Events/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Status string }
+		json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i := range f.events {
+			if f.events[i].ID.String() == r.PathValue("id") {
+				f.events[i].Status = body.Status
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"ErrorCode":"PTA0001E","ErrorMessage":"Event not found"}`))
+	})
+	mux.HandleFunc("GET /PasswordVault/API/pta/API/Settings/RiskyActivities", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(f.rules)
+	})
+	mux.HandleFunc("PUT /PasswordVault/API/pta/API/Settings/RiskyActivities/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var opts eventsecurity.SetRuleOptions
+		json.NewDecoder(r.Body).Decode(&opts)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.ruleUpdates[r.PathValue("id")] = opts
+	})
+	mux.HandleFunc("GET /PasswordVault/API/pta/API/Settings/PrivilegedUsers", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(f.users)
+	})
+	mux.HandleFunc("DELETE /PasswordVault/API/pta/API/Settings/PrivilegedUsers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.removed = append(f.removed, r.PathValue("id"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	sess, err := gopas.NewSession(context.Background(), gopas.SessionOptions{
+		BaseURL:          srv.URL,
+		Credentials:      gopas.Credentials{Username: "soc", Password: "pw"},
+		SkipVersionCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	execCtx := createTestExecutionContext(t)
+	execCtx.Session = sess
+	return f, execCtx
+}
+
+func TestPTACommand_Events(t *testing.T) {
+	f, execCtx := newFakePTA(t)
+
+	if err := (&PTACommand{}).Execute(execCtx, []string{"events", "--status=open", "--from=2021-01-01", "--min-score=50"}); err != nil {
+		t.Fatalf("events error = %v", err)
+	}
+	// PTA compares event times in milliseconds since the epoch
+	fromDate := "fromDate=" + strconv.FormatInt(time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local).UnixMilli(), 10)
+	if len(f.eventQuery) != 1 || !strings.Contains(f.eventQuery[0], "status=OPEN") || !strings.Contains(f.eventQuery[0], fromDate) {
+		t.Errorf("event queries = %v, want %s", f.eventQuery, fromDate)
+	}
+
+	if err := (&PTACommand{}).Execute(execCtx, []string{"events", "--status=pending"}); err == nil {
+		t.Error("events with an invalid status succeeded")
+	}
+}
+
+func TestPTACommand_CloseAndOpen(t *testing.T) {
+	f, execCtx := newFakePTA(t)
+	cmd := &PTACommand{}
+
+	if err := cmd.Execute(execCtx, []string{"close", "1", "2"}); err != nil {
+		t.Fatalf("close error = %v", err)
+	}
+	if err := cmd.Execute(execCtx, []string{"open", "2"}); err != nil {
+		t.Fatalf("open error = %v", err)
+	}
+	if f.events[0].Status != ptaStatusClosed || f.events[1].Status != ptaStatusOpen {
+		t.Errorf("statuses = %s, %s", f.events[0].Status, f.events[1].Status)
+	}
+
+	if err := cmd.Execute(execCtx, []string{"close", "99"}); err == nil {
+		t.Error("closing an unknown event succeeded")
+	}
+	if err := cmd.Execute(execCtx, []string{"event", "1"}); err != nil {
+		t.Errorf("event error = %v", err)
+	}
+}
+
+func TestPTACommand_Rules(t *testing.T) {
+	f, execCtx := newFakePTA(t)
+	cmd := &PTACommand{}
+
+	if err := cmd.Execute(execCtx, []string{"rules", "disable", "23"}); err != nil {
+		t.Fatalf("rules disable error = %v", err)
+	}
+	// Disabling keeps the rule's score
+	if got := f.ruleUpdates["23"]; got.Active || got.Score != 80 {
+		t.Errorf("rule update = %+v", got)
+	}
+	if err := cmd.Execute(execCtx, []string{"rules", "enable", "99"}); err == nil {
+		t.Error("enabling an unknown rule succeeded")
+	}
+}
+
+func TestPTACommand_PrivilegedUsersRemoveByName(t *testing.T) {
+	f, execCtx := newFakePTA(t)
+
+	if err := (&PTACommand{}).Execute(execCtx, []string{"privileged-users", "remove", "SVC_BACKUP"}); err != nil {
+		t.Fatalf("remove error = %v", err)
+	}
+	if len(f.removed) != 1 || f.removed[0] != "7" {
+		t.Errorf("removed = %v, want [7]", f.removed)
+	}
+	if err := (&PTACommand{}).Execute(execCtx, []string{"privileged-users", "remove", "nobody"}); err == nil {
+		t.Error("removing an unknown user succeeded")
+	}
+}
+
+func TestParseEventTime(t *testing.T) {
+	before := time.Now().Add(-24 * time.Hour).UnixMilli()
+	got, err := parseEventTime("24h")
+	if err != nil {
+		t.Fatalf("parseEventTime() error = %v", err)
+	}
+	if after := time.Now().Add(-24 * time.Hour).UnixMilli(); got < before || got > after {
+		t.Errorf("parseEventTime(24h) = %d, want between %d and %d", got, before, after)
+	}
+
+	got, err = parseEventTime("2021-01-01T12:00:00Z")
+	if err != nil {
+		t.Fatalf("parseEventTime() error = %v", err)
+	}
+	if got != 1609502400000 {
+		t.Errorf("parseEventTime(2021-01-01T12:00:00Z) = %d, want 1609502400000", got)
+	}
+}
+
+func TestColorScore(t *testing.T) {
+	for _, score := range []float64{0, 39.5, 40, 74, 75, 100} {
+		if got := colorScore(score); !strings.Contains(got, strconv.FormatFloat(score, 'f', -1, 64)) {
+			t.Errorf("colorScore(%v) = %q", score, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package commands
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"sort"
 
@@ -78,3 +79,40 @@ func RequireSession(execCtx *ExecutionContext) error {
 	}
 	return nil
 }
+
+// ExitCodeDrift is the exit status used when plan or snapshot --diff find
+// differences.
+const ExitCodeDrift = 2
+
+// ExitError is returned by commands that need pasctl to exit with a
+// specific status in single command and script mode.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// parseFlags parses args with fs, allowing flags to follow positional
+// arguments as in "requests approve 42 --reason=ok", and returns the
+// positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,533 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/chrisranney/gopas/pkg/requests"
+
+	"pasctl/internal/output"
+)
+
+// RequestsCommand handles dual-control access requests.
+type RequestsCommand struct{}
+
+func (c *RequestsCommand) Name() string {
+	return "requests"
+}
+
+func (c *RequestsCommand) Description() string {
+	return "Review and manage access requests"
+}
+
+func (c *RequestsCommand) Usage() string {
+	return `requests <subcommand> [options]
+
+Subcommands:
+  incoming              List requests waiting for your approval
+  mine                  List your own requests
+  create <account-id>   Request access to an account
+  approve <request-id>  Approve one or more requests
+  deny <request-id>     Deny one or more requests
+  cancel <request-id>   Cancel one of your requests
+  watch                 Poll for new incoming requests and notify
+
+Options for 'incoming' and 'mine':
+  --safe=NAME           Only requests for accounts in this safe
+  --requestor=USER      Only requests made by this user ('incoming' only)
+  --waiting             Only requests still waiting for confirmation
+  --expired             Include expired requests
+  --limit=N             Maximum results (default: 25)
+
+Options for 'create':
+  --reason=TEXT         Reason for access
+  --ticket-system=NAME  Ticketing system name
+  --ticket-id=ID        Ticket ID
+  --from=TIME           Start of the access window (YYYY-MM-DD HH:MM or RFC 3339)
+  --to=TIME             End of the access window
+  --multiple            Request access for multiple uses
+
+Options for 'approve' and 'deny':
+  --reason=TEXT         Reason for the decision (prompted when omitted for 'deny'
+                        and bulk approvals)
+  --all-from=USER       Approve every waiting request from USER ('approve' only)
+  --safe=NAME           With --all-from, only requests for this safe
+  --yes                 With --all-from, skip the confirmation prompt
+
+Options for 'watch':
+  --interval=DURATION   Polling interval (default: 30s)
+  --safe=NAME           Only notify about requests for this safe
+  --duration=DURATION   Stop after DURATION (default: until Ctrl+C)
+
+Examples:
+  requests incoming --waiting
+  requests incoming --safe=Production --requestor=jsmith
+  requests mine --expired
+  requests create 12_34 --reason="Patch window" --from="2024-06-01 22:00" --to="2024-06-02 02:00"
+  requests approve 42 --reason="Approved for CHG0042"
+  requests approve --all-from=jsmith --safe=Production
+  requests deny 43 --reason="No change ticket"
+  requests cancel 44
+  requests watch --interval=1m
+`
+}
+
+func (c *RequestsCommand) Subcommands() []string {
+	return []string{"incoming", "mine", "create", "approve", "deny", "cancel", "watch"}
+}
+
+func (c *RequestsCommand) Execute(execCtx *ExecutionContext, args []string) error {
+	if err := RequireSession(execCtx); err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		fmt.Println(c.Usage())
+		return nil
+	}
+
+	switch args[0] {
+	case "incoming":
+		return c.list(execCtx, "incoming", args[1:])
+	case "mine":
+		return c.list(execCtx, "mine", args[1:])
+	case "create":
+		return c.create(execCtx, args[1:])
+	case "approve":
+		return c.approve(execCtx, args[1:])
+	case "deny":
+		return c.deny(execCtx, args[1:])
+	case "cancel":
+		return c.cancel(execCtx, args[1:])
+	case "watch":
+		return c.watch(execCtx, args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+}
+
+// requestFilter holds the client-side filters the API does not support.
+type requestFilter struct {
+	safe      string
+	requestor string
+}
+
+func (f requestFilter) matches(req requests.Request) bool {
+	if f.safe != "" && !strings.EqualFold(req.SafeName, f.safe) {
+		return false
+	}
+	if f.requestor != "" && !strings.EqualFold(req.RequestorUserName, f.requestor) {
+		return false
+	}
+	return true
+}
+
+func (f requestFilter) apply(reqs []requests.Request) []requests.Request {
+	var matched []requests.Request
+	for _, req := range reqs {
+		if f.matches(req) {
+			matched = append(matched, req)
+		}
+	}
+	return matched
+}
+
+func (c *RequestsCommand) list(execCtx *ExecutionContext, which string, args []string) error {
+	fs := flag.NewFlagSet("requests "+which, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	safe := fs.String("safe", "", "Only requests for this safe")
+	requestor := fs.String("requestor", "", "Only requests made by this user")
+	waiting := fs.Bool("waiting", false, "Only waiting requests")
+	expired := fs.Bool("expired", false, "Include expired requests")
+	limit := fs.Int("limit", 25, "Maximum results")
+
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts := requests.ListOptions{
+		OnlyWaiting: *waiting,
+		Expired:     *expired,
+	}
+	filter := requestFilter{safe: *safe, requestor: *requestor}
+
+	var list func(context.Context, *ExecutionContext, requests.ListOptions) ([]requests.Request, error)
+	if which == "incoming" {
+		list = listIncomingRequests
+	} else {
+		list = listMyRequests
+	}
+
+	// Filtering happens locally, so fetch every page before applying the limit
+	all, err := list(execCtx.Ctx, execCtx, opts)
+	if err != nil {
+		return err
+	}
+	matched := filter.apply(all)
+	total := len(matched)
+	if *limit > 0 && len(matched) > *limit {
+		matched = matched[:*limit]
+	}
+
+	if len(matched) == 0 {
+		output.PrintInfo("No requests found")
+		return nil
+	}
+
+	if execCtx.Formatter.GetFormat() == output.FormatTable {
+		printRequestsTable(matched)
+		fmt.Printf("\nShowing %d of %d requests\n", len(matched), total)
+	} else {
+		return execCtx.Formatter.Format(matched)
+	}
+
+	return nil
+}
+
+// requestsPageSize is the page size used when fetching every request.
+const requestsPageSize = 100
+
+func listIncomingRequests(ctx context.Context, execCtx *ExecutionContext, opts requests.ListOptions) ([]requests.Request, error) {
+	return listAllRequests(ctx, opts, func(opts requests.ListOptions) (*requests.RequestsResponse, error) {
+		return requests.ListIncoming(ctx, execCtx.Session, opts)
+	})
+}
+
+func listMyRequests(ctx context.Context, execCtx *ExecutionContext, opts requests.ListOptions) ([]requests.Request, error) {
+	return listAllRequests(ctx, opts, func(opts requests.ListOptions) (*requests.RequestsResponse, error) {
+		return requests.ListMyRequests(ctx, execCtx.Session, opts)
+	})
+}
+
+// listAllRequests pages through a request listing.
+func listAllRequests(ctx context.Context, opts requests.ListOptions, fetch func(requests.ListOptions) (*requests.RequestsResponse, error)) ([]requests.Request, error) {
+	var all []requests.Request
+	opts.Limit = requestsPageSize
+	for {
+		result, err := fetch(opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, result.Requests...)
+		if len(result.Requests) == 0 || len(all) >= result.Total {
+			return all, nil
+		}
+		opts.Offset += len(result.Requests)
+	}
+}
+
+func printRequestsTable(reqs []requests.Request) {
+	table := output.NewTable("ID", "REQUESTOR", "ACCOUNT", "SAFE", "STATUS", "CREATED", "EXPIRES", "REASON")
+	for _, req := range reqs {
+		table.AddRow(
+			req.RequestID.String(),
+			req.RequestorUserName,
+			requestAccount(req),
+			req.SafeName,
+			requestStatus(req),
+			formatUnixTime(req.CreationDate),
+			formatUnixTime(req.ExpirationDate),
+			truncate(req.RequestorReason, 40),
+		)
+	}
+	table.Render()
+}
+
+// requestAccount describes the account a request is for.
+func requestAccount(req requests.Request) string {
+	if req.AccountDetails == nil {
+		return ""
+	}
+	if req.AccountDetails.Address != "" && req.AccountDetails.AccountName != "" {
+		return req.AccountDetails.AccountName + " (" + req.AccountDetails.Address + ")"
+	}
+	if req.AccountDetails.AccountName != "" {
+		return req.AccountDetails.AccountName
+	}
+	return req.AccountDetails.AccountID.String()
+}
+
+func requestStatus(req requests.Request) string {
+	status := req.StatusTitle
+	if status == "" {
+		status = fmt.Sprintf("%d", req.Status)
+	}
+	if req.RequiredConfirmers > 1 && req.ConfirmationsLeft > 0 {
+		status += fmt.Sprintf(" (%d/%d)", req.RequiredConfirmers-req.ConfirmationsLeft, req.RequiredConfirmers)
+	}
+	return status
+}
+
+func formatUnixTime(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).Format("2006-01-02 15:04")
+}
+
+// parseRequestTime parses an access window boundary.
+func parseRequestTime(value string) (int64, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %q: use YYYY-MM-DD HH:MM or RFC 3339", value)
+}
+
+func (c *RequestsCommand) create(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("requests create", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	reason := fs.String("reason", "", "Reason for access")
+	ticketSystem := fs.String("ticket-system", "", "Ticketing system name")
+	ticketID := fs.String("ticket-id", "", "Ticket ID")
+	from := fs.String("from", "", "Start of the access window")
+	to := fs.String("to", "", "End of the access window")
+	multiple := fs.Bool("multiple", false, "Request access for multiple uses")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("account ID required")
+	}
+
+	opts := requests.CreateOptions{
+		AccountID:              positional[0],
+		Reason:                 *reason,
+		TicketingSystemName:    *ticketSystem,
+		TicketID:               *ticketID,
+		MultipleAccessRequired: *multiple,
+	}
+	if *from != "" {
+		if opts.FromDate, err = parseRequestTime(*from); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if opts.ToDate, err = parseRequestTime(*to); err != nil {
+			return err
+		}
+	}
+	if opts.FromDate != 0 && opts.ToDate != 0 && opts.ToDate <= opts.FromDate {
+		return fmt.Errorf("--to must be after --from")
+	}
+
+	req, err := requests.Create(execCtx.Ctx, execCtx.Session, opts)
+	if err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Request %s created for account %s", req.RequestID, opts.AccountID)
+	return execCtx.Formatter.Format(req)
+}
+
+func (c *RequestsCommand) approve(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("requests approve", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	reason := fs.String("reason", "", "Reason for approval")
+	allFrom := fs.String("all-from", "", "Approve every waiting request from this user")
+	safe := fs.String("safe", "", "With --all-from, only requests for this safe")
+	yes := fs.Bool("yes", false, "Skip the confirmation prompt")
+
+	ids, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *allFrom == "" {
+		if *safe != "" {
+			return fmt.Errorf("--safe requires --all-from")
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("request ID or --all-from required")
+		}
+		return decideRequests(ids, "approved", func(id string) error {
+			_, err := requests.Approve(execCtx.Ctx, execCtx.Session, id, requests.ApproveOptions{Reason: *reason})
+			return err
+		})
+	}
+	if len(ids) > 0 {
+		return fmt.Errorf("request IDs cannot be combined with --all-from")
+	}
+
+	all, err := listIncomingRequests(execCtx.Ctx, execCtx, requests.ListOptions{OnlyWaiting: true})
+	if err != nil {
+		return err
+	}
+	matched := requestFilter{safe: *safe, requestor: *allFrom}.apply(all)
+	if len(matched) == 0 {
+		output.PrintInfo("No waiting requests from %s", *allFrom)
+		return nil
+	}
+
+	printRequestsTable(matched)
+	if !*yes {
+		fmt.Printf("Approve these %d requests? [y/N]: ", len(matched))
+		var confirm string
+		fmt.Scanln(&confirm)
+		if strings.ToLower(confirm) != "y" && strings.ToLower(confirm) != "yes" {
+			output.PrintInfo("Approval cancelled")
+			return nil
+		}
+	}
+	if *reason == "" {
+		if *reason, err = prompt("Reason: "); err != nil {
+			return err
+		}
+	}
+
+	ids = make([]string, len(matched))
+	for i, req := range matched {
+		ids[i] = req.RequestID.String()
+	}
+	return decideRequests(ids, "approved", func(id string) error {
+		_, err := requests.Approve(execCtx.Ctx, execCtx.Session, id, requests.ApproveOptions{Reason: *reason})
+		return err
+	})
+}
+
+func (c *RequestsCommand) deny(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("requests deny", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	reason := fs.String("reason", "", "Reason for denial")
+
+	ids, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("request ID required")
+	}
+	if *reason == "" {
+		if *reason, err = prompt("Reason: "); err != nil {
+			return err
+		}
+	}
+
+	return decideRequests(ids, "denied", func(id string) error {
+		_, err := requests.Deny(execCtx.Ctx, execCtx.Session, id, requests.DenyOptions{Reason: *reason})
+		return err
+	})
+}
+
+// decideRequests applies decide to each request, reporting every outcome,
+// and fails if any request could not be processed.
+func decideRequests(ids []string, verb string, decide func(id string) error) error {
+	failed := 0
+	for _, id := range ids {
+		if err := decide(id); err != nil {
+			output.PrintError("Request %s: %v", id, err)
+			failed++
+			continue
+		}
+		output.PrintSuccess("Request %s %s", id, verb)
+	}
+
+	if len(ids) > 1 {
+		fmt.Printf("\n%d of %d requests %s\n", len(ids)-failed, len(ids), verb)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d requests could not be %s", failed, len(ids), verb)
+	}
+	return nil
+}
+
+func (c *RequestsCommand) cancel(execCtx *ExecutionContext, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("request ID required")
+	}
+
+	requestID := args[0]
+	if err := requests.Delete(execCtx.Ctx, execCtx.Session, requestID); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Request %s cancelled", requestID)
+	return nil
+}
+
+func (c *RequestsCommand) watch(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("requests watch", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	interval := fs.Duration("interval", 30*time.Second, "Polling interval")
+	safe := fs.String("safe", "", "Only notify about requests for this safe")
+	duration := fs.Duration("duration", 0, "Stop after this long")
+
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	ctx, stop := signal.NotifyContext(execCtx.Ctx, os.Interrupt)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	filter := requestFilter{safe: *safe}
+	poll := func() ([]requests.Request, error) {
+		all, err := listIncomingRequests(ctx, execCtx, requests.ListOptions{OnlyWaiting: true})
+		if err != nil {
+			return nil, err
+		}
+		return filter.apply(all), nil
+	}
+
+	pending, err := poll()
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(pending))
+	for _, req := range pending {
+		seen[req.RequestID.String()] = true
+	}
+	output.PrintInfo("Watching for new requests every %s (%d waiting) - press Ctrl+C to stop", *interval, len(pending))
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			output.PrintInfo("Stopped watching requests")
+			return nil
+		case <-ticker.C:
+		}
+
+		pending, err := poll()
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			output.PrintWarning("Failed to poll requests: %v", err)
+			continue
+		}
+		for _, req := range pending {
+			id := req.RequestID.String()
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			// The bell gets the attention of an approver working elsewhere
+			fmt.Print("\a")
+			output.PrintWarning("%s New request %s from %s for %s in %s: %s",
+				time.Now().Format("15:04:05"), id, req.RequestorUserName, requestAccount(req), req.SafeName, req.RequestorReason)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrisranney/gopas"
+	"github.com/chrisranney/gopas/pkg/accounts"
+	"github.com/chrisranney/gopas/pkg/pvwatest"
+	"github.com/chrisranney/gopas/pkg/requests"
+	"github.com/chrisranney/gopas/pkg/safemembers"
+	"github.com/chrisranney/gopas/pkg/safes"
+	"github.com/chrisranney/gopas/pkg/users"
+)
+
+// newRequestsTestServer returns a server with two safes that "approver" may
+// authorize requests for, and an execution context logged on as approver.
+func newRequestsTestServer(t *testing.T) (*pvwatest.Server, *ExecutionContext, map[string]string) {
+	t.Helper()
+	srv := pvwatest.NewServer()
+	t.Cleanup(srv.Close)
+
+	srv.AddUser(users.CreateOptions{Username: "approver", InitialPassword: "pw"})
+	srv.AddUser(users.CreateOptions{Username: "jsmith", InitialPassword: "pw"})
+	srv.AddUser(users.CreateOptions{Username: "mjones", InitialPassword: "pw"})
+	accountIDs := map[string]string{}
+	for _, safe := range []string{"Production", "Test"} {
+		srv.AddSafe(safes.CreateOptions{SafeName: safe})
+		srv.AddSafeMember(safe, safemembers.AddOptions{
+			MemberName:  "approver",
+			Permissions: &safemembers.Permissions{ListAccounts: true, RequestsAuthorizationLevel1: true},
+		})
+		acct := srv.AddAccount(accounts.CreateOptions{SafeName: safe, PlatformID: "UnixSSH", Address: "web01", UserName: "root"})
+		accountIDs[safe] = string(acct.ID)
+	}
+
+	sess, err := gopas.NewSession(context.Background(), gopas.SessionOptions{
+		BaseURL:          srv.URL,
+		Credentials:      gopas.Credentials{Username: "approver", Password: "pw"},
+		SkipVersionCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	execCtx := createTestExecutionContext(t)
+	execCtx.Session = sess
+	return srv, execCtx, accountIDs
+}
+
+func requestStatusByID(t *testing.T, execCtx *ExecutionContext) map[string]string {
+	t.Helper()
+	all, err := listIncomingRequests(execCtx.Ctx, execCtx, requests.ListOptions{})
+	if err != nil {
+		t.Fatalf("listIncomingRequests() error = %v", err)
+	}
+	status := map[string]string{}
+	for _, req := range all {
+		status[req.RequestID.String()] = req.StatusTitle
+	}
+	return status
+}
+
+func TestRequestsCommand_ApproveAndDeny(t *testing.T) {
+	srv, execCtx, accountIDs := newRequestsTestServer(t)
+	a := srv.AddRequest("jsmith", requests.CreateOptions{AccountID: accountIDs["Production"]})
+	b := srv.AddRequest("jsmith", requests.CreateOptions{AccountID: accountIDs["Production"]})
+	cmd := &RequestsCommand{}
+
+	if err := cmd.Execute(execCtx, []string{"approve", a.RequestID.String(), "--reason=CHG0042"}); err != nil {
+		t.Fatalf("approve error = %v", err)
+	}
+	if err := cmd.Execute(execCtx, []string{"deny", b.RequestID.String(), "--reason=No ticket"}); err != nil {
+		t.Fatalf("deny error = %v", err)
+	}
+	status := requestStatusByID(t, execCtx)
+	if status[a.RequestID.String()] != "Confirmed" || status[b.RequestID.String()] != "Rejected" {
+		t.Errorf("statuses = %v", status)
+	}
+
+	// Deciding a request twice fails
+	if err := cmd.Execute(execCtx, []string{"approve", a.RequestID.String(), "--reason=again"}); err == nil {
+		t.Error("approving a confirmed request succeeded")
+	}
+}
+
+func TestRequestsCommand_BulkApprove(t *testing.T) {
+	srv, execCtx, accountIDs := newRequestsTestServer(t)
+	prod1 := srv.AddRequest("jsmith", requests.CreateOptions{AccountID: accountIDs["Production"]})
+	prod2 := srv.AddRequest("jsmith", requests.CreateOptions{AccountID: accountIDs["Production"]})
+	test := srv.AddRequest("jsmith", requests.CreateOptions{AccountID: accountIDs["Test"]})
+	other := srv.AddRequest("mjones", requests.CreateOptions{AccountID: accountIDs["Production"]})
+
+	err := (&RequestsCommand{}).Execute(execCtx, []string{"approve", "--all-from=jsmith", "--safe=production", "--yes", "--reason=Release"})
+	if err != nil {
+		t.Fatalf("approve --all-from error = %v", err)
+	}
+
+	status := requestStatusByID(t, execCtx)
+	want := map[string]string{
+		prod1.RequestID.String(): "Confirmed",
+		prod2.RequestID.String(): "Confirmed",
+		test.RequestID.String():  "Pending",
+		other.RequestID.String(): "Pending",
+	}
+	for id, s := range want {
+		if status[id] != s {
+			t.Errorf("request %s status = %q, want %q", id, status[id], s)
+		}
+	}
+}
+
+func TestRequestsCommand_ArgumentErrors(t *testing.T) {
+	_, execCtx, _ := newRequestsTestServer(t)
+	cmd := &RequestsCommand{}
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"approve"}, "request ID or --all-from required"},
+		{[]string{"approve", "1", "--all-from=jsmith"}, "cannot be combined"},
+		{[]string{"approve", "1", "--safe=Production"}, "--safe requires --all-from"},
+		{[]string{"create"}, "account ID required"},
+		{[]string{"create", "1", "--from=tomorrow"}, "invalid time"},
+		{[]string{"create", "1", "--from=2024-06-02 10:00", "--to=2024-06-01 10:00"}, "--to must be after --from"},
+		{[]string{"cancel"}, "request ID required"},
+		{[]string{"watch", "--interval=0s"}, "--interval must be positive"},
+		{[]string{"bogus"}, "unknown subcommand"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			err := cmd.Execute(execCtx, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Execute() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequestsCommand_CreateAndCancel(t *testing.T) {
+	_, execCtx, accountIDs := newRequestsTestServer(t)
+	cmd := &RequestsCommand{}
+
+	err := cmd.Execute(execCtx, []string{"create", accountIDs["Test"], "--reason=Patching",
+		"--from=2024-06-01 22:00", "--to=2024-06-02 02:00"})
+	if err != nil {
+		t.Fatalf("create error = %v", err)
+	}
+	mine, err := listMyRequests(execCtx.Ctx, execCtx, requests.ListOptions{})
+	if err != nil || len(mine) != 1 {
+		t.Fatalf("listMyRequests() = %v, %v", mine, err)
+	}
+	if mine[0].RequestorReason != "Patching" || mine[0].AccessFrom == 0 || mine[0].AccessTo <= mine[0].AccessFrom {
+		t.Errorf("created request = %+v", mine[0])
+	}
+
+	if err := cmd.Execute(execCtx, []string{"cancel", mine[0].RequestID.String()}); err != nil {
+		t.Fatalf("cancel error = %v", err)
+	}
+	if mine, _ := listMyRequests(execCtx.Ctx, execCtx, requests.ListOptions{}); len(mine) != 0 {
+		t.Errorf("requests after cancel = %v", mine)
+	}
+}
+
+func TestRequestsCommand_Watch(t *testing.T) {
+	srv, execCtx, accountIDs := newRequestsTestServer(t)
+	srv.AddRequest("jsmith", requests.CreateOptions{AccountID: accountIDs["Production"]})
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		srv.AddRequest("mjones", requests.CreateOptions{AccountID: accountIDs["Production"]})
+	}()
+
+	start := time.Now()
+	err := (&RequestsCommand{}).Execute(execCtx, []string{"watch", "--interval=10ms", "--duration=100ms"})
+	if err != nil {
+		t.Fatalf("watch error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("watch returned after %s, want at least --duration", elapsed)
+	}
+
+	polls := 0
+	for _, call := range srv.Calls() {
+		if call.Path == "/IncomingRequests" {
+			polls++
+		}
+	}
+	if polls < 3 {
+		t.Errorf("incoming requests polled %d times, want several", polls)
+	}
+}
+
+func TestRequestFilter(t *testing.T) {
+	reqs := []requests.Request{
+		{RequestID: "1", SafeName: "Production", RequestorUserName: "jsmith"},
+		{RequestID: "2", SafeName: "Test", RequestorUserName: "jsmith"},
+		{RequestID: "3", SafeName: "production", RequestorUserName: "mjones"},
+	}
+	got := requestFilter{safe: "PRODUCTION", requestor: "JSmith"}.apply(reqs)
+	if len(got) != 1 || got[0].RequestID != "1" {
+		t.Errorf("apply() = %v", got)
+	}
+	if got := (requestFilter{}).apply(reqs); len(got) != 3 {
+		t.Errorf("empty filter apply() = %d requests, want 3", len(got))
+	}
+}
@@ -2,9 +2,8 @@ package commands
 
 import (
 	"bufio"
-	"crypto/tls"
+	"context"
 	"fmt"
-	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -37,8 +36,14 @@ Arguments:
 
 Options:
   --user=USERNAME     Username for authentication
-  --auth=METHOD       Authentication method: cyberark, ldap, radius, windows (default: cyberark)
+  --auth=METHOD       Authentication method: cyberark, ldap, radius, windows, pki (default: cyberark)
+  --cert=PATH         Client certificate PEM file for mutual TLS or --auth=pki
+  --key=PATH          Client private key PEM file for --cert
+  --otp=CODE          One-time password answering a RADIUS challenge; without it
+                      you are prompted when the server sends a challenge
   --insecure          Skip TLS certificate verification
+  --ca-file=PATH      Trust the CAs in a PEM file (default: config ca_file)
+  --proxy=URL         Connect through an http, https or socks5 proxy
   --ccp               Use CCP (Central Credential Provider) for default login
                       Requires CCP to be configured (see 'ccp setup')
 
@@ -46,6 +51,9 @@ Examples:
   connect https://cyberark.example.com
   connect https://cyberark.example.com --user=admin --auth=ldap
   connect https://cyberark.example.com --insecure
+  connect https://cyberark.example.com --auth=radius --otp=123456
+  connect https://cyberark.example.com --auth=pki --cert=user.crt --key=user.key
+  connect https://cyberark.example.com --proxy=http://proxy.example.com:3128
   connect --ccp                          # Use CCP default login
   connect https://cyberark.example.com --ccp
 `
@@ -57,16 +65,28 @@ func (c *ConnectCommand) Execute(execCtx *ExecutionContext, args []string) error
 	}
 
 	// Parse arguments
-	var serverURL, username, authMethod string
+	var serverURL, username, authMethod, certFile, keyFile, otp string
 	var insecure, useCCP bool
+	caFile := execCtx.Config.CAFile
+	proxy := execCtx.Config.Proxy
 
 	for _, arg := range args {
 		if strings.HasPrefix(arg, "--user=") {
 			username = strings.TrimPrefix(arg, "--user=")
 		} else if strings.HasPrefix(arg, "--auth=") {
 			authMethod = strings.ToLower(strings.TrimPrefix(arg, "--auth="))
+		} else if strings.HasPrefix(arg, "--cert=") {
+			certFile = strings.TrimPrefix(arg, "--cert=")
+		} else if strings.HasPrefix(arg, "--key=") {
+			keyFile = strings.TrimPrefix(arg, "--key=")
+		} else if strings.HasPrefix(arg, "--otp=") {
+			otp = strings.TrimPrefix(arg, "--otp=")
 		} else if arg == "--insecure" {
 			insecure = true
+		} else if strings.HasPrefix(arg, "--ca-file=") {
+			caFile = strings.TrimPrefix(arg, "--ca-file=")
+		} else if strings.HasPrefix(arg, "--proxy=") {
+			proxy = strings.TrimPrefix(arg, "--proxy=")
 		} else if arg == "--ccp" {
 			useCCP = true
 		} else if !strings.HasPrefix(arg, "-") && serverURL == "" {
@@ -74,6 +94,9 @@ func (c *ConnectCommand) Execute(execCtx *ExecutionContext, args []string) error
 		}
 	}
 
+	insecure = insecure || execCtx.Config.InsecureSSL
+	timeout := time.Duration(execCtx.Config.Timeout) * time.Second
+
 	var password string
 	var err error
 
@@ -102,13 +125,17 @@ func (c *ConnectCommand) Execute(execCtx *ExecutionContext, args []string) error
 		output.PrintInfo("Retrieving credentials from CCP...")
 
 		// Create CCP client
-		ccpClient, err := gopas.NewCCPClient(gopas.CCPClientConfig{
+		ccpConfig := gopas.CCPClientConfig{
 			BaseURL:       ccpURL,
-			SkipTLSVerify: insecure || execCtx.Config.InsecureSSL,
-			Timeout:       time.Duration(execCtx.Config.Timeout) * time.Second,
+			SkipTLSVerify: insecure,
+			Timeout:       timeout,
 			ClientCert:    execCtx.Config.CCP.ClientCert,
 			ClientKey:     execCtx.Config.CCP.ClientKey,
-		})
+		}
+		if caFile != "" {
+			ccpConfig.TLS = &gopas.TLSConfig{CAFile: caFile}
+		}
+		ccpClient, err := gopas.NewCCPClient(ccpConfig)
 		if err != nil {
 			return fmt.Errorf("failed to create CCP client: %w", err)
 		}
@@ -146,20 +173,29 @@ func (c *ConnectCommand) Execute(execCtx *ExecutionContext, args []string) error
 			}
 		}
 
-		// Prompt for username if not provided
-		if username == "" {
-			username, err = prompt("Username: ")
+		// PKI is only chosen explicitly: a certificate alone may just be
+		// for mutual TLS in front of a password logon
+		if authMethod == "" && execCtx.Config.DefaultAuthType == "pki" {
+			authMethod = "pki"
+		}
+
+		// PKI authenticates with the certificate alone
+		if authMethod != "pki" {
+			// Prompt for username if not provided
+			if username == "" {
+				username, err = prompt("Username: ")
+				if err != nil {
+					return err
+				}
+			}
+
+			// Prompt for password
+			password, err = promptPassword("Password: ")
 			if err != nil {
 				return err
 			}
 		}
 
-		// Prompt for password
-		password, err = promptPassword("Password: ")
-		if err != nil {
-			return err
-		}
-
 		// Prompt for auth method if not provided
 		if authMethod == "" {
 			if execCtx.Config.DefaultAuthType != "" {
@@ -190,6 +226,8 @@ func (c *ConnectCommand) Execute(execCtx *ExecutionContext, args []string) error
 		auth = gopas.AuthMethodRADIUS
 	case "windows":
 		auth = gopas.AuthMethodWindows
+	case "pki":
+		auth = gopas.AuthMethodPKI
 	default:
 		auth = gopas.AuthMethodCyberArk
 	}
@@ -201,17 +239,19 @@ func (c *ConnectCommand) Execute(execCtx *ExecutionContext, args []string) error
 			Username: username,
 			Password: password,
 		},
-		AuthMethod: auth,
+		AuthMethod:    auth,
+		SkipTLSVerify: insecure,
+		Timeout:       timeout,
+		Proxy:         proxy,
+		NoProxy:       execCtx.Config.NoProxy,
+
+		// RADIUS servers may ask for a second factor
+		OTP:              otp,
+		OTPMode:          gopas.OTPModeChallenge,
+		ChallengeHandler: gopas.ChallengeHandlerFunc(promptChallenge),
 	}
-
-	// Handle insecure mode
-	if insecure || execCtx.Config.InsecureSSL {
-		opts.CustomHTTPClient = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-			Timeout: time.Duration(execCtx.Config.Timeout) * time.Second,
-		}
+	if caFile != "" || certFile != "" || keyFile != "" {
+		opts.TLS = &gopas.TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
 	}
 
 	// Attempt connection
@@ -225,7 +265,7 @@ func (c *ConnectCommand) Execute(execCtx *ExecutionContext, args []string) error
 	// Store session in context (this will be updated by the REPL)
 	*execCtx.Session = *sess
 
-	output.PrintSuccess("Connected to %s as %s", serverURL, username)
+	output.PrintSuccess("Connected to %s as %s", serverURL, sess.User)
 
 	return nil
 }
@@ -335,6 +375,16 @@ func promptPassword(message string) (string, error) {
 	return string(password), nil
 }
 
+// promptChallenge asks the user to answer a logon challenge, such as a
+// RADIUS one-time password.
+func promptChallenge(ctx context.Context, challenge gopas.Challenge) (string, error) {
+	message := challenge.Message
+	if message == "" {
+		message = "Challenge response"
+	}
+	return promptPassword(strings.TrimRight(message, ": ") + ": ")
+}
+
 func formatDuration(d time.Duration) string {
 	d = d.Round(time.Second)
 	h := d / time.Hour
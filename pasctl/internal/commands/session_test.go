@@ -3,12 +3,24 @@ package commands
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/chrisranney/gopas"
+	"github.com/chrisranney/gopas/pkg/pvwatest"
 
 	"pasctl/internal/config"
 	"pasctl/internal/output"
@@ -432,6 +444,186 @@ func TestConnectCommand_Execute_InsecureFlag(t *testing.T) {
 	}
 }
 
+func TestConnectCommand_Execute_TLSSettings(t *testing.T) {
+	ccpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"Content": "testpassword", "UserName": "testuser"})
+	}))
+	defer ccpServer.Close()
+	pvwaServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode("token")
+	}))
+	defer pvwaServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pvwaServer.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		insecure bool
+		wantErr  bool
+	}{
+		{name: "untrusted certificate", wantErr: true},
+		{name: "insecure_ssl config", insecure: true},
+		{name: "insecure flag", args: []string{"--insecure"}},
+		{name: "CA file", args: []string{"--ca-file=" + caFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execCtx := createTestSessionExecutionContext(t)
+			execCtx.Config.CCP = &config.CCPConfig{
+				Enabled: true,
+				AppID:   "TestApp",
+				Safe:    "TestSafe",
+				CCPURL:  ccpServer.URL,
+				PVWAURL: pvwaServer.URL,
+			}
+			execCtx.Config.InsecureSSL = tt.insecure
+			execCtx.Session = &gopas.Session{}
+
+			err := (&ConnectCommand{}).Execute(execCtx, append([]string{"--ccp"}, tt.args...))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeClientCertificate writes a self-signed client certificate and key for
+// the given common name to dir.
+func writeClientCertificate(t *testing.T, dir, cn string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestConnectCommand_Execute_PKI(t *testing.T) {
+	srv := pvwatest.NewTLSServer()
+	defer srv.Close()
+	certFile, keyFile := writeClientCertificate(t, t.TempDir(), pvwatest.DefaultUsername)
+
+	execCtx := createTestSessionExecutionContext(t)
+	execCtx.Session = &gopas.Session{}
+
+	// No username or password is prompted for, so this must not block.
+	err := (&ConnectCommand{}).Execute(execCtx, []string{srv.URL, "--auth=pki", "--insecure",
+		"--cert=" + certFile, "--key=" + keyFile})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if execCtx.Session.User != pvwatest.DefaultUsername {
+		t.Errorf("Session.User = %q, want %q", execCtx.Session.User, pvwatest.DefaultUsername)
+	}
+	if execCtx.Session.AuthMethod != "PKI" {
+		t.Errorf("Session.AuthMethod = %q, want PKI", execCtx.Session.AuthMethod)
+	}
+
+	// default_auth selects PKI too
+	execCtx.Session = &gopas.Session{}
+	execCtx.Config.DefaultAuthType = "pki"
+	if err := (&ConnectCommand{}).Execute(execCtx, []string{srv.URL, "--insecure", "--cert=" + certFile, "--key=" + keyFile}); err != nil {
+		t.Fatalf("Execute() with default_auth=pki error = %v", err)
+	}
+	if execCtx.Session.AuthMethod != "PKI" {
+		t.Errorf("Session.AuthMethod with default_auth=pki = %q, want PKI", execCtx.Session.AuthMethod)
+	}
+}
+
+func TestConnectCommand_Execute_CertificateAloneIsNotPKI(t *testing.T) {
+	srv := pvwatest.NewTLSServer()
+	defer srv.Close()
+	certFile, keyFile := writeClientCertificate(t, t.TempDir(), pvwatest.DefaultUsername)
+
+	execCtx := createTestSessionExecutionContext(t)
+	execCtx.Session = &gopas.Session{}
+
+	// Without --auth the password is prompted for, which fails on a pipe
+	stdin, input, _ := os.Pipe()
+	input.Close()
+	origStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = origStdin }()
+
+	err := (&ConnectCommand{}).Execute(execCtx, []string{srv.URL, "--user=admin", "--insecure",
+		"--cert=" + certFile, "--key=" + keyFile})
+	if err == nil {
+		t.Fatal("Execute() succeeded without a password")
+	}
+	for _, call := range srv.Calls() {
+		if strings.Contains(call.Path, "/pki/") {
+			t.Errorf("Execute() attempted a PKI logon: %+v", call)
+		}
+	}
+}
+
+func TestConnectCommand_Execute_RADIUSChallenge(t *testing.T) {
+	ccpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"Content": "testpassword", "UserName": "testuser"})
+	}))
+	defer ccpServer.Close()
+
+	var passwords []string
+	pvwaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Password string }
+		json.NewDecoder(r.Body).Decode(&body)
+		passwords = append(passwords, body.Password)
+		if body.Password == "testpassword" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"ErrorCode":"ITATS542I","ErrorMessage":"Enter your token code"}`))
+			return
+		}
+		json.NewEncoder(w).Encode("token")
+	}))
+	defer pvwaServer.Close()
+
+	execCtx := createTestSessionExecutionContext(t)
+	execCtx.Config.CCP = &config.CCPConfig{
+		Enabled: true,
+		AppID:   "TestApp",
+		Safe:    "TestSafe",
+		CCPURL:  ccpServer.URL,
+		PVWAURL: pvwaServer.URL,
+	}
+	execCtx.Session = &gopas.Session{}
+
+	err := (&ConnectCommand{}).Execute(execCtx, []string{"--ccp", "--auth=radius", "--otp=123456"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if strings.Join(passwords, ",") != "testpassword,123456" {
+		t.Errorf("passwords sent = %q, want the password then the OTP", passwords)
+	}
+}
+
 func TestConnectCommand_Execute_AuthMethodFlag(t *testing.T) {
 	ccpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
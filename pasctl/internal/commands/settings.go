@@ -88,11 +88,14 @@ View current configuration or set a specific option.
 
 Options:
   default-server <url>   Set default server URL
-  default-auth <method>  Set default auth method (cyberark, ldap, radius, windows)
+  default-auth <method>  Set default auth method (cyberark, ldap, radius, windows, pki)
   output <format>        Set default output format (table, json, yaml)
   history-size <n>       Set history size
   insecure-ssl <bool>    Enable/disable SSL verification
   timeout <seconds>      Set request timeout
+  ca-file <path>         Trust the CAs in a PEM file ("none" to clear)
+  proxy <url>            Use an http, https or socks5 proxy ("none" to clear)
+  no-proxy <hosts>       Comma-separated hosts to reach without the proxy
 
 Examples:
   config                           Show all settings
@@ -100,6 +103,7 @@ Examples:
   config default-auth ldap
   config output json
   config insecure-ssl true
+  config proxy http://proxy.example.com:3128
 `
 }
 
@@ -120,7 +124,7 @@ func (c *ConfigCommand) Execute(execCtx *ExecutionContext, args []string) error
 		execCtx.Config.DefaultServer = value
 	case "default-auth":
 		switch strings.ToLower(value) {
-		case "cyberark", "ldap", "radius", "windows":
+		case "cyberark", "ldap", "radius", "windows", "pki":
 			execCtx.Config.DefaultAuthType = strings.ToLower(value)
 		default:
 			return fmt.Errorf("invalid auth method: %s", value)
@@ -153,6 +157,12 @@ func (c *ConfigCommand) Execute(execCtx *ExecutionContext, args []string) error
 			return fmt.Errorf("invalid timeout: %s", value)
 		}
 		execCtx.Config.Timeout = n
+	case "ca-file":
+		execCtx.Config.CAFile = noneToEmpty(value)
+	case "proxy":
+		execCtx.Config.Proxy = noneToEmpty(value)
+	case "no-proxy":
+		execCtx.Config.NoProxy = noneToEmpty(value)
 	default:
 		return fmt.Errorf("unknown option: %s", option)
 	}
@@ -177,11 +187,22 @@ func (c *ConfigCommand) showConfig(execCtx *ExecutionContext) error {
 	fmt.Printf("  History Size:     %d\n", execCtx.Config.HistorySize)
 	fmt.Printf("  Insecure SSL:     %s\n", boolToStr(execCtx.Config.InsecureSSL))
 	fmt.Printf("  Timeout:          %ds\n", execCtx.Config.Timeout)
+	fmt.Printf("  CA File:          %s\n", valueOrDefault(execCtx.Config.CAFile, "(system)"))
+	fmt.Printf("  Proxy:            %s\n", valueOrDefault(execCtx.Config.Proxy, "(environment)"))
+	fmt.Printf("  No Proxy:         %s\n", valueOrDefault(execCtx.Config.NoProxy, "(not set)"))
 	fmt.Println()
 
 	return nil
 }
 
+// noneToEmpty maps the value "none" to an empty string, to clear an option.
+func noneToEmpty(value string) string {
+	if strings.EqualFold(value, "none") {
+		return ""
+	}
+	return value
+}
+
 func valueOrDefault(value, defaultVal string) string {
 	if value == "" {
 		return output.Dim(defaultVal)
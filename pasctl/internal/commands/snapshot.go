@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"pasctl/internal/output"
+	"pasctl/internal/snapshot"
+)
+
+// ErrSnapshotsDiffer is returned by snapshot --diff when the snapshots differ.
+var ErrSnapshotsDiffer = errors.New("snapshots differ")
+
+// SnapshotCommand exports the configuration of a Vault.
+type SnapshotCommand struct{}
+
+func (c *SnapshotCommand) Name() string {
+	return "snapshot"
+}
+
+func (c *SnapshotCommand) Description() string {
+	return "Export or compare Vault configuration snapshots"
+}
+
+func (c *SnapshotCommand) Usage() string {
+	return `snapshot --out=DIR [options]
+snapshot --diff DIR_A DIR_B [options]
+
+Writes the Vault's safes and their members, platforms and their exported
+packages, applications and their authentication methods, users, groups,
+onboarding rules, LDAP directories and mappings, and IP allowlist entries
+to sorted JSON or YAML files. Account secrets are never included.
+
+With --diff, compares two snapshot directories without connecting. In
+single command and script mode, pasctl exits with status 2 when they differ.
+
+Options:
+  --out=DIR               Directory to write the snapshot to
+  --format=FORMAT         File format: json (default) or yaml
+  --no-exports            Skip the platform package exports
+  --diff                  Compare two snapshot directories
+  --all-fields            With --diff, also compare IDs and timestamps
+
+Examples:
+  snapshot --out=snapshots/prod
+  snapshot --out=snapshots/prod --format=yaml --no-exports
+  snapshot --diff snapshots/staging snapshots/prod
+`
+}
+
+func (c *SnapshotCommand) Execute(execCtx *ExecutionContext, args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	out := fs.String("out", "", "Directory to write the snapshot to")
+	format := fs.String("format", "json", "File format: json or yaml")
+	noExports := fs.Bool("no-exports", false, "Skip the platform package exports")
+	diff := fs.Bool("diff", false, "Compare two snapshot directories")
+	allFields := fs.Bool("all-fields", false, "Also compare IDs and timestamps")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *diff {
+		if fs.NArg() != 2 {
+			return fmt.Errorf("two snapshot directories required: snapshot --diff DIR_A DIR_B")
+		}
+		return c.diff(execCtx, fs.Arg(0), fs.Arg(1), *allFields)
+	}
+
+	if err := RequireSession(execCtx); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("output directory required (--out=DIR)")
+	}
+
+	output.PrintInfo("Taking snapshot of %s...", execCtx.Session.BaseURI)
+	snap, err := snapshot.Take(execCtx.Ctx, execCtx.Session, snapshot.Options{
+		Format:              snapshot.Format(*format),
+		SkipPlatformExports: *noExports,
+	})
+	if err != nil {
+		return err
+	}
+	if err := snap.Write(*out); err != nil {
+		return err
+	}
+
+	if execCtx.Formatter.GetFormat() != output.FormatTable {
+		return execCtx.Formatter.Format(snap.Manifest)
+	}
+
+	table := output.NewTable("SECTION", "OBJECTS")
+	for _, name := range snapshot.Sections() {
+		count, ok := snap.Manifest.Sections[name]
+		if !ok {
+			table.AddRow(name, output.Dim("skipped"))
+			continue
+		}
+		table.AddRow(name, fmt.Sprintf("%d", count))
+	}
+	table.Render()
+
+	for _, warning := range snap.Manifest.Warnings {
+		output.PrintWarning("%s", warning)
+	}
+	absOut, _ := filepath.Abs(*out)
+	output.PrintSuccess("Snapshot written to %s (%d platform exports)", absOut, len(snap.PlatformExports))
+	return nil
+}
+
+func (c *SnapshotCommand) diff(execCtx *ExecutionContext, dirA, dirB string, allFields bool) error {
+	from, err := snapshot.Load(dirA)
+	if err != nil {
+		return err
+	}
+	to, err := snapshot.Load(dirB)
+	if err != nil {
+		return err
+	}
+
+	opts := snapshot.DiffOptions{}
+	if !allFields {
+		opts.IgnoreFields = snapshot.DefaultIgnoreFields
+	}
+	diffs := snapshot.Diff(from, to, opts)
+
+	if execCtx.Formatter.GetFormat() != output.FormatTable {
+		if diffs == nil {
+			diffs = []snapshot.Difference{}
+		}
+		if err := execCtx.Formatter.Format(diffs); err != nil {
+			return err
+		}
+	} else {
+		renderSnapshotDiff(diffs)
+	}
+
+	if len(diffs) > 0 {
+		return &ExitError{Code: ExitCodeDrift, Err: ErrSnapshotsDiffer}
+	}
+	return nil
+}
+
+func renderSnapshotDiff(diffs []snapshot.Difference) {
+	if len(diffs) == 0 {
+		output.PrintSuccess("Snapshots are identical")
+		return
+	}
+
+	var added, removed, changed int
+	fmt.Println()
+	for _, d := range diffs {
+		switch d.Change {
+		case snapshot.ChangeAdded:
+			added++
+			fmt.Printf("  %s %s\n", output.Success("+"), output.Bold(d.Address()))
+		case snapshot.ChangeRemoved:
+			removed++
+			fmt.Printf("  %s %s\n", output.Error("-"), output.Bold(d.Address()))
+		default:
+			changed++
+			fmt.Printf("  %s %s\n", output.Warning("~"), output.Bold(d.Address()))
+			for _, f := range d.Fields {
+				fmt.Printf("      %s: %s → %s\n", f.Path, output.Dim(formatPlanValue(f.From)), formatPlanValue(f.To))
+			}
+		}
+	}
+	fmt.Println()
+	fmt.Printf("Snapshots differ: %s added, %s removed, %s changed.\n",
+		output.Success(added), output.Error(removed), output.Warning(changed))
+}
@@ -0,0 +1,58 @@
+// Package commands provides tests for the snapshot command.
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"pasctl/internal/snapshot"
+)
+
+func writeTestSnapshot(t *testing.T, description string) string {
+	t.Helper()
+	snap := &snapshot.Snapshot{
+		Manifest: snapshot.Manifest{Version: snapshot.Version, Format: snapshot.FormatJSON},
+		Sections: map[string][]snapshot.Item{
+			snapshot.SectionSafes: {{"safeName": "Linux", "description": description, "safeNumber": 2}},
+		},
+	}
+	dir := t.TempDir()
+	if err := snap.Write(dir); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	return dir
+}
+
+func TestSnapshotCommand_Diff(t *testing.T) {
+	execCtx := createTestExecutionContext(t)
+	before := writeTestSnapshot(t, "old")
+
+	if err := (&SnapshotCommand{}).Execute(execCtx, []string{"--diff", before, writeTestSnapshot(t, "old")}); err != nil {
+		t.Errorf("identical snapshots: error = %v, want nil", err)
+	}
+
+	err := (&SnapshotCommand{}).Execute(execCtx, []string{"--diff", before, writeTestSnapshot(t, "new")})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitCodeDrift || !errors.Is(err, ErrSnapshotsDiffer) {
+		t.Errorf("different snapshots: error = %v, want exit code %d", err, ExitCodeDrift)
+	}
+}
+
+func TestSnapshotCommand_Errors(t *testing.T) {
+	execCtx := createTestExecutionContext(t)
+
+	tests := []struct {
+		args        []string
+		errContains string
+	}{
+		{[]string{"--diff", "only-one"}, "two snapshot directories"},
+		{[]string{"--out=dir"}, "not connected"},
+	}
+	for _, tt := range tests {
+		err := (&SnapshotCommand{}).Execute(execCtx, tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+			t.Errorf("Execute(%v) error = %v, want %q", tt.args, err, tt.errContains)
+		}
+	}
+}
@@ -16,6 +16,14 @@ type Config struct {
 	InsecureSSL     bool   `json:"insecure_ssl,omitempty"`
 	Timeout         int    `json:"timeout_seconds,omitempty"`
 
+	// CAFile is a PEM bundle of CAs trusted for the PVWA and CCP servers
+	CAFile string `json:"ca_file,omitempty"`
+
+	// Proxy is an http, https or socks5 proxy URL; NoProxy lists hosts to
+	// reach directly. When unset, the proxy environment variables apply.
+	Proxy   string `json:"proxy,omitempty"`
+	NoProxy string `json:"no_proxy,omitempty"`
+
 	// CCP (Central Credential Provider) settings for default login
 	// Note: Passwords are NEVER stored - they are retrieved from CCP at runtime
 	CCP *CCPConfig `json:"ccp,omitempty"`
@@ -8,7 +8,17 @@ import (
 func NewCompleter() *readline.PrefixCompleter {
 	return readline.NewPrefixCompleter(
 		// Session commands
-		readline.PcItem("connect"),
+		readline.PcItem("connect",
+			readline.PcItem("--user="),
+			readline.PcItem("--auth="),
+			readline.PcItem("--cert="),
+			readline.PcItem("--key="),
+			readline.PcItem("--otp="),
+			readline.PcItem("--insecure"),
+			readline.PcItem("--ca-file="),
+			readline.PcItem("--proxy="),
+			readline.PcItem("--ccp"),
+		),
 		readline.PcItem("disconnect"),
 		readline.PcItem("status"),
 
@@ -122,6 +132,183 @@ func NewCompleter() *readline.PrefixCompleter {
 			),
 			readline.PcItem("delete"),
 		),
+		readline.PcItem("requests",
+			readline.PcItem("incoming",
+				readline.PcItem("--safe="),
+				readline.PcItem("--requestor="),
+				readline.PcItem("--waiting"),
+				readline.PcItem("--expired"),
+				readline.PcItem("--limit="),
+			),
+			readline.PcItem("mine",
+				readline.PcItem("--safe="),
+				readline.PcItem("--waiting"),
+				readline.PcItem("--expired"),
+				readline.PcItem("--limit="),
+			),
+			readline.PcItem("create",
+				readline.PcItem("--reason="),
+				readline.PcItem("--ticket-system="),
+				readline.PcItem("--ticket-id="),
+				readline.PcItem("--from="),
+				readline.PcItem("--to="),
+				readline.PcItem("--multiple"),
+			),
+			readline.PcItem("approve",
+				readline.PcItem("--reason="),
+				readline.PcItem("--all-from="),
+				readline.PcItem("--safe="),
+				readline.PcItem("--yes"),
+			),
+			readline.PcItem("deny",
+				readline.PcItem("--reason="),
+			),
+			readline.PcItem("cancel"),
+			readline.PcItem("watch",
+				readline.PcItem("--interval="),
+				readline.PcItem("--safe="),
+				readline.PcItem("--duration="),
+			),
+		),
+
+		// Application commands
+		readline.PcItem("applications",
+			readline.PcItem("list",
+				readline.PcItem("--location="),
+				readline.PcItem("--sublocations"),
+			),
+			readline.PcItem("get"),
+			readline.PcItem("create",
+				readline.PcItem("--description="),
+				readline.PcItem("--location="),
+				readline.PcItem("--owner-first="),
+				readline.PcItem("--owner-last="),
+				readline.PcItem("--owner-email="),
+				readline.PcItem("--owner-phone="),
+			),
+			readline.PcItem("delete",
+				readline.PcItem("--yes"),
+			),
+			readline.PcItem("auth",
+				readline.PcItem("list"),
+				readline.PcItem("add",
+					readline.PcItem("--type=",
+						readline.PcItem("machine"),
+						readline.PcItem("os-user"),
+						readline.PcItem("path"),
+						readline.PcItem("hash"),
+						readline.PcItem("cert-serial"),
+						readline.PcItem("cert-attr"),
+					),
+					readline.PcItem("--value="),
+					readline.PcItem("--folder"),
+					readline.PcItem("--allow-internal-scripts"),
+					readline.PcItem("--comment="),
+					readline.PcItem("--subject="),
+					readline.PcItem("--issuer="),
+					readline.PcItem("--san="),
+				),
+				readline.PcItem("remove"),
+			),
+			readline.PcItem("provision",
+				readline.PcItem("--safes="),
+				readline.PcItem("--providers="),
+				readline.PcItem("--machine="),
+				readline.PcItem("--os-user="),
+				readline.PcItem("--path="),
+				readline.PcItem("--hash="),
+				readline.PcItem("--cert-serial="),
+				readline.PcItem("--description="),
+				readline.PcItem("--location="),
+			),
+		),
+
+		// Onboarding commands
+		readline.PcItem("onboarding",
+			readline.PcItem("rules",
+				readline.PcItem("list"),
+				readline.PcItem("create",
+					readline.PcItem("--safe="),
+					readline.PcItem("--platform="),
+					readline.PcItem("--description="),
+					readline.PcItem("--system-type="),
+					readline.PcItem("--machine-type="),
+					readline.PcItem("--account-category="),
+					readline.PcItem("--admin-only"),
+					readline.PcItem("--user-filter="),
+					readline.PcItem("--user-method="),
+					readline.PcItem("--address-filter="),
+					readline.PcItem("--address-method="),
+					readline.PcItem("--precedence="),
+					readline.PcItem("--reconcile-account="),
+				),
+				readline.PcItem("update",
+					readline.PcItem("--safe="),
+					readline.PcItem("--platform="),
+					readline.PcItem("--description="),
+					readline.PcItem("--system-type="),
+					readline.PcItem("--machine-type="),
+					readline.PcItem("--account-category="),
+					readline.PcItem("--admin-only"),
+					readline.PcItem("--user-filter="),
+					readline.PcItem("--user-method="),
+					readline.PcItem("--address-filter="),
+					readline.PcItem("--address-method="),
+					readline.PcItem("--precedence="),
+					readline.PcItem("--reconcile-account="),
+				),
+				readline.PcItem("delete",
+					readline.PcItem("--yes"),
+				),
+			),
+			readline.PcItem("discovered",
+				readline.PcItem("list",
+					readline.PcItem("--search="),
+					readline.PcItem("--platform-type="),
+					readline.PcItem("--privileged"),
+					readline.PcItem("--enabled"),
+					readline.PcItem("--limit="),
+				),
+			),
+			readline.PcItem("publish",
+				readline.PcItem("--safe="),
+				readline.PcItem("--platform="),
+				readline.PcItem("--manual-reason="),
+			),
+			readline.PcItem("simulate",
+				readline.PcItem("--search="),
+				readline.PcItem("--platform-type="),
+				readline.PcItem("--privileged"),
+				readline.PcItem("--enabled"),
+				readline.PcItem("--limit="),
+				readline.PcItem("--unmatched"),
+				readline.PcItem("--explain"),
+			),
+		),
+
+		// Desired state commands
+		readline.PcItem("plan",
+			readline.PcItem("-f"),
+			readline.PcItem("--allow-deletes"),
+			readline.PcItem("--ignore-members="),
+		),
+		readline.PcItem("apply",
+			readline.PcItem("-f"),
+			readline.PcItem("--allow-deletes"),
+			readline.PcItem("--ignore-members="),
+			readline.PcItem("--continue-on-error"),
+			readline.PcItem("--yes"),
+		),
+		readline.PcItem("snapshot",
+			readline.PcItem("--out="),
+			readline.PcItem("--format=",
+				readline.PcItem("json"),
+				readline.PcItem("yaml"),
+			),
+			readline.PcItem("--no-exports"),
+			readline.PcItem("--diff"),
+			readline.PcItem("--all-fields"),
+		),
 
 		// PSM monitoring commands
 		readline.PcItem("psm",
@@ -152,6 +339,39 @@ func NewCompleter() *readline.PrefixCompleter {
 			readline.PcItem("summary"),
 		),
 
+		// PTA commands
+		readline.PcItem("pta",
+			readline.PcItem("events",
+				readline.PcItem("--from="),
+				readline.PcItem("--to="),
+				readline.PcItem("--status=",
+					readline.PcItem("open"),
+					readline.PcItem("closed"),
+				),
+				readline.PcItem("--min-score="),
+				readline.PcItem("--account="),
+				readline.PcItem("--limit="),
+			),
+			readline.PcItem("event"),
+			readline.PcItem("close"),
+			readline.PcItem("open"),
+			readline.PcItem("rules",
+				readline.PcItem("list"),
+				readline.PcItem("enable"),
+				readline.PcItem("disable"),
+			),
+			readline.PcItem("privileged-users",
+				readline.PcItem("list"),
+				readline.PcItem("add"),
+				readline.PcItem("remove"),
+			),
+			readline.PcItem("privileged-groups",
+				readline.PcItem("list"),
+				readline.PcItem("add"),
+				readline.PcItem("remove"),
+			),
+		),
+
 		// Settings commands
 		readline.PcItem("set",
 			readline.PcItem("output",
@@ -167,6 +387,7 @@ func NewCompleter() *readline.PrefixCompleter {
 				readline.PcItem("ldap"),
 				readline.PcItem("radius"),
 				readline.PcItem("windows"),
+				readline.PcItem("pki"),
 			),
 			readline.PcItem("output",
 				readline.PcItem("table"),
@@ -179,6 +400,9 @@ func NewCompleter() *readline.PrefixCompleter {
 				readline.PcItem("false"),
 			),
 			readline.PcItem("timeout"),
+			readline.PcItem("ca-file"),
+			readline.PcItem("proxy"),
+			readline.PcItem("no-proxy"),
 		),
 
 		// Utility commands
@@ -187,8 +411,15 @@ func NewCompleter() *readline.PrefixCompleter {
 			readline.PcItem("safes"),
 			readline.PcItem("users"),
 			readline.PcItem("platforms"),
+			readline.PcItem("requests"),
+			readline.PcItem("applications"),
+			readline.PcItem("onboarding"),
+			readline.PcItem("plan"),
+			readline.PcItem("apply"),
+			readline.PcItem("snapshot"),
 			readline.PcItem("psm"),
 			readline.PcItem("health"),
+			readline.PcItem("pta"),
 			readline.PcItem("connect"),
 			readline.PcItem("disconnect"),
 			readline.PcItem("status"),
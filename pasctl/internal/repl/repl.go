@@ -3,6 +3,7 @@ package repl
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -79,10 +80,19 @@ func (r *REPL) registerCommands() {
 	r.registry.Register(&commands.SafesCommand{})
 	r.registry.Register(&commands.UsersCommand{})
 	r.registry.Register(&commands.PlatformsCommand{})
+	r.registry.Register(&commands.RequestsCommand{})
+	r.registry.Register(&commands.ApplicationsCommand{})
+	r.registry.Register(&commands.OnboardingCommand{})
+
+	// Configuration commands
+	r.registry.Register(&commands.PlanCommand{})
+	r.registry.Register(&commands.ApplyCommand{})
+	r.registry.Register(&commands.SnapshotCommand{})
 
 	// Monitoring commands
 	r.registry.Register(&commands.PSMCommand{})
 	r.registry.Register(&commands.HealthCommand{})
+	r.registry.Register(&commands.PTACommand{})
 
 	// Settings commands
 	r.registry.Register(&commands.SetCommand{})
@@ -130,6 +140,11 @@ func (r *REPL) Run() error {
 
 		// Execute the command
 		if err := r.execute(line); err != nil {
+			var exitErr *commands.ExitError
+			if errors.As(err, &exitErr) && exitErr.Code == commands.ExitCodeDrift {
+				// Differences were already shown and are not a failure here
+				continue
+			}
 			fmt.Printf("\033[31mError: %v\033[0m\n", err)
 		}
 	}
@@ -0,0 +1,200 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeType is how an object differs between two snapshots.
+type ChangeType string
+
+// Change types
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+)
+
+// DefaultIgnoreFields are the Vault-assigned identifiers and timestamps that
+// differ between environments and over time without a configuration change.
+var DefaultIgnoreFields = []string{
+	"creationTime",
+	"DirectoryID",
+	"groupID",
+	"id",
+	"lastModificationTime",
+	"lastSuccessfulLoginDate",
+	"MappingID",
+	"memberId",
+	"RuleId",
+	"safeNumber",
+	"safeUrlId",
+}
+
+// nestedKeys identify the elements of nested lists, such as safe members,
+// so that they are compared by identity rather than by position.
+var nestedKeys = []string{"memberName", "username", "DirectoryMappingName", "AuthValue", "Name"}
+
+// FieldDiff is a field whose value differs. From is nil for added fields and
+// To is nil for removed ones.
+type FieldDiff struct {
+	Path string      `json:"path" yaml:"path"`
+	From interface{} `json:"from" yaml:"from"`
+	To   interface{} `json:"to" yaml:"to"`
+}
+
+// Difference is an object that was added, removed or changed.
+type Difference struct {
+	Section string      `json:"section" yaml:"section"`
+	Key     string      `json:"key" yaml:"key"`
+	Change  ChangeType  `json:"change" yaml:"change"`
+	Fields  []FieldDiff `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// Address identifies the object, such as "safes/Linux-Prod".
+func (d Difference) Address() string {
+	return d.Section + "/" + d.Key
+}
+
+// DiffOptions configures Diff.
+type DiffOptions struct {
+	// IgnoreFields are skipped at any depth, see DefaultIgnoreFields
+	IgnoreFields []string
+}
+
+// Diff compares two snapshots section by section, matching objects by their
+// identifying field. Differences are sorted by section and key.
+func Diff(from, to *Snapshot, opts DiffOptions) []Difference {
+	ignored := map[string]bool{}
+	for _, f := range opts.IgnoreFields {
+		ignored[f] = true
+	}
+
+	var diffs []Difference
+	for _, name := range Sections() {
+		keys := sectionKeys(name)
+		before := indexItems(from.Sections[name], keys)
+		after := indexItems(to.Sections[name], keys)
+
+		for _, key := range unionKeys(before, after) {
+			a, inBefore := before[key]
+			b, inAfter := after[key]
+			switch {
+			case !inBefore:
+				diffs = append(diffs, Difference{Section: name, Key: key, Change: ChangeAdded})
+			case !inAfter:
+				diffs = append(diffs, Difference{Section: name, Key: key, Change: ChangeRemoved})
+			default:
+				if fields := diffFields(a, b, ignored); len(fields) > 0 {
+					diffs = append(diffs, Difference{Section: name, Key: key, Change: ChangeChanged, Fields: fields})
+				}
+			}
+		}
+	}
+	return diffs
+}
+
+func indexItems(items []Item, keys []string) map[string]Item {
+	index := make(map[string]Item, len(items))
+	for _, item := range items {
+		index[itemKey(item, keys)] = item
+	}
+	return index
+}
+
+func unionKeys(a, b map[string]Item) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !strings.EqualFold(keys[i], keys[j]) {
+			return strings.ToLower(keys[i]) < strings.ToLower(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func diffFields(a, b Item, ignored map[string]bool) []FieldDiff {
+	before, after := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", a, ignored, before)
+	flatten("", b, ignored, after)
+
+	paths := make([]string, 0, len(before)+len(after))
+	for p := range before {
+		paths = append(paths, p)
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var fields []FieldDiff
+	for _, p := range paths {
+		from, to := before[p], after[p]
+		if canonical(from) != canonical(to) {
+			fields = append(fields, FieldDiff{Path: p, From: from, To: to})
+		}
+	}
+	return fields
+}
+
+// flatten records the scalar values of v by their path. Lists of scalars
+// are kept whole; lists of objects are expanded by their identifying field.
+func flatten(path string, v interface{}, ignored map[string]bool, out map[string]interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if ignored[k] {
+				continue
+			}
+			flatten(joinPath(path, k), child, ignored, out)
+		}
+	case []interface{}:
+		if len(val) == 0 {
+			out[path] = val
+			return
+		}
+		for i, child := range val {
+			item, ok := child.(map[string]interface{})
+			if !ok {
+				out[path] = val
+				return
+			}
+			key := itemKey(item, nestedKeys)
+			if key == "" {
+				key = fmt.Sprint(i)
+			}
+			flatten(fmt.Sprintf("%s[%s]", path, key), item, ignored, out)
+		}
+	default:
+		out[path] = val
+	}
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// canonical renders a value so that equal values from JSON and YAML files
+// compare equal.
+func canonical(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,547 @@
+// Package snapshot captures the configuration of a Vault as sorted JSON or
+// YAML files, for audit evidence and for comparing environments over time.
+//
+// A snapshot directory holds a manifest, one file per section and the
+// exported platform packages:
+//
+//	manifest.json
+//	safes.json
+//	platforms.json
+//	platforms/UnixSSH.zip
+//	applications.json
+//	...
+//
+// Account secrets are never requested, and any password or secret field
+// returned by the API is removed before it is written.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chrisranney/gopas"
+	"github.com/chrisranney/gopas/pkg/applications"
+	"github.com/chrisranney/gopas/pkg/ipallowlist"
+	"github.com/chrisranney/gopas/pkg/ldapdirectories"
+	"github.com/chrisranney/gopas/pkg/onboardingrules"
+	"github.com/chrisranney/gopas/pkg/platforms"
+	"github.com/chrisranney/gopas/pkg/safemembers"
+	"github.com/chrisranney/gopas/pkg/safes"
+	"github.com/chrisranney/gopas/pkg/types"
+	"github.com/chrisranney/gopas/pkg/users"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the snapshot layout version recorded in the manifest.
+const Version = 1
+
+// Format is the encoding of the snapshot files.
+type Format string
+
+// Supported formats
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// Section names, in the order they are captured
+const (
+	SectionSafes           = "safes"
+	SectionPlatforms       = "platforms"
+	SectionApplications    = "applications"
+	SectionUsers           = "users"
+	SectionGroups          = "groups"
+	SectionOnboardingRules = "onboarding-rules"
+	SectionLDAPDirectories = "ldap-directories"
+	SectionIPAllowList     = "ip-allowlist"
+)
+
+// sensitiveFields are removed from every object, at any depth. Matching
+// ignores case.
+var sensitiveFields = map[string]bool{
+	"bindpassword":    true,
+	"content":         true,
+	"initialpassword": true,
+	"newpassword":     true,
+	"password":        true,
+	"secret":          true,
+}
+
+// Item is one object of a section, as decoded from its API representation.
+type Item = map[string]interface{}
+
+// Manifest describes a snapshot.
+type Manifest struct {
+	Version  int            `json:"version" yaml:"version"`
+	Server   string         `json:"server" yaml:"server"`
+	TakenAt  time.Time      `json:"takenAt" yaml:"takenAt"`
+	Format   Format         `json:"format" yaml:"format"`
+	Sections map[string]int `json:"sections" yaml:"sections"`
+
+	// Warnings lists the sections or platform exports that could not be
+	// captured, such as the IP allowlist on a self-hosted Vault
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// Snapshot is the captured configuration of a Vault.
+type Snapshot struct {
+	Manifest Manifest
+
+	// Sections maps each section name to its objects, sorted by key
+	Sections map[string][]Item
+
+	// PlatformExports holds the exported platform packages by platform ID
+	PlatformExports map[string][]byte
+}
+
+// Options configures Take.
+type Options struct {
+	Format Format
+
+	// SkipPlatformExports does not download the platform packages
+	SkipPlatformExports bool
+}
+
+// section describes how one kind of object is captured and identified.
+type section struct {
+	name string
+
+	// keys are the fields that identify an object, in order of preference
+	keys []string
+
+	collect func(ctx context.Context, sess *gopas.Session) (interface{}, error)
+}
+
+var sections = []section{
+	{name: SectionSafes, keys: []string{"safeName"}, collect: collectSafes},
+	{name: SectionPlatforms, keys: []string{"platformId", "id"}, collect: collectPlatforms},
+	{name: SectionApplications, keys: []string{"AppID"}, collect: collectApplications},
+	{name: SectionUsers, keys: []string{"username"}, collect: collectUsers},
+	{name: SectionGroups, keys: []string{"groupName"}, collect: collectGroups},
+	{name: SectionOnboardingRules, keys: []string{"RuleName", "RuleId"}, collect: collectOnboardingRules},
+	{name: SectionLDAPDirectories, keys: []string{"DomainName"}, collect: collectLDAPDirectories},
+	{name: SectionIPAllowList, keys: []string{"ip"}, collect: collectIPAllowList},
+}
+
+// Sections returns the names of the sections in capture order.
+func Sections() []string {
+	names := make([]string, len(sections))
+	for i, s := range sections {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Take captures the configuration of the Vault. A section that cannot be
+// read is recorded as a warning in the manifest rather than failing the
+// snapshot, unless the session itself is invalid.
+func Take(ctx context.Context, sess *gopas.Session, opts Options) (*Snapshot, error) {
+	if sess == nil || !sess.IsValid() {
+		return nil, gopas.ErrSessionInvalid
+	}
+
+	format := opts.Format
+	if format == "" {
+		format = FormatJSON
+	}
+	if format != FormatJSON && format != FormatYAML {
+		return nil, fmt.Errorf("unsupported snapshot format: %s", format)
+	}
+
+	snap := &Snapshot{
+		Manifest: Manifest{
+			Version:  Version,
+			Server:   sess.BaseURI,
+			TakenAt:  time.Now().UTC().Truncate(time.Second),
+			Format:   format,
+			Sections: map[string]int{},
+		},
+		Sections:        map[string][]Item{},
+		PlatformExports: map[string][]byte{},
+	}
+
+	for _, s := range sections {
+		data, err := s.collect(ctx, sess)
+		if errors.Is(err, gopas.ErrSessionInvalid) || ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to capture %s: %w", s.name, err)
+		}
+		if err != nil {
+			snap.Manifest.Warnings = append(snap.Manifest.Warnings, fmt.Sprintf("%s: %v", s.name, err))
+			continue
+		}
+
+		items, err := toItems(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture %s: %w", s.name, err)
+		}
+		sortItems(items, s.keys)
+		snap.Sections[s.name] = items
+		snap.Manifest.Sections[s.name] = len(items)
+	}
+
+	if !opts.SkipPlatformExports {
+		for _, item := range snap.Sections[SectionPlatforms] {
+			id := itemKey(item, []string{"platformId", "id"})
+			data, err := platforms.ExportPlatform(ctx, sess, id)
+			if err != nil {
+				snap.Manifest.Warnings = append(snap.Manifest.Warnings, fmt.Sprintf("platform %s export: %v", id, err))
+				continue
+			}
+			snap.PlatformExports[id] = data
+			sum := sha256.Sum256(data)
+			item["exportSha256"] = hex.EncodeToString(sum[:])
+		}
+	}
+
+	return snap, nil
+}
+
+func collectSafes(ctx context.Context, sess *gopas.Session) (interface{}, error) {
+	type safeWithMembers struct {
+		safes.Safe
+		Members []safemembers.SafeMember `json:"members"`
+	}
+
+	var result []safeWithMembers
+	for safe, err := range safes.All(ctx, sess, safes.ListOptions{ExtendedDetails: true}, types.PageOptions{}) {
+		if err != nil {
+			return nil, err
+		}
+		entry := safeWithMembers{Safe: safe, Members: []safemembers.SafeMember{}}
+		for member, err := range safemembers.All(ctx, sess, safe.SafeName, safemembers.ListOptions{}, types.PageOptions{}) {
+			if err != nil {
+				return nil, err
+			}
+			entry.Members = append(entry.Members, member)
+		}
+		sort.Slice(entry.Members, func(i, j int) bool {
+			return strings.ToLower(entry.Members[i].MemberName) < strings.ToLower(entry.Members[j].MemberName)
+		})
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+func collectPlatforms(ctx context.Context, sess *gopas.Session) (interface{}, error) {
+	resp, err := platforms.List(ctx, sess, platforms.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Platforms, nil
+}
+
+func collectApplications(ctx context.Context, sess *gopas.Session) (interface{}, error) {
+	type applicationWithAuth struct {
+		applications.Application
+		AuthMethods []applications.AuthMethod `json:"authMethods"`
+	}
+
+	apps, err := applications.List(ctx, sess, applications.ListOptions{Location: "\\", SubLocations: true})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]applicationWithAuth, 0, len(apps))
+	for _, app := range apps {
+		methods, err := applications.ListAuthMethods(ctx, sess, app.AppID)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(methods, func(i, j int) bool {
+			if !strings.EqualFold(methods[i].AuthType, methods[j].AuthType) {
+				return strings.ToLower(methods[i].AuthType) < strings.ToLower(methods[j].AuthType)
+			}
+			return methods[i].AuthValue < methods[j].AuthValue
+		})
+		if methods == nil {
+			methods = []applications.AuthMethod{}
+		}
+		result = append(result, applicationWithAuth{Application: app, AuthMethods: methods})
+	}
+	return result, nil
+}
+
+func collectUsers(ctx context.Context, sess *gopas.Session) (interface{}, error) {
+	var result []users.User
+	for user, err := range users.All(ctx, sess, users.ListOptions{}, types.PageOptions{}) {
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, user)
+	}
+	return result, nil
+}
+
+func collectGroups(ctx context.Context, sess *gopas.Session) (interface{}, error) {
+	var result []users.Group
+	for {
+		resp, err := users.ListGroups(ctx, sess, users.ListGroupsOptions{IncludeMembers: true, Offset: len(result)})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resp.Value...)
+		if len(resp.Value) == 0 || len(result) >= resp.Count {
+			break
+		}
+	}
+	for i := range result {
+		members := result[i].Members
+		sort.Slice(members, func(a, b int) bool {
+			return strings.ToLower(members[a].Username) < strings.ToLower(members[b].Username)
+		})
+	}
+	return result, nil
+}
+
+func collectOnboardingRules(ctx context.Context, sess *gopas.Session) (interface{}, error) {
+	return onboardingrules.List(ctx, sess)
+}
+
+func collectLDAPDirectories(ctx context.Context, sess *gopas.Session) (interface{}, error) {
+	type directoryWithMappings struct {
+		ldapdirectories.Directory
+		Mappings []ldapdirectories.DirectoryMapping `json:"mappings"`
+	}
+
+	directories, err := ldapdirectories.List(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]directoryWithMappings, 0, len(directories))
+	for _, directory := range directories {
+		id := directory.DomainName
+		if id == "" {
+			id = directory.DirectoryID.String()
+		}
+		mappings, err := ldapdirectories.ListMappings(ctx, sess, id)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(mappings, func(i, j int) bool {
+			return strings.ToLower(mappings[i].DirectoryMappingName) < strings.ToLower(mappings[j].DirectoryMappingName)
+		})
+		if mappings == nil {
+			mappings = []ldapdirectories.DirectoryMapping{}
+		}
+		result = append(result, directoryWithMappings{Directory: directory, Mappings: mappings})
+	}
+	return result, nil
+}
+
+func collectIPAllowList(ctx context.Context, sess *gopas.Session) (interface{}, error) {
+	return ipallowlist.List(ctx, sess)
+}
+
+// toItems converts API objects to generic items through their JSON form,
+// so the files use the API's field names, and strips sensitive fields.
+func toItems(data interface{}) ([]Item, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+	var raw []interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(raw))
+	for _, v := range raw {
+		if item, ok := normalize(v).(Item); ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// normalize removes sensitive fields and turns JSON numbers into Go numbers
+// so that JSON and YAML files encode them the same way.
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if sensitiveFields[strings.ToLower(k)] {
+				delete(val, k)
+				continue
+			}
+			val[k] = normalize(child)
+		}
+		return val
+	case []interface{}:
+		for i := range val {
+			val[i] = normalize(val[i])
+		}
+		return val
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n
+		}
+		f, _ := val.Float64()
+		return f
+	default:
+		return v
+	}
+}
+
+// itemKey returns the first non-empty identifying field of an item.
+func itemKey(item Item, keys []string) string {
+	for _, k := range keys {
+		if v, ok := item[k]; ok && v != nil && fmt.Sprint(v) != "" {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+func sortItems(items []Item, keys []string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := itemKey(items[i], keys), itemKey(items[j], keys)
+		if !strings.EqualFold(a, b) {
+			return strings.ToLower(a) < strings.ToLower(b)
+		}
+		return a < b
+	})
+}
+
+func sectionKeys(name string) []string {
+	for _, s := range sections {
+		if s.name == name {
+			return s.keys
+		}
+	}
+	return nil
+}
+
+// unsafeFileChars matches characters replaced in platform export file names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Write stores the snapshot in dir, creating it if needed. Existing files of
+// a previous snapshot are overwritten.
+func (s *Snapshot) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	ext := string(s.Manifest.Format)
+	if err := writeFile(filepath.Join(dir, "manifest."+ext), s.Manifest.Format, s.Manifest); err != nil {
+		return err
+	}
+	for _, name := range Sections() {
+		items, ok := s.Sections[name]
+		if !ok {
+			continue
+		}
+		if err := writeFile(filepath.Join(dir, name+"."+ext), s.Manifest.Format, items); err != nil {
+			return err
+		}
+	}
+
+	if len(s.PlatformExports) == 0 {
+		return nil
+	}
+	exportDir := filepath.Join(dir, SectionPlatforms)
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	for id, data := range s.PlatformExports {
+		name := unsafeFileChars.ReplaceAllString(id, "_") + ".zip"
+		if err := os.WriteFile(filepath.Join(exportDir, name), data, 0644); err != nil {
+			return fmt.Errorf("failed to write platform export %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, format Format, v interface{}) error {
+	var buf bytes.Buffer
+	if format == FormatYAML {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+		}
+		enc.Close()
+	} else {
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// Load reads a snapshot written by Write. Platform exports are not loaded;
+// their checksums are part of the platforms section.
+func Load(dir string) (*Snapshot, error) {
+	format := FormatJSON
+	if _, err := os.Stat(filepath.Join(dir, "manifest.json")); err != nil {
+		if _, yamlErr := os.Stat(filepath.Join(dir, "manifest.yaml")); yamlErr != nil {
+			return nil, fmt.Errorf("%s is not a snapshot: no manifest found", dir)
+		}
+		format = FormatYAML
+	}
+
+	snap := &Snapshot{Sections: map[string][]Item{}, PlatformExports: map[string][]byte{}}
+	ext := string(format)
+	if err := readFile(filepath.Join(dir, "manifest."+ext), format, &snap.Manifest); err != nil {
+		return nil, err
+	}
+
+	for _, name := range Sections() {
+		path := filepath.Join(dir, name+"."+ext)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		var items []interface{}
+		if err := readFile(path, format, &items); err != nil {
+			return nil, err
+		}
+		section := make([]Item, 0, len(items))
+		for _, v := range items {
+			if item, ok := normalize(v).(Item); ok {
+				section = append(section, item)
+			}
+		}
+		snap.Sections[name] = section
+	}
+	return snap, nil
+}
+
+func readFile(path string, format Format, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	if format == FormatYAML {
+		err = yaml.Unmarshal(data, v)
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(v)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+// Package snapshot provides tests for taking and comparing snapshots.
+package snapshot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrisranney/gopas"
+)
+
+// vaultResponses maps API paths to the JSON the mock Vault returns.
+func vaultResponses() map[string]string {
+	return map[string]string{
+		"GET /Safes":                                    `{"value":[{"safeName":"Windows","safeNumber":3,"creationTime":1700000001},{"safeName":"Linux","safeNumber":2,"description":"Linux servers","creationTime":1700000000}],"count":2}`,
+		"GET /Safes/Linux/Members":                      `{"value":[{"memberName":"ops","permissions":{"listAccounts":true}},{"memberName":"Admins","permissions":{"listAccounts":true,"useAccounts":true}}],"count":2}`,
+		"GET /Safes/Windows/Members":                    `{"value":[],"count":0}`,
+		"GET /Platforms":                                `{"Platforms":[{"id":7,"platformId":"UnixSSH","name":"Unix via SSH","active":true}]}`,
+		"POST /Platforms/UnixSSH/export":                "PK-zip-bytes",
+		"GET /WebServices/PIMServices.svc/Applications": `{"application":[{"AppID":"billing","Location":"\\Applications"}]}`,
+		"GET /WebServices/PIMServices.svc/Applications/billing/Authentications": `{"authentication":[{"authID":2,"AuthType":"path","AuthValue":"/opt/app"},{"authID":1,"AuthType":"machineAddress","AuthValue":"10.0.0.1"}]}`,
+		"GET /Users":                          `{"Users":[{"id":1,"username":"Administrator","lastSuccessfulLoginDate":1700000000}],"Total":1}`,
+		"GET /UserGroups":                     `{"value":[{"id":5,"groupName":"Auditors","members":[{"id":1,"username":"zed"},{"id":2,"username":"amy"}]}],"count":1}`,
+		"GET /AutomaticOnboardingRules":       `{"AutomaticOnboardingRules":[{"RuleId":4,"RuleName":"Unix roots","TargetPlatformId":"UnixSSH","TargetSafeName":"Linux"}]}`,
+		"GET /Configuration/LDAP/Directories": `{"Directories":[{"DomainName":"corp.example.com","BindUsername":"svc-ldap","BindPassword":"hunter2"}]}`,
+		"GET /Configuration/LDAP/Directories/corp.example.com/Mappings": `{"Mappings":[{"MappingID":9,"DirectoryMappingName":"Vault Admins","LDAPBranch":"DC=corp"}]}`,
+	}
+}
+
+func newTestSession(t *testing.T, responses map[string]string) *gopas.Session {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/Logon") {
+			w.Write([]byte(`"test-token"`))
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/PasswordVault/API")
+		path = strings.TrimPrefix(path, "/PasswordVault")
+		body, ok := responses[r.Method+" "+path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"ErrorCode":"PASWS164E","ErrorMessage":"not found"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	sess, err := gopas.NewSession(context.Background(), gopas.SessionOptions{
+		BaseURL:          server.URL,
+		Credentials:      gopas.Credentials{Username: "admin", Password: "secret"},
+		SkipVersionCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+	return sess
+}
+
+func TestTake(t *testing.T) {
+	sess := newTestSession(t, vaultResponses())
+
+	snap, err := Take(context.Background(), sess, Options{})
+	if err != nil {
+		t.Fatalf("Take() error: %v", err)
+	}
+
+	safes := snap.Sections[SectionSafes]
+	if len(safes) != 2 || safes[0]["safeName"] != "Linux" {
+		t.Fatalf("safes = %v, want sorted by name", safes)
+	}
+	members := safes[0]["members"].([]interface{})
+	if first := members[0].(map[string]interface{})["memberName"]; first != "Admins" {
+		t.Errorf("first member = %v, want Admins", first)
+	}
+
+	if _, ok := snap.Sections[SectionIPAllowList]; ok {
+		t.Error("IP allowlist should be skipped on a self-hosted Vault")
+	}
+	if len(snap.Manifest.Warnings) != 1 || !strings.HasPrefix(snap.Manifest.Warnings[0], SectionIPAllowList) {
+		t.Errorf("Warnings = %v, want the IP allowlist only", snap.Manifest.Warnings)
+	}
+
+	if string(snap.PlatformExports["UnixSSH"]) != "PK-zip-bytes" {
+		t.Error("platform export was not captured")
+	}
+	if snap.Sections[SectionPlatforms][0]["exportSha256"] == nil {
+		t.Error("platform export checksum was not recorded")
+	}
+
+	directory := snap.Sections[SectionLDAPDirectories][0]
+	if _, ok := directory["BindPassword"]; ok {
+		t.Error("LDAP bind password was not removed")
+	}
+	if directory["BindUsername"] != "svc-ldap" {
+		t.Error("non-sensitive LDAP fields should be kept")
+	}
+}
+
+func TestWriteLoad_RoundTrip(t *testing.T) {
+	sess := newTestSession(t, vaultResponses())
+
+	for _, format := range []Format{FormatJSON, FormatYAML} {
+		t.Run(string(format), func(t *testing.T) {
+			snap, err := Take(context.Background(), sess, Options{Format: format})
+			if err != nil {
+				t.Fatalf("Take() error: %v", err)
+			}
+
+			dir := t.TempDir()
+			if err := snap.Write(dir); err != nil {
+				t.Fatalf("Write() error: %v", err)
+			}
+
+			files, _ := filepath.Glob(filepath.Join(dir, "*"))
+			for _, file := range files {
+				data, _ := os.ReadFile(file)
+				if strings.Contains(string(data), "hunter2") {
+					t.Errorf("%s contains a secret", filepath.Base(file))
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, "platforms", "UnixSSH.zip")); err != nil {
+				t.Errorf("platform export not written: %v", err)
+			}
+
+			loaded, err := Load(dir)
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if loaded.Manifest.Format != format {
+				t.Errorf("Format = %v, want %v", loaded.Manifest.Format, format)
+			}
+			if diffs := Diff(snap, loaded, DiffOptions{}); len(diffs) != 0 {
+				t.Errorf("round trip differs: %+v", diffs)
+			}
+
+			// A second snapshot of the same Vault is byte-for-byte identical
+			again, _ := Take(context.Background(), sess, Options{Format: format})
+			dir2 := t.TempDir()
+			again.Write(dir2)
+			a, _ := os.ReadFile(filepath.Join(dir, "safes."+string(format)))
+			b, _ := os.ReadFile(filepath.Join(dir2, "safes."+string(format)))
+			if string(a) != string(b) {
+				t.Error("snapshot output is not stable")
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before, err := Take(context.Background(), newTestSession(t, vaultResponses()), Options{SkipPlatformExports: true})
+	if err != nil {
+		t.Fatalf("Take() error: %v", err)
+	}
+
+	responses := vaultResponses()
+	responses["GET /Safes"] = `{"value":[{"safeName":"Linux","safeNumber":20,"description":"Linux prod","creationTime":1800000000},{"safeName":"Oracle"}],"count":2}`
+	responses["GET /Safes/Linux/Members"] = `{"value":[{"memberName":"Admins","permissions":{"listAccounts":true,"useAccounts":false}},{"memberName":"ops","permissions":{"listAccounts":true}}],"count":2}`
+	responses["GET /Safes/Oracle/Members"] = `{"value":[],"count":0}`
+	after, err := Take(context.Background(), newTestSession(t, responses), Options{SkipPlatformExports: true})
+	if err != nil {
+		t.Fatalf("Take() error: %v", err)
+	}
+
+	diffs := Diff(before, after, DiffOptions{IgnoreFields: DefaultIgnoreFields})
+	var got []string
+	for _, d := range diffs {
+		got = append(got, string(d.Change)+" "+d.Address())
+	}
+	want := []string{"changed safes/Linux", "added safes/Oracle", "removed safes/Windows"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Diff() = %v, want %v", got, want)
+	}
+
+	var paths []string
+	for _, f := range diffs[0].Fields {
+		paths = append(paths, f.Path)
+	}
+	wantPaths := []string{"description", "members[Admins].permissions.useAccounts"}
+	if strings.Join(paths, ",") != strings.Join(wantPaths, ",") {
+		t.Errorf("changed fields = %v, want %v", paths, wantPaths)
+	}
+
+	// Without ignored fields, the Vault-assigned values differ too
+	all := Diff(before, after, DiffOptions{})
+	if len(all[0].Fields) <= len(diffs[0].Fields) {
+		t.Error("IDs and timestamps should be compared without IgnoreFields")
+	}
+}
+
+func TestLoad_NotASnapshot(t *testing.T) {
+	if _, err := Load(t.TempDir()); err == nil || !strings.Contains(err.Error(), "not a snapshot") {
+		t.Errorf("Load() error = %v, want not a snapshot", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 )
 
@@ -35,7 +36,7 @@ type AccountACLResponse struct {
 // This is equivalent to Get-PASAccountACL in psPAS.
 func List(ctx context.Context, sess *session.Session, accountID string, safeName string, folderName string) ([]AccountACL, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -77,7 +78,7 @@ type AddOptions struct {
 // This is equivalent to Add-PASAccountACL in psPAS.
 func Add(ctx context.Context, sess *session.Session, accountID string, safeName string, folderName string, opts AddOptions) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -112,7 +113,7 @@ func Add(ctx context.Context, sess *session.Session, accountID string, safeName
 // This is equivalent to Remove-PASAccountACL in psPAS.
 func Remove(ctx context.Context, sess *session.Session, accountID string, safeName string, folderName string, aclID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 )
 
@@ -30,7 +31,7 @@ type AccountGroupMember struct {
 // This is equivalent to Get-PASAccountGroup in psPAS.
 func List(ctx context.Context, sess *session.Session, safeName string) ([]AccountGroup, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if safeName == "" {
@@ -64,7 +65,7 @@ type CreateOptions struct {
 // This is equivalent to Add-PASAccountGroup in psPAS.
 func Create(ctx context.Context, sess *session.Session, opts CreateOptions) (*AccountGroup, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if opts.GroupName == "" {
@@ -94,7 +95,7 @@ func Create(ctx context.Context, sess *session.Session, opts CreateOptions) (*Ac
 // This is equivalent to Get-PASAccountGroupMember in psPAS.
 func GetMembers(ctx context.Context, sess *session.Session, groupID string) ([]AccountGroupMember, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if groupID == "" {
@@ -120,7 +121,7 @@ func GetMembers(ctx context.Context, sess *session.Session, groupID string) ([]A
 // This is equivalent to Add-PASAccountGroupMember in psPAS.
 func AddMember(ctx context.Context, sess *session.Session, groupID string, accountID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if groupID == "" {
@@ -146,7 +147,7 @@ func AddMember(ctx context.Context, sess *session.Session, groupID string, accou
 // This is equivalent to Remove-PASAccountGroupMember in psPAS.
 func RemoveMember(ctx context.Context, sess *session.Session, groupID string, accountID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if groupID == "" {
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -99,7 +100,7 @@ type SuccessAccount struct {
 // This is equivalent to Start-PASAccountImportJob in psPAS.
 func StartImportJob(ctx context.Context, sess *session.Session, opts StartImportJobOptions) (*ImportJob, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if len(opts.Accounts) == 0 {
@@ -123,7 +124,7 @@ func StartImportJob(ctx context.Context, sess *session.Session, opts StartImport
 // This is equivalent to Get-PASAccountImportJob in psPAS.
 func GetImportJob(ctx context.Context, sess *session.Session, jobID string) (*ImportJobResult, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if jobID == "" {
@@ -146,7 +147,7 @@ func GetImportJob(ctx context.Context, sess *session.Session, jobID string) (*Im
 // ListImportJobs retrieves all account import jobs.
 func ListImportJobs(ctx context.Context, sess *session.Session) ([]ImportJob, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/BulkActions/Accounts", nil)
@@ -205,7 +206,7 @@ type PendingAccountsResponse struct {
 // This is equivalent to Add-PASPendingAccount in psPAS.
 func AddPendingAccount(ctx context.Context, sess *session.Session, account ImportAccount) (*PendingAccount, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if account.UserName == "" {
@@ -245,7 +246,7 @@ type PasswordVersionInfo struct {
 // This is equivalent to Get-PASAccountPasswordVersion in psPAS.
 func GetAccountPasswordVersions(ctx context.Context, sess *session.Session, accountID string) ([]PasswordVersionInfo, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -276,7 +277,7 @@ type GeneratePasswordOptions struct {
 // This is equivalent to New-PASAccountPassword in psPAS.
 func GeneratePassword(ctx context.Context, sess *session.Session, accountID string) (string, error) {
 	if sess == nil || !sess.IsValid() {
-		return "", fmt.Errorf("valid session is required")
+		return "", client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
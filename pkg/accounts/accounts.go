@@ -7,10 +7,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 	"time"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -70,7 +73,7 @@ type ListOptions struct {
 // This is equivalent to Get-PASAccount in psPAS.
 func List(ctx context.Context, sess *session.Session, opts ListOptions) (*AccountsResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -109,11 +112,27 @@ func List(ctx context.Context, sess *session.Session, opts ListOptions) (*Accoun
 	return &result, nil
 }
 
+// All returns an iterator over every account matching opts, requesting
+// further pages as the caller consumes them. opts.Offset is the starting
+// position and opts.Limit the page size.
+func All(ctx context.Context, sess *session.Session, opts ListOptions, pageOpts types.PageOptions) iter.Seq2[Account, error] {
+	fetch := func(ctx context.Context, offset int) (*helpers.Page[Account], error) {
+		pageQuery := opts
+		pageQuery.Offset = offset
+		result, err := List(ctx, sess, pageQuery)
+		if err != nil {
+			return nil, err
+		}
+		return &helpers.Page[Account]{Items: result.Value, Total: result.Count, NextLink: result.NextLink}, nil
+	}
+	return helpers.Paginate(ctx, fetch, opts.Offset, pageOpts)
+}
+
 // Get retrieves a specific account by ID.
 // This is equivalent to Get-PASAccount -id in psPAS.
 func Get(ctx context.Context, sess *session.Session, accountID string) (*Account, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -151,7 +170,7 @@ type CreateOptions struct {
 // This is equivalent to Add-PASAccount in psPAS.
 func Create(ctx context.Context, sess *session.Session, opts CreateOptions) (*Account, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if opts.SafeName == "" {
@@ -202,7 +221,7 @@ type PatchOperation struct {
 // This is equivalent to Set-PASAccount in psPAS.
 func Update(ctx context.Context, sess *session.Session, accountID string, operations []PatchOperation) (*Account, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -226,7 +245,7 @@ func Update(ctx context.Context, sess *session.Session, accountID string, operat
 // This is equivalent to Remove-PASAccount in psPAS.
 func Delete(ctx context.Context, sess *session.Session, accountID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -245,7 +264,7 @@ func Delete(ctx context.Context, sess *session.Session, accountID string) error
 // This is equivalent to Get-PASAccountPassword in psPAS.
 func GetPassword(ctx context.Context, sess *session.Session, accountID string, reason string) (string, error) {
 	if sess == nil || !sess.IsValid() {
-		return "", fmt.Errorf("valid session is required")
+		return "", client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -281,7 +300,7 @@ type ChangeCredentialsOptions struct {
 // This is equivalent to Invoke-PASCPMOperation -ChangeImmediately in psPAS.
 func ChangeCredentialsImmediately(ctx context.Context, sess *session.Session, accountID string, opts ChangeCredentialsOptions) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -300,7 +319,7 @@ func ChangeCredentialsImmediately(ctx context.Context, sess *session.Session, ac
 // This is equivalent to Invoke-PASCPMOperation -VerifyTask in psPAS.
 func VerifyCredentials(ctx context.Context, sess *session.Session, accountID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -319,7 +338,7 @@ func VerifyCredentials(ctx context.Context, sess *session.Session, accountID str
 // This is equivalent to Invoke-PASCPMOperation -ReconcileTask in psPAS.
 func ReconcileCredentials(ctx context.Context, sess *session.Session, accountID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -338,7 +357,7 @@ func ReconcileCredentials(ctx context.Context, sess *session.Session, accountID
 // This is equivalent to Set-PASAccountPassword in psPAS.
 func SetNextPassword(ctx context.Context, sess *session.Session, accountID string, newPassword string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -376,7 +395,7 @@ type AccountActivity struct {
 // This is equivalent to Get-PASAccountActivity in psPAS.
 func GetActivities(ctx context.Context, sess *session.Session, accountID string) ([]AccountActivity, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
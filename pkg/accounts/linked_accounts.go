@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -37,7 +38,7 @@ type LinkAccountOptions struct {
 // This is equivalent to Add-PASAccountLinking in psPAS.
 func LinkAccount(ctx context.Context, sess *session.Session, accountID string, linkedAccountID string, opts LinkAccountOptions) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -66,7 +67,7 @@ func LinkAccount(ctx context.Context, sess *session.Session, accountID string, l
 // This is equivalent to Remove-PASAccountLinking in psPAS.
 func UnlinkAccount(ctx context.Context, sess *session.Session, accountID string, extraPassID int) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -88,7 +89,7 @@ func UnlinkAccount(ctx context.Context, sess *session.Session, accountID string,
 // GetLinkedAccounts retrieves the linked accounts for an account.
 func GetLinkedAccounts(ctx context.Context, sess *session.Session, accountID string) ([]LinkedAccount, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/types"
 )
 
 // Application represents a CyberArk application.
@@ -42,7 +44,7 @@ type ListOptions struct {
 // This is equivalent to Get-PASApplication in psPAS.
 func List(ctx context.Context, sess *session.Session, opts ListOptions) ([]Application, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -69,7 +71,7 @@ func List(ctx context.Context, sess *session.Session, opts ListOptions) ([]Appli
 // Get retrieves a specific application.
 func Get(ctx context.Context, sess *session.Session, appID string) (*Application, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if appID == "" {
@@ -110,7 +112,7 @@ type CreateOptions struct {
 // This is equivalent to Add-PASApplication in psPAS.
 func Create(ctx context.Context, sess *session.Session, opts CreateOptions) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if opts.AppID == "" {
@@ -133,7 +135,7 @@ func Create(ctx context.Context, sess *session.Session, opts CreateOptions) erro
 // This is equivalent to Remove-PASApplication in psPAS.
 func Delete(ctx context.Context, sess *session.Session, appID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if appID == "" {
@@ -148,21 +150,35 @@ func Delete(ctx context.Context, sess *session.Session, appID string) error {
 	return nil
 }
 
+// Authentication method types accepted by AddAuthMethod.
+const (
+	AuthTypeMachineAddress          = "machineAddress"
+	AuthTypeOSUser                  = "osUser"
+	AuthTypePath                    = "path"
+	AuthTypeHash                    = "hash"
+	AuthTypeCertificateSerialNumber = "certificateserialnumber"
+	AuthTypeCertificateAttribute    = "certificateattr"
+)
+
 // AuthMethod represents an application authentication method.
 type AuthMethod struct {
-	AppID                string `json:"AppID"`
-	AuthType             string `json:"AuthType"`
-	AuthValue            string `json:"AuthValue"`
-	Comment              string `json:"Comment,omitempty"`
-	IsFolder             bool   `json:"IsFolder,omitempty"`
-	AllowInternalScripts bool   `json:"AllowInternalScripts,omitempty"`
+	AuthID                 types.FlexibleID `json:"authID,omitempty"`
+	AppID                  string           `json:"AppID"`
+	AuthType               string           `json:"AuthType"`
+	AuthValue              string           `json:"AuthValue"`
+	Comment                string           `json:"Comment,omitempty"`
+	IsFolder               bool             `json:"IsFolder,omitempty"`
+	AllowInternalScripts   bool             `json:"AllowInternalScripts,omitempty"`
+	Subject                []string         `json:"Subject,omitempty"`
+	Issuer                 []string         `json:"Issuer,omitempty"`
+	SubjectAlternativeName []string         `json:"SubjectAlternativeName,omitempty"`
 }
 
 // ListAuthMethods retrieves authentication methods for an application.
 // This is equivalent to Get-PASApplicationAuthenticationMethod in psPAS.
 func ListAuthMethods(ctx context.Context, sess *session.Session, appID string) ([]AuthMethod, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if appID == "" {
@@ -185,19 +201,24 @@ func ListAuthMethods(ctx context.Context, sess *session.Session, appID string) (
 }
 
 // AddAuthMethodOptions holds options for adding an authentication method.
+// Certificate attribute methods (AuthTypeCertificateAttribute) match on
+// Subject, Issuer and SubjectAlternativeName instead of AuthValue.
 type AddAuthMethodOptions struct {
-	AuthType             string `json:"AuthType"`
-	AuthValue            string `json:"AuthValue"`
-	Comment              string `json:"Comment,omitempty"`
-	IsFolder             bool   `json:"IsFolder,omitempty"`
-	AllowInternalScripts bool   `json:"AllowInternalScripts,omitempty"`
+	AuthType               string   `json:"AuthType"`
+	AuthValue              string   `json:"AuthValue,omitempty"`
+	Comment                string   `json:"Comment,omitempty"`
+	IsFolder               bool     `json:"IsFolder,omitempty"`
+	AllowInternalScripts   bool     `json:"AllowInternalScripts,omitempty"`
+	Subject                []string `json:"Subject,omitempty"`
+	Issuer                 []string `json:"Issuer,omitempty"`
+	SubjectAlternativeName []string `json:"SubjectAlternativeName,omitempty"`
 }
 
 // AddAuthMethod adds an authentication method to an application.
 // This is equivalent to Add-PASApplicationAuthenticationMethod in psPAS.
 func AddAuthMethod(ctx context.Context, sess *session.Session, appID string, opts AddAuthMethodOptions) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if appID == "" {
@@ -224,7 +245,7 @@ func AddAuthMethod(ctx context.Context, sess *session.Session, appID string, opt
 // This is equivalent to Remove-PASApplicationAuthenticationMethod in psPAS.
 func RemoveAuthMethod(ctx context.Context, sess *session.Session, appID string, authID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if appID == "" {
@@ -380,6 +380,40 @@ func TestAddAuthMethod(t *testing.T) {
 	}
 }
 
+func TestAddAuthMethod_CertificateAttribute(t *testing.T) {
+	var body map[string]map[string]interface{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	sess, server := createTestSession(t, handler)
+	defer server.Close()
+
+	err := AddAuthMethod(context.Background(), sess, "App1", AddAuthMethodOptions{
+		AuthType: AuthTypeCertificateAttribute,
+		Subject:  []string{"CN=billing", "O=Example"},
+		Issuer:   []string{"CN=Example CA"},
+	})
+	if err != nil {
+		t.Fatalf("AddAuthMethod() unexpected error: %v", err)
+	}
+
+	auth := body["authentication"]
+	if auth["AuthType"] != "certificateattr" {
+		t.Errorf("AuthType = %v, want certificateattr", auth["AuthType"])
+	}
+	if subject, _ := auth["Subject"].([]interface{}); len(subject) != 2 {
+		t.Errorf("Subject = %v, want 2 attributes", auth["Subject"])
+	}
+	if _, ok := auth["AuthValue"]; ok {
+		t.Errorf("AuthValue sent for a certificate attribute method: %v", auth["AuthValue"])
+	}
+	if _, ok := auth["SubjectAlternativeName"]; ok {
+		t.Error("empty SubjectAlternativeName should be omitted")
+	}
+}
+
 func TestRemoveAuthMethod(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -8,9 +8,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/ccp"
+	"github.com/chrisranney/gopas/pkg/tlsconfig"
 	"github.com/chrisranney/gopas/pkg/types"
 )
 
@@ -26,6 +31,9 @@ const (
 	AuthMethodRADIUS AuthMethod = "RADIUS"
 	// AuthMethodWindows uses Windows authentication
 	AuthMethodWindows AuthMethod = "Windows"
+	// AuthMethodPKI uses the client certificate of the TLS connection,
+	// for example from a smartcard, instead of a password
+	AuthMethodPKI AuthMethod = "PKI"
 )
 
 // Credentials holds the authentication credentials.
@@ -34,6 +42,11 @@ type Credentials struct {
 	Password string
 }
 
+// CredentialProvider supplies credentials at logon time. It is called for the
+// initial logon when no Credentials are given, and again on every
+// re-authentication so that rotated passwords are picked up.
+type CredentialProvider func(ctx context.Context) (Credentials, error)
+
 // SessionOptions holds options for creating a new session.
 type SessionOptions struct {
 	// BaseURL is the CyberArk server URL (required)
@@ -51,8 +64,80 @@ type SessionOptions struct {
 	// SkipVersionCheck skips the version check after authentication
 	SkipVersionCheck bool
 
-	// CustomHTTPClient allows using a custom HTTP client
+	// SkipVersionEnforcement lets SDK functions run even when the vault
+	// version or deployment type does not meet their declared requirements
+	SkipVersionEnforcement bool
+
+	// PrivilegeCloud marks the connection as Privilege Cloud. It is detected
+	// automatically for *.cyberark.cloud hosts.
+	PrivilegeCloud bool
+
+	// SelfHosted marks the connection as Self-Hosted, so functions that only
+	// exist in Privilege Cloud fail before sending a request. When neither
+	// this nor PrivilegeCloud applies, those functions are not blocked.
+	SelfHosted bool
+
+	// CustomHTTPClient allows using a custom HTTP client. When set, the
+	// TLS, SkipTLSVerify, Proxy, NoProxy and Timeout options are ignored.
 	CustomHTTPClient *http.Client
+
+	// TLS configures a private CA, client certificates, pinning and the
+	// minimum TLS version (optional)
+	TLS *tlsconfig.Config
+
+	// SkipTLSVerify disables TLS certificate verification (not recommended for production)
+	SkipTLSVerify bool
+
+	// Proxy is an http, https or socks5 proxy URL (optional). When empty
+	// the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables are used.
+	Proxy string
+
+	// NoProxy is a comma-separated list of hosts, domains, IP addresses and
+	// CIDR ranges to reach without the proxy (optional)
+	NoProxy string
+
+	// Timeout is the HTTP request timeout (default 30 seconds)
+	Timeout time.Duration
+
+	// RetryPolicy enables automatic retries of failed API requests (optional)
+	RetryPolicy *client.RetryPolicy
+
+	// Middlewares wrap every API request, for logging, metrics or tracing (optional)
+	Middlewares []client.Middleware
+
+	// RateLimit throttles requests and caps concurrency for the session and
+	// its clones (optional)
+	RateLimit *client.RateLimit
+
+	// Instrumentation observes every API call, see the otelgopas module for
+	// an OpenTelemetry implementation (optional)
+	Instrumentation client.Instrumentation
+
+	// CredentialProvider supplies credentials instead of Credentials (optional)
+	CredentialProvider CredentialProvider
+
+	// OTP is a one-time password for RADIUS logons that require a second
+	// factor, sent as selected by OTPMode. It is ignored by other
+	// authentication methods and only used for the first logon (optional)
+	OTP string
+
+	// OTPMode selects whether OTP is appended to the password or answers
+	// the RADIUS challenge (default: OTPModeAppend)
+	OTPMode OTPMode
+
+	// OTPDelimiter separates the password and OTP in OTPModeAppend
+	// (default: DefaultOTPDelimiter)
+	OTPDelimiter string
+
+	// ChallengeHandler answers RADIUS challenges (ITATS542I) that OTP does
+	// not (optional)
+	ChallengeHandler ChallengeHandler
+
+	// Reauthenticate logs in again transparently when the session token
+	// expires, using CredentialProvider when set or Credentials otherwise.
+	// Credentials are kept in memory for the lifetime of the session. A
+	// logon that needs a second factor fails with ErrSecondFactorRequired.
+	Reauthenticate bool
 }
 
 // LoginRequest represents the login request body.
@@ -84,39 +169,146 @@ func NewSession(ctx context.Context, opts SessionOptions) (*session.Session, err
 		return nil, fmt.Errorf("baseURL is required")
 	}
 
-	if opts.Credentials.Username == "" {
-		return nil, fmt.Errorf("username is required")
-	}
-
-	if opts.Credentials.Password == "" {
-		return nil, fmt.Errorf("password is required")
-	}
-
 	// Set default auth method
 	if opts.AuthMethod == "" {
 		opts.AuthMethod = AuthMethodCyberArk
 	}
 
+	creds := opts.Credentials
+	if opts.AuthMethod == AuthMethodPKI {
+		if !opts.TLS.HasClientCertificate() && opts.CustomHTTPClient == nil {
+			return nil, fmt.Errorf("PKI authentication requires a client certificate in TLS or a CustomHTTPClient")
+		}
+	} else {
+		if creds.Username == "" && creds.Password == "" && opts.CredentialProvider != nil {
+			var err error
+			creds, err = opts.CredentialProvider(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to obtain credentials: %w", err)
+			}
+		}
+
+		if creds.Username == "" {
+			return nil, fmt.Errorf("username is required")
+		}
+
+		if creds.Password == "" {
+			return nil, fmt.Errorf("password is required")
+		}
+	}
+
 	// Create a new session
-	sess, err := session.NewSession(opts.BaseURL)
+	sess, err := session.NewSessionWithConfig(client.Config{
+		BaseURL:          opts.BaseURL,
+		Timeout:          opts.Timeout,
+		SkipTLSVerify:    opts.SkipTLSVerify,
+		CustomHTTPClient: opts.CustomHTTPClient,
+		TLS:              opts.TLS,
+		Proxy:            opts.Proxy,
+		NoProxy:          opts.NoProxy,
+		RetryPolicy:      opts.RetryPolicy,
+		Middlewares:      opts.Middlewares,
+
+		Instrumentation: opts.Instrumentation,
+		RateLimit:       opts.RateLimit,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
+	token, err := logon(ctx, sess, opts, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the session as authenticated
+	sess.SetAuthenticated(creds.Username, token, string(opts.AuthMethod))
+	sess.SetPrivilegeCloud(opts.PrivilegeCloud || isPrivilegeCloudURL(opts.BaseURL))
+	sess.SetSelfHosted(opts.SelfHosted)
+	sess.SetSkipVersionEnforcement(opts.SkipVersionEnforcement)
+
+	// The certificate identifies the user, so ask the vault who it is
+	if opts.AuthMethod == AuthMethodPKI && creds.Username == "" {
+		user, err := GetLoggedOnUser(ctx, sess)
+		if err != nil {
+			return nil, fmt.Errorf("failed to identify the PKI user: %w", err)
+		}
+		sess.SetAuthenticated(user.Username, token, string(opts.AuthMethod))
+	}
+
+	if opts.Reauthenticate {
+		// The OTP has been used and nobody may be there to answer a challenge
+		secondFactor := usesOTP(opts)
+		reauthOpts := opts
+		reauthOpts.OTP = ""
+		reauthOpts.ChallengeHandler = nil
+
+		sess.SetCredentialSource(func(ctx context.Context) (string, error) {
+			if secondFactor {
+				return "", ErrSecondFactorRequired
+			}
+			creds := reauthOpts.Credentials
+			if reauthOpts.CredentialProvider != nil && reauthOpts.AuthMethod != AuthMethodPKI {
+				var err error
+				creds, err = reauthOpts.CredentialProvider(ctx)
+				if err != nil {
+					return "", fmt.Errorf("failed to obtain credentials: %w", err)
+				}
+			}
+			token, err := logon(ctx, sess, reauthOpts, creds)
+			if IsChallenge(err) {
+				return "", fmt.Errorf("%w: %w", ErrSecondFactorRequired, err)
+			}
+			return token, err
+		})
+	}
+
+	// Get server version unless skipped
+	if !opts.SkipVersionCheck {
+		if err := fetchServerVersion(ctx, sess); err != nil {
+			// Log warning but don't fail - version check is optional
+			_ = err
+		}
+	}
+
+	return sess, nil
+}
+
+// isPrivilegeCloudURL reports whether the URL points at a Privilege Cloud tenant.
+func isPrivilegeCloudURL(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return strings.HasSuffix(host, ".cyberark.cloud") || strings.HasSuffix(host, ".privilegecloud.cyberark.com")
+}
+
+// logon authenticates with the given credentials and returns the session token.
+func logon(ctx context.Context, sess *session.Session, opts SessionOptions, creds Credentials) (string, error) {
 	// Build the authentication endpoint based on method
 	authPath := getAuthPath(opts.AuthMethod)
 
 	// Create login request
-	loginReq := LoginRequest{
-		Username:          opts.Credentials.Username,
-		Password:          opts.Credentials.Password,
+	var loginReq interface{} = LoginRequest{
+		Username:          creds.Username,
+		Password:          logonPassword(opts, creds),
 		ConcurrentSession: opts.ConcurrentSession,
 	}
+	if opts.AuthMethod == AuthMethodPKI {
+		// The client certificate authenticates the user
+		loginReq = struct {
+			ConcurrentSession bool `json:"concurrentSession,omitempty"`
+		}{opts.ConcurrentSession}
+	}
 
 	// Perform authentication
 	resp, err := sess.Client.Post(ctx, authPath, loginReq)
+	if IsChallenge(err) {
+		resp, err = answerChallenges(ctx, sess, opts, creds, authPath, resp, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("authentication failed: %w", err)
+		return "", fmt.Errorf("authentication failed: %w", err)
 	}
 
 	// Parse the response
@@ -129,21 +321,22 @@ func NewSession(ctx context.Context, opts SessionOptions) (*session.Session, err
 	}
 
 	if loginResp.Token == "" {
-		return nil, fmt.Errorf("no authentication token received")
+		return "", fmt.Errorf("no authentication token received")
 	}
 
-	// Set the session as authenticated
-	sess.SetAuthenticated(opts.Credentials.Username, loginResp.Token, string(opts.AuthMethod))
+	return loginResp.Token, nil
+}
 
-	// Get server version unless skipped
-	if !opts.SkipVersionCheck {
-		if err := fetchServerVersion(ctx, sess); err != nil {
-			// Log warning but don't fail - version check is optional
-			_ = err
+// CCPCredentialProvider returns a CredentialProvider that retrieves the logon
+// credentials from the Central Credential Provider.
+func CCPCredentialProvider(ccpClient *ccp.Client, req ccp.CredentialRequest) CredentialProvider {
+	return func(ctx context.Context) (Credentials, error) {
+		username, password, err := ccpClient.GetLoginCredentials(ctx, req)
+		if err != nil {
+			return Credentials{}, err
 		}
+		return Credentials{Username: username, Password: password}, nil
 	}
-
-	return sess, nil
 }
 
 // CloseSession closes the authenticated session.
@@ -192,7 +385,7 @@ func GetServerInfo(ctx context.Context, sess *session.Session) (*ServerInfo, err
 // This is equivalent to Get-PASComponentSummary in psPAS.
 func GetComponentsHealth(ctx context.Context, sess *session.Session) ([]ComponentHealth, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/ComponentsMonitoringSummary", nil)
@@ -229,6 +422,8 @@ func getAuthPath(method AuthMethod) string {
 		return "/Auth/RADIUS/Logon"
 	case AuthMethodWindows:
 		return "/Auth/Windows/Logon"
+	case AuthMethodPKI:
+		return "/Auth/pki/Logon"
 	default:
 		return "/Auth/CyberArk/Logon"
 	}
@@ -272,7 +467,7 @@ type LoggedOnUser struct {
 // This is equivalent to Get-PASLoggedOnUser in psPAS.
 func GetLoggedOnUser(ctx context.Context, sess *session.Session) (*LoggedOnUser, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/WebServices/PIMServices.svc/User", nil)
@@ -305,7 +500,7 @@ type UserLoginInfo struct {
 // This is equivalent to Get-PASUserLoginInfo in psPAS.
 func GetUserLoginInfo(ctx context.Context, sess *session.Session) (*UserLoginInfo, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/LoginsInfo", nil)
@@ -337,6 +532,7 @@ func GetSession(sess *session.Session) map[string]interface{} {
 		"StartTime":       sess.StartTime,
 		"ElapsedTime":     sess.GetElapsedTime().String(),
 		"PrivilegeCloud":  sess.PrivilegeCloud,
+		"SelfHosted":      sess.SelfHosted,
 	}
 }
 
@@ -3,12 +3,30 @@ package authentication
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/ccp"
+	"github.com/chrisranney/gopas/pkg/pvwatest"
+	"github.com/chrisranney/gopas/pkg/tlsconfig"
 )
 
 func TestNewSession(t *testing.T) {
@@ -228,6 +246,270 @@ func TestNewSession_EmptyTokenResponse(t *testing.T) {
 	}
 }
 
+// expiringTokenServer issues sequential tokens on logon and rejects any
+// token other than the latest one, simulating an expired session.
+func expiringTokenServer(t *testing.T, logons *int32, lastUser *atomic.Value) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if containsString(r.URL.Path, "/Logon") {
+			var req LoginRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			lastUser.Store(req.Username)
+			n := atomic.AddInt32(logons, 1)
+			json.NewEncoder(w).Encode(LoginResponse{Token: "token-" + string(rune('0'+n))})
+			return
+		}
+		current := "token-" + string(rune('0'+atomic.LoadInt32(logons)))
+		if atomic.LoadInt32(logons) < 2 || r.Header.Get("Authorization") != current {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ErrorCode":"PASWS013E","ErrorMessage":"Session expired"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func TestNewSession_Reauthenticate(t *testing.T) {
+	var logons int32
+	var lastUser atomic.Value
+	server := expiringTokenServer(t, &logons, &lastUser)
+	defer server.Close()
+
+	sess, err := NewSession(context.Background(), SessionOptions{
+		BaseURL:          server.URL,
+		Credentials:      Credentials{Username: "admin", Password: "password"},
+		SkipVersionCheck: true,
+		Reauthenticate:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+
+	if _, err := sess.Client.Get(context.Background(), "/Accounts", nil); err != nil {
+		t.Fatalf("Get() unexpected error after token expiry: %v", err)
+	}
+	if got := atomic.LoadInt32(&logons); got != 2 {
+		t.Errorf("logons = %d, want 2", got)
+	}
+	if got := sess.GetSessionToken(); got != "token-2" {
+		t.Errorf("SessionToken = %q, want token-2", got)
+	}
+}
+
+func TestNewSession_NoReauthenticateByDefault(t *testing.T) {
+	var logons int32
+	var lastUser atomic.Value
+	server := expiringTokenServer(t, &logons, &lastUser)
+	defer server.Close()
+
+	sess, err := NewSession(context.Background(), SessionOptions{
+		BaseURL:          server.URL,
+		Credentials:      Credentials{Username: "admin", Password: "password"},
+		SkipVersionCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+
+	if _, err := sess.Client.Get(context.Background(), "/Accounts", nil); err == nil {
+		t.Error("Get() expected 401 without re-authentication")
+	}
+	if got := atomic.LoadInt32(&logons); got != 1 {
+		t.Errorf("logons = %d, want 1", got)
+	}
+}
+
+func TestNewSession_CredentialProvider(t *testing.T) {
+	var logons int32
+	var lastUser atomic.Value
+	server := expiringTokenServer(t, &logons, &lastUser)
+	defer server.Close()
+
+	var provided int32
+	provider := func(ctx context.Context) (Credentials, error) {
+		n := atomic.AddInt32(&provided, 1)
+		return Credentials{Username: "svc" + string(rune('0'+n)), Password: "rotated"}, nil
+	}
+
+	sess, err := NewSession(context.Background(), SessionOptions{
+		BaseURL:            server.URL,
+		CredentialProvider: provider,
+		SkipVersionCheck:   true,
+		Reauthenticate:     true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+	if sess.User != "svc1" {
+		t.Errorf("User = %q, want svc1", sess.User)
+	}
+
+	if _, err := sess.Client.Get(context.Background(), "/Accounts", nil); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&provided); got != 2 {
+		t.Errorf("provider called %d times, want 2", got)
+	}
+	if got := lastUser.Load(); got != "svc2" {
+		t.Errorf("re-login used %v, want svc2", got)
+	}
+}
+
+func TestNewSession_CredentialProviderError(t *testing.T) {
+	providerErr := errors.New("provider down")
+	_, err := NewSession(context.Background(), SessionOptions{
+		BaseURL: "https://cyberark.example.com",
+		CredentialProvider: func(ctx context.Context) (Credentials, error) {
+			return Credentials{}, providerErr
+		},
+	})
+	if !errors.Is(err, providerErr) {
+		t.Errorf("NewSession() error = %v, want provider error", err)
+	}
+}
+
+func TestCCPCredentialProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("AppID") != "MyApp" {
+			t.Errorf("AppID = %q, want MyApp", r.URL.Query().Get("AppID"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Content": "vaulted-password", "UserName": "svc-admin"}`))
+	}))
+	defer server.Close()
+
+	ccpClient, err := ccp.NewClient(ccp.ClientConfig{BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("ccp.NewClient() error: %v", err)
+	}
+
+	provider := CCPCredentialProvider(ccpClient, ccp.CredentialRequest{AppID: "MyApp", Safe: "Admins"})
+	creds, err := provider(context.Background())
+	if err != nil {
+		t.Fatalf("provider() error: %v", err)
+	}
+	if creds.Username != "svc-admin" || creds.Password != "vaulted-password" {
+		t.Errorf("provider() = %+v", creds)
+	}
+}
+
+func TestNewSession_Middlewares(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LoginResponse{Token: "token"})
+	}))
+	defer server.Close()
+
+	var paths []string
+	record := client.MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.URL.Path)
+			return next.RoundTrip(req)
+		})
+	})
+
+	_, err := NewSession(context.Background(), SessionOptions{
+		BaseURL:          server.URL,
+		Credentials:      Credentials{Username: "admin", Password: "password"},
+		SkipVersionCheck: true,
+		Middlewares:      []client.Middleware{record},
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+	if len(paths) != 1 || !containsString(paths[0], "/Auth/CyberArk/Logon") {
+		t.Errorf("middleware saw %v, want the logon request", paths)
+	}
+}
+
+func TestNewSession_RateLimitSharedByClones(t *testing.T) {
+	var current, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LoginResponse{Token: "token"})
+	}))
+	defer server.Close()
+
+	sess, err := NewSession(context.Background(), SessionOptions{
+		BaseURL:          server.URL,
+		Credentials:      Credentials{Username: "admin", Password: "password"},
+		SkipVersionCheck: true,
+		RateLimit:        &client.RateLimit{MaxInFlight: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		clone := sess.Clone()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clone.Client.Get(context.Background(), "/Safes", nil)
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&peak); got != 1 {
+		t.Errorf("peak concurrency across clones = %d, want 1", got)
+	}
+}
+
+func TestIsPrivilegeCloudURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://tenant.privilegecloud.cyberark.cloud", want: true},
+		{url: "https://TENANT.cyberark.cloud/PasswordVault", want: true},
+		{url: "https://tenant.privilegecloud.cyberark.com", want: true},
+		{url: "https://pvwa.example.com", want: false},
+		{url: "https://cyberark.cloud.example.com", want: false},
+		{url: "://bad", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isPrivilegeCloudURL(tt.url); got != tt.want {
+			t.Errorf("isPrivilegeCloudURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestNewSession_VersionEnforcementOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LoginResponse{Token: "token"})
+	}))
+	defer server.Close()
+
+	sess, err := NewSession(context.Background(), SessionOptions{
+		BaseURL:                server.URL,
+		Credentials:            Credentials{Username: "admin", Password: "password"},
+		SkipVersionCheck:       true,
+		PrivilegeCloud:         true,
+		SkipVersionEnforcement: true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+	if !sess.PrivilegeCloud {
+		t.Error("PrivilegeCloud = false, want true")
+	}
+	if !sess.SkipVersionEnforcement {
+		t.Error("SkipVersionEnforcement = false, want true")
+	}
+}
+
 func TestNewSession_WithVersionCheck(t *testing.T) {
 	requestCount := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1186,3 +1468,384 @@ func TestSAMLLoginRequest_Struct(t *testing.T) {
 		t.Errorf("SAMLResponse = %v, want %v", parsed.SAMLResponse, req.SAMLResponse)
 	}
 }
+
+func TestNewSession_TLSOptions(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LoginResponse{Token: "token"})
+	}))
+	defer server.Close()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	tests := []struct {
+		name    string
+		opts    SessionOptions
+		wantErr bool
+	}{
+		{name: "untrusted server", wantErr: true},
+		{name: "skip verify", opts: SessionOptions{SkipTLSVerify: true}},
+		{name: "private CA", opts: SessionOptions{TLS: &tlsconfig.Config{CAPEM: caPEM}}},
+		{name: "custom HTTP client", opts: SessionOptions{CustomHTTPClient: server.Client()}},
+		{name: "TLS 1.3 minimum", opts: SessionOptions{TLS: &tlsconfig.Config{CAPEM: caPEM, MinVersion: tls.VersionTLS13}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.BaseURL = server.URL
+			tt.opts.Credentials = Credentials{Username: "admin", Password: "password"}
+			tt.opts.SkipVersionCheck = true
+			_, err := NewSession(context.Background(), tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewSession_Proxy(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute target URL.
+		if r.URL.Host == "pvwa.example.com" {
+			proxied.Add(1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LoginResponse{Token: "token"})
+	}))
+	defer proxy.Close()
+
+	opts := SessionOptions{
+		BaseURL:          "http://pvwa.example.com",
+		Credentials:      Credentials{Username: "admin", Password: "password"},
+		SkipVersionCheck: true,
+		Proxy:            proxy.URL,
+	}
+	if _, err := NewSession(context.Background(), opts); err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+	if proxied.Load() != 1 {
+		t.Errorf("proxy saw %d logons, want 1", proxied.Load())
+	}
+
+	opts.Proxy = "ftp://proxy.example.com"
+	if _, err := NewSession(context.Background(), opts); err == nil {
+		t.Error("NewSession() with an unsupported proxy scheme succeeded")
+	}
+}
+
+// selfSignedCertificate returns a client certificate for the given common name.
+func selfSignedCertificate(t *testing.T, cn string) *tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestNewSession_PKI(t *testing.T) {
+	srv := pvwatest.NewTLSServer()
+	defer srv.Close()
+
+	sess, err := NewSession(context.Background(), SessionOptions{
+		BaseURL:          srv.URL,
+		AuthMethod:       AuthMethodPKI,
+		SkipTLSVerify:    true,
+		SkipVersionCheck: true,
+		TLS:              &tlsconfig.Config{Certificate: selfSignedCertificate(t, pvwatest.DefaultUsername)},
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error: %v", err)
+	}
+	if sess.User != pvwatest.DefaultUsername {
+		t.Errorf("User = %q, want %q", sess.User, pvwatest.DefaultUsername)
+	}
+	if sess.AuthMethod != string(AuthMethodPKI) {
+		t.Errorf("AuthMethod = %q, want PKI", sess.AuthMethod)
+	}
+	calls := srv.Calls()
+	if len(calls) == 0 || calls[0].Path != "/Auth/pki/Logon" {
+		t.Errorf("Calls() = %+v, want the PKI logon first", calls)
+	}
+
+	_, err = NewSession(context.Background(), SessionOptions{
+		BaseURL:          srv.URL,
+		AuthMethod:       AuthMethodPKI,
+		SkipTLSVerify:    true,
+		SkipVersionCheck: true,
+		TLS:              &tlsconfig.Config{Certificate: selfSignedCertificate(t, "nobody")},
+	})
+	if apiErr, ok := client.AsAPIError(err); !ok || apiErr.ErrorCode != "ITATS004E" {
+		t.Errorf("NewSession() with an unknown certificate error = %v, want ITATS004E", err)
+	}
+
+	_, err = NewSession(context.Background(), SessionOptions{BaseURL: srv.URL, AuthMethod: AuthMethodPKI})
+	if err == nil || !strings.Contains(err.Error(), "client certificate") {
+		t.Errorf("NewSession() without a certificate error = %v", err)
+	}
+
+	// A session whose user cannot be identified is not returned
+	srv.InjectFault(pvwatest.Fault{Path: "/WebServices/PIMServices.svc/User", StatusCode: http.StatusInternalServerError})
+	_, err = NewSession(context.Background(), SessionOptions{
+		BaseURL:          srv.URL,
+		AuthMethod:       AuthMethodPKI,
+		SkipTLSVerify:    true,
+		SkipVersionCheck: true,
+		TLS:              &tlsconfig.Config{Certificate: selfSignedCertificate(t, pvwatest.DefaultUsername)},
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to identify the PKI user") {
+		t.Errorf("NewSession() with a failing user lookup error = %v", err)
+	}
+}
+
+// radiusChallengeServer answers a RADIUS logon with password "password" with
+// a challenge, and accepts "123456" as the response. The challenge response
+// must carry the cookie set with the challenge.
+func radiusChallengeServer(t *testing.T, passwords *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req LoginRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		*passwords = append(*passwords, req.Password)
+
+		switch {
+		case req.Password == "password":
+			http.SetCookie(w, &http.Cookie{Name: "ASP.NET_SessionId", Value: "challenge-1"})
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"ErrorCode":"ITATS542I","ErrorMessage":"Enter the code sent to your phone"}`))
+		case req.Password == "password,123456",
+			req.Password == "123456" && r.Header.Get("Cookie") == "ASP.NET_SessionId=challenge-1":
+			json.NewEncoder(w).Encode(LoginResponse{Token: "token"})
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ErrorCode":"ITATS004E","ErrorMessage":"Authentication failure"}`))
+		}
+	}))
+}
+
+func TestNewSession_RADIUSChallenge(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          SessionOptions
+		wantPasswords []string
+		wantErr       bool
+	}{
+		{
+			name: "challenge handler",
+			opts: SessionOptions{ChallengeHandler: ChallengeHandlerFunc(func(ctx context.Context, c Challenge) (string, error) {
+				if c.Username != "admin" || c.Message != "Enter the code sent to your phone" || c.Attempt != 1 {
+					return "", fmt.Errorf("unexpected challenge %+v", c)
+				}
+				return "123456", nil
+			})},
+			wantPasswords: []string{"password", "123456"},
+		},
+		{
+			name:          "OTP in challenge mode",
+			opts:          SessionOptions{OTP: "123456", OTPMode: OTPModeChallenge},
+			wantPasswords: []string{"password", "123456"},
+		},
+		{
+			name:          "OTP in append mode",
+			opts:          SessionOptions{OTP: "123456"},
+			wantPasswords: []string{"password,123456"},
+		},
+		{
+			name:          "no response configured",
+			wantPasswords: []string{"password"},
+			wantErr:       true,
+		},
+		{
+			name: "handler error",
+			opts: SessionOptions{ChallengeHandler: ChallengeHandlerFunc(func(ctx context.Context, c Challenge) (string, error) {
+				return "", errors.New("cancelled")
+			})},
+			wantPasswords: []string{"password"},
+			wantErr:       true,
+		},
+		{
+			name: "wrong response",
+			opts: SessionOptions{ChallengeHandler: ChallengeHandlerFunc(func(ctx context.Context, c Challenge) (string, error) {
+				return "000000", nil
+			})},
+			wantPasswords: []string{"password", "000000"},
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var passwords []string
+			server := radiusChallengeServer(t, &passwords)
+			defer server.Close()
+
+			opts := tt.opts
+			opts.BaseURL = server.URL
+			opts.Credentials = Credentials{Username: "admin", Password: "password"}
+			opts.AuthMethod = AuthMethodRADIUS
+			opts.SkipVersionCheck = true
+
+			sess, err := NewSession(context.Background(), opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && sess.GetSessionToken() != "token" {
+				t.Errorf("SessionToken = %q, want token", sess.GetSessionToken())
+			}
+			if strings.Join(passwords, " ") != strings.Join(tt.wantPasswords, " ") {
+				t.Errorf("passwords sent = %q, want %q", passwords, tt.wantPasswords)
+			}
+		})
+	}
+}
+
+func TestNewSession_OTPOnlyForRADIUS(t *testing.T) {
+	var passwords []string
+	server := radiusChallengeServer(t, &passwords)
+	defer server.Close()
+
+	_, err := NewSession(context.Background(), SessionOptions{
+		BaseURL:          server.URL,
+		Credentials:      Credentials{Username: "admin", Password: "password"},
+		AuthMethod:       AuthMethodLDAP,
+		OTP:              "123456",
+		SkipVersionCheck: true,
+	})
+	if err == nil {
+		t.Fatal("NewSession() succeeded, want the challenge left unanswered")
+	}
+	if strings.Join(passwords, " ") != "password" {
+		t.Errorf("passwords sent = %q, want the OTP kept out of an LDAP logon", passwords)
+	}
+}
+
+// expiringRADIUSServer challenges every RADIUS logon, accepts "123456" as the
+// response and expires the first session token on its first use.
+func expiringRADIUSServer(t *testing.T, passwords *[]string) *httptest.Server {
+	var logons int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/Logon") {
+			var req LoginRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			*passwords = append(*passwords, req.Password)
+			if req.Password == "password" {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"ErrorCode":"ITATS542I","ErrorMessage":"Enter the code sent to your phone"}`))
+				return
+			}
+			if req.Password == "123456" || req.Password == "password,123456" {
+				n := atomic.AddInt32(&logons, 1)
+				json.NewEncoder(w).Encode(LoginResponse{Token: fmt.Sprintf("token-%d", n)})
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Authorization") == "token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ErrorCode":"PASWS013E","ErrorMessage":"Session expired"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func TestNewSession_ReauthenticateSecondFactor(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          SessionOptions
+		wantPasswords []string
+	}{
+		{
+			name:          "OTP is not replayed",
+			opts:          SessionOptions{OTP: "123456"},
+			wantPasswords: []string{"password,123456"},
+		},
+		{
+			name: "challenge handler is not prompted",
+			opts: SessionOptions{ChallengeHandler: ChallengeHandlerFunc(func(ctx context.Context, c Challenge) (string, error) {
+				if c.Attempt != 1 {
+					return "", fmt.Errorf("unexpected challenge %+v", c)
+				}
+				return "123456", nil
+			})},
+			wantPasswords: []string{"password", "123456", "password"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var passwords []string
+			server := expiringRADIUSServer(t, &passwords)
+			defer server.Close()
+
+			opts := tt.opts
+			opts.BaseURL = server.URL
+			opts.Credentials = Credentials{Username: "admin", Password: "password"}
+			opts.AuthMethod = AuthMethodRADIUS
+			opts.SkipVersionCheck = true
+			opts.Reauthenticate = true
+
+			var prompts int
+			if opts.ChallengeHandler != nil {
+				handler := opts.ChallengeHandler
+				opts.ChallengeHandler = ChallengeHandlerFunc(func(ctx context.Context, c Challenge) (string, error) {
+					prompts++
+					return handler.RespondToChallenge(ctx, c)
+				})
+			}
+
+			sess, err := NewSession(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("NewSession() error: %v", err)
+			}
+
+			_, err = sess.Client.Get(context.Background(), "/Accounts", nil)
+			if !errors.Is(err, ErrSecondFactorRequired) {
+				t.Errorf("Get() error = %v, want ErrSecondFactorRequired", err)
+			}
+			if prompts > 1 {
+				t.Errorf("challenge handler prompted %d times, want at most 1", prompts)
+			}
+			if strings.Join(passwords, " ") != strings.Join(tt.wantPasswords, " ") {
+				t.Errorf("passwords sent = %q, want %q", passwords, tt.wantPasswords)
+			}
+		})
+	}
+}
+
+func TestNewSession_RADIUSChallengeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"ErrorCode":"ITATS542I","ErrorMessage":"Next code"}`))
+	}))
+	defer server.Close()
+
+	var challenges int
+	_, err := NewSession(context.Background(), SessionOptions{
+		BaseURL:          server.URL,
+		Credentials:      Credentials{Username: "admin", Password: "password"},
+		AuthMethod:       AuthMethodRADIUS,
+		SkipVersionCheck: true,
+		ChallengeHandler: ChallengeHandlerFunc(func(ctx context.Context, c Challenge) (string, error) {
+			challenges++
+			return "123456", nil
+		}),
+	})
+	if !IsChallenge(err) {
+		t.Errorf("NewSession() error = %v, want a challenge error", err)
+	}
+	if challenges != maxChallenges {
+		t.Errorf("challenges answered = %d, want %d", challenges, maxChallenges)
+	}
+}
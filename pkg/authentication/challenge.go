@@ -0,0 +1,143 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/session"
+)
+
+// OTPMode selects how SessionOptions.OTP is sent to the Vault.
+type OTPMode string
+
+const (
+	// OTPModeAppend appends the OTP to the password, separated by
+	// OTPDelimiter, and logs on with a single request
+	OTPModeAppend OTPMode = "Append"
+	// OTPModeChallenge sends the password first and the OTP as the answer
+	// to the RADIUS challenge that follows
+	OTPModeChallenge OTPMode = "Challenge"
+)
+
+// DefaultOTPDelimiter separates the password and the OTP in OTPModeAppend.
+const DefaultOTPDelimiter = ","
+
+// ErrSecondFactorRequired is returned when a session re-authenticates after
+// its token expired but the logon needs a second factor. A one-time password
+// cannot be replayed and a ChallengeHandler is not prompted in the middle of
+// an API call, so a new session must be created instead.
+var ErrSecondFactorRequired = errors.New("re-authentication requires a second factor")
+
+// maxChallenges bounds the number of challenges answered during one logon.
+const maxChallenges = 5
+
+// Challenge is a request for more input during a multi-step logon, such as
+// a RADIUS server asking for the one-time password sent to the user.
+type Challenge struct {
+	// Username is the user logging on
+	Username string
+	// Message is the prompt returned by the Vault
+	Message string
+	// Attempt is the number of this challenge within the logon, from 1
+	Attempt int
+}
+
+// ChallengeHandler answers logon challenges, for example by prompting the
+// user for a code from their authenticator.
+type ChallengeHandler interface {
+	RespondToChallenge(ctx context.Context, challenge Challenge) (string, error)
+}
+
+// ChallengeHandlerFunc adapts a function to a ChallengeHandler.
+type ChallengeHandlerFunc func(ctx context.Context, challenge Challenge) (string, error)
+
+// RespondToChallenge calls f(ctx, challenge).
+func (f ChallengeHandlerFunc) RespondToChallenge(ctx context.Context, challenge Challenge) (string, error) {
+	return f(ctx, challenge)
+}
+
+// IsChallenge reports whether err is a Vault challenge (ITATS542I) waiting
+// for a response.
+func IsChallenge(err error) bool {
+	apiErr, ok := client.AsAPIError(err)
+	return ok && apiErr.Category() == client.CategoryChallenge
+}
+
+// usesOTP reports whether the logon sends opts.OTP, which only RADIUS
+// logons accept.
+func usesOTP(opts SessionOptions) bool {
+	return opts.OTP != "" && opts.AuthMethod == AuthMethodRADIUS
+}
+
+// logonPassword returns the password to send in the first logon request.
+func logonPassword(opts SessionOptions, creds Credentials) string {
+	if !usesOTP(opts) || opts.OTPMode == OTPModeChallenge {
+		return creds.Password
+	}
+	delimiter := opts.OTPDelimiter
+	if delimiter == "" {
+		delimiter = DefaultOTPDelimiter
+	}
+	return creds.Password + delimiter + opts.OTP
+}
+
+// answerChallenges responds to the challenges returned for a logon request
+// until the Vault issues a token or rejects an answer. Cookies set with a
+// challenge are sent back with its answer, so load balancers route it to
+// the PVWA server holding the challenge state.
+func answerChallenges(ctx context.Context, sess *session.Session, opts SessionOptions, creds Credentials, authPath string, resp *client.Response, err error) (*client.Response, error) {
+	for attempt := 1; IsChallenge(err); attempt++ {
+		if attempt > maxChallenges {
+			return nil, fmt.Errorf("gave up after %d challenges: %w", maxChallenges, err)
+		}
+
+		apiErr, _ := client.AsAPIError(err)
+		challenge := Challenge{Username: creds.Username, Message: apiErr.ErrorMsg, Attempt: attempt}
+
+		var answer string
+		switch {
+		case attempt == 1 && usesOTP(opts) && opts.OTPMode == OTPModeChallenge:
+			answer = opts.OTP
+		case opts.ChallengeHandler != nil:
+			var handlerErr error
+			answer, handlerErr = opts.ChallengeHandler.RespondToChallenge(ctx, challenge)
+			if handlerErr != nil {
+				return nil, fmt.Errorf("failed to respond to challenge: %w", handlerErr)
+			}
+		default:
+			return nil, fmt.Errorf("%w (set OTP or a ChallengeHandler to respond)", err)
+		}
+
+		req := client.Request{
+			Method: http.MethodPost,
+			Path:   authPath,
+			Body: LoginRequest{
+				Username:          creds.Username,
+				Password:          answer,
+				ConcurrentSession: opts.ConcurrentSession,
+			},
+		}
+		if cookies := challengeCookies(resp); cookies != "" {
+			req.Headers = map[string]string{"Cookie": cookies}
+		}
+		resp, err = sess.Client.Do(ctx, req)
+	}
+	return resp, err
+}
+
+// challengeCookies returns a Cookie header for the cookies set by resp.
+func challengeCookies(resp *client.Response) string {
+	if resp == nil {
+		return ""
+	}
+	cookies := (&http.Response{Header: resp.Headers}).Cookies()
+	pairs := make([]string, 0, len(cookies))
+	for _, c := range cookies {
+		pairs = append(pairs, c.Name+"="+c.Value)
+	}
+	return strings.Join(pairs, "; ")
+}
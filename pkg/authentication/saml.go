@@ -7,7 +7,9 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/tlsconfig"
 )
 
 // SAMLSessionOptions holds options for SAML authentication.
@@ -23,6 +25,16 @@ type SAMLSessionOptions struct {
 
 	// IDPLoginURL is the Identity Provider login URL
 	IDPLoginURL string
+
+	// TLS configures a private CA, client certificates and pinning (optional)
+	TLS *tlsconfig.Config
+
+	// SkipTLSVerify disables TLS certificate verification (not recommended for production)
+	SkipTLSVerify bool
+
+	// Proxy and NoProxy configure the HTTP proxy, see SessionOptions (optional)
+	Proxy   string
+	NoProxy string
 }
 
 // SAMLLoginRequest represents the SAML login request body.
@@ -48,7 +60,13 @@ func NewSAMLSession(ctx context.Context, opts SAMLSessionOptions) (*session.Sess
 	}
 
 	// Create a new session
-	sess, err := session.NewSession(opts.BaseURL)
+	sess, err := session.NewSessionWithConfig(client.Config{
+		BaseURL:       opts.BaseURL,
+		SkipTLSVerify: opts.SkipTLSVerify,
+		TLS:           opts.TLS,
+		Proxy:         opts.Proxy,
+		NoProxy:       opts.NoProxy,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -36,7 +38,11 @@ type AuthenticationMethodsResponse struct {
 // This is equivalent to Get-PASAuthenticationMethod in psPAS.
 func ListAuthenticationMethods(ctx context.Context, sess *session.Session) ([]AuthenticationMethod, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "authmethods.ListAuthenticationMethods", MinVersion: "11.1"}); err != nil {
+		return nil, err
 	}
 
 	resp, err := sess.Client.Get(ctx, "/Configuration/AuthenticationMethods", nil)
@@ -55,7 +61,11 @@ func ListAuthenticationMethods(ctx context.Context, sess *session.Session) ([]Au
 // GetAuthenticationMethod retrieves a specific authentication method.
 func GetAuthenticationMethod(ctx context.Context, sess *session.Session, methodID string) (*AuthenticationMethod, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "authmethods.GetAuthenticationMethod", MinVersion: "11.1"}); err != nil {
+		return nil, err
 	}
 
 	if methodID == "" {
@@ -92,7 +102,11 @@ type AddAuthenticationMethodOptions struct {
 // This is equivalent to Add-PASAuthenticationMethod in psPAS.
 func AddAuthenticationMethod(ctx context.Context, sess *session.Session, opts AddAuthenticationMethodOptions) (*AuthenticationMethod, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "authmethods.AddAuthenticationMethod", MinVersion: "11.1"}); err != nil {
+		return nil, err
 	}
 
 	if opts.ID == "" {
@@ -132,7 +146,11 @@ type UpdateAuthenticationMethodOptions struct {
 // This is equivalent to Set-PASAuthenticationMethod in psPAS.
 func UpdateAuthenticationMethod(ctx context.Context, sess *session.Session, methodID string, opts UpdateAuthenticationMethodOptions) (*AuthenticationMethod, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "authmethods.UpdateAuthenticationMethod", MinVersion: "11.1"}); err != nil {
+		return nil, err
 	}
 
 	if methodID == "" {
@@ -156,7 +174,11 @@ func UpdateAuthenticationMethod(ctx context.Context, sess *session.Session, meth
 // This is equivalent to Remove-PASAuthenticationMethod in psPAS.
 func RemoveAuthenticationMethod(ctx context.Context, sess *session.Session, methodID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "authmethods.RemoveAuthenticationMethod", MinVersion: "11.1"}); err != nil {
+		return err
 	}
 
 	if methodID == "" {
@@ -182,7 +204,11 @@ type UserAllowedAuthMethod struct {
 // This is equivalent to getting user's authentication methods in psPAS.
 func ListUserAllowedAuthMethods(ctx context.Context, sess *session.Session, userID int) ([]UserAllowedAuthMethod, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "authmethods.ListUserAllowedAuthMethods", MinVersion: "11.1"}); err != nil {
+		return nil, err
 	}
 
 	resp, err := sess.Client.Get(ctx, fmt.Sprintf("/Users/%d/AuthenticationMethods", userID), nil)
@@ -204,7 +230,11 @@ func ListUserAllowedAuthMethods(ctx context.Context, sess *session.Session, user
 // This is equivalent to Add-PASUserAllowedAuthenticationMethod in psPAS.
 func AddUserAllowedAuthMethod(ctx context.Context, sess *session.Session, userID int, methodID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "authmethods.AddUserAllowedAuthMethod", MinVersion: "11.1"}); err != nil {
+		return err
 	}
 
 	if methodID == "" {
@@ -227,7 +257,11 @@ func AddUserAllowedAuthMethod(ctx context.Context, sess *session.Session, userID
 // This is equivalent to Remove-PASUserAllowedAuthenticationMethod in psPAS.
 func RemoveUserAllowedAuthMethod(ctx context.Context, sess *session.Session, userID int, methodID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "authmethods.RemoveUserAllowedAuthMethod", MinVersion: "11.1"}); err != nil {
+		return err
 	}
 
 	if methodID == "" {
@@ -252,7 +286,11 @@ type AllowedReferrer struct {
 // This is equivalent to Get-PASAllowedReferrer in psPAS.
 func ListAllowedReferrers(ctx context.Context, sess *session.Session) ([]AllowedReferrer, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "authmethods.ListAllowedReferrers", MinVersion: "11.1"}); err != nil {
+		return nil, err
 	}
 
 	resp, err := sess.Client.Get(ctx, "/Configuration/AccessRestriction/AllowedReferrers", nil)
@@ -274,7 +312,11 @@ func ListAllowedReferrers(ctx context.Context, sess *session.Session) ([]Allowed
 // This is equivalent to Add-PASAllowedReferrer in psPAS.
 func AddAllowedReferrer(ctx context.Context, sess *session.Session, referrerURL string, isRegex bool) (*AllowedReferrer, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "authmethods.AddAllowedReferrer", MinVersion: "11.1"}); err != nil {
+		return nil, err
 	}
 
 	if referrerURL == "" {
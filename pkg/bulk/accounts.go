@@ -0,0 +1,91 @@
+// Package bulk provides bulk CPM operations on accounts.
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/accounts"
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+// AccountSelector chooses the accounts a bulk operation applies to. Set
+// either IDs or Filter.
+type AccountSelector struct {
+	// IDs lists the accounts explicitly
+	IDs []string
+
+	// Filter selects every account matching the list options
+	Filter *accounts.ListOptions
+}
+
+// ids returns the selected account IDs as an iterator.
+func (s AccountSelector) ids(ctx context.Context, sess *session.Session) (iter.Seq2[string, error], error) {
+	switch {
+	case len(s.IDs) > 0 && s.Filter != nil:
+		return nil, fmt.Errorf("account IDs and filter are mutually exclusive")
+	case len(s.IDs) > 0:
+		return func(yield func(string, error) bool) {
+			for _, id := range s.IDs {
+				if !yield(id, nil) {
+					return
+				}
+			}
+		}, nil
+	case s.Filter != nil:
+		return func(yield func(string, error) bool) {
+			for account, err := range accounts.All(ctx, sess, *s.Filter, types.PageOptions{}) {
+				if !yield(account.ID.String(), err) {
+					return
+				}
+			}
+		}, nil
+	default:
+		return nil, fmt.Errorf("account IDs or filter is required")
+	}
+}
+
+// AccountOperation is applied to each selected account.
+type AccountOperation func(ctx context.Context, sess *session.Session, accountID string) error
+
+// Accounts applies op to every selected account. When the accounts are
+// selected by filter, operations start while later pages are still being
+// listed; a listing error ends the run and is returned with the summary.
+// op must therefore not remove accounts from the filtered listing.
+func Accounts(ctx context.Context, sess *session.Session, sel AccountSelector, op AccountOperation, opts Options) (*Summary[string], error) {
+	if sess == nil || !sess.IsValid() {
+		return nil, client.ErrSessionInvalid
+	}
+
+	ids, err := sel.ids(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	return RunSeq(ctx, ids, func(ctx context.Context, accountID string) error {
+		return op(ctx, sess, accountID)
+	}, opts)
+}
+
+// VerifyAccounts initiates a credentials verification for every selected account.
+// This is equivalent to Get-PASAccount | Invoke-PASCPMOperation -VerifyTask in psPAS.
+func VerifyAccounts(ctx context.Context, sess *session.Session, sel AccountSelector, opts Options) (*Summary[string], error) {
+	return Accounts(ctx, sess, sel, accounts.VerifyCredentials, opts)
+}
+
+// ChangeAccounts initiates an immediate password change for every selected account.
+// This is equivalent to Get-PASAccount | Invoke-PASCPMOperation -ChangeImmediately in psPAS.
+func ChangeAccounts(ctx context.Context, sess *session.Session, sel AccountSelector, changeOpts accounts.ChangeCredentialsOptions, opts Options) (*Summary[string], error) {
+	return Accounts(ctx, sess, sel, func(ctx context.Context, sess *session.Session, accountID string) error {
+		return accounts.ChangeCredentialsImmediately(ctx, sess, accountID, changeOpts)
+	}, opts)
+}
+
+// ReconcileAccounts initiates a credentials reconciliation for every selected account.
+// This is equivalent to Get-PASAccount | Invoke-PASCPMOperation -ReconcileTask in psPAS.
+func ReconcileAccounts(ctx context.Context, sess *session.Session, sel AccountSelector, opts Options) (*Summary[string], error) {
+	return Accounts(ctx, sess, sel, accounts.ReconcileCredentials, opts)
+}
@@ -0,0 +1,171 @@
+// Package bulk provides tests for bulk account operations.
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/accounts"
+)
+
+// createTestSession creates a test session with a mock server
+func createTestSession(t *testing.T, handler http.Handler) (*session.Session, *httptest.Server) {
+	server := httptest.NewServer(handler)
+
+	sess, err := session.NewSession(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	c, err := client.NewClient(client.Config{BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	sess.Client = c
+	sess.SetAuthenticated("testuser", "test-token", "CyberArk")
+
+	return sess, server
+}
+
+func TestVerifyAccounts_IDs(t *testing.T) {
+	var mu sync.Mutex
+	var verified []string
+
+	sess, server := createTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/Verify") {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if strings.Contains(r.URL.Path, "/Accounts/bad/") {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"ErrorCode":"PASWS164E","ErrorMessage":"Account was not found"}`))
+			return
+		}
+		mu.Lock()
+		verified = append(verified, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	summary, err := VerifyAccounts(context.Background(), sess, AccountSelector{IDs: []string{"12_1", "bad", "12_2"}}, Options{})
+	if err != nil {
+		t.Fatalf("VerifyAccounts() error: %v", err)
+	}
+
+	if summary.Succeeded != 2 || summary.Failed != 1 || len(verified) != 2 {
+		t.Errorf("summary = %s, verified %v", summary, verified)
+	}
+	if failed := summary.Results[1]; failed.Item != "bad" || !errors.Is(failed.Err, client.ErrNotFound) {
+		t.Errorf("Results[1] = %+v, want not found for \"bad\"", failed)
+	}
+}
+
+func TestChangeAccounts_Filter(t *testing.T) {
+	var mu sync.Mutex
+	var changed []string
+
+	sess, server := createTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/Accounts"):
+			if got := r.URL.Query().Get("filter"); got != "safeName eq Linux" {
+				t.Errorf("filter = %q", got)
+			}
+			json.NewEncoder(w).Encode(accounts.AccountsResponse{
+				Value: []accounts.Account{{ID: "12_1"}, {ID: "12_2"}, {ID: "12_3"}},
+				Count: 3,
+			})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/Change"):
+			var body accounts.ChangeCredentialsOptions
+			json.NewDecoder(r.Body).Decode(&body)
+			if !body.ChangeEntireGroup {
+				t.Error("change options were not sent")
+			}
+			mu.Lock()
+			changed = append(changed, r.URL.Path)
+			mu.Unlock()
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	summary, err := ChangeAccounts(context.Background(), sess,
+		AccountSelector{Filter: &accounts.ListOptions{SafeName: "Linux"}},
+		accounts.ChangeCredentialsOptions{ChangeEntireGroup: true},
+		Options{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("ChangeAccounts() error: %v", err)
+	}
+
+	if summary.Total != 3 || summary.Succeeded != 3 || len(changed) != 3 {
+		t.Errorf("summary = %s, changed %v", summary, changed)
+	}
+}
+
+func TestReconcileAccounts_DryRun(t *testing.T) {
+	sess, server := createTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s in dry run", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+
+	summary, err := ReconcileAccounts(context.Background(), sess, AccountSelector{IDs: []string{"12_1", "12_2"}}, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("ReconcileAccounts() error: %v", err)
+	}
+	if summary.Total != 2 || summary.Results[0].Status != StatusDryRun {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestAccounts_InvalidSelector(t *testing.T) {
+	sess, server := createTestSession(t, http.NotFoundHandler())
+	defer server.Close()
+
+	noop := func(ctx context.Context, sess *session.Session, accountID string) error { return nil }
+
+	if _, err := Accounts(context.Background(), sess, AccountSelector{}, noop, Options{}); err == nil {
+		t.Error("Accounts() with empty selector expected error")
+	}
+	sel := AccountSelector{IDs: []string{"1"}, Filter: &accounts.ListOptions{}}
+	if _, err := Accounts(context.Background(), sess, sel, noop, Options{}); err == nil {
+		t.Error("Accounts() with IDs and filter expected error")
+	}
+	if _, err := Accounts(context.Background(), nil, AccountSelector{IDs: []string{"1"}}, noop, Options{}); !errors.Is(err, client.ErrSessionInvalid) {
+		t.Errorf("Accounts() with nil session error = %v", err)
+	}
+}
+
+func TestRemoveSafeMembers(t *testing.T) {
+	var mu sync.Mutex
+	var removed []string
+
+	sess, server := createTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		mu.Lock()
+		removed = append(removed, r.URL.Path)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	memberships := []SafeMembership{{SafeName: "Linux", MemberName: "jdoe"}, {SafeName: "Windows", MemberName: "jdoe"}}
+	summary, err := RemoveSafeMembers(context.Background(), sess, memberships, Options{})
+	if err != nil {
+		t.Fatalf("RemoveSafeMembers() error: %v", err)
+	}
+	if summary.Succeeded != 2 || len(removed) != 2 {
+		t.Errorf("summary = %s, removed %v", summary, removed)
+	}
+	if got := summary.Results[1].Item.String(); got != "Windows/jdoe" {
+		t.Errorf("Item.String() = %q", got)
+	}
+}
@@ -0,0 +1,255 @@
+// Package bulk runs an operation over many items with bounded concurrency.
+// It backs the bulk account, safe member and user helpers in this package
+// and can be used directly with any per-item function:
+//
+//	summary := bulk.Run(ctx, accountIDs, func(ctx context.Context, id string) error {
+//		return accounts.VerifyCredentials(ctx, sess, id)
+//	}, bulk.Options{Concurrency: 8})
+//
+//	for _, r := range summary.Failures() {
+//		log.Printf("%s: %v", r.Item, r.Err)
+//	}
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultConcurrency is the number of workers used when Options.Concurrency is not set.
+const DefaultConcurrency = 4
+
+// ErrStopped is the error recorded for items skipped because Options.StopOnError
+// ended the run after a failure.
+var ErrStopped = errors.New("bulk run stopped after a failure")
+
+// Status is the outcome of a single item.
+type Status string
+
+// Item outcomes
+const (
+	// StatusSucceeded means the operation returned no error
+	StatusSucceeded Status = "succeeded"
+
+	// StatusFailed means the operation returned an error
+	StatusFailed Status = "failed"
+
+	// StatusSkipped means the operation was not attempted because the run
+	// was cancelled or stopped after an earlier failure
+	StatusSkipped Status = "skipped"
+
+	// StatusDryRun means the item would have been processed in a real run
+	StatusDryRun Status = "dry-run"
+)
+
+// Options configures a bulk run.
+type Options struct {
+	// Concurrency is the maximum number of items processed at once
+	// (default: DefaultConcurrency)
+	Concurrency int
+
+	// DryRun collects the items without calling the operation
+	DryRun bool
+
+	// StopOnError skips the remaining items after the first failure.
+	// Operations already in progress are allowed to finish.
+	StopOnError bool
+}
+
+// Result is the outcome of the operation for one item.
+type Result[T any] struct {
+	// Index is the position of the item in the input
+	Index int
+
+	Item     T
+	Status   Status
+	Err      error
+	Duration time.Duration
+}
+
+// Summary collects the results of a bulk run.
+type Summary[T any] struct {
+	// Results holds one entry per item, in input order
+	Results []Result[T]
+
+	Total     int
+	Succeeded int
+	Failed    int
+	Skipped   int
+	DryRun    bool
+	Duration  time.Duration
+}
+
+// Failures returns the results of the items whose operation failed.
+func (s *Summary[T]) Failures() []Result[T] {
+	var failures []Result[T]
+	for _, r := range s.Results {
+		if r.Status == StatusFailed {
+			failures = append(failures, r)
+		}
+	}
+	return failures
+}
+
+// Err returns nil if every attempted item succeeded. Otherwise it returns an
+// error that wraps each failure, so errors.Is can test for a given cause.
+func (s *Summary[T]) Err() error {
+	if s.Failed == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, s.Failed)
+	for _, r := range s.Failures() {
+		errs = append(errs, fmt.Errorf("%v: %w", r.Item, r.Err))
+	}
+	return fmt.Errorf("%d of %d operations failed: %w", s.Failed, s.Total, errors.Join(errs...))
+}
+
+// String returns a one-line description of the summary.
+func (s *Summary[T]) String() string {
+	if s.DryRun {
+		return fmt.Sprintf("dry run: %d items would be processed", s.Total)
+	}
+	return fmt.Sprintf("%d items: %d succeeded, %d failed, %d skipped in %s",
+		s.Total, s.Succeeded, s.Failed, s.Skipped, s.Duration.Round(time.Millisecond))
+}
+
+// Operation is applied to each item of a bulk run.
+type Operation[T any] func(ctx context.Context, item T) error
+
+// Run applies op to every item using at most opts.Concurrency workers and
+// returns the outcome of each. If ctx is cancelled, items that have not been
+// started are reported as skipped.
+func Run[T any](ctx context.Context, items []T, op Operation[T], opts Options) *Summary[T] {
+	source := func(yield func(T, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+
+	// Iterating a slice is free, so every item is reported even after cancellation
+	summary, _ := run(ctx, source, op, opts, true)
+	return summary
+}
+
+// RunSeq applies op to every item produced by items, such as a list
+// iterator, so work can start before every page has been fetched. It stops
+// reading items once ctx is cancelled. An error yielded by items ends the run
+// and is returned along with the results of the items already read.
+func RunSeq[T any](ctx context.Context, items iter.Seq2[T, error], op Operation[T], opts Options) (*Summary[T], error) {
+	return run(ctx, items, op, opts, false)
+}
+
+type job[T any] struct {
+	index int
+	item  T
+}
+
+func run[T any](ctx context.Context, items iter.Seq2[T, error], op Operation[T], opts Options, drain bool) (*Summary[T], error) {
+	start := time.Now()
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	// stop is cancelled by StopOnError without cancelling in-flight operations
+	stop, halt := context.WithCancelCause(ctx)
+	defer halt(nil)
+
+	var (
+		mu      sync.Mutex
+		results []Result[T]
+		wg      sync.WaitGroup
+	)
+	record := func(r Result[T]) {
+		mu.Lock()
+		results = append(results, r)
+		mu.Unlock()
+	}
+
+	jobs := make(chan job[T])
+	if !opts.DryRun {
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					if stop.Err() != nil {
+						record(Result[T]{Index: j.index, Item: j.item, Status: StatusSkipped, Err: context.Cause(stop)})
+						continue
+					}
+
+					began := time.Now()
+					err := op(ctx, j.item)
+					r := Result[T]{Index: j.index, Item: j.item, Status: StatusSucceeded, Duration: time.Since(began)}
+					if err != nil {
+						r.Status = StatusFailed
+						r.Err = err
+						if opts.StopOnError {
+							halt(ErrStopped)
+						}
+					}
+					record(r)
+				}
+			}()
+		}
+	}
+
+	var sourceErr error
+	index := 0
+	for item, err := range items {
+		if err != nil {
+			sourceErr = fmt.Errorf("failed to read items: %w", err)
+			break
+		}
+
+		switch {
+		case opts.DryRun:
+			record(Result[T]{Index: index, Item: item, Status: StatusDryRun})
+		case stop.Err() != nil:
+			record(Result[T]{Index: index, Item: item, Status: StatusSkipped, Err: context.Cause(stop)})
+		default:
+			select {
+			case jobs <- job[T]{index: index, item: item}:
+			case <-stop.Done():
+				record(Result[T]{Index: index, Item: item, Status: StatusSkipped, Err: context.Cause(stop)})
+			}
+		}
+		index++
+
+		if stop.Err() != nil && !drain && !opts.DryRun {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	slices.SortFunc(results, func(a, b Result[T]) int { return a.Index - b.Index })
+
+	summary := &Summary[T]{
+		Results:  results,
+		Total:    len(results),
+		DryRun:   opts.DryRun,
+		Duration: time.Since(start),
+	}
+	for _, r := range results {
+		switch r.Status {
+		case StatusSucceeded:
+			summary.Succeeded++
+		case StatusFailed:
+			summary.Failed++
+		case StatusSkipped:
+			summary.Skipped++
+		}
+	}
+
+	return summary, sourceErr
+}
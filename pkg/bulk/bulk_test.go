@@ -0,0 +1,182 @@
+// Package bulk provides tests for the bulk operation runner.
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	errOdd := errors.New("odd")
+
+	var current, peak int32
+	summary := Run(context.Background(), items, func(ctx context.Context, n int) error {
+		c := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if c <= p || atomic.CompareAndSwapInt32(&peak, p, c) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if n%2 == 1 {
+			return errOdd
+		}
+		return nil
+	}, Options{Concurrency: 3})
+
+	if peak > 3 {
+		t.Errorf("peak concurrency = %d, want at most 3", peak)
+	}
+	if summary.Total != 8 || summary.Succeeded != 4 || summary.Failed != 4 || summary.Skipped != 0 {
+		t.Errorf("summary = %s", summary)
+	}
+	for i, r := range summary.Results {
+		if r.Index != i || r.Item != items[i] {
+			t.Errorf("Results[%d] = %+v, want input order", i, r)
+		}
+	}
+	if len(summary.Failures()) != 4 {
+		t.Errorf("Failures() returned %d results, want 4", len(summary.Failures()))
+	}
+
+	err := summary.Err()
+	if !errors.Is(err, errOdd) {
+		t.Errorf("Err() = %v, want to wrap the item errors", err)
+	}
+}
+
+func TestRun_AllSucceeded(t *testing.T) {
+	summary := Run(context.Background(), []string{"a", "b"}, func(ctx context.Context, s string) error {
+		return nil
+	}, Options{})
+
+	if summary.Succeeded != 2 || summary.Err() != nil {
+		t.Errorf("summary = %s, Err() = %v", summary, summary.Err())
+	}
+}
+
+func TestRun_DryRun(t *testing.T) {
+	var calls int32
+	summary := Run(context.Background(), []string{"a", "b", "c"}, func(ctx context.Context, s string) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}, Options{DryRun: true})
+
+	if calls != 0 {
+		t.Errorf("operation called %d times in dry run", calls)
+	}
+	if !summary.DryRun || summary.Total != 3 {
+		t.Errorf("summary = %+v", summary)
+	}
+	for _, r := range summary.Results {
+		if r.Status != StatusDryRun {
+			t.Errorf("result status = %s, want %s", r.Status, StatusDryRun)
+		}
+	}
+}
+
+func TestRun_StopOnError(t *testing.T) {
+	errBoom := errors.New("boom")
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+
+	summary := Run(context.Background(), items, func(ctx context.Context, n int) error {
+		if n == 0 {
+			return errBoom
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}, Options{Concurrency: 1, StopOnError: true})
+
+	if summary.Total != 20 || summary.Failed != 1 {
+		t.Fatalf("summary = %s", summary)
+	}
+	if summary.Skipped == 0 {
+		t.Fatal("no items were skipped after the failure")
+	}
+	for _, r := range summary.Results {
+		if r.Status == StatusSkipped && !errors.Is(r.Err, ErrStopped) {
+			t.Errorf("skipped item error = %v, want ErrStopped", r.Err)
+		}
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	items := make([]int, 10)
+
+	var calls int32
+	summary := Run(ctx, items, func(ctx context.Context, n int) error {
+		if atomic.AddInt32(&calls, 1) == 2 {
+			cancel()
+		}
+		return nil
+	}, Options{Concurrency: 1})
+
+	if summary.Total != 10 {
+		t.Errorf("Total = %d, want every item reported", summary.Total)
+	}
+	if summary.Skipped == 0 || summary.Succeeded+summary.Skipped != 10 {
+		t.Errorf("summary = %s", summary)
+	}
+	for _, r := range summary.Results {
+		if r.Status == StatusSkipped && !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("skipped item error = %v, want context.Canceled", r.Err)
+		}
+	}
+}
+
+func TestRunSeq(t *testing.T) {
+	errList := errors.New("list failed")
+	seq := func(yield func(string, error) bool) {
+		for i := 0; i < 3; i++ {
+			if !yield(fmt.Sprintf("item-%d", i), nil) {
+				return
+			}
+		}
+		yield("", errList)
+	}
+
+	summary, err := RunSeq(context.Background(), seq, func(ctx context.Context, s string) error {
+		return nil
+	}, Options{})
+
+	if !errors.Is(err, errList) {
+		t.Errorf("RunSeq() error = %v, want the source error", err)
+	}
+	if summary.Total != 3 || summary.Succeeded != 3 {
+		t.Errorf("summary = %s, want the items read before the error", summary)
+	}
+}
+
+func TestRunSeq_StopsReadingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var read int
+	seq := func(yield func(int, error) bool) {
+		for i := 0; i < 100; i++ {
+			read++
+			if !yield(i, nil) {
+				return
+			}
+		}
+	}
+
+	RunSeq(ctx, seq, func(ctx context.Context, n int) error {
+		cancel()
+		return nil
+	}, Options{Concurrency: 1})
+
+	if read >= 100 {
+		t.Errorf("read %d items after cancellation, want iteration to stop", read)
+	}
+}
@@ -0,0 +1,64 @@
+// Package bulk provides bulk safe membership changes.
+package bulk
+
+import (
+	"context"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/safemembers"
+)
+
+// SafeMembership identifies a member of a safe.
+type SafeMembership struct {
+	SafeName   string
+	MemberName string
+}
+
+// String returns the membership as "safe/member".
+func (m SafeMembership) String() string {
+	return m.SafeName + "/" + m.MemberName
+}
+
+// AddSafeMembers adds the same member, with the same permissions, to every safe.
+// This is equivalent to piping safe names to Add-PASSafeMember in psPAS.
+func AddSafeMembers(ctx context.Context, sess *session.Session, safeNames []string, member safemembers.AddOptions, opts Options) (*Summary[SafeMembership], error) {
+	if sess == nil || !sess.IsValid() {
+		return nil, client.ErrSessionInvalid
+	}
+
+	memberships := make([]SafeMembership, len(safeNames))
+	for i, safeName := range safeNames {
+		memberships[i] = SafeMembership{SafeName: safeName, MemberName: member.MemberName}
+	}
+
+	return Run(ctx, memberships, func(ctx context.Context, m SafeMembership) error {
+		_, err := safemembers.Add(ctx, sess, m.SafeName, member)
+		return err
+	}, opts), nil
+}
+
+// UpdateSafeMembers applies the same update to every membership.
+// This is equivalent to piping safe members to Set-PASSafeMember in psPAS.
+func UpdateSafeMembers(ctx context.Context, sess *session.Session, memberships []SafeMembership, update safemembers.UpdateOptions, opts Options) (*Summary[SafeMembership], error) {
+	if sess == nil || !sess.IsValid() {
+		return nil, client.ErrSessionInvalid
+	}
+
+	return Run(ctx, memberships, func(ctx context.Context, m SafeMembership) error {
+		_, err := safemembers.Update(ctx, sess, m.SafeName, m.MemberName, update)
+		return err
+	}, opts), nil
+}
+
+// RemoveSafeMembers removes every membership.
+// This is equivalent to piping safe members to Remove-PASSafeMember in psPAS.
+func RemoveSafeMembers(ctx context.Context, sess *session.Session, memberships []SafeMembership, opts Options) (*Summary[SafeMembership], error) {
+	if sess == nil || !sess.IsValid() {
+		return nil, client.ErrSessionInvalid
+	}
+
+	return Run(ctx, memberships, func(ctx context.Context, m SafeMembership) error {
+		return safemembers.Remove(ctx, sess, m.SafeName, m.MemberName)
+	}, opts), nil
+}
@@ -0,0 +1,94 @@
+// Package bulk provides bulk user operations.
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/types"
+	"github.com/chrisranney/gopas/pkg/users"
+)
+
+// UserSelector chooses the users a bulk operation applies to. Set either
+// IDs or Filter.
+type UserSelector struct {
+	// IDs lists the users explicitly
+	IDs []int
+
+	// Filter selects every user matching the list options
+	Filter *users.ListOptions
+}
+
+// ids returns the selected user IDs as an iterator. Filtered users are
+// listed in full before the iterator is returned: users.All pages by
+// offset, so deleting users while later pages are fetched would shift
+// them and skip part of the selection.
+func (s UserSelector) ids(ctx context.Context, sess *session.Session) (iter.Seq2[int, error], error) {
+	switch {
+	case len(s.IDs) > 0 && s.Filter != nil:
+		return nil, fmt.Errorf("user IDs and filter are mutually exclusive")
+	case len(s.IDs) > 0:
+		return seqIDs(s.IDs), nil
+	case s.Filter != nil:
+		var ids []int
+		for user, err := range users.All(ctx, sess, *s.Filter, types.PageOptions{}) {
+			if err != nil {
+				return nil, fmt.Errorf("failed to list users: %w", err)
+			}
+			ids = append(ids, user.ID)
+		}
+		return seqIDs(ids), nil
+	default:
+		return nil, fmt.Errorf("user IDs or filter is required")
+	}
+}
+
+// seqIDs returns an iterator over ids.
+func seqIDs(ids []int) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		for _, id := range ids {
+			if !yield(id, nil) {
+				return
+			}
+		}
+	}
+}
+
+// UserOperation is applied to each selected user.
+type UserOperation func(ctx context.Context, sess *session.Session, userID int) error
+
+// Users applies op to every selected user. When the users are selected by
+// filter, every matching user is listed before any operation starts; a
+// listing error is returned without running op.
+func Users(ctx context.Context, sess *session.Session, sel UserSelector, op UserOperation, opts Options) (*Summary[int], error) {
+	if sess == nil || !sess.IsValid() {
+		return nil, client.ErrSessionInvalid
+	}
+
+	ids, err := sel.ids(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	return RunSeq(ctx, ids, func(ctx context.Context, userID int) error {
+		return op(ctx, sess, userID)
+	}, opts)
+}
+
+// ActivateUsers activates every selected user.
+// This is equivalent to Get-PASUser | Unblock-PASUser in psPAS.
+func ActivateUsers(ctx context.Context, sess *session.Session, sel UserSelector, opts Options) (*Summary[int], error) {
+	return Users(ctx, sess, sel, func(ctx context.Context, sess *session.Session, userID int) error {
+		_, err := users.ActivateUser(ctx, sess, userID)
+		return err
+	}, opts)
+}
+
+// DeleteUsers deletes every selected user.
+// This is equivalent to Get-PASUser | Remove-PASUser in psPAS.
+func DeleteUsers(ctx context.Context, sess *session.Session, sel UserSelector, opts Options) (*Summary[int], error) {
+	return Users(ctx, sess, sel, users.Delete, opts)
+}
@@ -0,0 +1,50 @@
+// Package bulk provides tests for bulk user operations.
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/chrisranney/gopas/pkg/authentication"
+	"github.com/chrisranney/gopas/pkg/pvwatest"
+	"github.com/chrisranney/gopas/pkg/users"
+)
+
+func TestDeleteUsers_FilterSpanningPages(t *testing.T) {
+	srv := pvwatest.NewServer()
+	defer srv.Close()
+	for i := 0; i < 25; i++ {
+		srv.AddUser(users.CreateOptions{Username: fmt.Sprintf("contractor%02d", i)})
+	}
+	srv.AddUser(users.CreateOptions{Username: "employee"})
+
+	sess, err := authentication.NewSession(context.Background(), authentication.SessionOptions{
+		BaseURL:          srv.URL,
+		Credentials:      authentication.Credentials{Username: pvwatest.DefaultUsername, Password: pvwatest.DefaultPassword},
+		SkipVersionCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+
+	// Pages of 10 are listed while earlier users are being deleted
+	filter := &users.ListOptions{Search: "contractor", Limit: 10}
+	summary, err := DeleteUsers(context.Background(), sess, UserSelector{Filter: filter}, Options{Concurrency: 4})
+	if err != nil {
+		t.Fatalf("DeleteUsers() error = %v", err)
+	}
+	if summary.Succeeded != 25 || summary.Failed != 0 {
+		t.Errorf("summary = %s, want 25 deleted", summary)
+	}
+
+	remaining, err := users.List(context.Background(), sess, users.ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	for _, u := range remaining.Users {
+		if u.Username != "employee" && u.Username != pvwatest.DefaultUsername {
+			t.Errorf("user %s was not deleted", u.Username)
+		}
+	}
+}
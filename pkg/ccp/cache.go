@@ -0,0 +1,188 @@
+package ccp
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is the cache TTL used when CacheConfig.TTL is zero.
+const DefaultCacheTTL = 5 * time.Minute
+
+// CacheConfig configures credential caching on a Client.
+//
+// Entries are keyed by the full CredentialRequest and held in memory only.
+// A credential is fresh for TTL after it was retrieved. For StaleTTL after
+// that it is still returned, while a single background request refreshes
+// it; this keeps callers working when CCP is briefly unavailable. Responses
+// with PasswordChangeInProcess set are never cached.
+type CacheConfig struct {
+	// TTL is how long a retrieved credential is served without contacting
+	// CCP (default DefaultCacheTTL)
+	TTL time.Duration
+
+	// StaleTTL is how long after TTL an expired credential may still be
+	// served while it is refreshed in the background (optional)
+	StaleTTL time.Duration
+}
+
+// credentialCache holds retrieved credentials. Secrets are kept as byte
+// slices so they can be zeroed when an entry is evicted; callers receive
+// their own copies.
+type credentialCache struct {
+	ttl      time.Duration
+	staleTTL time.Duration
+	fetch    func(context.Context, CredentialRequest) (*CredentialResponse, error)
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[CredentialRequest]*cacheEntry
+}
+
+type cacheEntry struct {
+	response   CredentialResponse // Content is always empty
+	content    []byte
+	fetched    time.Time
+	refreshing bool
+	timer      *time.Timer
+}
+
+func newCredentialCache(cfg CacheConfig, fetch func(context.Context, CredentialRequest) (*CredentialResponse, error)) *credentialCache {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &credentialCache{
+		ttl:      ttl,
+		staleTTL: max(cfg.StaleTTL, 0),
+		fetch:    fetch,
+		now:      time.Now,
+		entries:  make(map[CredentialRequest]*cacheEntry),
+	}
+}
+
+// get returns the cached credential for req, retrieving it from CCP when
+// there is no usable entry.
+func (c *credentialCache) get(ctx context.Context, req CredentialRequest) (*CredentialResponse, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[req]; ok {
+		age := c.now().Sub(e.fetched)
+		switch {
+		case age < c.ttl:
+			resp := e.credential()
+			c.mu.Unlock()
+			return resp, nil
+		case age < c.ttl+c.staleTTL:
+			if !e.refreshing {
+				e.refreshing = true
+				go c.refresh(req, e)
+			}
+			resp := e.credential()
+			c.mu.Unlock()
+			return resp, nil
+		}
+		c.evictLocked(req)
+	}
+	c.mu.Unlock()
+
+	resp, err := c.fetch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.storeLocked(req, resp)
+	c.mu.Unlock()
+	return resp, nil
+}
+
+// refresh retrieves a stale entry again. On failure the stale value is kept
+// until it expires.
+func (c *credentialCache) refresh(req CredentialRequest, e *cacheEntry) {
+	resp, err := c.fetch(context.Background(), req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[req] != e {
+		// Invalidated while the refresh was running.
+		return
+	}
+	if err != nil {
+		e.refreshing = false
+		return
+	}
+	c.storeLocked(req, resp)
+}
+
+// storeLocked caches resp for req, replacing any existing entry.
+func (c *credentialCache) storeLocked(req CredentialRequest, resp *CredentialResponse) {
+	c.evictLocked(req)
+	if resp.PasswordChangeInProcess {
+		return
+	}
+
+	e := &cacheEntry{
+		response: *resp,
+		content:  []byte(resp.Content),
+		fetched:  c.now(),
+	}
+	e.response.Content = ""
+	e.response.Properties = maps.Clone(resp.Properties)
+	e.timer = time.AfterFunc(c.ttl+c.staleTTL, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.entries[req] == e {
+			c.evictLocked(req)
+		}
+	})
+	c.entries[req] = e
+}
+
+// evictLocked removes the entry for req and zeroes its secret.
+func (c *credentialCache) evictLocked(req CredentialRequest) {
+	e, ok := c.entries[req]
+	if !ok {
+		return
+	}
+	delete(c.entries, req)
+	e.timer.Stop()
+	clear(e.content)
+}
+
+func (c *credentialCache) invalidate(req CredentialRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictLocked(req)
+}
+
+func (c *credentialCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for req := range c.entries {
+		c.evictLocked(req)
+	}
+}
+
+// credential returns a copy of the cached response.
+func (e *cacheEntry) credential() *CredentialResponse {
+	resp := e.response
+	resp.Content = string(e.content)
+	resp.Properties = maps.Clone(e.response.Properties)
+	return &resp
+}
+
+// Invalidate removes the cached credential for req, so the next request
+// retrieves it from CCP. It does nothing when caching is disabled.
+func (c *Client) Invalidate(req CredentialRequest) {
+	if c.cache != nil {
+		c.cache.invalidate(req)
+	}
+}
+
+// InvalidateAll removes every cached credential.
+func (c *Client) InvalidateAll() {
+	if c.cache != nil {
+		c.cache.invalidateAll()
+	}
+}
@@ -0,0 +1,264 @@
+package ccp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+// cacheTestServer is a CCP whose password and availability tests can change.
+type cacheTestServer struct {
+	*httptest.Server
+	calls atomic.Int32
+
+	mu             sync.Mutex
+	content        string
+	changing       bool
+	down           bool
+	blockRefreshes chan struct{}
+}
+
+func newCacheTestServer(t *testing.T) *cacheTestServer {
+	t.Helper()
+	s := &cacheTestServer{content: "v1"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls.Add(1)
+		s.mu.Lock()
+		block := s.blockRefreshes
+		s.mu.Unlock()
+		if block != nil {
+			<-block
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.down {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{ErrorCode: "APPAP007E", ErrorMsg: "Connection to the Vault has failed."})
+			return
+		}
+		json.NewEncoder(w).Encode(CredentialResponse{
+			Content:                 s.content,
+			UserName:                r.URL.Query().Get("Object"),
+			Properties:              map[string]string{"Env": "prod"},
+			PasswordChangeInProcess: types.FlexibleBool(s.changing),
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *cacheTestServer) set(fn func(*cacheTestServer)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s)
+}
+
+// newCachedClient returns a client whose cache uses the returned clock.
+func newCachedClient(t *testing.T, url string, cfg CacheConfig) (*Client, *time.Time) {
+	t.Helper()
+	client, err := NewClient(ClientConfig{BaseURL: url, Cache: &cfg})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	now := time.Now()
+	client.cache.now = func() time.Time { return now }
+	return client, &now
+}
+
+func mustGetPassword(t *testing.T, client *Client, req CredentialRequest) string {
+	t.Helper()
+	password, err := client.GetPassword(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetPassword() error = %v", err)
+	}
+	return password
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	srv := newCacheTestServer(t)
+	client, now := newCachedClient(t, srv.URL, CacheConfig{TTL: time.Minute})
+	req := CredentialRequest{AppID: "app", Safe: "safe", Object: "obj"}
+
+	if got := mustGetPassword(t, client, req); got != "v1" {
+		t.Errorf("GetPassword() = %q, want v1", got)
+	}
+	srv.set(func(s *cacheTestServer) { s.content = "v2" })
+	if got := mustGetPassword(t, client, req); got != "v1" {
+		t.Errorf("cached GetPassword() = %q, want v1", got)
+	}
+	if n := srv.calls.Load(); n != 1 {
+		t.Errorf("CCP calls = %d, want 1", n)
+	}
+
+	// A different request is a different cache key.
+	other := req
+	other.Reason = "audit"
+	mustGetPassword(t, client, other)
+	if n := srv.calls.Load(); n != 2 {
+		t.Errorf("CCP calls = %d, want 2", n)
+	}
+
+	*now = now.Add(time.Minute)
+	if got := mustGetPassword(t, client, req); got != "v2" {
+		t.Errorf("expired GetPassword() = %q, want v2", got)
+	}
+}
+
+func TestCache_ReturnsCopies(t *testing.T) {
+	srv := newCacheTestServer(t)
+	client, _ := newCachedClient(t, srv.URL, CacheConfig{TTL: time.Minute})
+	req := CredentialRequest{AppID: "app", Safe: "safe", Object: "obj"}
+
+	resp, err := client.GetCredential(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetCredential() error = %v", err)
+	}
+	resp.Properties["Env"] = "changed"
+
+	resp, err = client.GetCredential(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetCredential() error = %v", err)
+	}
+	if resp.Properties["Env"] != "prod" || resp.UserName != "obj" {
+		t.Errorf("cached GetCredential() = %+v", resp)
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	srv := newCacheTestServer(t)
+	client, now := newCachedClient(t, srv.URL, CacheConfig{TTL: time.Minute, StaleTTL: time.Minute})
+	req := CredentialRequest{AppID: "app", Safe: "safe", Object: "obj"}
+
+	mustGetPassword(t, client, req)
+	srv.set(func(s *cacheTestServer) {
+		s.content = "v2"
+		s.blockRefreshes = make(chan struct{})
+	})
+
+	*now = now.Add(90 * time.Second)
+	for i := 0; i < 3; i++ {
+		if got := mustGetPassword(t, client, req); got != "v1" {
+			t.Errorf("stale GetPassword() = %q, want v1", got)
+		}
+	}
+	waitFor(t, func() bool { return srv.calls.Load() == 2 })
+
+	srv.set(func(s *cacheTestServer) {
+		close(s.blockRefreshes)
+		s.blockRefreshes = nil
+	})
+	waitFor(t, func() bool { return mustGetPassword(t, client, req) == "v2" })
+	if n := srv.calls.Load(); n != 2 {
+		t.Errorf("CCP calls = %d, want a single refresh", n)
+	}
+}
+
+func TestCache_StaleWhileDown(t *testing.T) {
+	srv := newCacheTestServer(t)
+	client, now := newCachedClient(t, srv.URL, CacheConfig{TTL: time.Minute, StaleTTL: time.Minute})
+	req := CredentialRequest{AppID: "app", Safe: "safe", Object: "obj"}
+
+	mustGetPassword(t, client, req)
+	srv.set(func(s *cacheTestServer) { s.down = true })
+
+	*now = now.Add(90 * time.Second)
+	if got := mustGetPassword(t, client, req); got != "v1" {
+		t.Errorf("stale GetPassword() = %q, want v1", got)
+	}
+	waitFor(t, func() bool {
+		client.cache.mu.Lock()
+		defer client.cache.mu.Unlock()
+		return !client.cache.entries[req].refreshing
+	})
+	if got := mustGetPassword(t, client, req); got != "v1" {
+		t.Errorf("stale GetPassword() after failed refresh = %q, want v1", got)
+	}
+
+	*now = now.Add(time.Minute)
+	if _, err := client.GetPassword(context.Background(), req); err == nil {
+		t.Error("GetPassword() past StaleTTL with CCP down succeeded")
+	}
+}
+
+func TestCache_PasswordChangeInProcess(t *testing.T) {
+	srv := newCacheTestServer(t)
+	client, _ := newCachedClient(t, srv.URL, CacheConfig{TTL: time.Minute})
+	req := CredentialRequest{AppID: "app", Safe: "safe", Object: "obj"}
+
+	mustGetPassword(t, client, req)
+	client.cache.mu.Lock()
+	secret := client.cache.entries[req].content
+	client.cache.mu.Unlock()
+
+	// Responses reporting a change in process are not cached.
+	client.Invalidate(req)
+	srv.set(func(s *cacheTestServer) { s.changing = true })
+	mustGetPassword(t, client, req)
+	mustGetPassword(t, client, req)
+	if n := srv.calls.Load(); n != 3 {
+		t.Errorf("CCP calls = %d, want 3", n)
+	}
+	if string(secret) != "\x00\x00" {
+		t.Errorf("evicted secret = %q, want zeroed", secret)
+	}
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	srv := newCacheTestServer(t)
+	client, _ := newCachedClient(t, srv.URL, CacheConfig{TTL: time.Minute})
+	a := CredentialRequest{AppID: "app", Safe: "safe", Object: "a"}
+	b := CredentialRequest{AppID: "app", Safe: "safe", Object: "b"}
+
+	mustGetPassword(t, client, a)
+	mustGetPassword(t, client, b)
+	srv.set(func(s *cacheTestServer) { s.content = "v2" })
+
+	client.Invalidate(a)
+	if got := mustGetPassword(t, client, a); got != "v2" {
+		t.Errorf("GetPassword(a) = %q, want v2", got)
+	}
+	if got := mustGetPassword(t, client, b); got != "v1" {
+		t.Errorf("GetPassword(b) = %q, want v1", got)
+	}
+
+	client.InvalidateAll()
+	if got := mustGetPassword(t, client, b); got != "v2" {
+		t.Errorf("GetPassword(b) after InvalidateAll = %q, want v2", got)
+	}
+}
+
+func TestCache_Disabled(t *testing.T) {
+	srv := newCacheTestServer(t)
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	req := CredentialRequest{AppID: "app", Safe: "safe", Object: "obj"}
+
+	mustGetPassword(t, client, req)
+	mustGetPassword(t, client, req)
+	client.Invalidate(req)
+	if n := srv.calls.Load(); n != 2 {
+		t.Errorf("CCP calls = %d, want 2", n)
+	}
+}
@@ -5,7 +5,6 @@ package ccp
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,6 +12,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/chrisranney/gopas/pkg/tlsconfig"
 	"github.com/chrisranney/gopas/pkg/types"
 )
 
@@ -20,6 +20,7 @@ import (
 type Client struct {
 	httpClient *http.Client
 	baseURL    string
+	cache      *credentialCache
 }
 
 // ClientConfig holds configuration for creating a CCP client.
@@ -36,6 +37,13 @@ type ClientConfig struct {
 	// ClientCert and ClientKey for mutual TLS authentication (optional)
 	ClientCert string
 	ClientKey  string
+
+	// TLS configures client certificates from memory or PKCS#12, private
+	// CAs and certificate pinning (optional)
+	TLS *tlsconfig.Config
+
+	// Cache enables in-memory caching of retrieved credentials (optional)
+	Cache *CacheConfig
 }
 
 // CredentialRequest represents a request to retrieve credentials from CCP.
@@ -126,17 +134,23 @@ func NewClient(cfg ClientConfig) (*Client, error) {
 	}
 
 	// Configure TLS
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: cfg.SkipTLSVerify,
+	tlsOptions := tlsconfig.Config{}
+	if cfg.TLS != nil {
+		tlsOptions = *cfg.TLS
+	}
+	if cfg.SkipTLSVerify {
+		tlsOptions.InsecureSkipVerify = true
 	}
 
 	// Load client certificate if provided (for mutual TLS)
 	if cfg.ClientCert != "" && cfg.ClientKey != "" {
-		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load client certificate: %w", err)
-		}
-		tlsConfig.Certificates = []tls.Certificate{cert}
+		tlsOptions.CertFile = cfg.ClientCert
+		tlsOptions.KeyFile = cfg.ClientKey
+	}
+
+	tlsConfig, err := tlsOptions.Build()
+	if err != nil {
+		return nil, err
 	}
 
 	transport := &http.Transport{
@@ -148,10 +162,14 @@ func NewClient(cfg ClientConfig) (*Client, error) {
 		Timeout:   timeout,
 	}
 
-	return &Client{
+	client := &Client{
 		httpClient: httpClient,
 		baseURL:    cfg.BaseURL,
-	}, nil
+	}
+	if cfg.Cache != nil {
+		client.cache = newCredentialCache(*cfg.Cache, client.fetchCredential)
+	}
+	return client, nil
 }
 
 // GetCredential retrieves a credential from CCP.
@@ -163,6 +181,14 @@ func (c *Client) GetCredential(ctx context.Context, req CredentialRequest) (*Cre
 		return nil, fmt.Errorf("Safe is required")
 	}
 
+	if c.cache != nil {
+		return c.cache.get(ctx, req)
+	}
+	return c.fetchCredential(ctx, req)
+}
+
+// fetchCredential retrieves a credential from CCP, bypassing the cache.
+func (c *Client) fetchCredential(ctx context.Context, req CredentialRequest) (*CredentialResponse, error) {
 	// Build the CCP URL
 	endpoint := fmt.Sprintf("%s/AIMWebService/api/Accounts", c.baseURL)
 
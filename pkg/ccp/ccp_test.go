@@ -20,6 +20,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/chrisranney/gopas/pkg/tlsconfig"
 	"github.com/chrisranney/gopas/pkg/types"
 )
 
@@ -1087,3 +1088,50 @@ func TestNewClient_MutualTLS(t *testing.T) {
 		t.Errorf("GetCredential() with mTLS failed: %v", err)
 	}
 }
+
+func TestNewClient_TLSOptions(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(CredentialResponse{Content: "password"})
+	}))
+	defer server.Close()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	req := CredentialRequest{AppID: "TestApp", Safe: "TestSafe"}
+
+	client, err := NewClient(ClientConfig{BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := client.GetPassword(context.Background(), req); err == nil {
+		t.Error("GetPassword() against an untrusted server succeeded")
+	}
+
+	client, err = NewClient(ClientConfig{BaseURL: server.URL, TLS: &tlsconfig.Config{CAPEM: caPEM}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := client.GetPassword(context.Background(), req); err != nil {
+		t.Errorf("GetPassword() with private CA error = %v", err)
+	}
+
+	client, err = NewClient(ClientConfig{BaseURL: server.URL, TLS: &tlsconfig.Config{
+		CAPEM:            caPEM,
+		PinnedSPKIHashes: []string{"sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="},
+	}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := client.GetPassword(context.Background(), req); err == nil {
+		t.Error("GetPassword() with a mismatched pin succeeded")
+	}
+
+	certPath, keyPath, cleanup := createTestCertificates(t)
+	defer cleanup()
+	if _, err := NewClient(ClientConfig{
+		BaseURL:    server.URL,
+		ClientCert: certPath,
+		ClientKey:  keyPath,
+		TLS:        &tlsconfig.Config{PKCS12: []byte("bundle")},
+	}); err == nil {
+		t.Error("NewClient() with two client certificate sources succeeded")
+	}
+}
@@ -0,0 +1,358 @@
+// Package ccptest provides a fake Central Credential Provider for tests.
+//
+// The server answers GET /AIMWebService/api/Accounts the way the CCP web
+// service does: the AppID must be defined and allowed to use the safe, the
+// request must match exactly one account, and failures are reported with
+// CyberArk error codes such as APPAP004E:
+//
+//	srv := ccptest.NewServer(ccptest.Seed{
+//		Applications: map[string]ccptest.Application{
+//			"billing": {Safes: []string{"Billing"}},
+//		},
+//		Accounts: []ccptest.Account{
+//			{Safe: "Billing", Name: "db-prod", UserName: "svc_billing", Content: "s3cret"},
+//		},
+//	})
+//	defer srv.Close()
+//
+//	client, _ := ccp.NewClient(ccp.ClientConfig{BaseURL: srv.URL})
+//	password, err := client.GetPassword(ctx, ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Object: "db-prod"})
+//
+// A seed can also be loaded from YAML with LoadSeedFile.
+package ccptest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/chrisranney/gopas/pkg/ccp"
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+// Account is a credential the server can return.
+type Account struct {
+	Safe                    string            `yaml:"safe"`
+	Folder                  string            `yaml:"folder"`
+	Name                    string            `yaml:"name"`
+	UserName                string            `yaml:"userName"`
+	Address                 string            `yaml:"address"`
+	Content                 string            `yaml:"content"`
+	PolicyID                string            `yaml:"policyId"`
+	DeviceType              string            `yaml:"deviceType"`
+	Properties              map[string]string `yaml:"properties"`
+	PasswordChangeInProcess bool              `yaml:"passwordChangeInProcess"`
+}
+
+// Application is an application ID defined in the Vault.
+type Application struct {
+	// Safes lists the safes the application may retrieve from.
+	Safes []string `yaml:"safes"`
+
+	// CertificateCommonNames, when set, requires the request to present a
+	// client certificate issued by the server's CA with one of these
+	// subject common names. It only applies to TLS servers.
+	CertificateCommonNames []string `yaml:"certificateCommonNames"`
+}
+
+// Seed is the content a server starts with.
+type Seed struct {
+	// Applications maps AppIDs to their definitions.
+	Applications map[string]Application `yaml:"applications"`
+
+	// Accounts are the credentials stored in the Vault.
+	Accounts []Account `yaml:"accounts"`
+}
+
+// LoadSeedFile reads a seed from a YAML file:
+//
+//	applications:
+//	  billing:
+//	    safes: [Billing]
+//	accounts:
+//	  - safe: Billing
+//	    name: db-prod
+//	    userName: svc_billing
+//	    content: s3cret
+func LoadSeedFile(path string) (Seed, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Seed{}, fmt.Errorf("failed to read seed: %w", err)
+	}
+
+	var seed Seed
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&seed); err != nil {
+		return Seed{}, fmt.Errorf("failed to parse seed %s: %w", path, err)
+	}
+	return seed, nil
+}
+
+// Call records a credential request handled by the server.
+type Call struct {
+	AppID      string
+	Query      string
+	StatusCode int
+	ErrorCode  string
+}
+
+// Server is a fake CCP listening on a local address.
+type Server struct {
+	// URL is the base URL of the server, for ccp.ClientConfig.BaseURL
+	URL string
+
+	httpServer *httptest.Server
+	ca         *certificateAuthority
+
+	mu           sync.Mutex
+	applications map[string]Application
+	accounts     []*Account
+	failures     []cpError
+	calls        []Call
+}
+
+// NewServer starts a plain HTTP server with the given seed. The caller
+// must call Close when finished.
+func NewServer(seed Seed) *Server {
+	s := newServer(seed)
+	s.httpServer = httptest.NewServer(s)
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// TLSOptions configures a TLS server.
+type TLSOptions struct {
+	// RequireClientCert rejects TLS handshakes without a client certificate
+	// issued by the server's CA. Otherwise a certificate is only required
+	// by applications with CertificateCommonNames.
+	RequireClientCert bool
+}
+
+// NewTLSServer starts an HTTPS server with the given seed. Client
+// certificates are verified against a CA created for the server, see
+// IssueClientCertificate.
+func NewTLSServer(seed Seed, opts TLSOptions) (*Server, error) {
+	ca, err := newCertificateAuthority()
+	if err != nil {
+		return nil, err
+	}
+
+	s := newServer(seed)
+	s.ca = ca
+	s.httpServer = httptest.NewUnstartedServer(s)
+	s.httpServer.TLS = &tls.Config{
+		ClientCAs:  ca.pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}
+	if opts.RequireClientCert {
+		s.httpServer.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	s.httpServer.StartTLS()
+	s.URL = s.httpServer.URL
+	return s, nil
+}
+
+func newServer(seed Seed) *Server {
+	s := &Server{applications: make(map[string]Application)}
+	for appID, app := range seed.Applications {
+		s.applications[strings.ToLower(appID)] = app
+	}
+	for _, account := range seed.Accounts {
+		account := account
+		s.accounts = append(s.accounts, &account)
+	}
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// Client returns an HTTP client that trusts the server's certificate.
+func (s *Server) Client() *http.Client {
+	return s.httpServer.Client()
+}
+
+// AddApplication defines an application, replacing any with the same AppID.
+func (s *Server) AddApplication(appID string, app Application) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applications[strings.ToLower(appID)] = app
+}
+
+// AddAccount stores an account.
+func (s *Server) AddAccount(account Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts = append(s.accounts, &account)
+}
+
+// UpdateAccount applies fn to the account with the given safe and object
+// name, to simulate a password change. It reports whether the account
+// exists.
+func (s *Server) UpdateAccount(safe, name string, fn func(*Account)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, account := range s.accounts {
+		if strings.EqualFold(account.Safe, safe) && strings.EqualFold(account.Name, name) {
+			fn(account)
+			return true
+		}
+	}
+	return false
+}
+
+// FailNext makes the next request fail with the given status and
+// CyberArk error, such as APPAP007E when the Vault cannot be reached.
+func (s *Server) FailNext(status int, code, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, cpError{status: status, code: code, message: message})
+}
+
+// Calls returns the credential requests handled so far, in order.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// ServeHTTP handles credential requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.URL.Path, "/AIMWebService/api/Accounts") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, cerr := parseQuery(r.URL.Query())
+	if cerr == nil && len(s.failures) > 0 {
+		f := s.failures[0]
+		s.failures = s.failures[1:]
+		cerr = &f
+	}
+
+	var body interface{}
+	if cerr == nil {
+		body, cerr = s.retrieve(r, q)
+	}
+
+	call := Call{AppID: q.appID, Query: q.String(), StatusCode: http.StatusOK}
+	status := http.StatusOK
+	if cerr != nil {
+		status = cerr.status
+		body = ccp.ErrorResponse{ErrorCode: cerr.code, ErrorMsg: cerr.message}
+		call.StatusCode, call.ErrorCode = cerr.status, cerr.code
+	}
+	s.calls = append(s.calls, call)
+
+	data, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// retrieve applies the application checks and returns the single account
+// matching the query.
+func (s *Server) retrieve(r *http.Request, q *query) (*ccp.CredentialResponse, *cpError) {
+	app, ok := s.applications[strings.ToLower(q.appID)]
+	if !ok {
+		return nil, &cpError{http.StatusForbidden, "APPAP008E",
+			fmt.Sprintf("Problem occurred while trying to use user [%s] from the Vault. Please check that the application is defined.", q.appID)}
+	}
+	if err := s.authenticateApplication(r, q.appID, app); err != nil {
+		return nil, err
+	}
+
+	var matched []*Account
+	for _, account := range s.accounts {
+		if allowsSafe(app, account.Safe) && q.matches(account) {
+			matched = append(matched, account)
+		}
+	}
+
+	switch {
+	case len(matched) == 0:
+		return nil, &cpError{http.StatusNotFound, "APPAP004E", fmt.Sprintf(
+			"Password object matching query [%s] was not found (Diagnostic Info: 5). Please check that there is a password object that answers your query in the Vault and that both the Provider and the application user have the appropriate permissions needed in order to use the password.",
+			q)}
+	case len(matched) > 1:
+		return nil, &cpError{http.StatusBadRequest, "APPAP227E", fmt.Sprintf(
+			"Too many password objects matching query [%s] were found (Diagnostic Info: 41). Please be more specific in your query.", q)}
+	}
+
+	account := matched[0]
+	return &ccp.CredentialResponse{
+		Content:                 account.Content,
+		UserName:                account.UserName,
+		Address:                 account.Address,
+		Safe:                    account.Safe,
+		Folder:                  folderOrRoot(account.Folder),
+		Name:                    account.Name,
+		PolicyID:                account.PolicyID,
+		DeviceType:              account.DeviceType,
+		Properties:              account.Properties,
+		PasswordChangeInProcess: types.FlexibleBool(account.PasswordChangeInProcess),
+		CreationMethod:          "PVWA",
+	}, nil
+}
+
+// authenticateApplication enforces the application's certificate
+// authentication method.
+func (s *Server) authenticateApplication(r *http.Request, appID string, app Application) *cpError {
+	if len(app.CertificateCommonNames) == 0 {
+		return nil
+	}
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return &cpError{http.StatusForbidden, "APPAP306E",
+			fmt.Sprintf("Failed to authenticate application [%s]: a client certificate is required.", appID)}
+	}
+	cn := r.TLS.PeerCertificates[0].Subject.CommonName
+	for _, allowed := range app.CertificateCommonNames {
+		if strings.EqualFold(allowed, cn) {
+			return nil
+		}
+	}
+	return &cpError{http.StatusForbidden, "APPAP306E",
+		fmt.Sprintf("Failed to authenticate application [%s]: client certificate [%s] is not allowed.", appID, cn)}
+}
+
+func allowsSafe(app Application, safe string) bool {
+	for _, allowed := range app.Safes {
+		if strings.EqualFold(allowed, safe) {
+			return true
+		}
+	}
+	return false
+}
+
+func folderOrRoot(folder string) string {
+	if folder == "" {
+		return "Root"
+	}
+	return folder
+}
+
+// cpError is a CCP error response.
+type cpError struct {
+	status  int
+	code    string
+	message string
+}
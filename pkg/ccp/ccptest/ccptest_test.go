@@ -0,0 +1,313 @@
+// Package ccptest provides tests for the fake Central Credential Provider.
+package ccptest
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrisranney/gopas/pkg/ccp"
+)
+
+func testSeed() Seed {
+	return Seed{
+		Applications: map[string]Application{
+			"billing": {Safes: []string{"Billing"}},
+			"reports": {Safes: []string{"Reports"}},
+		},
+		Accounts: []Account{
+			{Safe: "Billing", Name: "db-prod", UserName: "svc_billing", Address: "db1.example.com", Content: "prod-secret", PolicyID: "MySQL"},
+			{Safe: "Billing", Name: "db-test", UserName: "svc_billing", Address: "db2.example.com", Content: "test-secret", PolicyID: "MySQL"},
+			{Safe: "Billing", Folder: "Root\\Legacy", Name: "ftp", UserName: "ftpuser", Content: "ftp-secret",
+				Properties: map[string]string{"Environment": "Legacy"}},
+			{Safe: "Reports", Name: "db-prod", UserName: "svc_reports", Content: "reports-secret"},
+		},
+	}
+}
+
+func newTestClient(t *testing.T, srv *Server) *ccp.Client {
+	t.Helper()
+	client, err := ccp.NewClient(ccp.ClientConfig{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+func TestServer_GetCredential(t *testing.T) {
+	srv := NewServer(testSeed())
+	defer srv.Close()
+	client := newTestClient(t, srv)
+
+	tests := []struct {
+		name        string
+		req         ccp.CredentialRequest
+		wantContent string
+		wantErr     string
+	}{
+		{
+			name:        "object",
+			req:         ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Object: "db-prod"},
+			wantContent: "prod-secret",
+		},
+		{
+			name:        "case insensitive",
+			req:         ccp.CredentialRequest{AppID: "BILLING", Safe: "billing", Object: "DB-PROD"},
+			wantContent: "prod-secret",
+		},
+		{
+			name:        "username and address",
+			req:         ccp.CredentialRequest{AppID: "billing", Safe: "Billing", UserName: "svc_billing", Address: "db2.example.com"},
+			wantContent: "test-secret",
+		},
+		{
+			name:        "folder",
+			req:         ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Folder: "Root\\Legacy", Object: "ftp"},
+			wantContent: "ftp-secret",
+		},
+		{
+			name:        "query property",
+			req:         ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Query: "Environment=Legacy"},
+			wantContent: "ftp-secret",
+		},
+		{
+			name:        "regexp",
+			req:         ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Query: "Object=db-t.*;PolicyID=My.*", QueryFormat: "Regexp"},
+			wantContent: "test-secret",
+		},
+		{
+			name:    "regexp is anchored",
+			req:     ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Object: "prod", QueryFormat: "Regexp"},
+			wantErr: "APPAP004E",
+		},
+		{
+			name:    "exact does not treat values as patterns",
+			req:     ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Object: "db-.*"},
+			wantErr: "APPAP004E",
+		},
+		{
+			name:    "too many matches",
+			req:     ccp.CredentialRequest{AppID: "billing", Safe: "Billing", UserName: "svc_billing"},
+			wantErr: "APPAP227E",
+		},
+		{
+			name:    "no access to safe",
+			req:     ccp.CredentialRequest{AppID: "reports", Safe: "Billing", Object: "db-prod"},
+			wantErr: "APPAP004E",
+		},
+		{
+			name:    "unknown application",
+			req:     ccp.CredentialRequest{AppID: "payroll", Safe: "Billing", Object: "db-prod"},
+			wantErr: "APPAP008E",
+		},
+		{
+			name:    "invalid query format",
+			req:     ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Object: "db-prod", QueryFormat: "Fuzzy"},
+			wantErr: "AIMWS031E",
+		},
+		{
+			name:    "invalid regexp",
+			req:     ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Object: "db-(", QueryFormat: "Regexp"},
+			wantErr: "AIMWS031E",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.GetCredential(context.Background(), tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetCredential() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetCredential() error = %v", err)
+			}
+			if resp.Content != tt.wantContent {
+				t.Errorf("Content = %q, want %q", resp.Content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestServer_Response(t *testing.T) {
+	srv := NewServer(testSeed())
+	defer srv.Close()
+
+	resp, err := newTestClient(t, srv).GetCredential(context.Background(),
+		ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Object: "ftp"})
+	if err != nil {
+		t.Fatalf("GetCredential() error = %v", err)
+	}
+	if resp.UserName != "ftpuser" || resp.Safe != "Billing" || resp.Folder != "Root\\Legacy" || resp.Name != "ftp" {
+		t.Errorf("GetCredential() = %+v", resp)
+	}
+	if resp.Properties["Environment"] != "Legacy" {
+		t.Errorf("Properties = %v", resp.Properties)
+	}
+}
+
+func TestServer_MissingAppID(t *testing.T) {
+	srv := NewServer(testSeed())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/AIMWebService/api/Accounts?Safe=Billing")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("StatusCode = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if calls := srv.Calls(); len(calls) != 1 || calls[0].ErrorCode != "AIMWS030E" {
+		t.Errorf("Calls() = %+v", calls)
+	}
+}
+
+func TestServer_UpdateAccountAndFailNext(t *testing.T) {
+	srv := NewServer(testSeed())
+	defer srv.Close()
+	client := newTestClient(t, srv)
+	req := ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Object: "db-prod"}
+
+	if !srv.UpdateAccount("Billing", "db-prod", func(a *Account) {
+		a.Content = "rotated"
+		a.PasswordChangeInProcess = true
+	}) {
+		t.Fatal("UpdateAccount() = false")
+	}
+	resp, err := client.GetCredential(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetCredential() error = %v", err)
+	}
+	if resp.Content != "rotated" || !resp.PasswordChangeInProcess {
+		t.Errorf("GetCredential() = %+v", resp)
+	}
+
+	srv.FailNext(http.StatusInternalServerError, "APPAP007E", "Connection to the Vault has failed.")
+	if _, err := client.GetCredential(context.Background(), req); err == nil || !strings.Contains(err.Error(), "APPAP007E") {
+		t.Errorf("GetCredential() error = %v, want APPAP007E", err)
+	}
+	if _, err := client.GetCredential(context.Background(), req); err != nil {
+		t.Errorf("GetCredential() after failure error = %v", err)
+	}
+
+	calls := srv.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("len(Calls()) = %d, want 3", len(calls))
+	}
+	if calls[1].StatusCode != http.StatusInternalServerError || calls[2].StatusCode != http.StatusOK {
+		t.Errorf("Calls() = %+v", calls)
+	}
+	if calls[0].Query != "Safe=Billing;Object=db-prod" {
+		t.Errorf("Query = %q", calls[0].Query)
+	}
+}
+
+func TestServer_ClientCertificate(t *testing.T) {
+	seed := testSeed()
+	seed.Applications["billing"] = Application{Safes: []string{"Billing"}, CertificateCommonNames: []string{"billing-app"}}
+	srv, err := NewTLSServer(seed, TLSOptions{})
+	if err != nil {
+		t.Fatalf("NewTLSServer() error = %v", err)
+	}
+	defer srv.Close()
+
+	get := func(t *testing.T, cfg ccp.ClientConfig, appID string) error {
+		t.Helper()
+		cfg.BaseURL = srv.URL
+		cfg.SkipTLSVerify = true
+		client, err := ccp.NewClient(cfg)
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		_, err = client.GetCredential(context.Background(), ccp.CredentialRequest{AppID: appID, Safe: "Billing", Object: "db-prod"})
+		return err
+	}
+
+	allowedCert, allowedKey, err := srv.WriteClientCertificate(t.TempDir(), "billing-app")
+	if err != nil {
+		t.Fatalf("WriteClientCertificate() error = %v", err)
+	}
+	otherCert, otherKey, err := srv.WriteClientCertificate(t.TempDir(), "other-app")
+	if err != nil {
+		t.Fatalf("WriteClientCertificate() error = %v", err)
+	}
+
+	if err := get(t, ccp.ClientConfig{ClientCert: allowedCert, ClientKey: allowedKey}, "billing"); err != nil {
+		t.Errorf("allowed certificate: error = %v", err)
+	}
+	if err := get(t, ccp.ClientConfig{}, "billing"); err == nil || !strings.Contains(err.Error(), "APPAP306E") {
+		t.Errorf("no certificate: error = %v, want APPAP306E", err)
+	}
+	if err := get(t, ccp.ClientConfig{ClientCert: otherCert, ClientKey: otherKey}, "billing"); err == nil || !strings.Contains(err.Error(), "APPAP306E") {
+		t.Errorf("other certificate: error = %v, want APPAP306E", err)
+	}
+	if err := get(t, ccp.ClientConfig{}, "reports"); err == nil || !strings.Contains(err.Error(), "APPAP004E") {
+		t.Errorf("application without certificate check: error = %v, want APPAP004E", err)
+	}
+}
+
+func TestServer_RequireClientCert(t *testing.T) {
+	srv, err := NewTLSServer(testSeed(), TLSOptions{RequireClientCert: true})
+	if err != nil {
+		t.Fatalf("NewTLSServer() error = %v", err)
+	}
+	defer srv.Close()
+
+	client, err := ccp.NewClient(ccp.ClientConfig{BaseURL: srv.URL, SkipTLSVerify: true})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := client.GetCredential(context.Background(), ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Object: "db-prod"}); err == nil {
+		t.Error("GetCredential() without a client certificate succeeded")
+	}
+	if len(srv.CACertificatePEM()) == 0 {
+		t.Error("CACertificatePEM() is empty")
+	}
+}
+
+func TestLoadSeedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seed.yaml")
+	data := `applications:
+  billing:
+    safes: [Billing]
+accounts:
+  - safe: Billing
+    name: db-prod
+    userName: svc_billing
+    content: s3cret
+    properties:
+      Environment: Production
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	seed, err := LoadSeedFile(path)
+	if err != nil {
+		t.Fatalf("LoadSeedFile() error = %v", err)
+	}
+	srv := NewServer(seed)
+	defer srv.Close()
+
+	password, err := newTestClient(t, srv).GetPassword(context.Background(),
+		ccp.CredentialRequest{AppID: "billing", Safe: "Billing", Query: "Environment=Production"})
+	if err != nil {
+		t.Fatalf("GetPassword() error = %v", err)
+	}
+	if password != "s3cret" {
+		t.Errorf("GetPassword() = %q, want s3cret", password)
+	}
+
+	if err := os.WriteFile(path, []byte("accounts:\n  - safe: Billing\n    secret: x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSeedFile(path); err == nil {
+		t.Error("LoadSeedFile() with an unknown field succeeded")
+	}
+}
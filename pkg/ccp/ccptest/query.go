@@ -0,0 +1,131 @@
+package ccptest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// query is a parsed credential request. The Safe, Folder, Object,
+// UserName and Address parameters and the Key=Value terms of the Query
+// parameter must all match.
+type query struct {
+	appID  string
+	regexp bool
+	terms  []term
+}
+
+type term struct {
+	key   string
+	value string
+	re    *regexp.Regexp
+}
+
+// queryParams are the request parameters that select accounts, in the
+// order they are reported in error messages.
+var queryParams = []string{"Safe", "Folder", "Object", "UserName", "Address"}
+
+// parseQuery reads the request parameters. The returned query is never
+// nil, so the AppID can be recorded even when parsing fails.
+func parseQuery(values url.Values) (*query, *cpError) {
+	q := &query{appID: values.Get("AppID")}
+	if q.appID == "" {
+		return q, &cpError{http.StatusBadRequest, "AIMWS030E", "Invalid request. The AppID parameter is required."}
+	}
+
+	switch format := values.Get("QueryFormat"); {
+	case format == "" || strings.EqualFold(format, "Exact"):
+	case strings.EqualFold(format, "Regexp"):
+		q.regexp = true
+	default:
+		return q, &cpError{http.StatusBadRequest, "AIMWS031E",
+			fmt.Sprintf("Invalid query format [%s]. Valid values are Exact and Regexp.", format)}
+	}
+
+	for _, key := range queryParams {
+		if value := values.Get(key); value != "" {
+			q.terms = append(q.terms, term{key: key, value: value})
+		}
+	}
+	if raw := values.Get("Query"); raw != "" {
+		for _, part := range strings.Split(raw, ";") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(part, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return q, &cpError{http.StatusBadRequest, "AIMWS031E",
+					fmt.Sprintf("Invalid query [%s]. Expected Key=Value pairs separated by semicolons.", raw)}
+			}
+			q.terms = append(q.terms, term{key: strings.TrimSpace(key), value: strings.TrimSpace(value)})
+		}
+	}
+
+	if q.regexp {
+		for i, t := range q.terms {
+			re, err := regexp.Compile("(?i)^(?:" + t.value + ")$")
+			if err != nil {
+				return q, &cpError{http.StatusBadRequest, "AIMWS031E",
+					fmt.Sprintf("Invalid regular expression [%s] for %s: %v", t.value, t.key, err)}
+			}
+			q.terms[i].re = re
+		}
+	}
+	return q, nil
+}
+
+// matches reports whether every term matches the account.
+func (q *query) matches(account *Account) bool {
+	for _, t := range q.terms {
+		value, ok := accountValue(account, t.key)
+		if !ok {
+			return false
+		}
+		if t.re != nil {
+			if !t.re.MatchString(value) {
+				return false
+			}
+		} else if !strings.EqualFold(t.value, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// accountValue returns the account attribute a query key refers to. Keys
+// other than the well-known ones are looked up in the account properties.
+func accountValue(account *Account, key string) (string, bool) {
+	switch strings.ToLower(key) {
+	case "safe":
+		return account.Safe, true
+	case "folder":
+		return folderOrRoot(account.Folder), true
+	case "object", "name":
+		return account.Name, true
+	case "username":
+		return account.UserName, true
+	case "address":
+		return account.Address, true
+	case "policyid":
+		return account.PolicyID, true
+	case "devicetype":
+		return account.DeviceType, true
+	}
+	for name, value := range account.Properties {
+		if strings.EqualFold(name, key) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// String formats the query the way CCP echoes it in error messages.
+func (q *query) String() string {
+	parts := make([]string, len(q.terms))
+	for i, t := range q.terms {
+		parts[i] = t.key + "=" + t.value
+	}
+	return strings.Join(parts, ";")
+}
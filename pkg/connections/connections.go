@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -32,7 +33,7 @@ type ConnectionResponse struct {
 // This is equivalent to New-PASPSMSession in psPAS.
 func Connect(ctx context.Context, sess *session.Session, accountID string, req ConnectionRequest) (*ConnectionResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -72,7 +73,7 @@ type PSMPrerequisites struct {
 // This is equivalent to New-PASPSMSession -AdHocConnect in psPAS.
 func AdHocConnect(ctx context.Context, sess *session.Session, req AdHocConnectRequest) (*ConnectionResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if req.UserName == "" {
@@ -114,7 +115,7 @@ type ConnectionComponent struct {
 // This is equivalent to Get-PASConnectionComponent in psPAS.
 func GetConnectionComponents(ctx context.Context, sess *session.Session, platformID string) ([]ConnectionComponent, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if platformID == "" {
@@ -148,7 +149,7 @@ type PSMServer struct {
 // This is equivalent to Get-PASPSMServer in psPAS.
 func GetPSMServers(ctx context.Context, sess *session.Session) ([]PSMServer, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/PSM/Servers", nil)
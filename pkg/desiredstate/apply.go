@@ -0,0 +1,91 @@
+// Package desiredstate provides application of plans to the Vault.
+package desiredstate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/session"
+)
+
+// ErrPlanNotExecutable is returned when applying a plan that was not built
+// by BuildPlan in this process, such as one decoded from JSON.
+var ErrPlanNotExecutable = errors.New("plan was not built by BuildPlan and cannot be applied")
+
+// ApplyOptions configures how a plan is applied.
+type ApplyOptions struct {
+	// ContinueOnError applies the remaining actions after one fails. By
+	// default Apply stops at the first failure, since later actions may
+	// depend on it.
+	ContinueOnError bool
+}
+
+// ActionResult is the outcome of one action.
+type ActionResult struct {
+	Action Action `json:"action"`
+
+	// Applied is false for actions that failed or were not attempted
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+
+	Err error `json:"-"`
+}
+
+// ApplyResult reports the outcome of Apply.
+type ApplyResult struct {
+	Results []ActionResult `json:"results"`
+	Applied int            `json:"applied"`
+	Failed  int            `json:"failed"`
+}
+
+// Apply carries out the actions of a plan in order. It returns the result of
+// every action attempted, along with an error if any of them failed.
+func Apply(ctx context.Context, sess *session.Session, plan *Plan, opts ApplyOptions) (*ApplyResult, error) {
+	if sess == nil || !sess.IsValid() {
+		return nil, client.ErrSessionInvalid
+	}
+
+	if plan == nil {
+		return nil, fmt.Errorf("plan is required")
+	}
+	for _, action := range plan.Actions {
+		if action.apply == nil {
+			return nil, ErrPlanNotExecutable
+		}
+	}
+
+	result := &ApplyResult{Results: []ActionResult{}}
+	var errs []error
+
+	for _, action := range plan.Actions {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		r := ActionResult{Action: action, Applied: true}
+		if err := action.apply(ctx, sess); err != nil {
+			r.Applied = false
+			r.Err = err
+			r.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", action, err))
+		}
+		result.Results = append(result.Results, r)
+
+		if r.Applied {
+			result.Applied++
+			continue
+		}
+		result.Failed++
+		if !opts.ContinueOnError {
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return result, fmt.Errorf("failed to apply plan: %w", errors.Join(errs...))
+	}
+	return result, nil
+}
@@ -0,0 +1,278 @@
+// Package desiredstate manages safes, safe members, accounts, platform
+// activation and applications declaratively.
+// A YAML or JSON document describes the desired configuration; BuildPlan
+// compares it with the Vault and Apply carries out the resulting actions:
+//
+//	doc, err := desiredstate.LoadFile("vault.yaml")
+//	if err != nil {
+//		return err
+//	}
+//
+//	plan, err := desiredstate.BuildPlan(ctx, sess, doc, desiredstate.PlanOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	json.NewEncoder(os.Stdout).Encode(plan)
+//
+//	result, err := desiredstate.Apply(ctx, sess, plan, desiredstate.ApplyOptions{})
+//
+// The document uses the same field names as the REST API:
+//
+//	safes:
+//	  - safeName: Linux-Prod
+//	    managingCPM: PasswordManager
+//	    numberOfDaysRetention: 7
+//	    members:
+//	      - memberName: linux-admins
+//	        searchIn: Vault
+//	        role: SafeManager
+//	    accounts:
+//	      - name: root-web01
+//	        address: web01.example.com
+//	        userName: root
+//	        platformId: UnixSSH
+//	platforms:
+//	  - platformId: UnixSSH
+//	    active: true
+//	applications:
+//	  - AppID: billing
+//	    Location: \\Applications
+//	    authMethods:
+//	      - AuthType: machineAddress
+//	        AuthValue: 10.0.0.12
+package desiredstate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/chrisranney/gopas/pkg/accounts"
+	"github.com/chrisranney/gopas/pkg/applications"
+	"github.com/chrisranney/gopas/pkg/safemembers"
+	"github.com/chrisranney/gopas/pkg/safes"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is the desired state of the Vault.
+type Document struct {
+	Safes        []Safe        `json:"safes,omitempty"`
+	Platforms    []Platform    `json:"platforms,omitempty"`
+	Applications []Application `json:"applications,omitempty"`
+}
+
+// Safe is the desired state of a safe and of its members and accounts.
+// Empty fields are left as they are in the Vault.
+type Safe struct {
+	safes.CreateOptions
+
+	// Absent deletes the safe. Deletes must be enabled with PlanOptions.AllowDeletes.
+	Absent bool `json:"absent,omitempty"`
+
+	// Members and Accounts are only managed when their key is present: an
+	// omitted key leaves the safe's members or accounts as they are, while
+	// an empty list declares that the safe has none.
+	Members  []Member  `json:"members,omitempty"`
+	Accounts []Account `json:"accounts,omitempty"`
+}
+
+// Member is the desired membership of a safe. Exactly one of Role and
+// Permissions must be set.
+type Member struct {
+	safemembers.AddOptions
+
+	// Role names a predefined set of permissions, such as "EndUser"
+	Role string `json:"role,omitempty"`
+}
+
+// Account is the desired state of an account. Accounts are matched by name
+// within their safe, and SafeName defaults to the enclosing safe. Secret is
+// only used when the account is created.
+type Account struct {
+	accounts.CreateOptions
+}
+
+// Platform is the desired activation state of an existing platform.
+type Platform struct {
+	PlatformID string `json:"platformId"`
+	Active     bool   `json:"active"`
+}
+
+// Application is the desired state of an application and its
+// authentication methods. The application's properties are only used when
+// it is created.
+type Application struct {
+	applications.CreateOptions
+
+	// Absent deletes the application. Deletes must be enabled with PlanOptions.AllowDeletes.
+	Absent bool `json:"absent,omitempty"`
+
+	// AuthMethods are matched with the live ones by type and value. As with
+	// safe members, an omitted key leaves the live methods unmanaged.
+	AuthMethods []applications.AddAuthMethodOptions `json:"authMethods,omitempty"`
+}
+
+// Load reads a YAML or JSON document. JSON is a subset of YAML, so both are
+// accepted; either way the fields use their REST API names.
+func Load(r io.Reader) (*Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read desired state: %w", err)
+	}
+
+	// Decode through JSON so the API's json tags apply to YAML documents too
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse desired state: %w", err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("desired state document is empty")
+	}
+	converted, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse desired state: %w", err)
+	}
+
+	var doc Document
+	dec := json.NewDecoder(bytes.NewReader(converted))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse desired state: %w", err)
+	}
+
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// LoadFile reads a YAML or JSON document from a file.
+func LoadFile(path string) (*Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open desired state: %w", err)
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Validate checks that the document is complete and unambiguous, and fills
+// in each account's safe from its enclosing safe.
+func (d *Document) Validate() error {
+	var errs []error
+	seenSafes := map[string]bool{}
+
+	for i := range d.Safes {
+		safe := &d.Safes[i]
+		if safe.SafeName == "" {
+			errs = append(errs, fmt.Errorf("safes[%d]: safeName is required", i))
+			continue
+		}
+		if seenSafes[safe.SafeName] {
+			errs = append(errs, fmt.Errorf("safe %s: declared more than once", safe.SafeName))
+		}
+		seenSafes[safe.SafeName] = true
+
+		if safe.Absent && (len(safe.Members) > 0 || len(safe.Accounts) > 0) {
+			errs = append(errs, fmt.Errorf("safe %s: an absent safe cannot declare members or accounts", safe.SafeName))
+		}
+
+		seenMembers := map[string]bool{}
+		for j, member := range safe.Members {
+			if member.MemberName == "" {
+				errs = append(errs, fmt.Errorf("safe %s: members[%d]: memberName is required", safe.SafeName, j))
+				continue
+			}
+			if seenMembers[member.MemberName] {
+				errs = append(errs, fmt.Errorf("safe %s: member %s declared more than once", safe.SafeName, member.MemberName))
+			}
+			seenMembers[member.MemberName] = true
+
+			if _, err := member.permissions(); err != nil {
+				errs = append(errs, fmt.Errorf("safe %s: member %s: %w", safe.SafeName, member.MemberName, err))
+			}
+		}
+
+		seenAccounts := map[string]bool{}
+		for j := range safe.Accounts {
+			account := &safe.Accounts[j]
+			if account.SafeName == "" {
+				account.SafeName = safe.SafeName
+			}
+
+			switch {
+			case account.Name == "":
+				errs = append(errs, fmt.Errorf("safe %s: accounts[%d]: name is required", safe.SafeName, j))
+				continue
+			case account.SafeName != safe.SafeName:
+				errs = append(errs, fmt.Errorf("safe %s: account %s belongs to safe %s", safe.SafeName, account.Name, account.SafeName))
+			case account.Address == "" || account.UserName == "" || account.PlatformID == "":
+				errs = append(errs, fmt.Errorf("safe %s: account %s: address, userName and platformId are required", safe.SafeName, account.Name))
+			}
+			if seenAccounts[account.Name] {
+				errs = append(errs, fmt.Errorf("safe %s: account %s declared more than once", safe.SafeName, account.Name))
+			}
+			seenAccounts[account.Name] = true
+		}
+	}
+
+	seenPlatforms := map[string]bool{}
+	for i, platform := range d.Platforms {
+		if platform.PlatformID == "" {
+			errs = append(errs, fmt.Errorf("platforms[%d]: platformId is required", i))
+			continue
+		}
+		if seenPlatforms[strings.ToLower(platform.PlatformID)] {
+			errs = append(errs, fmt.Errorf("platform %s: declared more than once", platform.PlatformID))
+		}
+		seenPlatforms[strings.ToLower(platform.PlatformID)] = true
+	}
+
+	seenApps := map[string]bool{}
+	for i, app := range d.Applications {
+		if app.AppID == "" {
+			errs = append(errs, fmt.Errorf("applications[%d]: AppID is required", i))
+			continue
+		}
+		if seenApps[strings.ToLower(app.AppID)] {
+			errs = append(errs, fmt.Errorf("application %s: declared more than once", app.AppID))
+		}
+		seenApps[strings.ToLower(app.AppID)] = true
+
+		if app.Absent && len(app.AuthMethods) > 0 {
+			errs = append(errs, fmt.Errorf("application %s: an absent application cannot declare auth methods", app.AppID))
+		}
+		for j, method := range app.AuthMethods {
+			if method.AuthType == "" || method.AuthValue == "" {
+				errs = append(errs, fmt.Errorf("application %s: authMethods[%d]: AuthType and AuthValue are required", app.AppID, j))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid desired state: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// permissions resolves the member's role or explicit permissions.
+func (m Member) permissions() (*safemembers.Permissions, error) {
+	switch {
+	case m.Role != "" && m.Permissions != nil:
+		return nil, fmt.Errorf("role and permissions are mutually exclusive")
+	case m.Role != "":
+		role, err := safemembers.ParseRole(m.Role)
+		if err != nil {
+			return nil, err
+		}
+		return safemembers.RolePermissions(role)
+	case m.Permissions != nil:
+		return m.Permissions, nil
+	default:
+		return nil, fmt.Errorf("role or permissions is required")
+	}
+}
@@ -0,0 +1,164 @@
+// Package desiredstate provides tests for desired state documents.
+package desiredstate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDocument = `
+safes:
+  - safeName: Linux-Prod
+    description: Production Linux servers
+    managingCPM: PasswordManager
+    numberOfDaysRetention: 7
+    members:
+      - memberName: linux-admins
+        searchIn: Vault
+        role: Safe Manager
+      - memberName: auditors
+        permissions:
+          listAccounts: true
+          viewAuditLog: true
+    accounts:
+      - name: root-web01
+        address: web01.example.com
+        userName: root
+        platformId: UnixSSH
+        platformAccountProperties:
+          Port: "22"
+  - safeName: Legacy
+    absent: true
+`
+
+func TestLoad_YAML(t *testing.T) {
+	doc, err := Load(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if len(doc.Safes) != 2 {
+		t.Fatalf("got %d safes, want 2", len(doc.Safes))
+	}
+	safe := doc.Safes[0]
+	if safe.SafeName != "Linux-Prod" || safe.ManagingCPM != "PasswordManager" || safe.NumberOfDaysRetention != 7 {
+		t.Errorf("safe = %+v", safe.CreateOptions)
+	}
+	if len(safe.Members) != 2 || safe.Members[0].Role != "Safe Manager" || safe.Members[0].SearchIn != "Vault" {
+		t.Errorf("members = %+v", safe.Members)
+	}
+	if perms := safe.Members[1].Permissions; perms == nil || !perms.ViewAuditLog || perms.UseAccounts {
+		t.Errorf("explicit permissions = %+v", perms)
+	}
+
+	account := safe.Accounts[0]
+	if account.SafeName != "Linux-Prod" {
+		t.Errorf("account SafeName = %q, want the enclosing safe", account.SafeName)
+	}
+	if account.PlatformAccountProperties["Port"] != "22" {
+		t.Errorf("platformAccountProperties = %v", account.PlatformAccountProperties)
+	}
+	if !doc.Safes[1].Absent {
+		t.Error("Legacy safe should be absent")
+	}
+}
+
+func TestLoadFile_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	content := `{"safes":[{"safeName":"Windows","members":[{"memberName":"jdoe","role":"EndUser"}]}]}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error: %v", err)
+	}
+	if doc.Safes[0].SafeName != "Windows" || doc.Safes[0].Members[0].MemberName != "jdoe" {
+		t.Errorf("doc = %+v", doc)
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadFile() with missing file expected error")
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		document    string
+		errContains string
+	}{
+		{
+			name:        "empty",
+			document:    "",
+			errContains: "empty",
+		},
+		{
+			name:        "unknown field",
+			document:    "safes:\n  - safeName: A\n    managingCMP: typo\n",
+			errContains: "unknown field",
+		},
+		{
+			name:        "missing safe name",
+			document:    "safes:\n  - description: nameless\n",
+			errContains: "safeName is required",
+		},
+		{
+			name:        "duplicate safe",
+			document:    "safes:\n  - safeName: A\n  - safeName: A\n",
+			errContains: "declared more than once",
+		},
+		{
+			name:        "member without permissions",
+			document:    "safes:\n  - safeName: A\n    members:\n      - memberName: jdoe\n",
+			errContains: "role or permissions is required",
+		},
+		{
+			name:        "unknown role",
+			document:    "safes:\n  - safeName: A\n    members:\n      - memberName: jdoe\n        role: Owner\n",
+			errContains: "unknown safe member role",
+		},
+		{
+			name:        "incomplete account",
+			document:    "safes:\n  - safeName: A\n    accounts:\n      - name: root\n        address: host\n",
+			errContains: "address, userName and platformId are required",
+		},
+		{
+			name:        "account in another safe",
+			document:    "safes:\n  - safeName: A\n    accounts:\n      - name: root\n        safeName: B\n        address: h\n        userName: u\n        platformId: p\n",
+			errContains: "belongs to safe B",
+		},
+		{
+			name:        "absent safe with members",
+			document:    "safes:\n  - safeName: A\n    absent: true\n    members:\n      - memberName: jdoe\n        role: Full\n",
+			errContains: "absent safe cannot declare",
+		},
+		{
+			name:        "duplicate platform",
+			document:    "platforms:\n  - platformId: UnixSSH\n    active: true\n  - platformId: unixssh\n    active: false\n",
+			errContains: "platform unixssh: declared more than once",
+		},
+		{
+			name:        "incomplete auth method",
+			document:    "applications:\n  - AppID: billing\n    authMethods:\n      - AuthType: path\n",
+			errContains: "AuthType and AuthValue are required",
+		},
+		{
+			name:        "absent application with auth methods",
+			document:    "applications:\n  - AppID: billing\n    absent: true\n    authMethods:\n      - AuthType: path\n        AuthValue: /bin/app\n",
+			errContains: "absent application cannot declare",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tt.document))
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Load() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
@@ -0,0 +1,771 @@
+// Package desiredstate provides planning of changes against the live Vault.
+package desiredstate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/accounts"
+	"github.com/chrisranney/gopas/pkg/applications"
+	"github.com/chrisranney/gopas/pkg/platforms"
+	"github.com/chrisranney/gopas/pkg/safemembers"
+	"github.com/chrisranney/gopas/pkg/safes"
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+// ActionType is the kind of change an action makes.
+type ActionType string
+
+// Action types
+const (
+	ActionCreate ActionType = "create"
+	ActionUpdate ActionType = "update"
+	ActionDelete ActionType = "delete"
+)
+
+// ResourceType is the kind of object an action changes.
+type ResourceType string
+
+// Resource types
+const (
+	ResourceSafe        ResourceType = "safe"
+	ResourceMember      ResourceType = "member"
+	ResourceAccount     ResourceType = "account"
+	ResourcePlatform    ResourceType = "platform"
+	ResourceApplication ResourceType = "application"
+	ResourceAuthMethod  ResourceType = "authMethod"
+)
+
+// sensitiveValue replaces secrets in plan output.
+const sensitiveValue = "(sensitive)"
+
+// Change is a field that an action sets. From is nil for created objects.
+type Change struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Action is a single change needed to reach the desired state.
+type Action struct {
+	Action   ActionType   `json:"action"`
+	Resource ResourceType `json:"resource"`
+
+	// Safe is set for safes and their members and accounts
+	Safe string `json:"safe,omitempty"`
+
+	// Name identifies the member, account, platform or application. For
+	// authentication methods it is "AppID/AuthType:AuthValue".
+	Name string `json:"name,omitempty"`
+
+	Changes []Change `json:"changes,omitempty"`
+
+	// Absent is set on deletes of safes and applications the document
+	// marks absent
+	Absent bool `json:"absent,omitempty"`
+
+	apply func(ctx context.Context, sess *session.Session) error
+}
+
+// Address identifies the resource, such as "member/Linux-Prod/jdoe".
+func (a Action) Address() string {
+	address := string(a.Resource)
+	for _, part := range []string{a.Safe, a.Name} {
+		if part != "" {
+			address += "/" + part
+		}
+	}
+	return address
+}
+
+// String describes the action, such as "update safe/Linux-Prod".
+func (a Action) String() string {
+	return string(a.Action) + " " + a.Address()
+}
+
+// PlanSummary counts the actions of a plan by type.
+type PlanSummary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Delete int `json:"delete"`
+}
+
+// Plan is the ordered list of actions that brings the Vault to the desired
+// state. It encodes to JSON for machine consumption.
+type Plan struct {
+	Actions []Action    `json:"actions"`
+	Summary PlanSummary `json:"summary"`
+
+	// SkippedDeletes lists the objects that are not in the desired state and
+	// would be deleted if PlanOptions.AllowDeletes were set
+	SkippedDeletes []Action `json:"skippedDeletes,omitempty"`
+}
+
+// HasChanges reports whether applying the plan would change the Vault.
+func (p *Plan) HasChanges() bool {
+	return len(p.Actions) > 0
+}
+
+// HasDrift reports whether the Vault differs from the desired state. Unlike
+// HasChanges it includes the skipped deletes of safes and applications the
+// document marks absent, which still exist even though deletes are not
+// allowed. Skipped deletes of unlisted members, accounts and authentication
+// methods are not drift.
+func (p *Plan) HasDrift() bool {
+	if p.HasChanges() {
+		return true
+	}
+	for _, action := range p.SkippedDeletes {
+		if action.Absent {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanOptions configures how a plan is built.
+type PlanOptions struct {
+	// AllowDeletes plans the deletion of safes and applications marked
+	// absent, of members and accounts of declared safes that the document
+	// does not list, and of unlisted authentication methods of declared
+	// applications. A safe without a members or accounts key, or an
+	// application without an authMethods key, keeps all of them; an empty
+	// list deletes them all.
+	AllowDeletes bool
+
+	// IgnoreMembers are never deleted from declared safes. Predefined members
+	// and the session's own user, which the Vault adds to the safes it
+	// creates, are always ignored.
+	IgnoreMembers []string
+}
+
+// BuildPlan compares the desired state with the Vault and returns the
+// actions needed to reconcile them. Safes, platforms and applications that
+// the document does not declare are never touched.
+func BuildPlan(ctx context.Context, sess *session.Session, doc *Document, opts PlanOptions) (*Plan, error) {
+	if sess == nil || !sess.IsValid() {
+		return nil, client.ErrSessionInvalid
+	}
+
+	if doc == nil {
+		return nil, fmt.Errorf("desired state is required")
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	liveSafes := map[string]safes.Safe{}
+	for safe, err := range safes.All(ctx, sess, safes.ListOptions{}, types.PageOptions{}) {
+		if err != nil {
+			return nil, err
+		}
+		liveSafes[strings.ToLower(safe.SafeName)] = safe
+	}
+
+	ignored := map[string]bool{strings.ToLower(sess.User): true}
+	for _, name := range opts.IgnoreMembers {
+		ignored[strings.ToLower(name)] = true
+	}
+
+	b := &planBuilder{allowDeletes: opts.AllowDeletes}
+	if err := b.planPlatforms(ctx, sess, doc.Platforms); err != nil {
+		return nil, err
+	}
+
+	for _, desired := range doc.Safes {
+		live, exists := liveSafes[strings.ToLower(desired.SafeName)]
+
+		if desired.Absent {
+			if exists {
+				action := safeDeleteAction(live.SafeName)
+				action.Absent = true
+				b.delete(action)
+			}
+			continue
+		}
+
+		if !exists {
+			b.safes = append(b.safes, safeCreateAction(desired))
+			for _, member := range desired.Members {
+				b.members = append(b.members, memberAction(desired.SafeName, member, nil))
+			}
+			for _, account := range desired.Accounts {
+				b.accounts = append(b.accounts, accountCreateAction(account))
+			}
+			continue
+		}
+
+		if action, ok := safeUpdateAction(live, desired); ok {
+			b.safes = append(b.safes, action)
+		}
+		if err := b.planMembers(ctx, sess, live.SafeName, desired, ignored); err != nil {
+			return nil, err
+		}
+		if err := b.planAccounts(ctx, sess, live.SafeName, desired); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, desired := range doc.Applications {
+		if err := b.planApplication(ctx, sess, desired); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.plan(), nil
+}
+
+// planBuilder collects actions by kind so they can be ordered by dependency.
+type planBuilder struct {
+	allowDeletes bool
+
+	platforms, safes, members, accounts, applications, authMethods []Action
+
+	// Deletes run in reverse dependency order
+	deleteAuthMethods, deleteApplications, deleteAccounts, deleteMembers, deleteSafes []Action
+}
+
+func (b *planBuilder) delete(action Action) {
+	switch action.Resource {
+	case ResourceAuthMethod:
+		b.deleteAuthMethods = append(b.deleteAuthMethods, action)
+	case ResourceApplication:
+		b.deleteApplications = append(b.deleteApplications, action)
+	case ResourceAccount:
+		b.deleteAccounts = append(b.deleteAccounts, action)
+	case ResourceMember:
+		b.deleteMembers = append(b.deleteMembers, action)
+	default:
+		b.deleteSafes = append(b.deleteSafes, action)
+	}
+}
+
+func (b *planBuilder) plan() *Plan {
+	p := &Plan{Actions: []Action{}}
+	for _, group := range [][]Action{b.platforms, b.safes, b.members, b.accounts, b.applications, b.authMethods} {
+		p.Actions = append(p.Actions, group...)
+	}
+	for _, group := range [][]Action{b.deleteAuthMethods, b.deleteApplications, b.deleteAccounts, b.deleteMembers, b.deleteSafes} {
+		if b.allowDeletes {
+			p.Actions = append(p.Actions, group...)
+			continue
+		}
+		for _, action := range group {
+			action.apply = nil
+			p.SkippedDeletes = append(p.SkippedDeletes, action)
+		}
+	}
+	for _, action := range p.Actions {
+		switch action.Action {
+		case ActionCreate:
+			p.Summary.Create++
+		case ActionUpdate:
+			p.Summary.Update++
+		case ActionDelete:
+			p.Summary.Delete++
+		}
+	}
+	return p
+}
+
+func (b *planBuilder) planMembers(ctx context.Context, sess *session.Session, safeName string, desired Safe, ignored map[string]bool) error {
+	if desired.Members == nil {
+		return nil
+	}
+
+	live := map[string]safemembers.SafeMember{}
+	for member, err := range safemembers.All(ctx, sess, safeName, safemembers.ListOptions{}, types.PageOptions{}) {
+		if err != nil {
+			return err
+		}
+		live[strings.ToLower(member.MemberName)] = member
+	}
+
+	for _, member := range desired.Members {
+		key := strings.ToLower(member.MemberName)
+		current, exists := live[key]
+		delete(live, key)
+
+		if !exists {
+			b.members = append(b.members, memberAction(safeName, member, nil))
+			continue
+		}
+		permissions := current.Permissions
+		if permissions == nil {
+			permissions = &safemembers.Permissions{}
+		}
+		if action := memberAction(safeName, member, permissions); len(action.Changes) > 0 {
+			b.members = append(b.members, action)
+		}
+	}
+
+	for _, name := range sortedKeys(live) {
+		member := live[name]
+		if member.IsPredefinedUser || ignored[name] {
+			continue
+		}
+		b.delete(Action{
+			Action:   ActionDelete,
+			Resource: ResourceMember,
+			Safe:     safeName,
+			Name:     member.MemberName,
+			apply: func(ctx context.Context, sess *session.Session) error {
+				return safemembers.Remove(ctx, sess, safeName, member.MemberName)
+			},
+		})
+	}
+	return nil
+}
+
+func (b *planBuilder) planAccounts(ctx context.Context, sess *session.Session, safeName string, desired Safe) error {
+	if desired.Accounts == nil {
+		return nil
+	}
+
+	live := map[string]accounts.Account{}
+	for account, err := range accounts.All(ctx, sess, accounts.ListOptions{SafeName: safeName}, types.PageOptions{}) {
+		if err != nil {
+			return err
+		}
+		live[strings.ToLower(account.Name)] = account
+	}
+
+	for _, account := range desired.Accounts {
+		key := strings.ToLower(account.Name)
+		current, exists := live[key]
+		delete(live, key)
+
+		if !exists {
+			b.accounts = append(b.accounts, accountCreateAction(account))
+			continue
+		}
+		if action, ok := accountUpdateAction(current, account); ok {
+			b.accounts = append(b.accounts, action)
+		}
+	}
+
+	for _, name := range sortedKeys(live) {
+		account := live[name]
+		accountID := account.ID.String()
+		b.delete(Action{
+			Action:   ActionDelete,
+			Resource: ResourceAccount,
+			Safe:     safeName,
+			Name:     account.Name,
+			apply: func(ctx context.Context, sess *session.Session) error {
+				return accounts.Delete(ctx, sess, accountID)
+			},
+		})
+	}
+	return nil
+}
+
+func (b *planBuilder) planPlatforms(ctx context.Context, sess *session.Session, desired []Platform) error {
+	if len(desired) == 0 {
+		return nil
+	}
+
+	resp, err := platforms.List(ctx, sess, platforms.ListOptions{})
+	if err != nil {
+		return err
+	}
+	live := map[string]platforms.Platform{}
+	for _, platform := range resp.Platforms {
+		live[strings.ToLower(platform.PlatformID.String())] = platform
+	}
+
+	for _, platform := range desired {
+		current, exists := live[strings.ToLower(platform.PlatformID)]
+		if !exists {
+			return fmt.Errorf("platform %s: not found in the Vault", platform.PlatformID)
+		}
+		if current.Active == platform.Active {
+			continue
+		}
+
+		platformID := current.ID.String()
+		if platformID == "" {
+			platformID = current.PlatformID.String()
+		}
+		active := platform.Active
+		b.platforms = append(b.platforms, Action{
+			Action:   ActionUpdate,
+			Resource: ResourcePlatform,
+			Name:     current.PlatformID.String(),
+			Changes:  []Change{{Field: "active", From: current.Active, To: active}},
+			apply: func(ctx context.Context, sess *session.Session) error {
+				if active {
+					return platforms.Activate(ctx, sess, platformID)
+				}
+				return platforms.Deactivate(ctx, sess, platformID)
+			},
+		})
+	}
+	return nil
+}
+
+func (b *planBuilder) planApplication(ctx context.Context, sess *session.Session, desired Application) error {
+	appID := desired.AppID
+	_, err := applications.Get(ctx, sess, appID)
+	exists := err == nil
+	if err != nil && !errors.Is(err, client.ErrNotFound) {
+		return err
+	}
+
+	if desired.Absent {
+		if exists {
+			b.delete(Action{
+				Action:   ActionDelete,
+				Resource: ResourceApplication,
+				Name:     appID,
+				Absent:   true,
+				apply: func(ctx context.Context, sess *session.Session) error {
+					return applications.Delete(ctx, sess, appID)
+				},
+			})
+		}
+		return nil
+	}
+
+	if !exists {
+		opts := desired.CreateOptions
+		b.applications = append(b.applications, Action{
+			Action:   ActionCreate,
+			Resource: ResourceApplication,
+			Name:     appID,
+			Changes:  fieldChanges(opts, "AppID"),
+			apply: func(ctx context.Context, sess *session.Session) error {
+				return applications.Create(ctx, sess, opts)
+			},
+		})
+		for _, method := range desired.AuthMethods {
+			b.authMethods = append(b.authMethods, authMethodCreateAction(appID, method))
+		}
+		return nil
+	}
+	if desired.AuthMethods == nil {
+		return nil
+	}
+
+	live, err := applications.ListAuthMethods(ctx, sess, appID)
+	if err != nil {
+		return err
+	}
+
+	for _, method := range desired.AuthMethods {
+		found := false
+		for i, current := range live {
+			if authMethodKey(current.AuthType, current.AuthValue) == authMethodKey(method.AuthType, method.AuthValue) {
+				live = append(live[:i], live[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			b.authMethods = append(b.authMethods, authMethodCreateAction(appID, method))
+		}
+	}
+
+	for _, current := range live {
+		authID := current.AuthID.String()
+		b.delete(Action{
+			Action:   ActionDelete,
+			Resource: ResourceAuthMethod,
+			Name:     appID + "/" + current.AuthType + ":" + current.AuthValue,
+			apply: func(ctx context.Context, sess *session.Session) error {
+				return applications.RemoveAuthMethod(ctx, sess, appID, authID)
+			},
+		})
+	}
+	return nil
+}
+
+func authMethodCreateAction(appID string, method applications.AddAuthMethodOptions) Action {
+	return Action{
+		Action:   ActionCreate,
+		Resource: ResourceAuthMethod,
+		Name:     appID + "/" + method.AuthType + ":" + method.AuthValue,
+		Changes:  fieldChanges(method, "AuthType", "AuthValue"),
+		apply: func(ctx context.Context, sess *session.Session) error {
+			return applications.AddAuthMethod(ctx, sess, appID, method)
+		},
+	}
+}
+
+// authMethodKey matches authentication methods by type, which the Vault
+// treats case-insensitively, and by value.
+func authMethodKey(authType, authValue string) string {
+	return strings.ToLower(authType) + ":" + authValue
+}
+
+func safeCreateAction(desired Safe) Action {
+	opts := desired.CreateOptions
+	return Action{
+		Action:   ActionCreate,
+		Resource: ResourceSafe,
+		Safe:     desired.SafeName,
+		Changes:  fieldChanges(opts, "safeName"),
+		apply: func(ctx context.Context, sess *session.Session) error {
+			_, err := safes.Create(ctx, sess, opts)
+			return err
+		},
+	}
+}
+
+func safeDeleteAction(safeName string) Action {
+	return Action{
+		Action:   ActionDelete,
+		Resource: ResourceSafe,
+		Safe:     safeName,
+		apply: func(ctx context.Context, sess *session.Session) error {
+			return safes.Delete(ctx, sess, safeName)
+		},
+	}
+}
+
+// safeUpdateAction compares the fields the document sets with the live safe.
+func safeUpdateAction(live safes.Safe, desired Safe) (Action, bool) {
+	var changes []Change
+	var update safes.UpdateOptions
+
+	if desired.Description != "" && desired.Description != live.Description {
+		changes = append(changes, Change{Field: "description", From: live.Description, To: desired.Description})
+		update.Description = desired.Description
+	}
+	if desired.Location != "" && desired.Location != live.Location {
+		changes = append(changes, Change{Field: "location", From: live.Location, To: desired.Location})
+		update.Location = desired.Location
+	}
+	if desired.ManagingCPM != "" && desired.ManagingCPM != live.ManagingCPM {
+		changes = append(changes, Change{Field: "managingCPM", From: live.ManagingCPM, To: desired.ManagingCPM})
+		update.ManagingCPM = desired.ManagingCPM
+	}
+	if desired.NumberOfVersionsRetention != nil && (live.NumberOfVersionsRetention == nil || *live.NumberOfVersionsRetention != *desired.NumberOfVersionsRetention) {
+		var from interface{}
+		if live.NumberOfVersionsRetention != nil {
+			from = *live.NumberOfVersionsRetention
+		}
+		changes = append(changes, Change{Field: "numberOfVersionsRetention", From: from, To: *desired.NumberOfVersionsRetention})
+		update.NumberOfVersionsRetention = desired.NumberOfVersionsRetention
+	}
+	if desired.NumberOfDaysRetention > 0 && desired.NumberOfDaysRetention != live.NumberOfDaysRetention {
+		changes = append(changes, Change{Field: "numberOfDaysRetention", From: live.NumberOfDaysRetention, To: desired.NumberOfDaysRetention})
+		days := desired.NumberOfDaysRetention
+		update.NumberOfDaysRetention = &days
+	}
+	if desired.OLACEnabled && !live.OLACEnabled {
+		changes = append(changes, Change{Field: "olacEnabled", From: false, To: true})
+		enabled := true
+		update.OLACEnabled = &enabled
+	}
+	if desired.AutoPurgeEnabled && !live.AutoPurgeEnabled {
+		changes = append(changes, Change{Field: "autoPurgeEnabled", From: false, To: true})
+		enabled := true
+		update.AutoPurgeEnabled = &enabled
+	}
+
+	if len(changes) == 0 {
+		return Action{}, false
+	}
+
+	safeName := live.SafeName
+	return Action{
+		Action:   ActionUpdate,
+		Resource: ResourceSafe,
+		Safe:     safeName,
+		Changes:  changes,
+		apply: func(ctx context.Context, sess *session.Session) error {
+			_, err := safes.Update(ctx, sess, safeName, update)
+			return err
+		},
+	}, true
+}
+
+// memberAction plans the creation of a member, or its update when current
+// holds the live permissions. The action has no changes if nothing differs.
+func memberAction(safeName string, member Member, current *safemembers.Permissions) Action {
+	// Validate has already resolved the permissions successfully
+	desired, _ := member.permissions()
+
+	action := Action{
+		Action:   ActionUpdate,
+		Resource: ResourceMember,
+		Safe:     safeName,
+		Name:     member.MemberName,
+		apply: func(ctx context.Context, sess *session.Session) error {
+			_, err := safemembers.Ensure(ctx, sess, safeName, member.MemberName, desired)
+			return err
+		},
+	}
+
+	if current == nil {
+		action.Action = ActionCreate
+		opts := member.AddOptions
+		opts.Permissions = desired
+		action.apply = func(ctx context.Context, sess *session.Session) error {
+			_, err := safemembers.Add(ctx, sess, safeName, opts)
+			return err
+		}
+		if member.SearchIn != "" {
+			action.Changes = append(action.Changes, Change{Field: "searchIn", To: member.SearchIn})
+		}
+	}
+
+	for _, change := range safemembers.DiffPermissions(current, desired) {
+		action.Changes = append(action.Changes, Change{
+			Field: "permissions." + change.Permission,
+			From:  change.From,
+			To:    change.To,
+		})
+	}
+	return action
+}
+
+func accountCreateAction(account Account) Action {
+	opts := account.CreateOptions
+	return Action{
+		Action:   ActionCreate,
+		Resource: ResourceAccount,
+		Safe:     account.SafeName,
+		Name:     account.Name,
+		Changes:  fieldChanges(opts, "name", "safeName"),
+		apply: func(ctx context.Context, sess *session.Session) error {
+			_, err := accounts.Create(ctx, sess, opts)
+			return err
+		},
+	}
+}
+
+// accountUpdateAction compares the fields the document sets with the live
+// account. The secret is never compared.
+func accountUpdateAction(live accounts.Account, desired Account) (Action, bool) {
+	var changes []Change
+	var ops []accounts.PatchOperation
+
+	set := func(field, path string, from, to interface{}) {
+		changes = append(changes, Change{Field: field, From: from, To: to})
+		ops = append(ops, accounts.PatchOperation{Op: "replace", Path: path, Value: to})
+	}
+
+	if desired.Address != live.Address {
+		set("address", "/address", live.Address, desired.Address)
+	}
+	if desired.UserName != live.UserName {
+		set("userName", "/userName", live.UserName, desired.UserName)
+	}
+	if desired.PlatformID != live.PlatformID.String() {
+		set("platformId", "/platformId", live.PlatformID.String(), desired.PlatformID)
+	}
+
+	for _, key := range sortedKeys(desired.PlatformAccountProperties) {
+		to := desired.PlatformAccountProperties[key]
+		from, exists := live.PlatformAccountProperties[key]
+		if exists && fmt.Sprint(from) == fmt.Sprint(to) {
+			continue
+		}
+		changes = append(changes, Change{Field: "platformAccountProperties." + key, From: from, To: to})
+		op := "replace"
+		if !exists {
+			op = "add"
+		}
+		ops = append(ops, accounts.PatchOperation{Op: op, Path: "/platformAccountProperties/" + key, Value: to})
+	}
+
+	if want := desired.SecretManagement; want != nil {
+		current := live.SecretManagement
+		if current == nil {
+			current = &accounts.SecretManagement{}
+		}
+		if want.AutomaticManagementEnabled != current.AutomaticManagementEnabled {
+			set("secretManagement.automaticManagementEnabled", "/secretManagement/automaticManagementEnabled",
+				current.AutomaticManagementEnabled, want.AutomaticManagementEnabled)
+		}
+		if want.ManualManagementReason != "" && want.ManualManagementReason != current.ManualManagementReason {
+			set("secretManagement.manualManagementReason", "/secretManagement/manualManagementReason",
+				current.ManualManagementReason, want.ManualManagementReason)
+		}
+	}
+
+	if want := desired.RemoteMachinesAccess; want != nil {
+		current := live.RemoteMachinesAccess
+		if current == nil {
+			current = &accounts.RemoteMachinesAccess{}
+		}
+		if want.RemoteMachines != current.RemoteMachines {
+			set("remoteMachinesAccess.remoteMachines", "/remoteMachinesAccess/remoteMachines",
+				current.RemoteMachines, want.RemoteMachines)
+		}
+		if want.AccessRestrictedToRemoteMachines != current.AccessRestrictedToRemoteMachines {
+			set("remoteMachinesAccess.accessRestrictedToRemoteMachines", "/remoteMachinesAccess/accessRestrictedToRemoteMachines",
+				current.AccessRestrictedToRemoteMachines, want.AccessRestrictedToRemoteMachines)
+		}
+	}
+
+	if len(changes) == 0 {
+		return Action{}, false
+	}
+
+	accountID := live.ID.String()
+	return Action{
+		Action:   ActionUpdate,
+		Resource: ResourceAccount,
+		Safe:     live.SafeName,
+		Name:     live.Name,
+		Changes:  changes,
+		apply: func(ctx context.Context, sess *session.Session) error {
+			_, err := accounts.Update(ctx, sess, accountID, ops)
+			return err
+		},
+	}, true
+}
+
+// fieldChanges lists the non-empty fields of v by their JSON names, in
+// sorted order, skipping the identifying fields. Secrets are masked.
+func fieldChanges(v interface{}, skip ...string) []Change {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+
+	var changes []Change
+	for _, field := range sortedKeys(fields) {
+		value := fields[field]
+		if value == nil || reflect.ValueOf(value).IsZero() || containsString(skip, field) {
+			continue
+		}
+		if field == "secret" {
+			value = sensitiveValue
+		}
+		changes = append(changes, Change{Field: field, To: value})
+	}
+	return changes
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,695 @@
+// Package desiredstate provides tests for planning and applying desired state.
+package desiredstate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/accounts"
+	"github.com/chrisranney/gopas/pkg/applications"
+	"github.com/chrisranney/gopas/pkg/platforms"
+	"github.com/chrisranney/gopas/pkg/safemembers"
+	"github.com/chrisranney/gopas/pkg/safes"
+	gopastypes "github.com/chrisranney/gopas/pkg/types"
+)
+
+// fakeVault is an in-memory Vault serving the safes, members, accounts,
+// platforms and applications APIs.
+type fakeVault struct {
+	mu           sync.Mutex
+	safes        map[string]*safes.Safe
+	members      map[string]map[string]*safemembers.SafeMember
+	accounts     map[string]*accounts.Account
+	platforms    map[string]*platforms.Platform
+	applications map[string]*applications.Application
+	authMethods  map[string][]applications.AuthMethod
+	nextAuthID   int
+	writes       []string
+}
+
+func newFakeVault() *fakeVault {
+	return &fakeVault{
+		safes:        map[string]*safes.Safe{},
+		members:      map[string]map[string]*safemembers.SafeMember{},
+		accounts:     map[string]*accounts.Account{},
+		platforms:    map[string]*platforms.Platform{},
+		applications: map[string]*applications.Application{},
+		authMethods:  map[string][]applications.AuthMethod{},
+	}
+}
+
+func (v *fakeVault) addSafe(safe safes.Safe, members ...safemembers.SafeMember) {
+	v.safes[safe.SafeName] = &safe
+	v.members[safe.SafeName] = map[string]*safemembers.SafeMember{}
+	for i := range members {
+		v.members[safe.SafeName][members[i].MemberName] = &members[i]
+	}
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/PasswordVault/API")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := range parts {
+		parts[i], _ = url.PathUnescape(parts[i])
+	}
+	if r.Method != http.MethodGet {
+		v.writes = append(v.writes, r.Method+" "+path)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"ErrorCode":"PASWS164E","ErrorMessage":"Object was not found"}`))
+	}
+
+	switch {
+	case parts[0] == "Safes" && len(parts) == 1 && r.Method == http.MethodGet:
+		list := []safes.Safe{}
+		for _, s := range v.safes {
+			list = append(list, *s)
+		}
+		json.NewEncoder(w).Encode(safes.SafesResponse{Value: list, Count: len(list)})
+
+	case parts[0] == "Safes" && len(parts) == 1 && r.Method == http.MethodPost:
+		var opts safes.CreateOptions
+		json.NewDecoder(r.Body).Decode(&opts)
+		v.addSafe(safes.Safe{SafeName: opts.SafeName, Description: opts.Description})
+		json.NewEncoder(w).Encode(v.safes[opts.SafeName])
+
+	case parts[0] == "Safes" && len(parts) == 2:
+		safe, ok := v.safes[parts[1]]
+		if !ok {
+			notFound()
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			var opts safes.UpdateOptions
+			json.NewDecoder(r.Body).Decode(&opts)
+			if opts.Description != "" {
+				safe.Description = opts.Description
+			}
+			if opts.NumberOfDaysRetention != nil {
+				safe.NumberOfDaysRetention = *opts.NumberOfDaysRetention
+			}
+			json.NewEncoder(w).Encode(safe)
+		case http.MethodDelete:
+			delete(v.safes, parts[1])
+		}
+
+	case parts[0] == "Safes" && len(parts) >= 3 && parts[2] == "Members":
+		members, ok := v.members[parts[1]]
+		if !ok {
+			notFound()
+			return
+		}
+		if len(parts) == 3 {
+			if r.Method == http.MethodPost {
+				var opts safemembers.AddOptions
+				json.NewDecoder(r.Body).Decode(&opts)
+				members[opts.MemberName] = &safemembers.SafeMember{MemberName: opts.MemberName, Permissions: opts.Permissions}
+				json.NewEncoder(w).Encode(members[opts.MemberName])
+				return
+			}
+			list := []safemembers.SafeMember{}
+			for _, m := range members {
+				list = append(list, *m)
+			}
+			json.NewEncoder(w).Encode(safemembers.SafeMembersResponse{Value: list, Count: len(list)})
+			return
+		}
+		member, ok := members[parts[3]]
+		if !ok {
+			notFound()
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(member)
+		case http.MethodPut:
+			var opts safemembers.UpdateOptions
+			json.NewDecoder(r.Body).Decode(&opts)
+			member.Permissions = opts.Permissions
+			json.NewEncoder(w).Encode(member)
+		case http.MethodDelete:
+			delete(members, parts[3])
+		}
+
+	case parts[0] == "Accounts" && len(parts) == 1 && r.Method == http.MethodGet:
+		safeName := strings.TrimPrefix(r.URL.Query().Get("filter"), "safeName eq ")
+		list := []accounts.Account{}
+		for _, a := range v.accounts {
+			if a.SafeName == safeName {
+				list = append(list, *a)
+			}
+		}
+		json.NewEncoder(w).Encode(accounts.AccountsResponse{Value: list, Count: len(list)})
+
+	case parts[0] == "Accounts" && len(parts) == 1 && r.Method == http.MethodPost:
+		var opts accounts.CreateOptions
+		json.NewDecoder(r.Body).Decode(&opts)
+		id := "id-" + opts.Name
+		v.accounts[id] = &accounts.Account{ID: gopastypes.FlexibleID(id), Name: opts.Name, SafeName: opts.SafeName, Address: opts.Address, UserName: opts.UserName, PlatformID: gopastypes.FlexibleID(opts.PlatformID)}
+		json.NewEncoder(w).Encode(v.accounts[id])
+
+	case parts[0] == "Accounts" && len(parts) == 2:
+		account, ok := v.accounts[parts[1]]
+		if !ok {
+			notFound()
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			var ops []accounts.PatchOperation
+			json.NewDecoder(r.Body).Decode(&ops)
+			for _, op := range ops {
+				if op.Path == "/address" {
+					account.Address = op.Value.(string)
+				}
+			}
+			json.NewEncoder(w).Encode(account)
+		case http.MethodDelete:
+			delete(v.accounts, parts[1])
+		}
+
+	case parts[0] == "Platforms" && len(parts) == 1 && r.Method == http.MethodGet:
+		list := []platforms.Platform{}
+		for _, p := range v.platforms {
+			list = append(list, *p)
+		}
+		json.NewEncoder(w).Encode(platforms.PlatformsResponse{Platforms: list, Total: len(list)})
+
+	case parts[0] == "Platforms" && len(parts) == 3 && r.Method == http.MethodPost:
+		// Activation addresses platforms by their numeric ID
+		for _, platform := range v.platforms {
+			if platform.ID.String() == parts[1] {
+				platform.Active = parts[2] == "activate"
+				return
+			}
+		}
+		notFound()
+
+	case parts[0] == "WebServices" && len(parts) >= 3 && parts[2] == "Applications":
+		v.serveApplications(w, r, parts[3:], notFound)
+
+	default:
+		t := "unexpected request " + r.Method + " " + path
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"ErrorCode": "TEST", "ErrorMessage": t})
+	}
+}
+
+func (v *fakeVault) serveApplications(w http.ResponseWriter, r *http.Request, parts []string, notFound func()) {
+	if len(parts) == 0 && r.Method == http.MethodPost {
+		var body struct {
+			Application applications.CreateOptions `json:"application"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		v.applications[body.Application.AppID] = &applications.Application{AppID: body.Application.AppID, Location: body.Application.Location}
+		return
+	}
+
+	if len(parts) == 0 {
+		notFound()
+		return
+	}
+	app, ok := v.applications[parts[0]]
+	if !ok {
+		notFound()
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"application": app})
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		delete(v.applications, app.AppID)
+		delete(v.authMethods, app.AppID)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"authentication": v.authMethods[app.AppID]})
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		var body struct {
+			Authentication applications.AddAuthMethodOptions `json:"authentication"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		v.nextAuthID++
+		v.authMethods[app.AppID] = append(v.authMethods[app.AppID], applications.AuthMethod{
+			AuthID:    gopastypes.FlexibleID(strconv.Itoa(v.nextAuthID)),
+			AppID:     app.AppID,
+			AuthType:  body.Authentication.AuthType,
+			AuthValue: body.Authentication.AuthValue,
+		})
+	case len(parts) == 3 && r.Method == http.MethodDelete:
+		methods := v.authMethods[app.AppID]
+		for i, method := range methods {
+			if method.AuthID.String() == parts[2] {
+				v.authMethods[app.AppID] = append(methods[:i], methods[i+1:]...)
+				return
+			}
+		}
+		notFound()
+	default:
+		notFound()
+	}
+}
+
+// createTestSession creates a test session against the fake Vault
+func createTestSession(t *testing.T, vault *fakeVault) (*session.Session, *httptest.Server) {
+	server := httptest.NewServer(vault)
+
+	sess, err := session.NewSession(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	c, err := client.NewClient(client.Config{BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	sess.Client = c
+	sess.SetAuthenticated("apiuser", "test-token", "CyberArk")
+
+	return sess, server
+}
+
+func mustLoad(t *testing.T, document string) *Document {
+	t.Helper()
+	doc, err := Load(strings.NewReader(document))
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	return doc
+}
+
+func actionStrings(actions []Action) []string {
+	var s []string
+	for _, a := range actions {
+		s = append(s, a.String())
+	}
+	return s
+}
+
+func seededVault() *fakeVault {
+	endUser, _ := safemembers.RolePermissions(safemembers.RoleEndUser)
+	auditor, _ := safemembers.RolePermissions(safemembers.RoleAuditor)
+
+	vault := newFakeVault()
+	vault.addSafe(safes.Safe{SafeName: "Linux", Description: "old", NumberOfDaysRetention: 7},
+		safemembers.SafeMember{MemberName: "jdoe", Permissions: endUser},
+		safemembers.SafeMember{MemberName: "auditors", Permissions: auditor},
+		safemembers.SafeMember{MemberName: "stale", Permissions: endUser},
+		safemembers.SafeMember{MemberName: "Master", IsPredefinedUser: true, Permissions: endUser},
+		safemembers.SafeMember{MemberName: "apiuser", Permissions: endUser},
+	)
+	vault.addSafe(safes.Safe{SafeName: "Legacy"})
+	vault.addSafe(safes.Safe{SafeName: "Unmanaged"})
+	vault.accounts["12_1"] = &accounts.Account{ID: "12_1", Name: "root-web01", SafeName: "Linux", Address: "web01", UserName: "root", PlatformID: "UnixSSH"}
+	vault.accounts["12_2"] = &accounts.Account{ID: "12_2", Name: "orphan", SafeName: "Linux", Address: "old", UserName: "x", PlatformID: "UnixSSH"}
+	return vault
+}
+
+const planDocument = `
+safes:
+  - safeName: Linux
+    description: Linux servers
+    numberOfDaysRetention: 7
+    members:
+      - memberName: jdoe
+        role: EndUser
+      - memberName: auditors
+        role: EndUser
+      - memberName: ops
+        searchIn: Vault
+        role: SafeManager
+    accounts:
+      - name: root-web01
+        address: web01.example.com
+        userName: root
+        platformId: UnixSSH
+      - name: root-web02
+        address: web02.example.com
+        userName: root
+        platformId: UnixSSH
+        secret: hunter2
+  - safeName: Windows
+    members:
+      - memberName: winadmins
+        role: Full
+  - safeName: Legacy
+    absent: true
+`
+
+func TestBuildPlan(t *testing.T) {
+	vault := seededVault()
+	sess, server := createTestSession(t, vault)
+	defer server.Close()
+
+	plan, err := BuildPlan(context.Background(), sess, mustLoad(t, planDocument), PlanOptions{})
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+
+	want := []string{
+		"update safe/Linux",
+		"create safe/Windows",
+		"update member/Linux/auditors",
+		"create member/Linux/ops",
+		"create member/Windows/winadmins",
+		"update account/Linux/root-web01",
+		"create account/Linux/root-web02",
+	}
+	if got := actionStrings(plan.Actions); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("actions =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if plan.Summary != (PlanSummary{Create: 4, Update: 3}) {
+		t.Errorf("Summary = %+v", plan.Summary)
+	}
+
+	// Deletes are only reported until they are allowed
+	skipped := actionStrings(plan.SkippedDeletes)
+	wantSkipped := []string{"delete account/Linux/orphan", "delete member/Linux/stale", "delete safe/Legacy"}
+	if strings.Join(skipped, ",") != strings.Join(wantSkipped, ",") {
+		t.Errorf("SkippedDeletes = %v, want %v", skipped, wantSkipped)
+	}
+
+	if len(vault.writes) != 0 {
+		t.Errorf("BuildPlan() made changes: %v", vault.writes)
+	}
+
+	update := plan.Actions[0]
+	if len(update.Changes) != 1 || update.Changes[0] != (Change{Field: "description", From: "old", To: "Linux servers"}) {
+		t.Errorf("safe changes = %+v", update.Changes)
+	}
+}
+
+func TestBuildPlan_AbsentIsDrift(t *testing.T) {
+	sess, server := createTestSession(t, seededVault())
+	defer server.Close()
+
+	doc := mustLoad(t, "safes:\n  - safeName: Legacy\n    absent: true\n")
+	plan, err := BuildPlan(context.Background(), sess, doc, PlanOptions{})
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("actions = %v, want none without AllowDeletes", actionStrings(plan.Actions))
+	}
+	if !plan.HasDrift() {
+		t.Error("HasDrift() = false, want true for an existing safe marked absent")
+	}
+
+	// Unlisted members are only deleted on request and are not drift
+	doc = mustLoad(t, "safes:\n  - safeName: Linux\n    description: old\n    members: []\n")
+	plan, err = BuildPlan(context.Background(), sess, doc, PlanOptions{})
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+	if len(plan.SkippedDeletes) == 0 || plan.HasDrift() {
+		t.Errorf("SkippedDeletes = %v, HasDrift() = %v, want skipped deletes without drift", actionStrings(plan.SkippedDeletes), plan.HasDrift())
+	}
+}
+
+func TestBuildPlan_JSON(t *testing.T) {
+	sess, server := createTestSession(t, seededVault())
+	defer server.Close()
+
+	plan, err := BuildPlan(context.Background(), sess, mustLoad(t, planDocument), PlanOptions{})
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Error("plan output contains the account secret")
+	}
+
+	var decoded Plan
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	if len(decoded.Actions) != len(plan.Actions) || decoded.Actions[0].Resource != ResourceSafe {
+		t.Errorf("decoded plan = %+v", decoded)
+	}
+
+	var members []Change
+	for _, a := range decoded.Actions {
+		if a.Address() == "member/Linux/auditors" {
+			members = a.Changes
+		}
+	}
+	if len(members) != 2 || members[0].Field != "permissions.useAccounts" || members[0].To != true {
+		t.Errorf("member changes = %+v", members)
+	}
+
+	// A decoded plan has lost its actions and cannot be applied
+	if _, err := Apply(context.Background(), sess, &decoded, ApplyOptions{}); !errors.Is(err, ErrPlanNotExecutable) {
+		t.Errorf("Apply() of decoded plan error = %v, want ErrPlanNotExecutable", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	vault := seededVault()
+	sess, server := createTestSession(t, vault)
+	defer server.Close()
+
+	doc := mustLoad(t, planDocument)
+	plan, err := BuildPlan(context.Background(), sess, doc, PlanOptions{AllowDeletes: true})
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+	if plan.Summary.Delete != 3 || len(plan.SkippedDeletes) != 0 {
+		t.Fatalf("Summary = %+v, SkippedDeletes = %v", plan.Summary, plan.SkippedDeletes)
+	}
+
+	result, err := Apply(context.Background(), sess, plan, ApplyOptions{})
+	if err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+	if result.Applied != len(plan.Actions) || result.Failed != 0 {
+		t.Errorf("result = %+v", result)
+	}
+
+	if _, ok := vault.safes["Windows"]; !ok {
+		t.Error("Windows safe was not created")
+	}
+	if _, ok := vault.safes["Legacy"]; ok {
+		t.Error("Legacy safe was not deleted")
+	}
+	if _, ok := vault.safes["Unmanaged"]; !ok {
+		t.Error("undeclared safe was deleted")
+	}
+	if _, ok := vault.members["Linux"]["stale"]; ok {
+		t.Error("stale member was not removed")
+	}
+	for _, kept := range []string{"Master", "apiuser"} {
+		if _, ok := vault.members["Linux"][kept]; !ok {
+			t.Errorf("member %s was removed", kept)
+		}
+	}
+	if !vault.members["Linux"]["auditors"].Permissions.UseAccounts {
+		t.Error("auditors permissions were not updated")
+	}
+	if vault.accounts["12_1"].Address != "web01.example.com" {
+		t.Error("account address was not updated")
+	}
+	if _, ok := vault.accounts["12_2"]; ok {
+		t.Error("orphan account was not deleted")
+	}
+
+	// Applying again finds nothing to do
+	again, err := BuildPlan(context.Background(), sess, doc, PlanOptions{AllowDeletes: true})
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+	if again.HasChanges() {
+		t.Errorf("second plan has changes: %v", actionStrings(again.Actions))
+	}
+}
+
+func TestBuildPlan_OmittedSectionsAreUnmanaged(t *testing.T) {
+	sess, server := createTestSession(t, seededVault())
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{
+			name:     "keys omitted",
+			document: "safes:\n  - safeName: Linux\n",
+		},
+		{
+			name:     "empty members",
+			document: "safes:\n  - safeName: Linux\n    members: []\n",
+			want:     []string{"delete member/Linux/auditors", "delete member/Linux/jdoe", "delete member/Linux/stale"},
+		},
+		{
+			name:     "empty accounts",
+			document: `{"safes": [{"safeName": "Linux", "accounts": []}]}`,
+			want:     []string{"delete account/Linux/orphan", "delete account/Linux/root-web01"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := BuildPlan(context.Background(), sess, mustLoad(t, tt.document), PlanOptions{AllowDeletes: true})
+			if err != nil {
+				t.Fatalf("BuildPlan() error: %v", err)
+			}
+			if got := actionStrings(plan.Actions); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("actions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply_StopsOnError(t *testing.T) {
+	vault := newFakeVault()
+	sess, server := createTestSession(t, vault)
+	defer server.Close()
+
+	calls := 0
+	failing := func(ctx context.Context, sess *session.Session) error {
+		calls++
+		return errors.New("boom")
+	}
+	plan := &Plan{Actions: []Action{
+		{Action: ActionCreate, Resource: ResourceSafe, Safe: "A", apply: failing},
+		{Action: ActionCreate, Resource: ResourceSafe, Safe: "B", apply: failing},
+	}}
+
+	result, err := Apply(context.Background(), sess, plan, ApplyOptions{})
+	if err == nil || calls != 1 || result.Failed != 1 || result.Results[0].Error != "boom" {
+		t.Errorf("Apply() = %+v, %v after %d calls", result, err, calls)
+	}
+
+	calls = 0
+	result, err = Apply(context.Background(), sess, plan, ApplyOptions{ContinueOnError: true})
+	if err == nil || calls != 2 || result.Failed != 2 {
+		t.Errorf("Apply(ContinueOnError) = %+v, %v after %d calls", result, err, calls)
+	}
+}
+
+func TestBuildPlan_InvalidSession(t *testing.T) {
+	if _, err := BuildPlan(context.Background(), nil, &Document{}, PlanOptions{}); !errors.Is(err, client.ErrSessionInvalid) {
+		t.Errorf("BuildPlan() error = %v, want ErrSessionInvalid", err)
+	}
+}
+
+const integrationDocument = `
+platforms:
+  - platformId: UnixSSH
+    active: true
+  - platformId: WinDomain
+    active: false
+applications:
+  - AppID: billing
+    authMethods:
+      - AuthType: machineAddress
+        AuthValue: 10.0.0.12
+      - AuthType: path
+        AuthValue: /opt/billing/bin/app
+  - AppID: reports
+    Location: \\Applications
+    authMethods:
+      - AuthType: hash
+        AuthValue: abc123
+  - AppID: retired
+    absent: true
+`
+
+func integrationVault() *fakeVault {
+	vault := newFakeVault()
+	vault.platforms["UnixSSH"] = &platforms.Platform{ID: "7", PlatformID: "UnixSSH", Name: "Unix via SSH", Active: false}
+	vault.platforms["WinDomain"] = &platforms.Platform{ID: "8", PlatformID: "WinDomain", Name: "Windows Domain", Active: true}
+	vault.applications["billing"] = &applications.Application{AppID: "billing"}
+	vault.authMethods["billing"] = []applications.AuthMethod{
+		{AuthID: "1", AppID: "billing", AuthType: "MachineAddress", AuthValue: "10.0.0.12"},
+		{AuthID: "2", AppID: "billing", AuthType: "osUser", AuthValue: "svc-billing"},
+	}
+	vault.applications["retired"] = &applications.Application{AppID: "retired"}
+	vault.nextAuthID = 2
+	return vault
+}
+
+func TestBuildPlan_PlatformsAndApplications(t *testing.T) {
+	vault := integrationVault()
+	sess, server := createTestSession(t, vault)
+	defer server.Close()
+
+	doc := mustLoad(t, integrationDocument)
+	plan, err := BuildPlan(context.Background(), sess, doc, PlanOptions{})
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+
+	want := []string{
+		"update platform/UnixSSH",
+		"update platform/WinDomain",
+		"create application/reports",
+		"create authMethod/billing/path:/opt/billing/bin/app",
+		"create authMethod/reports/hash:abc123",
+	}
+	if got := actionStrings(plan.Actions); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("actions =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	wantSkipped := []string{"delete authMethod/billing/osUser:svc-billing", "delete application/retired"}
+	if got := actionStrings(plan.SkippedDeletes); strings.Join(got, ",") != strings.Join(wantSkipped, ",") {
+		t.Errorf("SkippedDeletes = %v, want %v", got, wantSkipped)
+	}
+	if change := plan.Actions[0].Changes; len(change) != 1 || change[0] != (Change{Field: "active", From: false, To: true}) {
+		t.Errorf("platform changes = %+v", change)
+	}
+
+	plan, err = BuildPlan(context.Background(), sess, doc, PlanOptions{AllowDeletes: true})
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+	if _, err := Apply(context.Background(), sess, plan, ApplyOptions{}); err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+
+	if !vault.platforms["UnixSSH"].Active || vault.platforms["WinDomain"].Active {
+		t.Error("platform activation was not applied")
+	}
+	if _, ok := vault.applications["retired"]; ok {
+		t.Error("absent application was not deleted")
+	}
+	if got := len(vault.authMethods["billing"]); got != 2 {
+		t.Errorf("billing has %d auth methods, want 2", got)
+	}
+
+	plan, err = BuildPlan(context.Background(), sess, doc, PlanOptions{AllowDeletes: true})
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("second plan has changes: %v", actionStrings(plan.Actions))
+	}
+}
+
+func TestBuildPlan_UnknownPlatform(t *testing.T) {
+	sess, server := createTestSession(t, newFakeVault())
+	defer server.Close()
+
+	doc := mustLoad(t, "platforms:\n  - platformId: Missing\n    active: true\n")
+	if _, err := BuildPlan(context.Background(), sess, doc, PlanOptions{}); err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("BuildPlan() error = %v, want unknown platform", err)
+	}
+}
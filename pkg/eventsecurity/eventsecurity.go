@@ -7,9 +7,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -53,6 +56,7 @@ type PTAEventsResponse struct {
 
 // ListEventsOptions holds options for listing PTA events.
 type ListEventsOptions struct {
+	// FromDate and ToDate bound the event time in milliseconds since the epoch
 	FromDate  int64
 	ToDate    int64
 	Status    string
@@ -65,7 +69,7 @@ type ListEventsOptions struct {
 // This is equivalent to Get-PASPTAEvent in psPAS.
 func ListEvents(ctx context.Context, sess *session.Session, opts ListEventsOptions) (*PTAEventsResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -101,10 +105,25 @@ func ListEvents(ctx context.Context, sess *session.Session, opts ListEventsOptio
 	return &result, nil
 }
 
+// AllEvents returns an iterator over the PTA events matching opts,
+// reading every page returned by ListEvents.
+func AllEvents(ctx context.Context, sess *session.Session, opts ListEventsOptions, pageOpts types.PageOptions) iter.Seq2[PTAEvent, error] {
+	fetch := func(ctx context.Context, offset int) (*helpers.Page[PTAEvent], error) {
+		pageQuery := opts
+		pageQuery.Offset = offset
+		result, err := ListEvents(ctx, sess, pageQuery)
+		if err != nil {
+			return nil, err
+		}
+		return &helpers.Page[PTAEvent]{Items: result.PTAEvents, Total: result.Total, NextLink: result.NextLink}, nil
+	}
+	return helpers.Paginate(ctx, fetch, opts.Offset, pageOpts)
+}
+
 // GetEvent retrieves a specific PTA event.
 func GetEvent(ctx context.Context, sess *session.Session, eventID string) (*PTAEvent, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if eventID == "" {
@@ -128,7 +147,7 @@ func GetEvent(ctx context.Context, sess *session.Session, eventID string) (*PTAE
 // This is equivalent to Set-PASPTAEvent in psPAS.
 func SetEventStatus(ctx context.Context, sess *session.Session, eventID string, status string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if eventID == "" {
@@ -165,7 +184,7 @@ type PTARule struct {
 // This is equivalent to Get-PASPTARule in psPAS.
 func ListRules(ctx context.Context, sess *session.Session) ([]PTARule, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/pta/API/Settings/RiskyActivities", nil)
@@ -191,7 +210,7 @@ type SetRuleOptions struct {
 // This is equivalent to Set-PASPTARule in psPAS.
 func SetRule(ctx context.Context, sess *session.Session, ruleID string, opts SetRuleOptions) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if ruleID == "" {
@@ -218,7 +237,7 @@ type PTARemediation struct {
 // This is equivalent to Get-PASPTARemediation in psPAS.
 func ListRemediations(ctx context.Context, sess *session.Session) ([]PTARemediation, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/pta/API/Settings/AutomaticRemediations", nil)
@@ -245,7 +264,7 @@ type PrivilegedUser struct {
 // This is equivalent to Get-PASPTAPrivilegedUser in psPAS.
 func GetPrivilegedUsers(ctx context.Context, sess *session.Session) ([]PrivilegedUser, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/pta/API/Settings/PrivilegedUsers", nil)
@@ -265,7 +284,7 @@ func GetPrivilegedUsers(ctx context.Context, sess *session.Session) ([]Privilege
 // This is equivalent to Add-PASPTAPrivilegedUser in psPAS.
 func AddPrivilegedUser(ctx context.Context, sess *session.Session, userName string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if userName == "" {
@@ -288,7 +307,7 @@ func AddPrivilegedUser(ctx context.Context, sess *session.Session, userName stri
 // This is equivalent to Remove-PASPTAPrivilegedUser in psPAS.
 func RemovePrivilegedUser(ctx context.Context, sess *session.Session, userID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if userID == "" {
@@ -314,7 +333,7 @@ type PrivilegedGroup struct {
 // This is equivalent to Get-PASPTAPrivilegedGroup in psPAS.
 func GetPrivilegedGroups(ctx context.Context, sess *session.Session) ([]PrivilegedGroup, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/pta/API/Settings/PrivilegedGroups", nil)
@@ -334,7 +353,7 @@ func GetPrivilegedGroups(ctx context.Context, sess *session.Session) ([]Privileg
 // This is equivalent to Add-PASPTAPrivilegedGroup in psPAS.
 func AddPrivilegedGroup(ctx context.Context, sess *session.Session, groupName string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if groupName == "" {
@@ -357,7 +376,7 @@ func AddPrivilegedGroup(ctx context.Context, sess *session.Session, groupName st
 // This is equivalent to Remove-PASPTAPrivilegedGroup in psPAS.
 func RemovePrivilegedGroup(ctx context.Context, sess *session.Session, groupID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if groupID == "" {
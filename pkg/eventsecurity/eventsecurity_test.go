@@ -4,8 +4,10 @@ package eventsecurity
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/chrisranney/gopas/internal/client"
@@ -868,3 +870,61 @@ func TestPTARule_Struct(t *testing.T) {
 		t.Errorf("Active = %v, want true", rule.Active)
 	}
 }
+
+func TestAllEvents(t *testing.T) {
+	const totalItems = 5
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if limit := r.URL.Query().Get("limit"); limit != "2" {
+			t.Errorf("Expected limit=2, got %q", limit)
+		}
+
+		end := offset + 2
+		if end > totalItems {
+			end = totalItems
+		}
+		var page []json.RawMessage
+		for i := offset; i < end; i++ {
+			page = append(page, json.RawMessage(fmt.Sprintf(`{"id": "%d", "type": "event%d"}`, i, i)))
+		}
+
+		body := map[string]interface{}{"Events": page}
+		body["Total"] = totalItems
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+
+	sess, server := createTestSession(t, handler)
+	defer server.Close()
+
+	var got []PTAEvent
+	for item, err := range AllEvents(context.Background(), sess, ListEventsOptions{Limit: 2}, types.PageOptions{}) {
+		if err != nil {
+			t.Fatalf("AllEvents() unexpected error: %v", err)
+		}
+		got = append(got, item)
+	}
+	if len(got) != totalItems {
+		t.Fatalf("AllEvents() yielded %d items, want %d", len(got), totalItems)
+	}
+
+	var limited int
+	for _, err := range AllEvents(context.Background(), sess, ListEventsOptions{Limit: 2}, types.PageOptions{MaxItems: 3}) {
+		if err != nil {
+			t.Fatalf("AllEvents() unexpected error: %v", err)
+		}
+		limited++
+	}
+	if limited != 3 {
+		t.Errorf("AllEvents() with MaxItems yielded %d items, want 3", limited)
+	}
+}
+
+func TestAllEvents_InvalidSession(t *testing.T) {
+	for _, err := range AllEvents(context.Background(), nil, ListEventsOptions{Limit: 2}, types.PageOptions{}) {
+		if err == nil {
+			t.Error("AllEvents() expected error for nil session")
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 )
 
@@ -26,7 +28,11 @@ type IPAllowListResponse struct {
 // This is equivalent to Get-PASIPAllowList in psPAS.
 func List(ctx context.Context, sess *session.Session) ([]IPAllowListEntry, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "ipallowlist.List", PrivilegeCloudOnly: true}); err != nil {
+		return nil, err
 	}
 
 	resp, err := sess.Client.Get(ctx, "/WebServices/PIMServices.svc/IPAllowedList", nil)
@@ -52,7 +58,11 @@ type AddOptions struct {
 // This is equivalent to Add-PASIPAllowList in psPAS.
 func Add(ctx context.Context, sess *session.Session, opts AddOptions) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "ipallowlist.Add", PrivilegeCloudOnly: true}); err != nil {
+		return err
 	}
 
 	if opts.IP == "" {
@@ -80,7 +90,11 @@ func Add(ctx context.Context, sess *session.Session, opts AddOptions) error {
 // This is equivalent to Remove-PASIPAllowList in psPAS.
 func Remove(ctx context.Context, sess *session.Session, ip string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "ipallowlist.Remove", PrivilegeCloudOnly: true}); err != nil {
+		return err
 	}
 
 	if ip == "" {
@@ -4,6 +4,7 @@ package ipallowlist
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -23,6 +24,7 @@ func createTestSession(t *testing.T, handler http.Handler) (*session.Session, *h
 
 	sess.Client = createTestClient(t, server.URL)
 	sess.SetAuthenticated("testuser", "test-token", "CyberArk")
+	sess.SetPrivilegeCloud(true)
 
 	return sess, server
 }
@@ -110,6 +112,34 @@ func TestList_InvalidSession(t *testing.T) {
 	}
 }
 
+func TestList_SelfHosted(t *testing.T) {
+	sess, server := createTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("List() sent a request to a self-hosted vault")
+	}))
+	defer server.Close()
+	sess.SetPrivilegeCloud(false)
+	sess.SetSelfHosted(true)
+
+	_, err := List(context.Background(), sess)
+	if !errors.Is(err, client.ErrVersionUnsupported) {
+		t.Errorf("List() error = %v, want ErrVersionUnsupported", err)
+	}
+}
+
+func TestList_UnknownDeployment(t *testing.T) {
+	// A Privilege Cloud tenant on a custom domain is not detected
+	sess, server := createTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(IPAllowListResponse{})
+	}))
+	defer server.Close()
+	sess.SetPrivilegeCloud(false)
+
+	if _, err := List(context.Background(), sess); err != nil {
+		t.Errorf("List() error = %v, want nil", err)
+	}
+}
+
 func TestAdd(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -34,7 +36,11 @@ type JITAccess struct {
 // This is equivalent to Request-PASJustInTimeAccess in psPAS.
 func RequestJITAccess(ctx context.Context, sess *session.Session, accountID string, opts JITAccessRequest) (*JITAccess, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "jitaccess.RequestJITAccess", MinVersion: "10.4"}); err != nil {
+		return nil, err
 	}
 
 	if accountID == "" {
@@ -73,7 +79,11 @@ func RequestJITAccess(ctx context.Context, sess *session.Session, accountID stri
 // This is equivalent to Revoke-PASJustInTimeAccess in psPAS.
 func RevokeJITAccess(ctx context.Context, sess *session.Session, accountID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "jitaccess.RevokeJITAccess", MinVersion: "10.4"}); err != nil {
+		return err
 	}
 
 	if accountID == "" {
@@ -100,7 +110,11 @@ type JITAccessStatus struct {
 // GetJITAccessStatus retrieves the JIT access status for an account.
 func GetJITAccessStatus(ctx context.Context, sess *session.Session, accountID string) (*JITAccessStatus, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "jitaccess.GetJITAccessStatus", MinVersion: "10.4"}); err != nil {
+		return nil, err
 	}
 
 	if accountID == "" {
@@ -133,7 +147,11 @@ type EPVUserAccess struct {
 // ListEPVUserAccess lists all EPV user access grants for an account.
 func ListEPVUserAccess(ctx context.Context, sess *session.Session, accountID string) ([]EPVUserAccess, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "jitaccess.ListEPVUserAccess", MinVersion: "10.4"}); err != nil {
+		return nil, err
 	}
 
 	if accountID == "" {
@@ -4,11 +4,13 @@ package jitaccess
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 )
 
@@ -342,6 +344,39 @@ func TestListEPVUserAccess_InvalidSession(t *testing.T) {
 	}
 }
 
+func TestRequestJITAccess_UnsupportedVersion(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	sess, server := createTestSession(t, handler)
+	defer server.Close()
+	sess.SetVersion("10.2")
+
+	_, err := RequestJITAccess(context.Background(), sess, "acc-123", JITAccessRequest{})
+	if !errors.Is(err, client.ErrVersionUnsupported) {
+		t.Fatalf("RequestJITAccess() error = %v, want ErrVersionUnsupported", err)
+	}
+	var versionErr *helpers.VersionError
+	if !errors.As(err, &versionErr) || versionErr.MinVersion != "10.4" {
+		t.Errorf("RequestJITAccess() error = %#v, want VersionError with MinVersion 10.4", err)
+	}
+	if called {
+		t.Error("RequestJITAccess() sent a request to an unsupported vault")
+	}
+
+	// Enforcement can be bypassed per session
+	sess.SetSkipVersionEnforcement(true)
+	if _, err := RequestJITAccess(context.Background(), sess, "acc-123", JITAccessRequest{}); errors.Is(err, client.ErrVersionUnsupported) {
+		t.Errorf("RequestJITAccess() with enforcement skipped error = %v", err)
+	}
+	if !called {
+		t.Error("RequestJITAccess() with enforcement skipped did not send a request")
+	}
+}
+
 func TestGetJITAccessStatus_ServerError(t *testing.T) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -42,7 +43,7 @@ type DirectoriesResponse struct {
 // This is equivalent to Get-PASDirectory in psPAS.
 func List(ctx context.Context, sess *session.Session) ([]Directory, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/Configuration/LDAP/Directories", nil)
@@ -61,7 +62,7 @@ func List(ctx context.Context, sess *session.Session) ([]Directory, error) {
 // Get retrieves a specific LDAP directory.
 func Get(ctx context.Context, sess *session.Session, directoryID string) (*Directory, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if directoryID == "" {
@@ -96,7 +97,7 @@ type CreateOptions struct {
 // This is equivalent to Add-PASDirectory in psPAS.
 func Create(ctx context.Context, sess *session.Session, opts CreateOptions) (*Directory, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if opts.DomainName == "" {
@@ -120,7 +121,7 @@ func Create(ctx context.Context, sess *session.Session, opts CreateOptions) (*Di
 // This is equivalent to Remove-PASDirectory in psPAS.
 func Delete(ctx context.Context, sess *session.Session, directoryID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if directoryID == "" {
@@ -152,7 +153,7 @@ type DirectoryMapping struct {
 // This is equivalent to Get-PASDirectoryMapping in psPAS.
 func ListMappings(ctx context.Context, sess *session.Session, directoryID string) ([]DirectoryMapping, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if directoryID == "" {
@@ -190,7 +191,7 @@ type CreateMappingOptions struct {
 // This is equivalent to New-PASDirectoryMapping in psPAS.
 func CreateMapping(ctx context.Context, sess *session.Session, directoryID string, opts CreateMappingOptions) (*DirectoryMapping, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if directoryID == "" {
@@ -218,7 +219,7 @@ func CreateMapping(ctx context.Context, sess *session.Session, directoryID strin
 // This is equivalent to Remove-PASDirectoryMapping in psPAS.
 func DeleteMapping(ctx context.Context, sess *session.Session, directoryID string, mappingID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if directoryID == "" {
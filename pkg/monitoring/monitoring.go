@@ -7,9 +7,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -71,7 +74,7 @@ type ListOptions struct {
 // This is equivalent to Get-PASPSMSession in psPAS.
 func ListSessions(ctx context.Context, sess *session.Session, opts ListOptions) (*SessionsResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -110,10 +113,25 @@ func ListSessions(ctx context.Context, sess *session.Session, opts ListOptions)
 	return &result, nil
 }
 
+// AllSessions returns an iterator over the PSM recordings matching opts
+// across all pages.
+func AllSessions(ctx context.Context, sess *session.Session, opts ListOptions, pageOpts types.PageOptions) iter.Seq2[PSMSession, error] {
+	fetch := func(ctx context.Context, offset int) (*helpers.Page[PSMSession], error) {
+		pageQuery := opts
+		pageQuery.Offset = offset
+		result, err := ListSessions(ctx, sess, pageQuery)
+		if err != nil {
+			return nil, err
+		}
+		return &helpers.Page[PSMSession]{Items: result.Recordings, Total: result.Total, NextLink: result.NextLink}, nil
+	}
+	return helpers.Paginate(ctx, fetch, opts.Offset, pageOpts)
+}
+
 // GetSession retrieves a specific PSM session.
 func GetSession(ctx context.Context, sess *session.Session, sessionID string) (*PSMSession, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if sessionID == "" {
@@ -137,7 +155,7 @@ func GetSession(ctx context.Context, sess *session.Session, sessionID string) (*
 // This is equivalent to Get-PASPSMSession -LiveSession in psPAS.
 func ListLiveSessions(ctx context.Context, sess *session.Session, opts ListOptions) (*SessionsResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -168,7 +186,7 @@ func ListLiveSessions(ctx context.Context, sess *session.Session, opts ListOptio
 // This is equivalent to Stop-PASPSMSession in psPAS.
 func TerminateSession(ctx context.Context, sess *session.Session, liveSessionID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if liveSessionID == "" {
@@ -187,7 +205,7 @@ func TerminateSession(ctx context.Context, sess *session.Session, liveSessionID
 // This is equivalent to Suspend-PASPSMSession in psPAS.
 func SuspendSession(ctx context.Context, sess *session.Session, liveSessionID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if liveSessionID == "" {
@@ -206,7 +224,7 @@ func SuspendSession(ctx context.Context, sess *session.Session, liveSessionID st
 // This is equivalent to Resume-PASPSMSession in psPAS.
 func ResumeSession(ctx context.Context, sess *session.Session, liveSessionID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if liveSessionID == "" {
@@ -225,7 +243,7 @@ func ResumeSession(ctx context.Context, sess *session.Session, liveSessionID str
 // This is equivalent to Get-PASPSMRecording in psPAS.
 func GetRecording(ctx context.Context, sess *session.Session, recordingID string) ([]byte, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if recordingID == "" {
@@ -252,7 +270,7 @@ type SessionActivity struct {
 // This is equivalent to Get-PASPSMSessionActivity in psPAS.
 func GetSessionActivities(ctx context.Context, sess *session.Session, sessionID string) ([]SessionActivity, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if sessionID == "" {
@@ -278,7 +296,7 @@ func GetSessionActivities(ctx context.Context, sess *session.Session, sessionID
 // This is equivalent to Get-PASPSMSessionProperty in psPAS.
 func GetSessionProperties(ctx context.Context, sess *session.Session, sessionID string) (map[string]string, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if sessionID == "" {
@@ -4,12 +4,15 @@ package monitoring
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/types"
 )
 
 // createTestSession creates a test session with a mock server
@@ -733,3 +736,61 @@ func TestSessionActivity_Struct(t *testing.T) {
 		t.Errorf("Details = %v, want ls -la", activity.Details)
 	}
 }
+
+func TestAllSessions(t *testing.T) {
+	const totalItems = 5
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if limit := r.URL.Query().Get("limit"); limit != "2" {
+			t.Errorf("Expected limit=2, got %q", limit)
+		}
+
+		end := offset + 2
+		if end > totalItems {
+			end = totalItems
+		}
+		var page []json.RawMessage
+		for i := offset; i < end; i++ {
+			page = append(page, json.RawMessage(fmt.Sprintf(`{"SessionID": "%d", "User": "user%d"}`, i, i)))
+		}
+
+		body := map[string]interface{}{"Recordings": page}
+		body["Total"] = totalItems
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+
+	sess, server := createTestSession(t, handler)
+	defer server.Close()
+
+	var got []PSMSession
+	for item, err := range AllSessions(context.Background(), sess, ListOptions{Limit: 2}, types.PageOptions{}) {
+		if err != nil {
+			t.Fatalf("AllSessions() unexpected error: %v", err)
+		}
+		got = append(got, item)
+	}
+	if len(got) != totalItems {
+		t.Fatalf("AllSessions() yielded %d items, want %d", len(got), totalItems)
+	}
+
+	var limited int
+	for _, err := range AllSessions(context.Background(), sess, ListOptions{Limit: 2}, types.PageOptions{MaxItems: 3}) {
+		if err != nil {
+			t.Fatalf("AllSessions() unexpected error: %v", err)
+		}
+		limited++
+	}
+	if limited != 3 {
+		t.Errorf("AllSessions() with MaxItems yielded %d items, want 3", limited)
+	}
+}
+
+func TestAllSessions_InvalidSession(t *testing.T) {
+	for _, err := range AllSessions(context.Background(), nil, ListOptions{Limit: 2}, types.PageOptions{}) {
+		if err == nil {
+			t.Error("AllSessions() expected error for nil session")
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package onboardingrules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Methods for comparing UserNameFilter and AddressFilter.
+const (
+	MethodEquals   = "Equals"
+	MethodBegins   = "Begins"
+	MethodEnds     = "Ends"
+	MethodContains = "Contains"
+)
+
+// Values of SystemTypeFilter.
+const (
+	SystemTypeWindows = "Windows"
+	SystemTypeUnix    = "Unix"
+)
+
+// Values of MachineTypeFilter.
+const (
+	MachineTypeAny         = "Any"
+	MachineTypeWorkstation = "Workstation"
+	MachineTypeServer      = "Server"
+)
+
+// Values of AccountCategoryFilter.
+const (
+	AccountCategoryAny           = "Any"
+	AccountCategoryPrivileged    = "Privileged"
+	AccountCategoryNonPrivileged = "NonPrivileged"
+)
+
+// SystemType returns SystemTypeWindows or SystemTypeUnix for the account,
+// or "" if neither its platform type nor its OS family says.
+func (a DiscoveredAccount) SystemType() string {
+	for _, value := range []string{a.PlatformType, a.OSFamily} {
+		value = strings.ToLower(value)
+		switch {
+		case strings.HasPrefix(value, "windows"):
+			return SystemTypeWindows
+		case strings.HasPrefix(value, "unix"), strings.HasPrefix(value, "linux"):
+			return SystemTypeUnix
+		}
+	}
+	return ""
+}
+
+// MachineType returns MachineTypeServer or MachineTypeWorkstation for a
+// Windows account, or "" if the machine type is not known.
+func (a DiscoveredAccount) MachineType() string {
+	platformType := strings.ToLower(a.PlatformType)
+	switch {
+	case strings.Contains(platformType, "server"):
+		return MachineTypeServer
+	case strings.Contains(platformType, "desktop"), strings.Contains(platformType, "workstation"):
+		return MachineTypeWorkstation
+	case strings.Contains(strings.ToLower(a.OSVersion), "server"):
+		return MachineTypeServer
+	default:
+		return ""
+	}
+}
+
+// HasAdminID reports whether the account is the built-in administrator of
+// its machine: RID 500 on Windows or UID 0 on Unix.
+func (a DiscoveredAccount) HasAdminID() bool {
+	props := a.PlatformTypeAccountProperties
+	if props == nil {
+		return false
+	}
+	return strings.HasSuffix(props.SID, "-500") || props.UID == "0"
+}
+
+// SortByPrecedence sorts rules in the order the Vault evaluates them: by
+// RulePrecedence, lowest first, with rules that have no precedence last,
+// then by RuleID.
+func SortByPrecedence(rules []OnboardingRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		pi, pj := rules[i].RulePrecedence, rules[j].RulePrecedence
+		if (pi == 0) != (pj == 0) {
+			return pj == 0
+		}
+		if pi != pj {
+			return pi < pj
+		}
+		return rules[i].RuleID < rules[j].RuleID
+	})
+}
+
+// MismatchReasons returns why the rule's filters do not match the account,
+// or nil if they all match. Empty filters match every account, and text
+// filters ignore case. The machine type filter only applies to Windows
+// accounts.
+func (r OnboardingRule) MismatchReasons(account DiscoveredAccount) []string {
+	var reasons []string
+
+	if reason := textMismatch("user name", r.UserNameMethod, r.UserNameFilter, account.UserName); reason != "" {
+		reasons = append(reasons, reason)
+	}
+	if reason := textMismatch("address", r.AddressMethod, r.AddressFilter, account.Address); reason != "" {
+		reasons = append(reasons, reason)
+	}
+
+	systemType := account.SystemType()
+	if r.SystemTypeFilter != "" && !strings.EqualFold(r.SystemTypeFilter, systemType) {
+		reasons = append(reasons, fmt.Sprintf("system type is %s, rule requires %s", orUnknown(systemType), r.SystemTypeFilter))
+	}
+
+	if r.MachineTypeFilter != "" && !strings.EqualFold(r.MachineTypeFilter, MachineTypeAny) && systemType != SystemTypeUnix {
+		if machineType := account.MachineType(); !strings.EqualFold(r.MachineTypeFilter, machineType) {
+			reasons = append(reasons, fmt.Sprintf("machine type is %s, rule requires %s", orUnknown(machineType), r.MachineTypeFilter))
+		}
+	}
+
+	switch {
+	case strings.EqualFold(r.AccountCategoryFilter, AccountCategoryPrivileged) && !account.Privileged:
+		reasons = append(reasons, "account is not privileged, rule requires Privileged")
+	case strings.EqualFold(r.AccountCategoryFilter, AccountCategoryNonPrivileged) && account.Privileged:
+		reasons = append(reasons, "account is privileged, rule requires NonPrivileged")
+	}
+
+	if r.IsAdminIDFilter && !account.HasAdminID() {
+		reasons = append(reasons, "account does not have an administrator ID")
+	}
+
+	return reasons
+}
+
+// textMismatch compares a user name or address with a rule filter and
+// describes the mismatch, or returns "" if the value matches.
+func textMismatch(field, method, filter, value string) string {
+	if filter == "" {
+		return ""
+	}
+	f, v := strings.ToLower(filter), strings.ToLower(value)
+
+	var matched bool
+	var verb string
+	switch {
+	case strings.EqualFold(method, MethodBegins):
+		matched, verb = strings.HasPrefix(v, f), "begin with"
+	case strings.EqualFold(method, MethodEnds):
+		matched, verb = strings.HasSuffix(v, f), "end with"
+	case strings.EqualFold(method, MethodContains):
+		matched, verb = strings.Contains(v, f), "contain"
+	default:
+		matched, verb = v == f, "equal"
+	}
+	if matched {
+		return ""
+	}
+	return fmt.Sprintf("%s %q does not %s %q", field, value, verb, filter)
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// RuleResult is the outcome of one rule in an Evaluation.
+type RuleResult struct {
+	Rule OnboardingRule `json:"rule"`
+	// Matched is true only for the rule that onboards the account
+	Matched bool `json:"matched"`
+	// Reasons explains why the rule was not used
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Evaluation is the outcome of evaluating onboarding rules against one
+// discovered account.
+type Evaluation struct {
+	Account DiscoveredAccount `json:"account"`
+	// Match is the rule that would onboard the account, or nil
+	Match *OnboardingRule `json:"match,omitempty"`
+	// Results holds every rule in the order it was evaluated
+	Results []RuleResult `json:"results"`
+}
+
+// Evaluate predicts which rule would onboard account, evaluating the rules
+// in precedence order as the Vault does; the first rule whose filters all
+// match wins. It runs locally and does not change rules. Every other rule
+// is explained in Results, including rules that match but are outranked.
+func Evaluate(rules []OnboardingRule, account DiscoveredAccount) Evaluation {
+	sorted := append([]OnboardingRule(nil), rules...)
+	SortByPrecedence(sorted)
+
+	eval := Evaluation{Account: account, Results: make([]RuleResult, 0, len(sorted))}
+	for _, rule := range sorted {
+		result := RuleResult{Rule: rule, Reasons: rule.MismatchReasons(account)}
+		switch {
+		case len(result.Reasons) > 0:
+		case eval.Match == nil:
+			result.Matched = true
+			eval.Match = &result.Rule
+		default:
+			result.Reasons = []string{fmt.Sprintf("rule %q has higher precedence", eval.Match.RuleName)}
+		}
+		eval.Results = append(eval.Results, result)
+	}
+	return eval
+}
@@ -0,0 +1,214 @@
+package onboardingrules
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiscoveredAccount_Attributes(t *testing.T) {
+	tests := []struct {
+		name        string
+		account     DiscoveredAccount
+		systemType  string
+		machineType string
+		adminID     bool
+	}{
+		{
+			name:        "windows server administrator",
+			account:     DiscoveredAccount{PlatformType: "Windows Server Local", PlatformTypeAccountProperties: &PlatformTypeAccountProperties{SID: "S-1-5-21-1004336348-1177238915-682003330-500"}},
+			systemType:  SystemTypeWindows,
+			machineType: MachineTypeServer,
+			adminID:     true,
+		},
+		{
+			name:        "windows desktop",
+			account:     DiscoveredAccount{PlatformType: "Windows Desktop Local", PlatformTypeAccountProperties: &PlatformTypeAccountProperties{SID: "S-1-5-21-1004336348-1177238915-682003330-1001"}},
+			systemType:  SystemTypeWindows,
+			machineType: MachineTypeWorkstation,
+		},
+		{
+			name:        "domain account on a server OS",
+			account:     DiscoveredAccount{PlatformType: "Windows Domain", OSVersion: "Windows Server 2019 Datacenter"},
+			systemType:  SystemTypeWindows,
+			machineType: MachineTypeServer,
+		},
+		{
+			name:       "unix root",
+			account:    DiscoveredAccount{PlatformType: "Unix", PlatformTypeAccountProperties: &PlatformTypeAccountProperties{UID: "0"}},
+			systemType: SystemTypeUnix,
+			adminID:    true,
+		},
+		{
+			name:       "os family only",
+			account:    DiscoveredAccount{OSFamily: "Linux"},
+			systemType: SystemTypeUnix,
+		},
+		{
+			name:    "unknown",
+			account: DiscoveredAccount{PlatformType: "AWS"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.account.SystemType(); got != tt.systemType {
+				t.Errorf("SystemType() = %q, want %q", got, tt.systemType)
+			}
+			if got := tt.account.MachineType(); got != tt.machineType {
+				t.Errorf("MachineType() = %q, want %q", got, tt.machineType)
+			}
+			if got := tt.account.HasAdminID(); got != tt.adminID {
+				t.Errorf("HasAdminID() = %v, want %v", got, tt.adminID)
+			}
+		})
+	}
+}
+
+func TestOnboardingRule_MismatchReasons(t *testing.T) {
+	account := DiscoveredAccount{
+		UserName:     "Adm_Backup",
+		Address:      "srv01.corp.example.com",
+		PlatformType: "Windows Server Local",
+		Privileged:   true,
+	}
+
+	tests := []struct {
+		name       string
+		rule       OnboardingRule
+		wantReason string
+	}{
+		{name: "no filters", rule: OnboardingRule{}},
+		{name: "equals ignores case", rule: OnboardingRule{UserNameFilter: "adm_backup", UserNameMethod: MethodEquals}},
+		{name: "equals is the default method", rule: OnboardingRule{UserNameFilter: "adm_"}, wantReason: `user name "Adm_Backup" does not equal "adm_"`},
+		{name: "begins", rule: OnboardingRule{UserNameFilter: "adm_", UserNameMethod: MethodBegins}},
+		{name: "begins mismatch", rule: OnboardingRule{UserNameFilter: "svc_", UserNameMethod: MethodBegins}, wantReason: `user name "Adm_Backup" does not begin with "svc_"`},
+		{name: "ends", rule: OnboardingRule{AddressFilter: ".corp.example.com", AddressMethod: MethodEnds}},
+		{name: "ends mismatch", rule: OnboardingRule{AddressFilter: ".lab.example.com", AddressMethod: MethodEnds}, wantReason: `does not end with ".lab.example.com"`},
+		{name: "contains", rule: OnboardingRule{AddressFilter: "corp", AddressMethod: MethodContains}},
+		{name: "contains mismatch", rule: OnboardingRule{AddressFilter: "dmz", AddressMethod: MethodContains}, wantReason: `does not contain "dmz"`},
+		{name: "system type", rule: OnboardingRule{SystemTypeFilter: SystemTypeUnix}, wantReason: "system type is Windows, rule requires Unix"},
+		{name: "machine type any", rule: OnboardingRule{MachineTypeFilter: MachineTypeAny}},
+		{name: "machine type", rule: OnboardingRule{MachineTypeFilter: MachineTypeWorkstation}, wantReason: "machine type is Server, rule requires Workstation"},
+		{name: "privileged", rule: OnboardingRule{AccountCategoryFilter: AccountCategoryPrivileged}},
+		{name: "non-privileged", rule: OnboardingRule{AccountCategoryFilter: AccountCategoryNonPrivileged}, wantReason: "account is privileged"},
+		{name: "admin ID", rule: OnboardingRule{IsAdminIDFilter: true}, wantReason: "does not have an administrator ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons := tt.rule.MismatchReasons(account)
+			if tt.wantReason == "" {
+				if len(reasons) != 0 {
+					t.Errorf("MismatchReasons() = %v, want a match", reasons)
+				}
+				return
+			}
+			if len(reasons) != 1 || !strings.Contains(reasons[0], tt.wantReason) {
+				t.Errorf("MismatchReasons() = %v, want %q", reasons, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestOnboardingRule_MismatchReasons_UnixIgnoresMachineType(t *testing.T) {
+	rule := OnboardingRule{MachineTypeFilter: MachineTypeServer}
+	if reasons := rule.MismatchReasons(DiscoveredAccount{PlatformType: "Unix"}); len(reasons) != 0 {
+		t.Errorf("MismatchReasons() = %v, want a match", reasons)
+	}
+	if reasons := rule.MismatchReasons(DiscoveredAccount{PlatformType: "Windows Domain"}); len(reasons) != 1 || !strings.Contains(reasons[0], "unknown") {
+		t.Errorf("MismatchReasons() = %v, want unknown machine type", reasons)
+	}
+}
+
+func TestSortByPrecedence(t *testing.T) {
+	rules := []OnboardingRule{
+		{RuleID: 1, RulePrecedence: 0},
+		{RuleID: 2, RulePrecedence: 2},
+		{RuleID: 3, RulePrecedence: 1},
+		{RuleID: 4, RulePrecedence: 2},
+	}
+	SortByPrecedence(rules)
+
+	want := []int{3, 2, 4, 1}
+	for i, rule := range rules {
+		if rule.RuleID != want[i] {
+			t.Fatalf("order = %v, want rule IDs %v", rules, want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rules := []OnboardingRule{
+		{RuleID: 1, RuleName: "Catch-all", RulePrecedence: 3},
+		{RuleID: 2, RuleName: "Server admins", RulePrecedence: 1, MachineTypeFilter: MachineTypeServer, UserNameFilter: "adm_", UserNameMethod: MethodBegins},
+		{RuleID: 3, RuleName: "Lab", RulePrecedence: 2, AddressFilter: ".lab.example.com", AddressMethod: MethodEnds},
+	}
+
+	t.Run("highest precedence match wins", func(t *testing.T) {
+		eval := Evaluate(rules, DiscoveredAccount{UserName: "adm_jsmith", Address: "pc42.lab.example.com", PlatformType: "Windows Desktop Local"})
+		if eval.Match == nil || eval.Match.RuleName != "Lab" {
+			t.Fatalf("Match = %+v, want Lab", eval.Match)
+		}
+		if len(eval.Results) != 3 {
+			t.Fatalf("Results = %d, want 3", len(eval.Results))
+		}
+
+		// Results are in evaluation order and explain every other rule
+		first, second, third := eval.Results[0], eval.Results[1], eval.Results[2]
+		if first.Rule.RuleName != "Server admins" || first.Matched || !strings.Contains(first.Reasons[0], "machine type is Workstation") {
+			t.Errorf("first result = %+v", first)
+		}
+		if !second.Matched || len(second.Reasons) != 0 {
+			t.Errorf("second result = %+v", second)
+		}
+		if third.Matched || len(third.Reasons) != 1 || third.Reasons[0] != `rule "Lab" has higher precedence` {
+			t.Errorf("third result = %+v", third)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		eval := Evaluate(rules[1:], DiscoveredAccount{UserName: "svc_web", Address: "web01.example.com", PlatformType: "Windows Server Local"})
+		if eval.Match != nil {
+			t.Fatalf("Match = %+v, want nil", eval.Match)
+		}
+		for _, result := range eval.Results {
+			if result.Matched || len(result.Reasons) == 0 {
+				t.Errorf("result = %+v, want an explained mismatch", result)
+			}
+		}
+	})
+
+	t.Run("rules are not reordered", func(t *testing.T) {
+		Evaluate(rules, DiscoveredAccount{})
+		if rules[0].RuleID != 1 {
+			t.Errorf("Evaluate() reordered its input: %+v", rules)
+		}
+	})
+}
+
+func TestEvaluation_JSON(t *testing.T) {
+	rules := []OnboardingRule{{RuleID: 1, RuleName: "Unix", SystemTypeFilter: SystemTypeUnix}}
+	data, err := json.Marshal(Evaluate(rules, DiscoveredAccount{ID: "a1", PlatformType: "Windows Server Local"}))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if _, ok := decoded["match"]; ok {
+		t.Errorf("unmatched evaluation has a match: %s", data)
+	}
+	for _, key := range []string{"account", "results"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("evaluation is missing %q: %s", key, data)
+		}
+	}
+	for _, key := range []string{`"rule":`, `"matched":false`, `"reasons":["system type is Windows`} {
+		if !strings.Contains(string(decoded["results"]), key) {
+			t.Errorf("results = %s, want %s", decoded["results"], key)
+		}
+	}
+}
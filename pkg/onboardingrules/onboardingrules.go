@@ -7,8 +7,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/url"
+	"strconv"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -42,7 +46,11 @@ type OnboardingRulesResponse struct {
 // This is equivalent to Get-PASOnboardingRule in psPAS.
 func List(ctx context.Context, sess *session.Session) ([]OnboardingRule, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.List", MinVersion: "10.2"}); err != nil {
+		return nil, err
 	}
 
 	resp, err := sess.Client.Get(ctx, "/AutomaticOnboardingRules", nil)
@@ -61,7 +69,11 @@ func List(ctx context.Context, sess *session.Session) ([]OnboardingRule, error)
 // Get retrieves a specific onboarding rule.
 func Get(ctx context.Context, sess *session.Session, ruleID int) (*OnboardingRule, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.Get", MinVersion: "10.2"}); err != nil {
+		return nil, err
 	}
 
 	resp, err := sess.Client.Get(ctx, fmt.Sprintf("/AutomaticOnboardingRules/%d", ruleID), nil)
@@ -100,7 +112,11 @@ type CreateOptions struct {
 // This is equivalent to New-PASOnboardingRule in psPAS.
 func Create(ctx context.Context, sess *session.Session, opts CreateOptions) (*OnboardingRule, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.Create", MinVersion: "10.2"}); err != nil {
+		return nil, err
 	}
 
 	if opts.RuleName == "" {
@@ -149,7 +165,11 @@ type UpdateOptions struct {
 // This is equivalent to Set-PASOnboardingRule in psPAS.
 func Update(ctx context.Context, sess *session.Session, ruleID int, opts UpdateOptions) (*OnboardingRule, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.Update", MinVersion: "10.2"}); err != nil {
+		return nil, err
 	}
 
 	resp, err := sess.Client.Put(ctx, fmt.Sprintf("/AutomaticOnboardingRules/%d", ruleID), opts)
@@ -169,7 +189,11 @@ func Update(ctx context.Context, sess *session.Session, ruleID int, opts UpdateO
 // This is equivalent to Remove-PASOnboardingRule in psPAS.
 func Delete(ctx context.Context, sess *session.Session, ruleID int) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.Delete", MinVersion: "10.2"}); err != nil {
+		return err
 	}
 
 	_, err := sess.Client.Delete(ctx, fmt.Sprintf("/AutomaticOnboardingRules/%d", ruleID))
@@ -199,7 +223,18 @@ type DiscoveredAccount struct {
 	Description                string                 `json:"description,omitempty"`
 	PasswordExpirationDateTime int64                  `json:"passwordExpirationDateTime,omitempty"`
 	OU                         string                 `json:"ou,omitempty"`
+	OSFamily                   string                 `json:"osFamily,omitempty"`
 	Dependencies               []DiscoveredDependency `json:"dependencies,omitempty"`
+
+	PlatformTypeAccountProperties *PlatformTypeAccountProperties `json:"platformTypeAccountProperties,omitempty"`
+}
+
+// PlatformTypeAccountProperties holds the operating system identifiers of a
+// discovered account.
+type PlatformTypeAccountProperties struct {
+	SID string           `json:"SID,omitempty"`
+	UID types.FlexibleID `json:"UID,omitempty"`
+	GID types.FlexibleID `json:"GID,omitempty"`
 }
 
 // DiscoveredDependency represents a dependency of a discovered account.
@@ -228,7 +263,11 @@ type ListDiscoveredOptions struct {
 // This is equivalent to Get-PASDiscoveredAccount in psPAS.
 func ListDiscoveredAccounts(ctx context.Context, sess *session.Session, opts ListDiscoveredOptions) (*DiscoveredAccountsResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.ListDiscoveredAccounts", MinVersion: "14.3"}); err != nil {
+		return nil, err
 	}
 
 	params := url.Values{}
@@ -238,6 +277,12 @@ func ListDiscoveredAccounts(ctx context.Context, sess *session.Session, opts Lis
 	if opts.Filter != "" {
 		params.Set("filter", opts.Filter)
 	}
+	if opts.Offset > 0 {
+		params.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
 
 	resp, err := sess.Client.Get(ctx, "/DiscoveredAccounts", params)
 	if err != nil {
@@ -252,10 +297,29 @@ func ListDiscoveredAccounts(ctx context.Context, sess *session.Session, opts Lis
 	return &result, nil
 }
 
+// AllDiscoveredAccounts returns an iterator over every discovered account
+// matching opts, reading every page returned by ListDiscoveredAccounts.
+func AllDiscoveredAccounts(ctx context.Context, sess *session.Session, opts ListDiscoveredOptions, pageOpts types.PageOptions) iter.Seq2[DiscoveredAccount, error] {
+	fetch := func(ctx context.Context, offset int) (*helpers.Page[DiscoveredAccount], error) {
+		pageQuery := opts
+		pageQuery.Offset = offset
+		result, err := ListDiscoveredAccounts(ctx, sess, pageQuery)
+		if err != nil {
+			return nil, err
+		}
+		return &helpers.Page[DiscoveredAccount]{Items: result.Value, Total: result.Count, NextLink: result.NextLink}, nil
+	}
+	return helpers.Paginate(ctx, fetch, opts.Offset, pageOpts)
+}
+
 // GetDiscoveredAccount retrieves a specific discovered account by ID.
 func GetDiscoveredAccount(ctx context.Context, sess *session.Session, accountID string) (*DiscoveredAccount, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.GetDiscoveredAccount", MinVersion: "14.3"}); err != nil {
+		return nil, err
 	}
 
 	if accountID == "" {
@@ -300,7 +364,11 @@ type AddDiscoveredAccountOptions struct {
 // This is equivalent to Add-PASDiscoveredAccount in psPAS.
 func AddDiscoveredAccount(ctx context.Context, sess *session.Session, opts AddDiscoveredAccountOptions) (*DiscoveredAccount, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.AddDiscoveredAccount", MinVersion: "10.8"}); err != nil {
+		return nil, err
 	}
 
 	if opts.UserName == "" {
@@ -327,7 +395,11 @@ func AddDiscoveredAccount(ctx context.Context, sess *session.Session, opts AddDi
 // DeleteDiscoveredAccount removes a discovered account from the list.
 func DeleteDiscoveredAccount(ctx context.Context, sess *session.Session, accountID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.DeleteDiscoveredAccount", MinVersion: "14.3"}); err != nil {
+		return err
 	}
 
 	if accountID == "" {
@@ -351,7 +423,11 @@ type ClearDiscoveredAccountsOptions struct {
 // This is equivalent to Clear-PASDiscoveredAccountList in psPAS.
 func ClearDiscoveredAccounts(ctx context.Context, sess *session.Session, opts ClearDiscoveredAccountsOptions) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.ClearDiscoveredAccounts", MinVersion: "11.7"}); err != nil {
+		return err
 	}
 
 	_, err := sess.Client.Delete(ctx, "/DiscoveredAccounts")
@@ -385,7 +461,11 @@ type PublishedAccount struct {
 // This is equivalent to Publish-PASDiscoveredAccount in psPAS.
 func PublishDiscoveredAccount(ctx context.Context, sess *session.Session, opts PublishDiscoveredAccountOptions) (*PublishedAccount, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "onboardingrules.PublishDiscoveredAccount", MinVersion: "14.3"}); err != nil {
+		return nil, err
 	}
 
 	if opts.AccountID == "" {
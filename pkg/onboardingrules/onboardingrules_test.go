@@ -4,8 +4,11 @@ package onboardingrules
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/chrisranney/gopas/internal/client"
@@ -868,6 +871,57 @@ func TestPublishDiscoveredAccount_InvalidSession(t *testing.T) {
 	}
 }
 
+func TestDiscoveredAccounts_UnsupportedVersion(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	sess, server := createTestSession(t, handler)
+	defer server.Close()
+	sess.SetVersion("11.6")
+
+	ctx := context.Background()
+	calls := map[string]func() error{
+		"ListDiscoveredAccounts": func() error {
+			_, err := ListDiscoveredAccounts(ctx, sess, ListDiscoveredOptions{})
+			return err
+		},
+		"GetDiscoveredAccount": func() error {
+			_, err := GetDiscoveredAccount(ctx, sess, "da-1")
+			return err
+		},
+		"DeleteDiscoveredAccount": func() error {
+			return DeleteDiscoveredAccount(ctx, sess, "da-1")
+		},
+		"ClearDiscoveredAccounts": func() error {
+			return ClearDiscoveredAccounts(ctx, sess, ClearDiscoveredAccountsOptions{})
+		},
+		"PublishDiscoveredAccount": func() error {
+			_, err := PublishDiscoveredAccount(ctx, sess, PublishDiscoveredAccountOptions{AccountID: "da-1", SafeName: "Safe"})
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, client.ErrVersionUnsupported) {
+			t.Errorf("%s() error = %v, want ErrVersionUnsupported", name, err)
+		}
+	}
+	if called {
+		t.Error("a request was sent to an unsupported vault")
+	}
+
+	// Clearing the list is older than the other discovered account endpoints
+	sess.SetVersion("12.6")
+	if err := calls["ClearDiscoveredAccounts"](); errors.Is(err, client.ErrVersionUnsupported) {
+		t.Errorf("ClearDiscoveredAccounts() on 12.6 error = %v", err)
+	}
+	if err := calls["PublishDiscoveredAccount"](); !errors.Is(err, client.ErrVersionUnsupported) {
+		t.Errorf("PublishDiscoveredAccount() on 12.6 error = %v, want ErrVersionUnsupported", err)
+	}
+}
+
 func TestOnboardingRule_Struct(t *testing.T) {
 	rule := OnboardingRule{
 		RuleID:                1,
@@ -921,3 +975,63 @@ func TestDiscoveredAccount_Struct(t *testing.T) {
 		t.Errorf("Dependencies length = %v, want 1", len(account.Dependencies))
 	}
 }
+
+func TestAllDiscoveredAccounts(t *testing.T) {
+	const totalItems = 5
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if limit := r.URL.Query().Get("limit"); limit != "2" {
+			t.Errorf("Expected limit=2, got %q", limit)
+		}
+
+		end := offset + 2
+		if end > totalItems {
+			end = totalItems
+		}
+		var page []json.RawMessage
+		for i := offset; i < end; i++ {
+			page = append(page, json.RawMessage(fmt.Sprintf(`{"id": "%d", "userName": "account%d"}`, i, i)))
+		}
+
+		body := map[string]interface{}{"value": page}
+		if end < totalItems {
+			body["nextLink"] = fmt.Sprintf("DiscoveredAccounts?offset=%d&limit=2", end)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+
+	sess, server := createTestSession(t, handler)
+	defer server.Close()
+
+	var got []DiscoveredAccount
+	for item, err := range AllDiscoveredAccounts(context.Background(), sess, ListDiscoveredOptions{Limit: 2}, types.PageOptions{}) {
+		if err != nil {
+			t.Fatalf("AllDiscoveredAccounts() unexpected error: %v", err)
+		}
+		got = append(got, item)
+	}
+	if len(got) != totalItems {
+		t.Fatalf("AllDiscoveredAccounts() yielded %d items, want %d", len(got), totalItems)
+	}
+
+	var limited int
+	for _, err := range AllDiscoveredAccounts(context.Background(), sess, ListDiscoveredOptions{Limit: 2}, types.PageOptions{MaxItems: 3}) {
+		if err != nil {
+			t.Fatalf("AllDiscoveredAccounts() unexpected error: %v", err)
+		}
+		limited++
+	}
+	if limited != 3 {
+		t.Errorf("AllDiscoveredAccounts() with MaxItems yielded %d items, want 3", limited)
+	}
+}
+
+func TestAllDiscoveredAccounts_InvalidSession(t *testing.T) {
+	for _, err := range AllDiscoveredAccounts(context.Background(), nil, ListDiscoveredOptions{Limit: 2}, types.PageOptions{}) {
+		if err == nil {
+			t.Error("AllDiscoveredAccounts() expected error for nil session")
+		}
+	}
+}
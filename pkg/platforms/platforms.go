@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"strconv"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -127,7 +128,7 @@ type ListOptions struct {
 // This is equivalent to Get-PASPlatform in psPAS.
 func List(ctx context.Context, sess *session.Session, opts ListOptions) (*PlatformsResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -161,7 +162,7 @@ func List(ctx context.Context, sess *session.Session, opts ListOptions) (*Platfo
 // This is equivalent to Get-PASPlatform -PlatformID in psPAS.
 func Get(ctx context.Context, sess *session.Session, platformID string) (*Platform, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if platformID == "" {
@@ -185,7 +186,7 @@ func Get(ctx context.Context, sess *session.Session, platformID string) (*Platfo
 // This is equivalent to Enable-PASPlatform in psPAS.
 func Activate(ctx context.Context, sess *session.Session, platformID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if platformID == "" {
@@ -204,7 +205,7 @@ func Activate(ctx context.Context, sess *session.Session, platformID string) err
 // This is equivalent to Disable-PASPlatform in psPAS.
 func Deactivate(ctx context.Context, sess *session.Session, platformID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if platformID == "" {
@@ -223,7 +224,7 @@ func Deactivate(ctx context.Context, sess *session.Session, platformID string) e
 // This is equivalent to Remove-PASPlatform in psPAS.
 func Delete(ctx context.Context, sess *session.Session, platformID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if platformID == "" {
@@ -248,7 +249,7 @@ type DuplicateOptions struct {
 // This is equivalent to Copy-PASPlatform in psPAS.
 func Duplicate(ctx context.Context, sess *session.Session, platformID string, opts DuplicateOptions) (*Platform, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if platformID == "" {
@@ -276,7 +277,7 @@ func Duplicate(ctx context.Context, sess *session.Session, platformID string, op
 // This is equivalent to Export-PASPlatform in psPAS.
 func ExportPlatform(ctx context.Context, sess *session.Session, platformID string) ([]byte, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if platformID == "" {
@@ -295,7 +296,7 @@ func ExportPlatform(ctx context.Context, sess *session.Session, platformID strin
 // This is equivalent to Import-PASPlatform in psPAS.
 func ImportPlatform(ctx context.Context, sess *session.Session, platformZip []byte) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if len(platformZip) == 0 {
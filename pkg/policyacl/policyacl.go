@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -33,7 +34,7 @@ type PolicyACLResponse struct {
 // This is equivalent to Get-PASPolicyACL in psPAS.
 func List(ctx context.Context, sess *session.Session, policyID string) ([]PolicyACL, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if policyID == "" {
@@ -67,7 +68,7 @@ type AddOptions struct {
 // This is equivalent to Add-PASPolicyACL in psPAS.
 func Add(ctx context.Context, sess *session.Session, policyID string, opts AddOptions) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if policyID == "" {
@@ -93,7 +94,7 @@ func Add(ctx context.Context, sess *session.Session, policyID string, opts AddOp
 // This is equivalent to Remove-PASPolicyACL in psPAS.
 func Remove(ctx context.Context, sess *session.Session, policyID string, aclID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if policyID == "" {
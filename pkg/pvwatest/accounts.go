@@ -0,0 +1,333 @@
+package pvwatest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisranney/gopas/pkg/accounts"
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+// AddAccount stores an account directly, as if it had been created through
+// the API, and returns it. The safe must exist.
+func (s *Server) AddAccount(opts accounts.CreateOptions) accounts.Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, code, message := s.createAccountLocked(opts)
+	if a == nil {
+		panic(fmt.Sprintf("pvwatest: AddAccount: %s %s", code, message))
+	}
+	return a.Account
+}
+
+// Secret returns the current password or key of an account, so tests can
+// check the effect of password changes.
+func (s *Server) Secret(accountID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.accounts[accountID]
+	if !ok {
+		return "", false
+	}
+	return a.secret, true
+}
+
+// createAccountLocked validates and stores a new account. On failure it
+// returns the error code and message to report.
+func (s *Server) createAccountLocked(opts accounts.CreateOptions) (*account, string, string) {
+	if opts.SafeName == "" || opts.PlatformID == "" {
+		return nil, "PASWS167E", "There are some invalid parameters: safeName and platformId are required."
+	}
+	sf, ok := s.safes[strings.ToLower(opts.SafeName)]
+	if !ok {
+		return nil, "SFWS0007", "Safe [" + opts.SafeName + "] was not found."
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = fmt.Sprintf("%s-%s-%s", opts.PlatformID, opts.Address, opts.UserName)
+	}
+	for _, a := range s.accounts {
+		if strings.EqualFold(a.SafeName, sf.SafeName) && strings.EqualFold(a.Name, name) {
+			return nil, "PASWS027E", "Object [" + name + "] already exists in safe [" + sf.SafeName + "]."
+		}
+	}
+
+	secretType := opts.SecretType
+	if secretType == "" {
+		secretType = "password"
+	}
+	management := opts.SecretManagement
+	if management == nil {
+		management = &accounts.SecretManagement{AutomaticManagementEnabled: true}
+	}
+
+	a := &account{
+		Account: accounts.Account{
+			ID:                        types.FlexibleID(fmt.Sprintf("%d_%d", sf.SafeNumber, s.newID())),
+			Name:                      name,
+			Address:                   opts.Address,
+			UserName:                  opts.UserName,
+			PlatformID:                types.FlexibleID(opts.PlatformID),
+			SafeName:                  sf.SafeName,
+			SecretType:                secretType,
+			PlatformAccountProperties: opts.PlatformAccountProperties,
+			SecretManagement:          management,
+			RemoteMachinesAccess:      opts.RemoteMachinesAccess,
+			CreatedTime:               time.Now().Unix(),
+		},
+		secret: opts.Secret,
+	}
+	s.accounts[string(a.ID)] = a
+	return a, "", ""
+}
+
+// findAccount returns the account named in the request path, writing a
+// not found error if there is none.
+func (s *Server) findAccount(w http.ResponseWriter, r *http.Request) *account {
+	id := r.PathValue("id")
+	a, ok := s.accounts[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "PASWS164E", "Account ["+id+"] was not found.")
+		return nil
+	}
+	return a
+}
+
+// logActivity appends an entry to the account's activity log.
+func (a *account) logActivity(caller *user, action, reason string) {
+	a.activities = append(a.activities, accounts.AccountActivity{
+		Time:     time.Now().Unix(),
+		Action:   action,
+		ClientID: "PVWA",
+		Reason:   reason,
+		UserName: caller.Username,
+	})
+}
+
+func (s *Server) listAccounts(w http.ResponseWriter, r *http.Request, caller *user) {
+	query := r.URL.Query()
+	search := query.Get("search")
+	safeName := ""
+	if filter := query.Get("filter"); filter != "" {
+		field, value, _ := strings.Cut(filter, " eq ")
+		if !strings.EqualFold(strings.TrimSpace(field), "safeName") {
+			writeError(w, http.StatusBadRequest, "PASWS167E", "There are some invalid parameters: unsupported filter ["+filter+"].")
+			return
+		}
+		safeName = strings.TrimSpace(value)
+	}
+
+	matched := []accounts.Account{}
+	for _, a := range s.accounts {
+		if safeName != "" && !strings.EqualFold(a.SafeName, safeName) {
+			continue
+		}
+		if !matchesSearch(search, a.Name, a.Address, a.UserName) {
+			continue
+		}
+		matched = append(matched, a.Account)
+	}
+	sort.Slice(matched, func(i, j int) bool { return accountIDLess(matched[i].ID, matched[j].ID) })
+
+	items, next := page(r, matched)
+	writeJSON(w, http.StatusOK, accounts.AccountsResponse{Value: items, Count: len(matched), NextLink: next})
+}
+
+// accountIDLess orders account IDs by creation.
+func accountIDLess(a, b types.FlexibleID) bool {
+	_, sa, _ := strings.Cut(string(a), "_")
+	_, sb, _ := strings.Cut(string(b), "_")
+	na, _ := strconv.Atoi(sa)
+	nb, _ := strconv.Atoi(sb)
+	return na < nb
+}
+
+func (s *Server) createAccount(w http.ResponseWriter, r *http.Request, caller *user) {
+	var opts accounts.CreateOptions
+	if !decodeBody(w, r, &opts) {
+		return
+	}
+
+	a, code, message := s.createAccountLocked(opts)
+	if a == nil {
+		status := http.StatusBadRequest
+		switch code {
+		case "SFWS0007":
+			status = http.StatusNotFound
+		case "PASWS027E":
+			status = http.StatusConflict
+		}
+		writeError(w, status, code, message)
+		return
+	}
+	a.logActivity(caller, "Store password", "")
+	writeJSON(w, http.StatusCreated, a.Account)
+}
+
+func (s *Server) getAccount(w http.ResponseWriter, r *http.Request, caller *user) {
+	if a := s.findAccount(w, r); a != nil {
+		writeJSON(w, http.StatusOK, a.Account)
+	}
+}
+
+func (s *Server) updateAccount(w http.ResponseWriter, r *http.Request, caller *user) {
+	a := s.findAccount(w, r)
+	if a == nil {
+		return
+	}
+
+	var operations []accounts.PatchOperation
+	if !decodeBody(w, r, &operations) {
+		return
+	}
+	for _, op := range operations {
+		if err := a.applyPatch(op); err != nil {
+			writeError(w, http.StatusBadRequest, "PASWS167E", "There are some invalid parameters: "+err.Error())
+			return
+		}
+	}
+	a.logActivity(caller, "Update account properties", "")
+	writeJSON(w, http.StatusOK, a.Account)
+}
+
+// applyPatch applies a JSON Patch operation to the account's properties.
+func (a *account) applyPatch(op accounts.PatchOperation) error {
+	if op.Op != "replace" && op.Op != "add" && op.Op != "remove" {
+		return fmt.Errorf("unsupported operation [%s]", op.Op)
+	}
+	value := ""
+	if op.Value != nil {
+		value = fmt.Sprint(op.Value)
+	}
+
+	path := strings.TrimPrefix(op.Path, "/")
+	switch {
+	case path == "name":
+		a.Name = value
+	case path == "address":
+		a.Address = value
+	case path == "userName":
+		a.UserName = value
+	case path == "platformId":
+		a.PlatformID = types.FlexibleID(value)
+	case strings.HasPrefix(path, "platformAccountProperties/"):
+		key := strings.TrimPrefix(path, "platformAccountProperties/")
+		if op.Op == "remove" {
+			delete(a.PlatformAccountProperties, key)
+			break
+		}
+		if a.PlatformAccountProperties == nil {
+			a.PlatformAccountProperties = make(map[string]interface{})
+		}
+		a.PlatformAccountProperties[key] = op.Value
+	case path == "secretManagement/automaticManagementEnabled":
+		a.SecretManagement.AutomaticManagementEnabled = value == "true"
+	case path == "secretManagement/manualManagementReason":
+		a.SecretManagement.ManualManagementReason = value
+	default:
+		return fmt.Errorf("unsupported path [%s]", op.Path)
+	}
+	return nil
+}
+
+func (s *Server) deleteAccount(w http.ResponseWriter, r *http.Request, caller *user) {
+	if a := s.findAccount(w, r); a != nil {
+		delete(s.accounts, string(a.ID))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) retrievePassword(w http.ResponseWriter, r *http.Request, caller *user) {
+	a := s.findAccount(w, r)
+	if a == nil {
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	a.logActivity(caller, "Retrieve password", body.Reason)
+	writeJSON(w, http.StatusOK, a.secret)
+}
+
+func (s *Server) changePassword(w http.ResponseWriter, r *http.Request, caller *user) {
+	a := s.findAccount(w, r)
+	if a == nil {
+		return
+	}
+	a.secret = newPassword()
+	a.SecretManagement.Status = "success"
+	a.SecretManagement.LastModifiedTime = time.Now().Unix()
+	a.logActivity(caller, "CPM Change Password", "")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) setNextPassword(w http.ResponseWriter, r *http.Request, caller *user) {
+	a := s.findAccount(w, r)
+	if a == nil {
+		return
+	}
+
+	var body struct {
+		NewCredentials string `json:"NewCredentials"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.NewCredentials == "" {
+		writeError(w, http.StatusBadRequest, "PASWS167E", "There are some invalid parameters: NewCredentials is required.")
+		return
+	}
+	a.secret = body.NewCredentials
+	a.SecretManagement.LastModifiedTime = time.Now().Unix()
+	a.logActivity(caller, "CPM Change Password", "")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) verifyAccount(w http.ResponseWriter, r *http.Request, caller *user) {
+	if a := s.findAccount(w, r); a != nil {
+		a.SecretManagement.LastVerifiedTime = time.Now().Unix()
+		a.logActivity(caller, "CPM Verify Password", "")
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *Server) reconcileAccount(w http.ResponseWriter, r *http.Request, caller *user) {
+	if a := s.findAccount(w, r); a != nil {
+		a.secret = newPassword()
+		a.SecretManagement.LastReconciledTime = time.Now().Unix()
+		a.logActivity(caller, "CPM Reconcile Password", "")
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *Server) accountActivities(w http.ResponseWriter, r *http.Request, caller *user) {
+	if a := s.findAccount(w, r); a != nil {
+		activities := append([]accounts.AccountActivity{}, a.activities...)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"Activities": activities})
+	}
+}
+
+// newPassword returns a random password, as the CPM would generate.
+func newPassword() string {
+	const alphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789!@#$%"
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
@@ -0,0 +1,212 @@
+package pvwatest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chrisranney/gopas/pkg/applications"
+	"github.com/chrisranney/gopas/pkg/types"
+	"github.com/chrisranney/gopas/pkg/users"
+)
+
+type application struct {
+	applications.Application
+	auths []applications.AuthMethod
+}
+
+// AddApplication stores an application directly, as if it had been created
+// through the API, and returns it. Like the Vault, the server also creates
+// a user for the application so it can be added to safes.
+func (s *Server) AddApplication(opts applications.CreateOptions) applications.Application {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, code, message := s.createApplicationLocked(opts)
+	if app == nil {
+		panic(fmt.Sprintf("pvwatest: AddApplication: %s %s", code, message))
+	}
+	return app.Application
+}
+
+// createApplicationLocked validates and stores a new application. On
+// failure it returns the error code and message to report.
+func (s *Server) createApplicationLocked(opts applications.CreateOptions) (*application, string, string) {
+	if opts.AppID == "" {
+		return nil, "PASWS167E", "There are some invalid parameters: AppID is required."
+	}
+	if s.findApplication(opts.AppID) != nil || s.findUser(opts.AppID) != nil {
+		return nil, "APPAP008E", "Application [" + opts.AppID + "] already exists."
+	}
+
+	location := opts.Location
+	if location == "" {
+		location = "\\"
+	}
+	app := &application{Application: applications.Application{
+		AppID:               opts.AppID,
+		Description:         opts.Description,
+		Location:            location,
+		AccessPermittedFrom: opts.AccessPermittedFrom,
+		AccessPermittedTo:   opts.AccessPermittedTo,
+		ExpirationDate:      opts.ExpirationDate,
+		Disabled:            opts.Disabled,
+		BusinessOwnerFName:  opts.BusinessOwnerFName,
+		BusinessOwnerLName:  opts.BusinessOwnerLName,
+		BusinessOwnerEmail:  opts.BusinessOwnerEmail,
+		BusinessOwnerPhone:  opts.BusinessOwnerPhone,
+	}}
+	s.createUserLocked(users.CreateOptions{Username: opts.AppID, UserType: "Application", Location: location})
+	s.applications = append(s.applications, app)
+	return app, "", ""
+}
+
+// findApplication returns the application with the given ID, or nil.
+func (s *Server) findApplication(appID string) *application {
+	for _, app := range s.applications {
+		if strings.EqualFold(app.AppID, appID) {
+			return app
+		}
+	}
+	return nil
+}
+
+// lookupApplication returns the application in the request path, writing
+// a not found error if there is none.
+func (s *Server) lookupApplication(w http.ResponseWriter, r *http.Request) *application {
+	id := r.PathValue("id")
+	app := s.findApplication(id)
+	if app == nil {
+		writeError(w, http.StatusNotFound, "APPAP004E", "Application ["+id+"] was not found.")
+	}
+	return app
+}
+
+func (s *Server) listApplications(w http.ResponseWriter, r *http.Request, caller *user) {
+	query := r.URL.Query()
+	location := query.Get("location")
+	sublocations := query.Get("includeSublocations") == "true"
+
+	matched := []applications.Application{}
+	for _, app := range s.applications {
+		if location != "" && !strings.EqualFold(app.Location, location) {
+			prefix := strings.TrimSuffix(location, "\\") + "\\"
+			if !sublocations || !strings.HasPrefix(strings.ToLower(app.Location), strings.ToLower(prefix)) {
+				continue
+			}
+		}
+		matched = append(matched, app.Application)
+	}
+	writeJSON(w, http.StatusOK, applications.ApplicationsResponse{Applications: matched})
+}
+
+func (s *Server) createApplication(w http.ResponseWriter, r *http.Request, caller *user) {
+	var body struct {
+		Application applications.CreateOptions `json:"application"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	app, code, message := s.createApplicationLocked(body.Application)
+	if app == nil {
+		status := http.StatusBadRequest
+		if code == "APPAP008E" {
+			status = http.StatusConflict
+		}
+		writeError(w, status, code, message)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"application": app.Application})
+}
+
+func (s *Server) getApplication(w http.ResponseWriter, r *http.Request, caller *user) {
+	if app := s.lookupApplication(w, r); app != nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"application": app.Application})
+	}
+}
+
+func (s *Server) deleteApplication(w http.ResponseWriter, r *http.Request, caller *user) {
+	app := s.lookupApplication(w, r)
+	if app == nil {
+		return
+	}
+	for i, a := range s.applications {
+		if a == app {
+			s.applications = append(s.applications[:i], s.applications[i+1:]...)
+			break
+		}
+	}
+	if u := s.findUser(app.AppID); u != nil {
+		delete(s.users, u.ID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listAuthMethods(w http.ResponseWriter, r *http.Request, caller *user) {
+	app := s.lookupApplication(w, r)
+	if app == nil {
+		return
+	}
+	auths := append([]applications.AuthMethod{}, app.auths...)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"authentication": auths})
+}
+
+func (s *Server) addAuthMethod(w http.ResponseWriter, r *http.Request, caller *user) {
+	app := s.lookupApplication(w, r)
+	if app == nil {
+		return
+	}
+
+	var body struct {
+		Authentication applications.AddAuthMethodOptions `json:"authentication"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	opts := body.Authentication
+	if opts.AuthType == "" {
+		writeError(w, http.StatusBadRequest, "PASWS167E", "There are some invalid parameters: AuthType is required.")
+		return
+	}
+	if opts.AuthValue == "" && !strings.EqualFold(opts.AuthType, applications.AuthTypeCertificateAttribute) {
+		writeError(w, http.StatusBadRequest, "PASWS167E", "There are some invalid parameters: AuthValue is required.")
+		return
+	}
+	for _, auth := range app.auths {
+		if strings.EqualFold(auth.AuthType, opts.AuthType) && auth.AuthValue == opts.AuthValue && opts.AuthValue != "" {
+			writeError(w, http.StatusConflict, "APPAP010E", "Authentication ["+opts.AuthType+"] ["+opts.AuthValue+"] already exists for application ["+app.AppID+"].")
+			return
+		}
+	}
+
+	app.auths = append(app.auths, applications.AuthMethod{
+		AuthID:                 types.FlexibleID(strconv.Itoa(s.newID())),
+		AppID:                  app.AppID,
+		AuthType:               opts.AuthType,
+		AuthValue:              opts.AuthValue,
+		Comment:                opts.Comment,
+		IsFolder:               opts.IsFolder,
+		AllowInternalScripts:   opts.AllowInternalScripts,
+		Subject:                opts.Subject,
+		Issuer:                 opts.Issuer,
+		SubjectAlternativeName: opts.SubjectAlternativeName,
+	})
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) removeAuthMethod(w http.ResponseWriter, r *http.Request, caller *user) {
+	app := s.lookupApplication(w, r)
+	if app == nil {
+		return
+	}
+	authID := r.PathValue("authID")
+	for i, auth := range app.auths {
+		if auth.AuthID.String() == authID {
+			app.auths = append(app.auths[:i], app.auths[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "APPAP007E", "Authentication ["+authID+"] was not found for application ["+app.AppID+"].")
+}
@@ -0,0 +1,123 @@
+package pvwatest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fault is an error response the server returns in place of handling a
+// matching request.
+type Fault struct {
+	// Method restricts the fault to one HTTP method. Empty matches any.
+	Method string
+
+	// Path restricts the fault to requests whose path below
+	// /PasswordVault/API starts with it, such as "/Accounts". Empty
+	// matches any.
+	Path string
+
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+
+	// ErrorCode and ErrorMessage form the error body. They default to a
+	// code and message matching the status code.
+	ErrorCode    string
+	ErrorMessage string
+
+	// RetryAfter sets the Retry-After header of a 429 or 503 response.
+	RetryAfter time.Duration
+
+	// Times is the number of matching requests that fail before the fault
+	// is removed. Zero fails every matching request until ClearFaults.
+	Times int
+}
+
+// TooManyRequests returns a fault that throttles the next times requests
+// with 429 Too Many Requests.
+func TooManyRequests(times int, retryAfter time.Duration) Fault {
+	return Fault{
+		StatusCode:   http.StatusTooManyRequests,
+		ErrorCode:    "PASWS232E",
+		ErrorMessage: "Too many requests. Try again later.",
+		RetryAfter:   retryAfter,
+		Times:        times,
+	}
+}
+
+// faultState tracks how many more requests a fault applies to.
+type faultState struct {
+	Fault
+	remaining int
+}
+
+// InjectFault adds a fault. Faults are matched in the order they were added.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &faultState{Fault: f, remaining: f.Times})
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// takeFault returns the first fault matching r, consuming one of its uses.
+func (s *Server) takeFault(r *http.Request) *Fault {
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	for i, f := range s.faults {
+		if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+			continue
+		}
+		if f.Path != "" && !strings.HasPrefix(strings.ToLower(path), strings.ToLower(f.Path)) {
+			continue
+		}
+		if f.Times > 0 {
+			f.remaining--
+			if f.remaining <= 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		fault := f.Fault
+		return &fault
+	}
+	return nil
+}
+
+// write sends the fault's error response.
+func (f *Fault) write(w http.ResponseWriter) {
+	status := f.StatusCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	code, message := f.ErrorCode, f.ErrorMessage
+	if code == "" {
+		switch status {
+		case http.StatusUnauthorized:
+			code = "PASWS006E"
+		case http.StatusTooManyRequests:
+			code = "PASWS232E"
+		default:
+			code = "PASWS001E"
+		}
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+
+	if f.RetryAfter > 0 || status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", retryAfterSeconds(f.RetryAfter))
+	}
+	writeError(w, status, code, message)
+}
@@ -0,0 +1,142 @@
+package pvwatest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chrisranney/gopas/pkg/platforms"
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+// AddPlatform stores a platform and returns it with its numeric ID
+// assigned. PlatformID and Name are required.
+func (s *Server) AddPlatform(p platforms.Platform) platforms.Platform {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.PlatformID == "" || p.Name == "" {
+		panic("pvwatest: AddPlatform: PlatformID and Name are required")
+	}
+	if s.findPlatform(string(p.PlatformID)) != nil {
+		panic(fmt.Sprintf("pvwatest: AddPlatform: platform %s already exists", p.PlatformID))
+	}
+	p.ID = types.FlexibleID(strconv.Itoa(s.newID()))
+	s.platforms = append(s.platforms, &p)
+	return p
+}
+
+// findPlatform returns the platform with the given numeric ID or platform
+// ID, or nil.
+func (s *Server) findPlatform(id string) *platforms.Platform {
+	for _, p := range s.platforms {
+		if string(p.ID) == id || strings.EqualFold(string(p.PlatformID), id) {
+			return p
+		}
+	}
+	return nil
+}
+
+// lookupPlatform returns the platform in the request path, writing a not
+// found error if there is none.
+func (s *Server) lookupPlatform(w http.ResponseWriter, r *http.Request) *platforms.Platform {
+	id := r.PathValue("id")
+	p := s.findPlatform(id)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "PASWS182E", "Platform ["+id+"] was not found.")
+	}
+	return p
+}
+
+func (s *Server) listPlatforms(w http.ResponseWriter, r *http.Request, caller *user) {
+	query := r.URL.Query()
+	search := query.Get("search")
+
+	matched := []platforms.Platform{}
+	for _, p := range s.platforms {
+		if active := query.Get("active"); active != "" && strconv.FormatBool(p.Active) != active {
+			continue
+		}
+		if platformType := query.Get("platformType"); platformType != "" && !strings.EqualFold(p.PlatformType, platformType) {
+			continue
+		}
+		if systemType := query.Get("systemType"); systemType != "" && !strings.EqualFold(p.SystemType, systemType) {
+			continue
+		}
+		if !matchesSearch(search, string(p.PlatformID), p.Name, p.Description) {
+			continue
+		}
+		matched = append(matched, *p)
+	}
+	writeJSON(w, http.StatusOK, platforms.PlatformsResponse{Platforms: matched, Total: len(matched)})
+}
+
+func (s *Server) getPlatform(w http.ResponseWriter, r *http.Request, caller *user) {
+	if p := s.lookupPlatform(w, r); p != nil {
+		writeJSON(w, http.StatusOK, p)
+	}
+}
+
+func (s *Server) deletePlatform(w http.ResponseWriter, r *http.Request, caller *user) {
+	p := s.lookupPlatform(w, r)
+	if p == nil {
+		return
+	}
+	for _, a := range s.accounts {
+		if strings.EqualFold(string(a.PlatformID), string(p.PlatformID)) {
+			writeError(w, http.StatusConflict, "PASWS183E", "Platform ["+string(p.PlatformID)+"] is in use by accounts and cannot be deleted.")
+			return
+		}
+	}
+	for i, platform := range s.platforms {
+		if platform == p {
+			s.platforms = append(s.platforms[:i], s.platforms[i+1:]...)
+			break
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) activatePlatform(w http.ResponseWriter, r *http.Request, caller *user) {
+	if p := s.lookupPlatform(w, r); p != nil {
+		p.Active = true
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *Server) deactivatePlatform(w http.ResponseWriter, r *http.Request, caller *user) {
+	if p := s.lookupPlatform(w, r); p != nil {
+		p.Active = false
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// exportPlatform returns a zip package holding the platform definition.
+func (s *Server) exportPlatform(w http.ResponseWriter, r *http.Request, caller *user) {
+	p := s.lookupPlatform(w, r)
+	if p == nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("Policy-" + string(p.PlatformID) + ".json")
+	if err == nil {
+		err = json.NewEncoder(f).Encode(p)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "PASWS001E", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
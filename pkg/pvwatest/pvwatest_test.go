@@ -0,0 +1,452 @@
+// Package pvwatest provides tests for the in-memory PVWA.
+package pvwatest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/accounts"
+	"github.com/chrisranney/gopas/pkg/applications"
+	"github.com/chrisranney/gopas/pkg/authentication"
+	"github.com/chrisranney/gopas/pkg/platforms"
+	"github.com/chrisranney/gopas/pkg/requests"
+	"github.com/chrisranney/gopas/pkg/safemembers"
+	"github.com/chrisranney/gopas/pkg/safes"
+	"github.com/chrisranney/gopas/pkg/types"
+	"github.com/chrisranney/gopas/pkg/users"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func logon(t *testing.T, srv *Server, username, password string, configure ...func(*authentication.SessionOptions)) *session.Session {
+	t.Helper()
+	opts := authentication.SessionOptions{
+		BaseURL:     srv.URL,
+		Credentials: authentication.Credentials{Username: username, Password: password},
+	}
+	for _, fn := range configure {
+		fn(&opts)
+	}
+	sess, err := authentication.NewSession(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("NewSession(%s) error: %v", username, err)
+	}
+	return sess
+}
+
+func TestServer_Logon(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetVersion("13.2")
+
+	sess := logon(t, srv, DefaultUsername, DefaultPassword)
+	if sess.ExternalVersion != "13.2" {
+		t.Errorf("ExternalVersion = %q, want 13.2", sess.ExternalVersion)
+	}
+
+	_, err := authentication.NewSession(context.Background(), authentication.SessionOptions{
+		BaseURL:     srv.URL,
+		Credentials: authentication.Credentials{Username: DefaultUsername, Password: "wrong"},
+	})
+	apiErr, ok := client.AsAPIError(err)
+	if !ok || apiErr.ErrorCode != "ITATS004E" || !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("logon with a bad password error = %v, want ITATS004E", err)
+	}
+
+	if err := authentication.CloseSession(context.Background(), sess); err != nil {
+		t.Fatalf("CloseSession() error: %v", err)
+	}
+	calls := srv.Calls()
+	if last := calls[len(calls)-1]; last.Path != "/Auth/Logoff" || last.StatusCode != http.StatusOK {
+		t.Errorf("last call = %+v, want a successful logoff", last)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.sessions) != 0 {
+		t.Errorf("%d sessions remain after logoff", len(srv.sessions))
+	}
+}
+
+func TestServer_Accounts(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	sess := logon(t, srv, DefaultUsername, DefaultPassword)
+
+	if _, err := safes.Create(ctx, sess, safes.CreateOptions{SafeName: "Linux", ManagingCPM: "PasswordManager"}); err != nil {
+		t.Fatalf("safes.Create() error: %v", err)
+	}
+	acct, err := accounts.Create(ctx, sess, accounts.CreateOptions{
+		SafeName: "Linux", PlatformID: "UnixSSH", Address: "web01", UserName: "root", Secret: "initial",
+	})
+	if err != nil {
+		t.Fatalf("accounts.Create() error: %v", err)
+	}
+	if acct.Secret != "" {
+		t.Error("the secret must not be returned")
+	}
+
+	password, err := accounts.GetPassword(ctx, sess, string(acct.ID), "testing")
+	if err != nil || password != "initial" {
+		t.Fatalf("GetPassword() = %q, %v, want initial", password, err)
+	}
+
+	if err := accounts.ChangeCredentialsImmediately(ctx, sess, string(acct.ID), accounts.ChangeCredentialsOptions{}); err != nil {
+		t.Fatalf("ChangeCredentialsImmediately() error: %v", err)
+	}
+	if secret, _ := srv.Secret(string(acct.ID)); secret == "initial" || secret == "" {
+		t.Errorf("secret after change = %q, want a new password", secret)
+	}
+	if err := accounts.SetNextPassword(ctx, sess, string(acct.ID), "next-one"); err != nil {
+		t.Fatalf("SetNextPassword() error: %v", err)
+	}
+	if password, _ := accounts.GetPassword(ctx, sess, string(acct.ID), ""); password != "next-one" {
+		t.Errorf("GetPassword() after SetNextPassword = %q", password)
+	}
+
+	updated, err := accounts.Update(ctx, sess, string(acct.ID), []accounts.PatchOperation{
+		{Op: "replace", Path: "/address", Value: "web02"},
+	})
+	if err != nil || updated.Address != "web02" {
+		t.Fatalf("Update() = %+v, %v", updated, err)
+	}
+
+	activities, err := accounts.GetActivities(ctx, sess, string(acct.ID))
+	if err != nil || len(activities) == 0 {
+		t.Errorf("GetActivities() = %v, %v, want entries", activities, err)
+	}
+
+	_, err = accounts.Create(ctx, sess, accounts.CreateOptions{
+		SafeName: "Linux", PlatformID: "UnixSSH", Address: "web01", UserName: "root",
+	})
+	if !errors.Is(err, client.ErrConflict) {
+		t.Errorf("duplicate account error = %v, want conflict", err)
+	}
+
+	if err := accounts.Delete(ctx, sess, string(acct.ID)); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	_, err = accounts.Get(ctx, sess, string(acct.ID))
+	if apiErr, ok := client.AsAPIError(err); !ok || apiErr.ErrorCode != "PASWS164E" || !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Get() deleted account error = %v, want PASWS164E not found", err)
+	}
+}
+
+func TestServer_AccountPaging(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddSafe(safes.CreateOptions{SafeName: "Windows"})
+	srv.AddSafe(safes.CreateOptions{SafeName: "Linux"})
+	for _, host := range []string{"a", "b", "c", "d", "e"} {
+		srv.AddAccount(accounts.CreateOptions{SafeName: "Linux", PlatformID: "UnixSSH", Address: host, UserName: "root"})
+	}
+	srv.AddAccount(accounts.CreateOptions{SafeName: "Windows", PlatformID: "WinDomain", Address: "dc01", UserName: "admin"})
+	sess := logon(t, srv, DefaultUsername, DefaultPassword)
+
+	var addresses []string
+	for acct, err := range accounts.All(context.Background(), sess, accounts.ListOptions{SafeName: "Linux", Limit: 2}, types.PageOptions{}) {
+		if err != nil {
+			t.Fatalf("All() error: %v", err)
+		}
+		addresses = append(addresses, acct.Address)
+	}
+	if len(addresses) != 5 || addresses[0] != "a" || addresses[4] != "e" {
+		t.Errorf("addresses = %v, want a through e", addresses)
+	}
+
+	list, err := accounts.List(context.Background(), sess, accounts.ListOptions{Search: "dc0"})
+	if err != nil || list.Count != 1 || list.Value[0].SafeName != "Windows" {
+		t.Errorf("search = %+v, %v", list, err)
+	}
+}
+
+func TestServer_SafesAndMembers(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddUser(users.CreateOptions{Username: "jdoe", InitialPassword: "pw"})
+	ctx := context.Background()
+	sess := logon(t, srv, DefaultUsername, DefaultPassword)
+
+	if _, err := safes.Create(ctx, sess, safes.CreateOptions{SafeName: "Linux"}); err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	_, err := safes.Create(ctx, sess, safes.CreateOptions{SafeName: "linux"})
+	if apiErr, ok := client.AsAPIError(err); !ok || apiErr.ErrorCode != "SFWS0002" || !errors.Is(err, client.ErrConflict) {
+		t.Errorf("duplicate safe error = %v, want SFWS0002", err)
+	}
+
+	if _, err := safemembers.Add(ctx, sess, "Linux", safemembers.AddOptions{
+		MemberName: "jdoe", SearchIn: "Vault", Permissions: safemembers.DefaultUserPermissions(),
+	}); err != nil {
+		t.Fatalf("safemembers.Add() error: %v", err)
+	}
+	if _, err := safemembers.Add(ctx, sess, "Linux", safemembers.AddOptions{MemberName: "ghost", SearchIn: "Vault", Permissions: safemembers.DefaultUserPermissions()}); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("adding an unknown member error = %v, want not found", err)
+	}
+
+	member, err := safemembers.Update(ctx, sess, "Linux", "jdoe", safemembers.UpdateOptions{Permissions: safemembers.DefaultAdminPermissions()})
+	if err != nil || !member.Permissions.ManageSafe {
+		t.Fatalf("safemembers.Update() = %+v, %v", member, err)
+	}
+
+	days := 3
+	updated, err := safes.Update(ctx, sess, "Linux", safes.UpdateOptions{SafeName: "Linux-Prod", NumberOfDaysRetention: &days})
+	if err != nil || updated.SafeName != "Linux-Prod" || updated.NumberOfDaysRetention != 3 {
+		t.Fatalf("safes.Update() = %+v, %v", updated, err)
+	}
+	members, err := safemembers.List(ctx, sess, "Linux-Prod", safemembers.ListOptions{})
+	if err != nil || members.Count != 1 || members.Value[0].SafeName != "Linux-Prod" {
+		t.Errorf("members after rename = %+v, %v", members, err)
+	}
+
+	if err := safes.Delete(ctx, sess, "Linux-Prod"); err != nil {
+		t.Fatalf("safes.Delete() error: %v", err)
+	}
+	if _, err := safes.Get(ctx, sess, "Linux-Prod"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Get() deleted safe error = %v, want not found", err)
+	}
+}
+
+func TestServer_UsersAndGroups(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	sess := logon(t, srv, DefaultUsername, DefaultPassword)
+
+	created, err := users.Create(ctx, sess, users.CreateOptions{Username: "jdoe", InitialPassword: "first"})
+	if err != nil {
+		t.Fatalf("users.Create() error: %v", err)
+	}
+	if _, err := users.Create(ctx, sess, users.CreateOptions{Username: "JDoe"}); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("duplicate user error = %v, want conflict", err)
+	}
+	logon(t, srv, "jdoe", "first")
+
+	if err := users.ResetPassword(ctx, sess, created.ID, "second"); err != nil {
+		t.Fatalf("ResetPassword() error: %v", err)
+	}
+	logon(t, srv, "jdoe", "second")
+
+	group, err := users.CreateGroup(ctx, sess, users.CreateGroupOptions{GroupName: "Auditors"})
+	if err != nil {
+		t.Fatalf("CreateGroup() error: %v", err)
+	}
+	if err := users.AddGroupMember(ctx, sess, group.ID, users.AddGroupMemberOptions{MemberName: "jdoe"}); err != nil {
+		t.Fatalf("AddGroupMember() error: %v", err)
+	}
+	got, err := users.Get(ctx, sess, created.ID)
+	if err != nil || len(got.GroupsMembership) != 1 || got.GroupsMembership[0].GroupName != "Auditors" {
+		t.Errorf("Get() = %+v, %v, want membership of Auditors", got, err)
+	}
+
+	if err := users.Delete(ctx, sess, created.ID); err != nil {
+		t.Fatalf("users.Delete() error: %v", err)
+	}
+	members, err := users.ListGroupMembers(ctx, sess, group.ID)
+	if err != nil || len(members) != 0 {
+		t.Errorf("group members after user deletion = %v, %v", members, err)
+	}
+}
+
+func TestServer_Requests(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddUser(users.CreateOptions{Username: "requester", InitialPassword: "pw"})
+	srv.AddUser(users.CreateOptions{Username: "approver", InitialPassword: "pw"})
+	srv.AddSafe(safes.CreateOptions{SafeName: "Finance"})
+	srv.AddSafeMember("Finance", safemembers.AddOptions{
+		MemberName:  "approver",
+		Permissions: &safemembers.Permissions{ListAccounts: true, RequestsAuthorizationLevel1: true},
+	})
+	acct := srv.AddAccount(accounts.CreateOptions{SafeName: "Finance", PlatformID: "WinDomain", Address: "corp", UserName: "svc"})
+	ctx := context.Background()
+
+	requester := logon(t, srv, "requester", "pw")
+	approver := logon(t, srv, "approver", "pw")
+
+	req, err := requests.Create(ctx, requester, requests.CreateOptions{AccountID: string(acct.ID), Reason: "month end"})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	second := srv.AddRequest("requester", requests.CreateOptions{AccountID: string(acct.ID)})
+
+	incoming, err := requests.ListIncoming(ctx, approver, requests.ListOptions{OnlyWaiting: true})
+	if err != nil || incoming.Total != 2 {
+		t.Fatalf("ListIncoming() = %+v, %v, want 2", incoming, err)
+	}
+	if none, _ := requests.ListIncoming(ctx, requester, requests.ListOptions{}); none.Total != 0 {
+		t.Error("requesters must not see their own requests as incoming")
+	}
+
+	approved, err := requests.Approve(ctx, approver, string(req.RequestID), requests.ApproveOptions{Reason: "ok"})
+	if err != nil || approved.Status != requestConfirmed {
+		t.Fatalf("Approve() = %+v, %v", approved, err)
+	}
+	if _, err := requests.Deny(ctx, approver, string(req.RequestID), requests.DenyOptions{}); err == nil {
+		t.Error("denying a confirmed request should fail")
+	}
+	if _, err := requests.Deny(ctx, approver, string(second.RequestID), requests.DenyOptions{Reason: "no"}); err != nil {
+		t.Fatalf("Deny() error: %v", err)
+	}
+
+	mine, err := requests.ListMyRequests(ctx, requester, requests.ListOptions{})
+	if err != nil || mine.Total != 2 || mine.Requests[0].StatusTitle != "Confirmed" || mine.Requests[1].StatusTitle != "Rejected" {
+		t.Fatalf("ListMyRequests() = %+v, %v", mine, err)
+	}
+	if err := requests.Delete(ctx, requester, string(req.RequestID)); err != nil {
+		t.Errorf("Delete() error: %v", err)
+	}
+}
+
+func TestServer_Platforms(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddPlatform(platforms.Platform{PlatformID: "UnixSSH", Name: "Unix via SSH", Active: true})
+	added := srv.AddPlatform(platforms.Platform{PlatformID: "WinDomain", Name: "Windows Domain"})
+	ctx := context.Background()
+	sess := logon(t, srv, DefaultUsername, DefaultPassword)
+
+	if err := platforms.Activate(ctx, sess, string(added.ID)); err != nil {
+		t.Fatalf("Activate() error: %v", err)
+	}
+	active := true
+	list, err := platforms.List(ctx, sess, platforms.ListOptions{Active: &active})
+	if err != nil || list.Total != 2 {
+		t.Fatalf("List(active) = %+v, %v, want 2", list, err)
+	}
+
+	if err := platforms.Deactivate(ctx, sess, "UnixSSH"); err != nil {
+		t.Fatalf("Deactivate() error: %v", err)
+	}
+	got, err := platforms.Get(ctx, sess, "UnixSSH")
+	if err != nil || got.Active {
+		t.Errorf("Get() = %+v, %v, want inactive", got, err)
+	}
+
+	export, err := platforms.ExportPlatform(ctx, sess, "WinDomain")
+	if err != nil || len(export) < 4 || string(export[:2]) != "PK" {
+		t.Errorf("ExportPlatform() = %d bytes, %v, want a zip package", len(export), err)
+	}
+	if _, err := platforms.Get(ctx, sess, "Missing"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Get() missing platform error = %v, want not found", err)
+	}
+}
+
+func TestServer_Applications(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddSafe(safes.CreateOptions{SafeName: "Billing"})
+	srv.AddApplication(applications.CreateOptions{AppID: "Reports", Location: "\\Finance\\EMEA"})
+	ctx := context.Background()
+	sess := logon(t, srv, DefaultUsername, DefaultPassword)
+
+	if err := applications.Create(ctx, sess, applications.CreateOptions{AppID: "Billing"}); err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if err := applications.Create(ctx, sess, applications.CreateOptions{AppID: "billing"}); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("Create() duplicate error = %v, want conflict", err)
+	}
+	list, err := applications.List(ctx, sess, applications.ListOptions{Location: "\\Finance", SubLocations: true})
+	if err != nil || len(list) != 1 || list[0].AppID != "Reports" {
+		t.Errorf("List(sublocations) = %+v, %v, want Reports", list, err)
+	}
+
+	// Applications are Vault users, so they can be added to safes
+	if _, err := safemembers.Add(ctx, sess, "Billing", safemembers.AddOptions{MemberName: "Billing", Permissions: safemembers.DefaultApplicationPermissions()}); err != nil {
+		t.Errorf("safemembers.Add() application error: %v", err)
+	}
+
+	if err := applications.AddAuthMethod(ctx, sess, "Billing", applications.AddAuthMethodOptions{AuthType: applications.AuthTypeOSUser, AuthValue: "svc_billing"}); err != nil {
+		t.Fatalf("AddAuthMethod() error: %v", err)
+	}
+	err = applications.AddAuthMethod(ctx, sess, "Billing", applications.AddAuthMethodOptions{AuthType: applications.AuthTypeOSUser, AuthValue: "svc_billing"})
+	if !errors.Is(err, client.ErrConflict) {
+		t.Errorf("AddAuthMethod() duplicate error = %v, want conflict", err)
+	}
+	auths, err := applications.ListAuthMethods(ctx, sess, "Billing")
+	if err != nil || len(auths) != 1 {
+		t.Fatalf("ListAuthMethods() = %+v, %v, want 1", auths, err)
+	}
+	if err := applications.RemoveAuthMethod(ctx, sess, "Billing", auths[0].AuthID.String()); err != nil {
+		t.Errorf("RemoveAuthMethod() error: %v", err)
+	}
+
+	if err := applications.Delete(ctx, sess, "Billing"); err != nil {
+		t.Errorf("Delete() error: %v", err)
+	}
+	if _, err := applications.Get(ctx, sess, "Billing"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Get() deleted application error = %v, want not found", err)
+	}
+}
+
+func TestServer_ExpireSessions(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	sess := logon(t, srv, DefaultUsername, DefaultPassword)
+	srv.ExpireSessions()
+	_, err := safes.List(ctx, sess, safes.ListOptions{})
+	if apiErr, ok := client.AsAPIError(err); !ok || apiErr.StatusCode != http.StatusUnauthorized || !errors.Is(err, client.ErrSessionInvalid) {
+		t.Fatalf("List() after expiry error = %v, want 401 PASWS006E", err)
+	}
+
+	reauth := logon(t, srv, DefaultUsername, DefaultPassword, func(opts *authentication.SessionOptions) {
+		opts.Reauthenticate = true
+	})
+	srv.ExpireSessions()
+	if _, err := safes.List(ctx, reauth, safes.ListOptions{}); err != nil {
+		t.Fatalf("List() with re-authentication error: %v", err)
+	}
+
+	srv.SetSessionTimeout(time.Nanosecond)
+	short := logon(t, srv, DefaultUsername, DefaultPassword)
+	time.Sleep(time.Millisecond)
+	if _, err := safes.List(ctx, short, safes.ListOptions{}); !errors.Is(err, client.ErrSessionInvalid) {
+		t.Errorf("List() after session timeout error = %v, want session invalid", err)
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	sess := logon(t, srv, DefaultUsername, DefaultPassword, func(opts *authentication.SessionOptions) {
+		opts.RetryPolicy = &client.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	})
+
+	srv.InjectFault(TooManyRequests(2, 0))
+	if _, err := safes.List(ctx, sess, safes.ListOptions{}); err != nil {
+		t.Fatalf("List() should succeed after throttling: %v", err)
+	}
+	var throttled int
+	for _, call := range srv.Calls() {
+		if call.StatusCode == http.StatusTooManyRequests {
+			throttled++
+		}
+	}
+	if throttled != 2 {
+		t.Errorf("throttled calls = %d, want 2", throttled)
+	}
+
+	srv.InjectFault(Fault{Method: http.MethodGet, Path: "/Users", StatusCode: http.StatusForbidden, ErrorCode: "PASWS041E", ErrorMessage: "Access denied"})
+	if _, err := safes.List(ctx, sess, safes.ListOptions{}); err != nil {
+		t.Errorf("fault for /Users should not affect /Safes: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := users.List(ctx, sess, users.ListOptions{}); !errors.Is(err, client.ErrForbidden) {
+			t.Errorf("users.List() error = %v, want forbidden", err)
+		}
+	}
+	srv.ClearFaults()
+	if _, err := users.List(ctx, sess, users.ListOptions{}); err != nil {
+		t.Errorf("users.List() after ClearFaults error: %v", err)
+	}
+
+	srv.SetLatency(50 * time.Millisecond)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := safes.List(timeoutCtx, sess, safes.ListOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("List() with latency error = %v, want deadline exceeded", err)
+	}
+}
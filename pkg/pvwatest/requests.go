@@ -0,0 +1,219 @@
+package pvwatest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisranney/gopas/pkg/requests"
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+// Access request statuses.
+const (
+	requestPending   = 1
+	requestConfirmed = 2
+	requestRejected  = 3
+)
+
+// AddRequest stores an access request made by the named user, as if it had
+// been created through the API, and returns it. The account must exist.
+func (s *Server) AddRequest(requestor string, opts requests.CreateOptions) requests.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.findUser(requestor)
+	if u == nil {
+		panic(fmt.Sprintf("pvwatest: AddRequest: user %s was not found", requestor))
+	}
+	req, code, message := s.createRequestLocked(u, opts)
+	if req == nil {
+		panic(fmt.Sprintf("pvwatest: AddRequest: %s %s", code, message))
+	}
+	return *req
+}
+
+// createRequestLocked validates and stores a new access request. On
+// failure it returns the error code and message to report.
+func (s *Server) createRequestLocked(caller *user, opts requests.CreateOptions) (*requests.Request, string, string) {
+	a, ok := s.accounts[opts.AccountID]
+	if !ok {
+		return nil, "PASWS164E", "Account [" + opts.AccountID + "] was not found."
+	}
+
+	now := time.Now()
+	req := &requests.Request{
+		RequestID:          types.FlexibleID(strconv.Itoa(s.newID())),
+		SafeName:           a.SafeName,
+		RequestorUserName:  caller.Username,
+		RequestorReason:    opts.Reason,
+		CreationDate:       now.Unix(),
+		Operation:          "Access",
+		ExpirationDate:     now.Add(24 * time.Hour).Unix(),
+		AccessType:         "Timed",
+		ConfirmationsLeft:  1,
+		AccessFrom:         opts.FromDate,
+		AccessTo:           opts.ToDate,
+		Status:             requestPending,
+		StatusTitle:        "Pending",
+		RequiredConfirmers: 1,
+		AccountDetails: &requests.AccountDetails{
+			AccountID:   a.ID,
+			AccountName: a.Name,
+			SafeName:    a.SafeName,
+			PlatformID:  a.PlatformID,
+			Address:     a.Address,
+		},
+	}
+	if opts.MultipleAccessRequired {
+		req.AccessType = "Multiple"
+	}
+	s.requests[string(req.RequestID)] = req
+	return req, "", ""
+}
+
+// canConfirm reports whether the user is an authorizer of the request's safe.
+func (s *Server) canConfirm(u *user, req *requests.Request) bool {
+	if strings.EqualFold(req.RequestorUserName, u.Username) {
+		return false
+	}
+	sf, ok := s.safes[strings.ToLower(req.SafeName)]
+	if !ok {
+		return false
+	}
+	for _, m := range sf.members {
+		if m.Permissions == nil || !(m.Permissions.RequestsAuthorizationLevel1 || m.Permissions.RequestsAuthorizationLevel2) {
+			continue
+		}
+		if strings.EqualFold(m.MemberName, u.Username) {
+			return true
+		}
+		if g := s.findGroup(m.MemberName); g != nil {
+			for _, gm := range g.Members {
+				if gm.ID == u.ID {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// listRequests writes the requests for which include returns true.
+func (s *Server) listRequests(w http.ResponseWriter, r *http.Request, include func(*requests.Request) bool) {
+	onlyWaiting := r.URL.Query().Get("onlyWaiting") == "true"
+
+	matched := []requests.Request{}
+	for _, req := range s.requests {
+		if onlyWaiting && req.Status != requestPending {
+			continue
+		}
+		if include(req) {
+			matched = append(matched, *req)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, _ := strconv.Atoi(string(matched[i].RequestID))
+		b, _ := strconv.Atoi(string(matched[j].RequestID))
+		return a < b
+	})
+
+	items, _ := page(r, matched)
+	writeJSON(w, http.StatusOK, requests.RequestsResponse{Requests: items, Total: len(matched)})
+}
+
+func (s *Server) listMyRequests(w http.ResponseWriter, r *http.Request, caller *user) {
+	s.listRequests(w, r, func(req *requests.Request) bool {
+		return strings.EqualFold(req.RequestorUserName, caller.Username)
+	})
+}
+
+func (s *Server) listIncomingRequests(w http.ResponseWriter, r *http.Request, caller *user) {
+	s.listRequests(w, r, func(req *requests.Request) bool {
+		return s.canConfirm(caller, req)
+	})
+}
+
+func (s *Server) createRequest(w http.ResponseWriter, r *http.Request, caller *user) {
+	var opts requests.CreateOptions
+	if !decodeBody(w, r, &opts) {
+		return
+	}
+	if opts.AccountID == "" {
+		writeError(w, http.StatusBadRequest, "PASWS167E", "There are some invalid parameters: AccountId is required.")
+		return
+	}
+
+	req, code, message := s.createRequestLocked(caller, opts)
+	if req == nil {
+		writeError(w, http.StatusNotFound, code, message)
+		return
+	}
+	writeJSON(w, http.StatusCreated, req)
+}
+
+func (s *Server) deleteRequest(w http.ResponseWriter, r *http.Request, caller *user) {
+	id := r.PathValue("id")
+	req, ok := s.requests[id]
+	if !ok || !strings.EqualFold(req.RequestorUserName, caller.Username) {
+		writeError(w, http.StatusNotFound, "PASWS186E", "Request ["+id+"] was not found.")
+		return
+	}
+	delete(s.requests, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findIncomingRequest returns the pending request in the path if the caller
+// may confirm it, writing an error otherwise.
+func (s *Server) findIncomingRequest(w http.ResponseWriter, r *http.Request, caller *user) *requests.Request {
+	id := r.PathValue("id")
+	req, ok := s.requests[id]
+	if !ok || !s.canConfirm(caller, req) {
+		writeError(w, http.StatusNotFound, "PASWS186E", "Request ["+id+"] was not found.")
+		return nil
+	}
+	if req.Status != requestPending {
+		writeError(w, http.StatusBadRequest, "PASWS187E", "Request ["+id+"] is not waiting for confirmation.")
+		return nil
+	}
+	return req
+}
+
+func (s *Server) confirmRequest(w http.ResponseWriter, r *http.Request, caller *user) {
+	req := s.findIncomingRequest(w, r, caller)
+	if req == nil {
+		return
+	}
+
+	var body requests.ApproveOptions
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	req.ConfirmationsLeft--
+	req.CurrentConfirmationLevel++
+	req.ConfirmedByUser = caller.Username
+	if req.ConfirmationsLeft <= 0 {
+		req.Status = requestConfirmed
+		req.StatusTitle = "Confirmed"
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+func (s *Server) rejectRequest(w http.ResponseWriter, r *http.Request, caller *user) {
+	req := s.findIncomingRequest(w, r, caller)
+	if req == nil {
+		return
+	}
+
+	var body requests.DenyOptions
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	req.Status = requestRejected
+	req.StatusTitle = "Rejected"
+	req.UserReason = body.Reason
+	writeJSON(w, http.StatusOK, req)
+}
@@ -0,0 +1,376 @@
+package pvwatest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chrisranney/gopas/pkg/safemembers"
+	"github.com/chrisranney/gopas/pkg/safes"
+	"github.com/chrisranney/gopas/pkg/types"
+)
+
+type safe struct {
+	safes.Safe
+	members []*safemembers.SafeMember
+}
+
+// AddSafe stores a safe directly, as if it had been created through the
+// API, and returns it.
+func (s *Server) AddSafe(opts safes.CreateOptions) safes.Safe {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sf, code, message := s.createSafeLocked(opts, s.findUser(DefaultUsername))
+	if sf == nil {
+		panic(fmt.Sprintf("pvwatest: AddSafe: %s %s", code, message))
+	}
+	return sf.Safe
+}
+
+// AddSafeMember adds a member to a safe directly and returns it.
+func (s *Server) AddSafeMember(safeName string, opts safemembers.AddOptions) safemembers.SafeMember {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sf, ok := s.safes[strings.ToLower(safeName)]
+	if !ok {
+		panic(fmt.Sprintf("pvwatest: AddSafeMember: safe %s was not found", safeName))
+	}
+	m, code, message := s.addSafeMemberLocked(sf, opts)
+	if m == nil {
+		panic(fmt.Sprintf("pvwatest: AddSafeMember: %s %s", code, message))
+	}
+	return *m
+}
+
+// createSafeLocked validates and stores a new safe. On failure it returns
+// the error code and message to report.
+func (s *Server) createSafeLocked(opts safes.CreateOptions, creator *user) (*safe, string, string) {
+	if opts.SafeName == "" {
+		return nil, "PASWS167E", "There are some invalid parameters: safeName is required."
+	}
+	key := strings.ToLower(opts.SafeName)
+	if _, ok := s.safes[key]; ok {
+		return nil, "SFWS0002", "Safe [" + opts.SafeName + "] already exists."
+	}
+
+	location := opts.Location
+	if location == "" {
+		location = "\\"
+	}
+	sf := &safe{Safe: safes.Safe{
+		SafeURLId:                 types.FlexibleID(opts.SafeName),
+		SafeName:                  opts.SafeName,
+		SafeNumber:                s.newID(),
+		Description:               opts.Description,
+		Location:                  location,
+		OLACEnabled:               opts.OLACEnabled,
+		ManagingCPM:               opts.ManagingCPM,
+		NumberOfVersionsRetention: opts.NumberOfVersionsRetention,
+		NumberOfDaysRetention:     opts.NumberOfDaysRetention,
+		AutoPurgeEnabled:          opts.AutoPurgeEnabled,
+		CreationTime:              time.Now().Unix(),
+	}}
+	if creator != nil {
+		sf.Creator = &safes.Creator{ID: types.FlexibleID(fmt.Sprint(creator.ID)), Name: creator.Username}
+	}
+	s.safes[key] = sf
+	return sf, "", ""
+}
+
+// addSafeMemberLocked validates and stores a new safe member. On failure
+// it returns the error code and message to report.
+func (s *Server) addSafeMemberLocked(sf *safe, opts safemembers.AddOptions) (*safemembers.SafeMember, string, string) {
+	if opts.MemberName == "" {
+		return nil, "PASWS167E", "There are some invalid parameters: memberName is required."
+	}
+	if sf.findMember(opts.MemberName) != nil {
+		return nil, "SFWS0012E", "[" + opts.MemberName + "] is already a member of safe [" + sf.SafeName + "]."
+	}
+
+	memberType := "User"
+	var memberID types.FlexibleID
+	if opts.SearchIn == "" || strings.EqualFold(opts.SearchIn, "Vault") {
+		if u := s.findUser(opts.MemberName); u != nil {
+			memberID = types.FlexibleID(fmt.Sprint(u.ID))
+		} else if g := s.findGroup(opts.MemberName); g != nil {
+			memberID = types.FlexibleID(fmt.Sprint(g.ID))
+			memberType = "Group"
+		} else {
+			return nil, "SFWS0013E", "Member [" + opts.MemberName + "] was not found in [Vault]."
+		}
+	}
+
+	permissions := opts.Permissions
+	if permissions == nil {
+		permissions = &safemembers.Permissions{}
+	}
+	m := &safemembers.SafeMember{
+		SafeURLID:                types.FlexibleID(sf.SafeName),
+		SafeName:                 sf.SafeName,
+		SafeNumber:               sf.SafeNumber,
+		MemberID:                 memberID,
+		MemberName:               opts.MemberName,
+		MemberType:               memberType,
+		MembershipExpirationDate: opts.MembershipExpirationDate,
+		Permissions:              permissions,
+	}
+	sf.members = append(sf.members, m)
+	return m, "", ""
+}
+
+func (sf *safe) findMember(name string) *safemembers.SafeMember {
+	for _, m := range sf.members {
+		if strings.EqualFold(m.MemberName, name) {
+			return m
+		}
+	}
+	return nil
+}
+
+// findSafe returns the safe named in the request path, writing a not found
+// error if there is none.
+func (s *Server) findSafe(w http.ResponseWriter, r *http.Request) *safe {
+	name := r.PathValue("name")
+	sf, ok := s.safes[strings.ToLower(name)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "SFWS0007", "Safe ["+name+"] was not found.")
+		return nil
+	}
+	return sf
+}
+
+// safeAccountCount returns the number of accounts stored in a safe.
+func (s *Server) safeAccountCount(safeName string) int {
+	count := 0
+	for _, a := range s.accounts {
+		if strings.EqualFold(a.SafeName, safeName) {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) listSafes(w http.ResponseWriter, r *http.Request, caller *user) {
+	search := r.URL.Query().Get("search")
+	includeAccounts := r.URL.Query().Get("includeAccounts") == "true"
+
+	matched := []safes.Safe{}
+	for _, sf := range s.safes {
+		if !matchesSearch(search, sf.SafeName, sf.Description) {
+			continue
+		}
+		safe := sf.Safe
+		if includeAccounts {
+			count := s.safeAccountCount(sf.SafeName)
+			safe.Accounts = &count
+		}
+		matched = append(matched, safe)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return strings.ToLower(matched[i].SafeName) < strings.ToLower(matched[j].SafeName)
+	})
+
+	items, next := page(r, matched)
+	writeJSON(w, http.StatusOK, safes.SafesResponse{Value: items, Count: len(matched), NextLink: next})
+}
+
+func (s *Server) createSafe(w http.ResponseWriter, r *http.Request, caller *user) {
+	var opts safes.CreateOptions
+	if !decodeBody(w, r, &opts) {
+		return
+	}
+
+	sf, code, message := s.createSafeLocked(opts, caller)
+	if sf == nil {
+		status := http.StatusBadRequest
+		if code == "SFWS0002" {
+			status = http.StatusConflict
+		}
+		writeError(w, status, code, message)
+		return
+	}
+	writeJSON(w, http.StatusCreated, sf.Safe)
+}
+
+func (s *Server) getSafe(w http.ResponseWriter, r *http.Request, caller *user) {
+	if sf := s.findSafe(w, r); sf != nil {
+		writeJSON(w, http.StatusOK, sf.Safe)
+	}
+}
+
+func (s *Server) updateSafe(w http.ResponseWriter, r *http.Request, caller *user) {
+	sf := s.findSafe(w, r)
+	if sf == nil {
+		return
+	}
+
+	var opts safes.UpdateOptions
+	if !decodeBody(w, r, &opts) {
+		return
+	}
+	if opts.SafeName != "" && !strings.EqualFold(opts.SafeName, sf.SafeName) {
+		if _, exists := s.safes[strings.ToLower(opts.SafeName)]; exists {
+			writeError(w, http.StatusConflict, "SFWS0002", "Safe ["+opts.SafeName+"] already exists.")
+			return
+		}
+		s.renameSafe(sf, opts.SafeName)
+	}
+	if opts.Description != "" {
+		sf.Description = opts.Description
+	}
+	if opts.Location != "" {
+		sf.Location = opts.Location
+	}
+	if opts.OLACEnabled != nil {
+		sf.OLACEnabled = *opts.OLACEnabled
+	}
+	if opts.ManagingCPM != "" {
+		sf.ManagingCPM = opts.ManagingCPM
+	}
+	if opts.NumberOfVersionsRetention != nil {
+		sf.NumberOfVersionsRetention = opts.NumberOfVersionsRetention
+		sf.NumberOfDaysRetention = 0
+	}
+	if opts.NumberOfDaysRetention != nil {
+		sf.NumberOfDaysRetention = *opts.NumberOfDaysRetention
+		sf.NumberOfVersionsRetention = nil
+	}
+	if opts.AutoPurgeEnabled != nil {
+		sf.AutoPurgeEnabled = *opts.AutoPurgeEnabled
+	}
+	sf.LastModificationTime = time.Now().Unix()
+	writeJSON(w, http.StatusOK, sf.Safe)
+}
+
+// renameSafe moves a safe, its members and its accounts to a new name.
+func (s *Server) renameSafe(sf *safe, name string) {
+	for _, a := range s.accounts {
+		if strings.EqualFold(a.SafeName, sf.SafeName) {
+			a.SafeName = name
+		}
+	}
+	for _, m := range sf.members {
+		m.SafeName = name
+		m.SafeURLID = types.FlexibleID(name)
+	}
+	delete(s.safes, strings.ToLower(sf.SafeName))
+	sf.SafeName = name
+	sf.SafeURLId = types.FlexibleID(name)
+	s.safes[strings.ToLower(name)] = sf
+}
+
+func (s *Server) deleteSafe(w http.ResponseWriter, r *http.Request, caller *user) {
+	sf := s.findSafe(w, r)
+	if sf == nil {
+		return
+	}
+	for id, a := range s.accounts {
+		if strings.EqualFold(a.SafeName, sf.SafeName) {
+			delete(s.accounts, id)
+		}
+	}
+	delete(s.safes, strings.ToLower(sf.SafeName))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listSafeMembers(w http.ResponseWriter, r *http.Request, caller *user) {
+	sf := s.findSafe(w, r)
+	if sf == nil {
+		return
+	}
+
+	search := r.URL.Query().Get("search")
+	matched := []safemembers.SafeMember{}
+	for _, m := range sf.members {
+		if matchesSearch(search, m.MemberName) {
+			matched = append(matched, *m)
+		}
+	}
+
+	items, next := page(r, matched)
+	writeJSON(w, http.StatusOK, safemembers.SafeMembersResponse{Value: items, Count: len(matched), NextLink: next})
+}
+
+// findSafeMember returns the member named in the request path, writing a
+// not found error if the safe or member does not exist.
+func (s *Server) findSafeMember(w http.ResponseWriter, r *http.Request) (*safe, *safemembers.SafeMember) {
+	sf := s.findSafe(w, r)
+	if sf == nil {
+		return nil, nil
+	}
+	name := r.PathValue("member")
+	m := sf.findMember(name)
+	if m == nil {
+		writeError(w, http.StatusNotFound, "SFWS0011E", "Member ["+name+"] was not found in safe ["+sf.SafeName+"].")
+		return nil, nil
+	}
+	return sf, m
+}
+
+func (s *Server) addSafeMember(w http.ResponseWriter, r *http.Request, caller *user) {
+	sf := s.findSafe(w, r)
+	if sf == nil {
+		return
+	}
+
+	var opts safemembers.AddOptions
+	if !decodeBody(w, r, &opts) {
+		return
+	}
+	m, code, message := s.addSafeMemberLocked(sf, opts)
+	if m == nil {
+		status := http.StatusBadRequest
+		switch code {
+		case "SFWS0012E":
+			status = http.StatusConflict
+		case "SFWS0013E":
+			status = http.StatusNotFound
+		}
+		writeError(w, status, code, message)
+		return
+	}
+	writeJSON(w, http.StatusCreated, m)
+}
+
+func (s *Server) getSafeMember(w http.ResponseWriter, r *http.Request, caller *user) {
+	if _, m := s.findSafeMember(w, r); m != nil {
+		writeJSON(w, http.StatusOK, m)
+	}
+}
+
+func (s *Server) updateSafeMember(w http.ResponseWriter, r *http.Request, caller *user) {
+	_, m := s.findSafeMember(w, r)
+	if m == nil {
+		return
+	}
+
+	var opts safemembers.UpdateOptions
+	if !decodeBody(w, r, &opts) {
+		return
+	}
+	if opts.Permissions == nil {
+		writeError(w, http.StatusBadRequest, "PASWS167E", "There are some invalid parameters: permissions is required.")
+		return
+	}
+	m.Permissions = opts.Permissions
+	m.MembershipExpirationDate = opts.MembershipExpirationDate
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (s *Server) removeSafeMember(w http.ResponseWriter, r *http.Request, caller *user) {
+	sf, m := s.findSafeMember(w, r)
+	if m == nil {
+		return
+	}
+	for i, member := range sf.members {
+		if member == m {
+			sf.members = append(sf.members[:i], sf.members[i+1:]...)
+			break
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
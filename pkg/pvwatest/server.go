@@ -0,0 +1,494 @@
+// Package pvwatest provides an in-memory, stateful PVWA for tests.
+//
+// The server speaks the same REST API as the Password Vault Web Access and
+// keeps accounts, safes, safe members, users, groups, access requests,
+// platforms and applications in memory, so code built on goPAS can be tested end to end
+// without a Vault:
+//
+//	srv := pvwatest.NewServer()
+//	defer srv.Close()
+//
+//	srv.AddSafe(safes.CreateOptions{SafeName: "Linux"})
+//	id := srv.AddAccount(accounts.CreateOptions{
+//		SafeName: "Linux", PlatformID: "UnixSSH", Address: "web01", UserName: "root", Secret: "s3cret",
+//	}).ID
+//
+//	sess, err := gopas.NewSession(ctx, gopas.SessionOptions{
+//		BaseURL:     srv.URL,
+//		Credentials: gopas.Credentials{Username: pvwatest.DefaultUsername, Password: pvwatest.DefaultPassword},
+//	})
+//
+// Errors are returned with the ErrorCode and ErrorMessage bodies the PVWA
+// uses, so they classify the same way through client.APIError. Latency,
+// expired sessions and arbitrary error responses can be injected to exercise
+// retry and re-authentication paths.
+package pvwatest
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrisranney/gopas/pkg/accounts"
+	"github.com/chrisranney/gopas/pkg/platforms"
+	"github.com/chrisranney/gopas/pkg/requests"
+	"github.com/chrisranney/gopas/pkg/users"
+)
+
+// Credentials of the Vault administrator every server starts with.
+const (
+	DefaultUsername = "Administrator"
+	DefaultPassword = "Cyberark1"
+)
+
+// DefaultVersion is the PVWA version reported by a new server.
+const DefaultVersion = "14.0"
+
+// apiPrefix is the path below which the PVWA REST API is served.
+const apiPrefix = "/PasswordVault/API"
+
+// Call records a request handled by the server.
+type Call struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+// Server is an in-memory PVWA listening on a local HTTP address.
+type Server struct {
+	// URL is the base URL of the server, for SessionOptions.BaseURL
+	URL string
+
+	httpServer *httptest.Server
+
+	mu             sync.Mutex
+	version        string
+	sessionTimeout time.Duration
+	latency        time.Duration
+	nextID         int
+	sessions       map[string]*logonSession
+	users          map[int]*user
+	groups         map[int]*users.Group
+	safes          map[string]*safe
+	accounts       map[string]*account
+	requests       map[string]*requests.Request
+	platforms      []*platforms.Platform
+	applications   []*application
+	faults         []*faultState
+	calls          []Call
+}
+
+type logonSession struct {
+	userID  int
+	expires time.Time
+}
+
+type user struct {
+	users.User
+	password string
+}
+
+type account struct {
+	accounts.Account
+	secret     string
+	activities []accounts.AccountActivity
+}
+
+// NewServer starts a server that knows only the DefaultUsername user.
+// The caller must call Close when finished.
+func NewServer() *Server {
+	s := newServer()
+	s.httpServer = httptest.NewServer(s.routes())
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// NewTLSServer starts a server like NewServer, using HTTPS. Sessions must
+// trust the server's certificate, see Client. PKI logons authenticate the
+// user named by the client certificate's subject common name; the
+// certificate itself is not verified.
+func NewTLSServer() *Server {
+	s := newServer()
+	s.httpServer = httptest.NewUnstartedServer(s.routes())
+	s.httpServer.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	s.httpServer.StartTLS()
+	s.URL = s.httpServer.URL
+	return s
+}
+
+func newServer() *Server {
+	s := &Server{
+		version:  DefaultVersion,
+		sessions: make(map[string]*logonSession),
+		users:    make(map[int]*user),
+		groups:   make(map[int]*users.Group),
+		safes:    make(map[string]*safe),
+		accounts: make(map[string]*account),
+		requests: make(map[string]*requests.Request),
+	}
+	s.AddUser(users.CreateOptions{
+		Username:           DefaultUsername,
+		InitialPassword:    DefaultPassword,
+		VaultAuthorization: []string{"AddUpdateUsers", "AddSafes", "AuditUsers", "ManageServerFileCategories", "ActivateUsers"},
+	})
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// Client returns an HTTP client that trusts the server, for
+// SessionOptions.CustomHTTPClient when the server uses TLS.
+func (s *Server) Client() *http.Client {
+	return s.httpServer.Client()
+}
+
+// SetVersion sets the PVWA version reported by the server info endpoint.
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+// SetSessionTimeout makes session tokens expire the given time after logon.
+// Zero, the default, keeps tokens valid until logoff or ExpireSessions.
+func (s *Server) SetSessionTimeout(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionTimeout = d
+}
+
+// ExpireSessions invalidates every session token, as the PVWA does when a
+// session times out. Subsequent requests fail with 401 until the client
+// logs on again.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.sessions)
+}
+
+// Calls returns the requests handled so far, in order.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// routes registers the API handlers.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST "+apiPrefix+"/Auth/{method}/Logon", s.logon)
+	mux.HandleFunc("GET "+apiPrefix+"/WebServices/PIMServices.svc/Server", s.serverInfo)
+	s.handle(mux, "POST /Auth/Logoff", s.logoff)
+	s.handle(mux, "GET /WebServices/PIMServices.svc/User", s.loggedOnUser)
+
+	s.handle(mux, "GET /Accounts", s.listAccounts)
+	s.handle(mux, "POST /Accounts", s.createAccount)
+	s.handle(mux, "GET /Accounts/{id}", s.getAccount)
+	s.handle(mux, "PATCH /Accounts/{id}", s.updateAccount)
+	s.handle(mux, "DELETE /Accounts/{id}", s.deleteAccount)
+	s.handle(mux, "POST /Accounts/{id}/Password/Retrieve", s.retrievePassword)
+	s.handle(mux, "POST /Accounts/{id}/Change", s.changePassword)
+	s.handle(mux, "POST /Accounts/{id}/SetNextPassword", s.setNextPassword)
+	s.handle(mux, "POST /Accounts/{id}/Verify", s.verifyAccount)
+	s.handle(mux, "POST /Accounts/{id}/Reconcile", s.reconcileAccount)
+	s.handle(mux, "GET /Accounts/{id}/Activities", s.accountActivities)
+
+	s.handle(mux, "GET /Safes", s.listSafes)
+	s.handle(mux, "POST /Safes", s.createSafe)
+	s.handle(mux, "GET /Safes/{name}", s.getSafe)
+	s.handle(mux, "PUT /Safes/{name}", s.updateSafe)
+	s.handle(mux, "DELETE /Safes/{name}", s.deleteSafe)
+	s.handle(mux, "GET /Safes/{name}/Members", s.listSafeMembers)
+	s.handle(mux, "POST /Safes/{name}/Members", s.addSafeMember)
+	s.handle(mux, "GET /Safes/{name}/Members/{member}", s.getSafeMember)
+	s.handle(mux, "PUT /Safes/{name}/Members/{member}", s.updateSafeMember)
+	s.handle(mux, "DELETE /Safes/{name}/Members/{member}", s.removeSafeMember)
+
+	s.handle(mux, "GET /Users", s.listUsers)
+	s.handle(mux, "POST /Users", s.createUser)
+	s.handle(mux, "GET /Users/{id}", s.getUser)
+	s.handle(mux, "PUT /Users/{id}", s.updateUser)
+	s.handle(mux, "DELETE /Users/{id}", s.deleteUser)
+	s.handle(mux, "POST /Users/{id}/Activate", s.activateUser)
+	s.handle(mux, "POST /Users/{id}/ResetPassword", s.resetUserPassword)
+
+	s.handle(mux, "GET /UserGroups", s.listGroups)
+	s.handle(mux, "POST /UserGroups", s.createGroup)
+	s.handle(mux, "GET /UserGroups/{id}", s.getGroup)
+	s.handle(mux, "DELETE /UserGroups/{id}", s.deleteGroup)
+	s.handle(mux, "GET /UserGroups/{id}/Members", s.listGroupMembers)
+	s.handle(mux, "POST /UserGroups/{id}/Members", s.addGroupMember)
+	s.handle(mux, "DELETE /UserGroups/{id}/Members/{member}", s.removeGroupMember)
+
+	s.handle(mux, "GET /MyRequests", s.listMyRequests)
+	s.handle(mux, "POST /MyRequests", s.createRequest)
+	s.handle(mux, "DELETE /MyRequests/{id}", s.deleteRequest)
+	s.handle(mux, "GET /IncomingRequests", s.listIncomingRequests)
+	s.handle(mux, "POST /IncomingRequests/{id}/Confirm", s.confirmRequest)
+	s.handle(mux, "POST /IncomingRequests/{id}/Reject", s.rejectRequest)
+
+	s.handle(mux, "GET /Platforms", s.listPlatforms)
+	s.handle(mux, "GET /Platforms/{id}", s.getPlatform)
+	s.handle(mux, "DELETE /Platforms/{id}", s.deletePlatform)
+	s.handle(mux, "POST /Platforms/{id}/activate", s.activatePlatform)
+	s.handle(mux, "POST /Platforms/{id}/deactivate", s.deactivatePlatform)
+	s.handle(mux, "POST /Platforms/{id}/export", s.exportPlatform)
+
+	s.handle(mux, "GET /WebServices/PIMServices.svc/Applications", s.listApplications)
+	s.handle(mux, "POST /WebServices/PIMServices.svc/Applications", s.createApplication)
+	s.handle(mux, "GET /WebServices/PIMServices.svc/Applications/{id}", s.getApplication)
+	s.handle(mux, "DELETE /WebServices/PIMServices.svc/Applications/{id}", s.deleteApplication)
+	s.handle(mux, "GET /WebServices/PIMServices.svc/Applications/{id}/Authentications", s.listAuthMethods)
+	s.handle(mux, "POST /WebServices/PIMServices.svc/Applications/{id}/Authentications", s.addAuthMethod)
+	s.handle(mux, "DELETE /WebServices/PIMServices.svc/Applications/{id}/Authentications/{authID}", s.removeAuthMethod)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "PASWS041E", "The requested URL was not found on this server.")
+	})
+
+	return s.intercept(mux)
+}
+
+// handlerFunc handles an authenticated API request. It is called with the
+// server lock held and the logged on user.
+type handlerFunc func(w http.ResponseWriter, r *http.Request, caller *user)
+
+// handle registers an authenticated handler for "METHOD /Path" below the
+// API prefix.
+func (s *Server) handle(mux *http.ServeMux, pattern string, h handlerFunc) {
+	method, path, _ := strings.Cut(pattern, " ")
+	mux.HandleFunc(method+" "+apiPrefix+path, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		caller, ok := s.authenticate(r.Header.Get("Authorization"))
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "PASWS006E", "Your session has expired. Please log on again.")
+			return
+		}
+		h(w, r, caller)
+	})
+}
+
+// intercept applies latency and injected faults, and records every call.
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		latency := s.latency
+		fault := s.takeFault(r)
+		s.mu.Unlock()
+
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		if fault != nil {
+			fault.write(rec)
+		} else {
+			next.ServeHTTP(rec, r)
+		}
+
+		s.mu.Lock()
+		s.calls = append(s.calls, Call{
+			Method:     r.Method,
+			Path:       strings.TrimPrefix(r.URL.Path, apiPrefix),
+			StatusCode: rec.status,
+		})
+		s.mu.Unlock()
+	})
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logon handles POST /Auth/{method}/Logon for every authentication method.
+func (s *Server) logon(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var u *user
+	if strings.EqualFold(r.PathValue("method"), "pki") {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			writeError(w, http.StatusForbidden, "ITATS004E", "Authentication failure: a client certificate is required.")
+			return
+		}
+		cn := r.TLS.PeerCertificates[0].Subject.CommonName
+		if u = s.findUser(cn); u == nil {
+			writeError(w, http.StatusForbidden, "ITATS004E", "Authentication failure for User ["+cn+"].")
+			return
+		}
+	} else if u = s.findUser(body.Username); u == nil || u.password != body.Password {
+		writeError(w, http.StatusForbidden, "ITATS004E", "Authentication failure for User ["+body.Username+"].")
+		return
+	}
+	if u.Suspended || !u.EnableUser {
+		writeError(w, http.StatusForbidden, "ITATS203E", "User ["+u.Username+"] is suspended. Contact your administrator.")
+		return
+	}
+
+	token := newToken()
+	sess := &logonSession{userID: u.ID}
+	if s.sessionTimeout > 0 {
+		sess.expires = time.Now().Add(s.sessionTimeout)
+	}
+	s.sessions[token] = sess
+	u.LastSuccessfulLoginDate = time.Now().Unix()
+
+	writeJSON(w, http.StatusOK, token)
+}
+
+// authenticate returns the user a session token belongs to.
+func (s *Server) authenticate(token string) (*user, bool) {
+	sess, ok := s.sessions[token]
+	if !ok {
+		return nil, false
+	}
+	if !sess.expires.IsZero() && time.Now().After(sess.expires) {
+		delete(s.sessions, token)
+		return nil, false
+	}
+	u, ok := s.users[sess.userID]
+	return u, ok
+}
+
+func (s *Server) logoff(w http.ResponseWriter, r *http.Request, caller *user) {
+	delete(s.sessions, r.Header.Get("Authorization"))
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) serverInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	version := s.version
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ServerID":         "pvwatest",
+		"ServerName":       "Vault",
+		"ServicesUsed":     "PVWA",
+		"ApplicationsUsed": "PasswordVault",
+		"ExternalVersion":  version,
+	})
+}
+
+func (s *Server) loggedOnUser(w http.ResponseWriter, r *http.Request, caller *user) {
+	writeJSON(w, http.StatusOK, caller.User)
+}
+
+// newID returns the next object ID.
+func (s *Server) newID() int {
+	s.nextID++
+	return s.nextID
+}
+
+// newToken returns a random session token.
+func newToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// apiError is the error body returned by the PVWA.
+type apiError struct {
+	ErrorCode    string `json:"ErrorCode"`
+	ErrorMessage string `json:"ErrorMessage"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiError{ErrorCode: code, ErrorMessage: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// decodeBody decodes a JSON request body, writing a validation error and
+// returning false if it cannot be parsed.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "PASWS167E", "There are some invalid parameters: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// page applies the offset and limit query parameters to items and returns
+// the nextLink for the following page, if any.
+func page[T any](r *http.Request, items []T) ([]T, string) {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if offset < 0 || offset > len(items) {
+		offset = len(items)
+	}
+	items = items[offset:]
+	if limit <= 0 || limit >= len(items) {
+		return items, ""
+	}
+
+	next := r.URL.Query()
+	next.Set("offset", strconv.Itoa(offset+limit))
+	next.Set("limit", strconv.Itoa(limit))
+	return items[:limit], "api" + strings.TrimPrefix(r.URL.Path, apiPrefix) + "?" + next.Encode()
+}
+
+// matchesSearch reports whether any of the fields contains the search
+// query, case-insensitively.
+func matchesSearch(search string, fields ...string) bool {
+	if search == "" {
+		return true
+	}
+	search = strings.ToLower(search)
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfterSeconds formats a Retry-After header value.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
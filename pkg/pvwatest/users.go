@@ -0,0 +1,448 @@
+package pvwatest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chrisranney/gopas/pkg/users"
+)
+
+// AddUser stores a Vault user directly, as if it had been created through
+// the API, and returns it. The user can log on with opts.InitialPassword.
+func (s *Server) AddUser(opts users.CreateOptions) users.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, code, message := s.createUserLocked(opts)
+	if u == nil {
+		panic(fmt.Sprintf("pvwatest: AddUser: %s %s", code, message))
+	}
+	return u.User
+}
+
+// AddGroup stores a Vault group directly and returns it.
+func (s *Server) AddGroup(opts users.CreateGroupOptions) users.Group {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, code, message := s.createGroupLocked(opts)
+	if g == nil {
+		panic(fmt.Sprintf("pvwatest: AddGroup: %s %s", code, message))
+	}
+	return *g
+}
+
+// createUserLocked validates and stores a new user. On failure it returns
+// the error code and message to report.
+func (s *Server) createUserLocked(opts users.CreateOptions) (*user, string, string) {
+	if opts.Username == "" {
+		return nil, "PASWS167E", "There are some invalid parameters: username is required."
+	}
+	if s.findUser(opts.Username) != nil {
+		return nil, "PASWS120E", "User [" + opts.Username + "] already exists."
+	}
+
+	userType := opts.UserType
+	if userType == "" {
+		userType = "EPVUser"
+	}
+	location := opts.Location
+	if location == "" {
+		location = "\\"
+	}
+	enabled := opts.EnableUser == nil || *opts.EnableUser
+
+	u := &user{
+		User: users.User{
+			ID:                     s.newID(),
+			Username:               opts.Username,
+			Source:                 "CyberArk",
+			UserType:               userType,
+			VaultAuthorization:     opts.VaultAuthorization,
+			Location:               location,
+			PersonalDetails:        opts.PersonalDetails,
+			EnableUser:             enabled,
+			AuthenticationMethod:   opts.AuthenticationMethod,
+			PasswordNeverExpires:   opts.PasswordNeverExpires != nil && *opts.PasswordNeverExpires,
+			Description:            opts.Description,
+			BusinessAddress:        opts.BusinessAddress,
+			Internet:               opts.Internet,
+			Phones:                 opts.Phones,
+			UnauthorizedInterfaces: opts.UnauthorizedInterfaces,
+			ExpiryDate:             opts.ExpiryDate,
+		},
+		password: opts.InitialPassword,
+	}
+	s.users[u.ID] = u
+	return u, "", ""
+}
+
+// findUser returns the user with the given name, or nil.
+func (s *Server) findUser(username string) *user {
+	for _, u := range s.users {
+		if strings.EqualFold(u.Username, username) {
+			return u
+		}
+	}
+	return nil
+}
+
+// lookupUser returns the user whose ID is in the request path, writing a
+// not found error if there is none.
+func (s *Server) lookupUser(w http.ResponseWriter, r *http.Request) *user {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	u, ok := s.users[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "PASWS143E", "User ["+r.PathValue("id")+"] was not found.")
+		return nil
+	}
+	return u
+}
+
+// withGroups returns the user with its current group memberships.
+func (s *Server) withGroups(u *user) users.User {
+	result := u.User
+	result.GroupsMembership = nil
+	for _, g := range s.sortedGroups() {
+		for _, m := range g.Members {
+			if m.ID == u.ID {
+				result.GroupsMembership = append(result.GroupsMembership, users.GroupMembership{
+					GroupID:   g.ID,
+					GroupName: g.GroupName,
+					GroupType: g.GroupType,
+				})
+			}
+		}
+	}
+	return result
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request, caller *user) {
+	query := r.URL.Query()
+	search := query.Get("search")
+	userType := query.Get("userType")
+
+	ids := make([]int, 0, len(s.users))
+	for id := range s.users {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	matched := []users.User{}
+	for _, id := range ids {
+		u := s.users[id]
+		if userType != "" && !strings.EqualFold(u.UserType, userType) {
+			continue
+		}
+		var first, last string
+		if u.PersonalDetails != nil {
+			first, last = u.PersonalDetails.FirstName, u.PersonalDetails.LastName
+		}
+		if !matchesSearch(search, u.Username, first, last) {
+			continue
+		}
+		matched = append(matched, s.withGroups(u))
+	}
+
+	items, next := page(r, matched)
+	writeJSON(w, http.StatusOK, users.UsersResponse{Users: items, Total: len(matched), NextLink: next})
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request, caller *user) {
+	var opts users.CreateOptions
+	if !decodeBody(w, r, &opts) {
+		return
+	}
+
+	u, code, message := s.createUserLocked(opts)
+	if u == nil {
+		status := http.StatusBadRequest
+		if code == "PASWS120E" {
+			status = http.StatusConflict
+		}
+		writeError(w, status, code, message)
+		return
+	}
+	writeJSON(w, http.StatusCreated, u.User)
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request, caller *user) {
+	if u := s.lookupUser(w, r); u != nil {
+		writeJSON(w, http.StatusOK, s.withGroups(u))
+	}
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request, caller *user) {
+	u := s.lookupUser(w, r)
+	if u == nil {
+		return
+	}
+
+	var opts users.UpdateOptions
+	if !decodeBody(w, r, &opts) {
+		return
+	}
+	if opts.EnableUser != nil {
+		u.EnableUser = *opts.EnableUser
+	}
+	if opts.Suspended != nil {
+		u.Suspended = *opts.Suspended
+	}
+	if opts.UnauthorizedInterfaces != nil {
+		u.UnauthorizedInterfaces = opts.UnauthorizedInterfaces
+	}
+	if opts.AuthenticationMethod != nil {
+		u.AuthenticationMethod = opts.AuthenticationMethod
+	}
+	if opts.PasswordNeverExpires != nil {
+		u.PasswordNeverExpires = *opts.PasswordNeverExpires
+	}
+	if opts.ExpiryDate != nil {
+		u.ExpiryDate = *opts.ExpiryDate
+	}
+	if opts.Location != "" {
+		u.Location = opts.Location
+	}
+	if opts.VaultAuthorization != nil {
+		u.VaultAuthorization = opts.VaultAuthorization
+	}
+	if opts.PersonalDetails != nil {
+		u.PersonalDetails = opts.PersonalDetails
+	}
+	if opts.Description != "" {
+		u.Description = opts.Description
+	}
+	if opts.BusinessAddress != nil {
+		u.BusinessAddress = opts.BusinessAddress
+	}
+	if opts.Internet != nil {
+		u.Internet = opts.Internet
+	}
+	if opts.Phones != nil {
+		u.Phones = opts.Phones
+	}
+	writeJSON(w, http.StatusOK, s.withGroups(u))
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request, caller *user) {
+	u := s.lookupUser(w, r)
+	if u == nil {
+		return
+	}
+	for _, g := range s.groups {
+		g.Members = withoutMember(g.Members, u.Username)
+	}
+	for token, sess := range s.sessions {
+		if sess.userID == u.ID {
+			delete(s.sessions, token)
+		}
+	}
+	delete(s.users, u.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) activateUser(w http.ResponseWriter, r *http.Request, caller *user) {
+	if u := s.lookupUser(w, r); u != nil {
+		u.Suspended = false
+		writeJSON(w, http.StatusOK, s.withGroups(u))
+	}
+}
+
+func (s *Server) resetUserPassword(w http.ResponseWriter, r *http.Request, caller *user) {
+	u := s.lookupUser(w, r)
+	if u == nil {
+		return
+	}
+
+	var body struct {
+		NewPassword string `json:"newPassword"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.NewPassword == "" {
+		writeError(w, http.StatusBadRequest, "PASWS167E", "There are some invalid parameters: newPassword is required.")
+		return
+	}
+	u.password = body.NewPassword
+	w.WriteHeader(http.StatusOK)
+}
+
+// createGroupLocked validates and stores a new group. On failure it
+// returns the error code and message to report.
+func (s *Server) createGroupLocked(opts users.CreateGroupOptions) (*users.Group, string, string) {
+	if opts.GroupName == "" {
+		return nil, "PASWS167E", "There are some invalid parameters: groupName is required."
+	}
+	if s.findGroup(opts.GroupName) != nil {
+		return nil, "PASWS126E", "Group [" + opts.GroupName + "] already exists."
+	}
+
+	location := opts.Location
+	if location == "" {
+		location = "\\"
+	}
+	g := &users.Group{
+		ID:          s.newID(),
+		GroupName:   opts.GroupName,
+		Description: opts.Description,
+		Location:    location,
+		GroupType:   "Vault",
+	}
+	s.groups[g.ID] = g
+	return g, "", ""
+}
+
+// findGroup returns the group with the given name, or nil.
+func (s *Server) findGroup(name string) *users.Group {
+	for _, g := range s.groups {
+		if strings.EqualFold(g.GroupName, name) {
+			return g
+		}
+	}
+	return nil
+}
+
+// lookupGroup returns the group whose ID is in the request path, writing a
+// not found error if there is none.
+func (s *Server) lookupGroup(w http.ResponseWriter, r *http.Request) *users.Group {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	g, ok := s.groups[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "PASWS148E", "Group ["+r.PathValue("id")+"] was not found.")
+		return nil
+	}
+	return g
+}
+
+// sortedGroups returns the groups ordered by ID.
+func (s *Server) sortedGroups() []*users.Group {
+	groups := make([]*users.Group, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups
+}
+
+func (s *Server) listGroups(w http.ResponseWriter, r *http.Request, caller *user) {
+	search := r.URL.Query().Get("search")
+	includeMembers := r.URL.Query().Get("includeMembers") == "true"
+
+	matched := []users.Group{}
+	for _, g := range s.sortedGroups() {
+		if !matchesSearch(search, g.GroupName) {
+			continue
+		}
+		group := *g
+		if !includeMembers {
+			group.Members = nil
+		}
+		matched = append(matched, group)
+	}
+
+	items, next := page(r, matched)
+	writeJSON(w, http.StatusOK, users.GroupsResponse{Value: items, Count: len(matched), NextLink: next})
+}
+
+func (s *Server) createGroup(w http.ResponseWriter, r *http.Request, caller *user) {
+	var opts users.CreateGroupOptions
+	if !decodeBody(w, r, &opts) {
+		return
+	}
+
+	g, code, message := s.createGroupLocked(opts)
+	if g == nil {
+		status := http.StatusBadRequest
+		if code == "PASWS126E" {
+			status = http.StatusConflict
+		}
+		writeError(w, status, code, message)
+		return
+	}
+	writeJSON(w, http.StatusCreated, g)
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, r *http.Request, caller *user) {
+	if g := s.lookupGroup(w, r); g != nil {
+		writeJSON(w, http.StatusOK, g)
+	}
+}
+
+func (s *Server) deleteGroup(w http.ResponseWriter, r *http.Request, caller *user) {
+	if g := s.lookupGroup(w, r); g != nil {
+		delete(s.groups, g.ID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) listGroupMembers(w http.ResponseWriter, r *http.Request, caller *user) {
+	if g := s.lookupGroup(w, r); g != nil {
+		members := append([]users.GroupMemberDetail{}, g.Members...)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"members": members})
+	}
+}
+
+func (s *Server) addGroupMember(w http.ResponseWriter, r *http.Request, caller *user) {
+	g := s.lookupGroup(w, r)
+	if g == nil {
+		return
+	}
+
+	var opts users.AddGroupMemberOptions
+	if !decodeBody(w, r, &opts) {
+		return
+	}
+
+	member := users.GroupMemberDetail{ID: opts.MemberID, Username: opts.MemberName, GroupID: g.ID, DomainName: opts.DomainName}
+	if opts.DomainName == "" {
+		var u *user
+		if opts.MemberID != 0 {
+			u = s.users[opts.MemberID]
+		} else {
+			u = s.findUser(opts.MemberName)
+		}
+		if u == nil {
+			writeError(w, http.StatusNotFound, "PASWS143E", fmt.Sprintf("User [%s] was not found.", opts.MemberName))
+			return
+		}
+		member.ID, member.Username = u.ID, u.Username
+	}
+	for _, m := range g.Members {
+		if strings.EqualFold(m.Username, member.Username) {
+			writeError(w, http.StatusConflict, "PASWS130E", "Member ["+member.Username+"] already exists in group ["+g.GroupName+"].")
+			return
+		}
+	}
+	g.Members = append(g.Members, member)
+	writeJSON(w, http.StatusCreated, member)
+}
+
+func (s *Server) removeGroupMember(w http.ResponseWriter, r *http.Request, caller *user) {
+	g := s.lookupGroup(w, r)
+	if g == nil {
+		return
+	}
+	name := r.PathValue("member")
+	remaining := withoutMember(g.Members, name)
+	if len(remaining) == len(g.Members) {
+		writeError(w, http.StatusNotFound, "PASWS143E", "Member ["+name+"] was not found in group ["+g.GroupName+"].")
+		return
+	}
+	g.Members = remaining
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// withoutMember returns members without the named member.
+func withoutMember(members []users.GroupMemberDetail, name string) []users.GroupMemberDetail {
+	var remaining []users.GroupMemberDetail
+	for _, m := range members {
+		if !strings.EqualFold(m.Username, name) {
+			remaining = append(remaining, m)
+		}
+	}
+	return remaining
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -31,7 +32,7 @@ type ReportsResponse struct {
 // This is equivalent to Get-PASReport in psPAS.
 func ListReports(ctx context.Context, sess *session.Session) ([]Report, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/Reports", nil)
@@ -50,7 +51,7 @@ func ListReports(ctx context.Context, sess *session.Session) ([]Report, error) {
 // GetReport retrieves a specific report.
 func GetReport(ctx context.Context, sess *session.Session, reportID string) (*Report, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if reportID == "" {
@@ -92,7 +93,7 @@ type ReportData struct {
 // This is equivalent to Export-PASReport in psPAS.
 func ExportReport(ctx context.Context, sess *session.Session, opts ExportReportOptions) (*ReportData, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if opts.ReportID == "" {
@@ -154,7 +155,7 @@ type ReportSchedulesResponse struct {
 // This is equivalent to Get-PASReportSchedule in psPAS.
 func ListReportSchedules(ctx context.Context, sess *session.Session) ([]ReportSchedule, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/Reports/Schedules", nil)
@@ -173,7 +174,7 @@ func ListReportSchedules(ctx context.Context, sess *session.Session) ([]ReportSc
 // GetReportSchedule retrieves a specific report schedule.
 func GetReportSchedule(ctx context.Context, sess *session.Session, scheduleID string) (*ReportSchedule, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if scheduleID == "" {
@@ -209,7 +210,7 @@ type CreateReportScheduleOptions struct {
 // This is equivalent to New-PASReportSchedule in psPAS.
 func CreateReportSchedule(ctx context.Context, sess *session.Session, opts CreateReportScheduleOptions) (*ReportSchedule, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if opts.ReportID == "" {
@@ -236,7 +237,7 @@ func CreateReportSchedule(ctx context.Context, sess *session.Session, opts Creat
 // UpdateReportSchedule updates an existing report schedule.
 func UpdateReportSchedule(ctx context.Context, sess *session.Session, scheduleID string, opts CreateReportScheduleOptions) (*ReportSchedule, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if scheduleID == "" {
@@ -259,7 +260,7 @@ func UpdateReportSchedule(ctx context.Context, sess *session.Session, scheduleID
 // DeleteReportSchedule deletes a report schedule.
 func DeleteReportSchedule(ctx context.Context, sess *session.Session, scheduleID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if scheduleID == "" {
@@ -287,7 +288,7 @@ type UserLicenseReport struct {
 // This is equivalent to Get-PASUserLicenseReport in psPAS.
 func GetUserLicenseReport(ctx context.Context, sess *session.Session) (*UserLicenseReport, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/Reports/UserLicense", nil)
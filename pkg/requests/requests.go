@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"strconv"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -68,7 +69,7 @@ type ListOptions struct {
 // This is equivalent to Get-PASRequest -OnlyWaiting -Incoming in psPAS.
 func ListIncoming(ctx context.Context, sess *session.Session, opts ListOptions) (*RequestsResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -102,7 +103,7 @@ func ListIncoming(ctx context.Context, sess *session.Session, opts ListOptions)
 // This is equivalent to Get-PASRequest -MyRequests in psPAS.
 func ListMyRequests(ctx context.Context, sess *session.Session, opts ListOptions) (*RequestsResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -151,7 +152,7 @@ type CreateOptions struct {
 // This is equivalent to New-PASRequest in psPAS.
 func Create(ctx context.Context, sess *session.Session, opts CreateOptions) (*Request, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if opts.AccountID == "" {
@@ -180,7 +181,7 @@ type ApproveOptions struct {
 // This is equivalent to Approve-PASRequest in psPAS.
 func Approve(ctx context.Context, sess *session.Session, requestID string, opts ApproveOptions) (*Request, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if requestID == "" {
@@ -209,7 +210,7 @@ type DenyOptions struct {
 // This is equivalent to Deny-PASRequest in psPAS.
 func Deny(ctx context.Context, sess *session.Session, requestID string, opts DenyOptions) (*Request, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if requestID == "" {
@@ -233,7 +234,7 @@ func Deny(ctx context.Context, sess *session.Session, requestID string, opts Den
 // This is equivalent to Remove-PASRequest in psPAS.
 func Delete(ctx context.Context, sess *session.Session, requestID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if requestID == "" {
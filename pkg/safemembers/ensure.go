@@ -0,0 +1,77 @@
+// Package safemembers provides idempotent safe membership management.
+package safemembers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/session"
+)
+
+// EnsureAction is what Ensure did to reach the desired permissions.
+type EnsureAction string
+
+// Ensure actions
+const (
+	EnsureAdded     EnsureAction = "added"
+	EnsureUpdated   EnsureAction = "updated"
+	EnsureUnchanged EnsureAction = "unchanged"
+)
+
+// EnsureResult reports the outcome of Ensure.
+type EnsureResult struct {
+	Action EnsureAction `json:"action"`
+
+	// Member is the membership after the call
+	Member *SafeMember `json:"member"`
+
+	// Changes lists the permissions that were granted or revoked. When the
+	// member was added, it lists every permission granted.
+	Changes []PermissionChange `json:"changes,omitempty"`
+}
+
+// Ensure makes memberName a member of safeName with exactly the desired
+// permissions. The member is added if missing and updated only if its
+// permissions differ, so repeated calls are safe.
+func Ensure(ctx context.Context, sess *session.Session, safeName string, memberName string, desired *Permissions) (*EnsureResult, error) {
+	if sess == nil || !sess.IsValid() {
+		return nil, client.ErrSessionInvalid
+	}
+
+	if desired == nil {
+		return nil, fmt.Errorf("permissions are required")
+	}
+
+	current, err := Get(ctx, sess, safeName, memberName)
+	if errors.Is(err, client.ErrNotFound) {
+		member, err := Add(ctx, sess, safeName, AddOptions{MemberName: memberName, Permissions: desired})
+		if err != nil {
+			return nil, err
+		}
+		return &EnsureResult{
+			Action:  EnsureAdded,
+			Member:  member,
+			Changes: DiffPermissions(nil, desired),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changes := DiffPermissions(current.Permissions, desired)
+	if len(changes) == 0 {
+		return &EnsureResult{Action: EnsureUnchanged, Member: current}, nil
+	}
+
+	member, err := Update(ctx, sess, safeName, memberName, UpdateOptions{
+		MembershipExpirationDate: current.MembershipExpirationDate,
+		Permissions:              desired,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &EnsureResult{Action: EnsureUpdated, Member: member, Changes: changes}, nil
+}
@@ -0,0 +1,120 @@
+// Package safemembers provides tests for idempotent safe membership management.
+package safemembers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/chrisranney/gopas/internal/client"
+)
+
+// membershipServer serves a single safe member, recording the write calls it receives.
+func membershipServer(t *testing.T, existing *SafeMember, writes *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			if existing == nil {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"ErrorCode":"SFWS0012","ErrorMessage":"Member jdoe was not found"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(existing)
+		case http.MethodPost, http.MethodPut:
+			*writes = append(*writes, r.Method)
+			var body struct {
+				MemberName               string       `json:"memberName"`
+				MembershipExpirationDate int64        `json:"membershipExpirationDate"`
+				Permissions              *Permissions `json:"permissions"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if existing != nil && body.MembershipExpirationDate != existing.MembershipExpirationDate {
+				t.Errorf("expiration date = %d, want it preserved", body.MembershipExpirationDate)
+			}
+			json.NewEncoder(w).Encode(SafeMember{SafeName: "Linux", MemberName: "jdoe", Permissions: body.Permissions})
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	endUser, _ := RolePermissions(RoleEndUser)
+	auditor, _ := RolePermissions(RoleAuditor)
+
+	tests := []struct {
+		name        string
+		existing    *SafeMember
+		wantAction  EnsureAction
+		wantWrites  []string
+		wantChanges int
+	}{
+		{
+			name:        "missing member is added",
+			existing:    nil,
+			wantAction:  EnsureAdded,
+			wantWrites:  []string{http.MethodPost},
+			wantChanges: 5,
+		},
+		{
+			name:       "matching member is unchanged",
+			existing:   &SafeMember{SafeName: "Linux", MemberName: "jdoe", Permissions: endUser},
+			wantAction: EnsureUnchanged,
+		},
+		{
+			name:        "differing member is updated",
+			existing:    &SafeMember{SafeName: "Linux", MemberName: "jdoe", MembershipExpirationDate: 1700000000, Permissions: auditor},
+			wantAction:  EnsureUpdated,
+			wantWrites:  []string{http.MethodPut},
+			wantChanges: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writes []string
+			sess, server := createTestSession(t, membershipServer(t, tt.existing, &writes))
+			defer server.Close()
+
+			result, err := Ensure(context.Background(), sess, "Linux", "jdoe", endUser)
+			if err != nil {
+				t.Fatalf("Ensure() error: %v", err)
+			}
+
+			if result.Action != tt.wantAction {
+				t.Errorf("Action = %s, want %s", result.Action, tt.wantAction)
+			}
+			if len(writes) != len(tt.wantWrites) || (len(writes) > 0 && writes[0] != tt.wantWrites[0]) {
+				t.Errorf("writes = %v, want %v", writes, tt.wantWrites)
+			}
+			if len(result.Changes) != tt.wantChanges {
+				t.Errorf("Changes = %v, want %d changes", result.Changes, tt.wantChanges)
+			}
+			if result.Member == nil || result.Member.MemberName != "jdoe" {
+				t.Errorf("Member = %+v", result.Member)
+			}
+		})
+	}
+}
+
+func TestEnsure_Errors(t *testing.T) {
+	if _, err := Ensure(context.Background(), nil, "Linux", "jdoe", &Permissions{}); !errors.Is(err, client.ErrSessionInvalid) {
+		t.Errorf("Ensure() with nil session error = %v", err)
+	}
+
+	sess, server := createTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"ErrorCode":"PASWS041E","ErrorMessage":"Insufficient permissions"}`))
+	}))
+	defer server.Close()
+
+	if _, err := Ensure(context.Background(), sess, "Linux", "jdoe", nil); err == nil {
+		t.Error("Ensure() without permissions expected error")
+	}
+	if _, err := Ensure(context.Background(), sess, "Linux", "jdoe", &Permissions{}); !errors.Is(err, client.ErrForbidden) {
+		t.Errorf("Ensure() error = %v, want ErrForbidden", err)
+	}
+}
@@ -0,0 +1,195 @@
+// Package safemembers provides the predefined safe member roles and permission diffing.
+package safemembers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Role is a predefined set of safe member permissions matching the roles
+// offered by the PVWA when adding a safe member.
+type Role string
+
+// Predefined safe member roles
+const (
+	// RoleEndUser can list, use and retrieve accounts
+	RoleEndUser Role = "EndUser"
+
+	// RoleAuditor can list accounts and view the audit log and members
+	RoleAuditor Role = "Auditor"
+
+	// RoleApprover can authorize account requests and manage safe members
+	RoleApprover Role = "Approver"
+
+	// RoleSafeManager can manage accounts, the safe and its members
+	RoleSafeManager Role = "SafeManager"
+
+	// RoleFull has every permission, authorizing requests at level 1
+	RoleFull Role = "Full"
+)
+
+// Roles returns the predefined roles from least to most privileged.
+func Roles() []Role {
+	return []Role{RoleAuditor, RoleEndUser, RoleApprover, RoleSafeManager, RoleFull}
+}
+
+// ParseRole returns the role with the given name. Matching ignores case,
+// spaces, hyphens and underscores, so "End User" and "end-user" both
+// select RoleEndUser.
+func ParseRole(name string) (Role, error) {
+	normalize := strings.NewReplacer(" ", "", "-", "", "_", "")
+	want := strings.ToLower(normalize.Replace(name))
+	for _, role := range Roles() {
+		if strings.ToLower(string(role)) == want {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("unknown safe member role %q", name)
+}
+
+// RolePermissions returns a new copy of the permissions granted by a role.
+func RolePermissions(role Role) (*Permissions, error) {
+	switch role {
+	case RoleEndUser:
+		return &Permissions{
+			UseAccounts:      true,
+			RetrieveAccounts: true,
+			ListAccounts:     true,
+			ViewAuditLog:     true,
+			ViewSafeMembers:  true,
+		}, nil
+	case RoleAuditor:
+		return &Permissions{
+			ListAccounts:    true,
+			ViewAuditLog:    true,
+			ViewSafeMembers: true,
+		}, nil
+	case RoleApprover:
+		return &Permissions{
+			ListAccounts:                true,
+			ManageSafeMembers:           true,
+			ViewSafeMembers:             true,
+			RequestsAuthorizationLevel1: true,
+		}, nil
+	case RoleSafeManager:
+		return &Permissions{
+			UseAccounts:                            true,
+			RetrieveAccounts:                       true,
+			ListAccounts:                           true,
+			AddAccounts:                            true,
+			UpdateAccountContent:                   true,
+			UpdateAccountProperties:                true,
+			InitiateCPMAccountManagementOperations: true,
+			SpecifyNextAccountContent:              true,
+			RenameAccounts:                         true,
+			DeleteAccounts:                         true,
+			UnlockAccounts:                         true,
+			ManageSafe:                             true,
+			ManageSafeMembers:                      true,
+			ViewAuditLog:                           true,
+			ViewSafeMembers:                        true,
+			CreateFolders:                          true,
+			DeleteFolders:                          true,
+			MoveAccountsAndFolders:                 true,
+			RequestsAuthorizationLevel1:            true,
+		}, nil
+	case RoleFull:
+		return &Permissions{
+			UseAccounts:                            true,
+			RetrieveAccounts:                       true,
+			ListAccounts:                           true,
+			AddAccounts:                            true,
+			UpdateAccountContent:                   true,
+			UpdateAccountProperties:                true,
+			InitiateCPMAccountManagementOperations: true,
+			SpecifyNextAccountContent:              true,
+			RenameAccounts:                         true,
+			DeleteAccounts:                         true,
+			UnlockAccounts:                         true,
+			ManageSafe:                             true,
+			ManageSafeMembers:                      true,
+			BackupSafe:                             true,
+			ViewAuditLog:                           true,
+			ViewSafeMembers:                        true,
+			AccessWithoutConfirmation:              true,
+			CreateFolders:                          true,
+			DeleteFolders:                          true,
+			MoveAccountsAndFolders:                 true,
+			RequestsAuthorizationLevel1:            true,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown safe member role %q", role)
+	}
+}
+
+// MatchRole returns the predefined role whose permissions are exactly p.
+func MatchRole(p *Permissions) (Role, bool) {
+	for _, role := range Roles() {
+		perms, _ := RolePermissions(role)
+		if len(DiffPermissions(p, perms)) == 0 {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+// PermissionChange describes one permission that differs between two sets.
+type PermissionChange struct {
+	// Permission is the API name of the permission, such as "retrieveAccounts"
+	Permission string `json:"permission"`
+	From       bool   `json:"from"`
+	To         bool   `json:"to"`
+}
+
+// String returns the change as "+permission" or "-permission".
+func (c PermissionChange) String() string {
+	if c.To {
+		return "+" + c.Permission
+	}
+	return "-" + c.Permission
+}
+
+// DiffPermissions returns the permissions that differ from a to b, in the
+// order they are declared in Permissions. A nil set grants nothing.
+func DiffPermissions(a, b *Permissions) []PermissionChange {
+	if a == nil {
+		a = &Permissions{}
+	}
+	if b == nil {
+		b = &Permissions{}
+	}
+
+	va := reflect.ValueOf(a).Elem()
+	vb := reflect.ValueOf(b).Elem()
+	t := va.Type()
+
+	var changes []PermissionChange
+	for i := 0; i < t.NumField(); i++ {
+		from, to := va.Field(i).Bool(), vb.Field(i).Bool()
+		if from != to {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			changes = append(changes, PermissionChange{Permission: name, From: from, To: to})
+		}
+	}
+	return changes
+}
+
+// MergePermissions returns the permissions granted by either a or b. A nil
+// set grants nothing.
+func MergePermissions(a, b *Permissions) *Permissions {
+	merged := &Permissions{}
+	vm := reflect.ValueOf(merged).Elem()
+	for _, p := range []*Permissions{a, b} {
+		if p == nil {
+			continue
+		}
+		vp := reflect.ValueOf(p).Elem()
+		for i := 0; i < vp.NumField(); i++ {
+			if vp.Field(i).Bool() {
+				vm.Field(i).SetBool(true)
+			}
+		}
+	}
+	return merged
+}
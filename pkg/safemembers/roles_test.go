@@ -0,0 +1,135 @@
+// Package safemembers provides tests for safe member roles and permission diffing.
+package safemembers
+
+import (
+	"testing"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Role
+		wantErr bool
+	}{
+		{name: "EndUser", want: RoleEndUser},
+		{name: "End User", want: RoleEndUser},
+		{name: "end-user", want: RoleEndUser},
+		{name: "safe_manager", want: RoleSafeManager},
+		{name: "AUDITOR", want: RoleAuditor},
+		{name: "Approver", want: RoleApprover},
+		{name: "full", want: RoleFull},
+		{name: "superuser", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRole(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRole(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRole(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	for _, role := range Roles() {
+		perms, err := RolePermissions(role)
+		if err != nil {
+			t.Fatalf("RolePermissions(%q) error: %v", role, err)
+		}
+		if !perms.ListAccounts || !perms.ViewSafeMembers {
+			t.Errorf("RolePermissions(%q) cannot list accounts or view members", role)
+		}
+		if perms.RequestsAuthorizationLevel2 {
+			t.Errorf("RolePermissions(%q) grants level 2 authorization", role)
+		}
+
+		got, ok := MatchRole(perms)
+		if !ok || got != role {
+			t.Errorf("MatchRole(RolePermissions(%q)) = %q, %v", role, got, ok)
+		}
+	}
+
+	// Each call returns an independent copy
+	a, _ := RolePermissions(RoleAuditor)
+	a.ManageSafe = true
+	b, _ := RolePermissions(RoleAuditor)
+	if b.ManageSafe {
+		t.Error("RolePermissions() returned shared permissions")
+	}
+
+	if _, err := RolePermissions("Owner"); err == nil {
+		t.Error("RolePermissions() with unknown role expected error")
+	}
+
+	auditor, _ := RolePermissions(RoleAuditor)
+	if auditor.UseAccounts || auditor.RetrieveAccounts {
+		t.Error("auditor can use or retrieve accounts")
+	}
+	endUser, _ := RolePermissions(RoleEndUser)
+	if !endUser.RetrieveAccounts || endUser.AddAccounts {
+		t.Errorf("end user permissions = %+v", endUser)
+	}
+	manager, _ := RolePermissions(RoleSafeManager)
+	if !manager.ManageSafe || manager.BackupSafe {
+		t.Errorf("safe manager permissions = %+v", manager)
+	}
+}
+
+func TestMatchRole_Custom(t *testing.T) {
+	perms, _ := RolePermissions(RoleEndUser)
+	perms.UnlockAccounts = true
+	if role, ok := MatchRole(perms); ok {
+		t.Errorf("MatchRole() = %q for custom permissions", role)
+	}
+}
+
+func TestDiffPermissions(t *testing.T) {
+	a := &Permissions{ListAccounts: true, UseAccounts: true, ViewAuditLog: true}
+	b := &Permissions{ListAccounts: true, RetrieveAccounts: true, ViewAuditLog: true}
+
+	changes := DiffPermissions(a, b)
+	want := []PermissionChange{
+		{Permission: "useAccounts", From: true, To: false},
+		{Permission: "retrieveAccounts", From: false, To: true},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("DiffPermissions() = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("changes[%d] = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+	if changes[0].String() != "-useAccounts" || changes[1].String() != "+retrieveAccounts" {
+		t.Errorf("String() = %q, %q", changes[0], changes[1])
+	}
+
+	if got := DiffPermissions(a, a); len(got) != 0 {
+		t.Errorf("DiffPermissions(a, a) = %v, want none", got)
+	}
+	if got := DiffPermissions(nil, &Permissions{}); len(got) != 0 {
+		t.Errorf("DiffPermissions(nil, empty) = %v, want none", got)
+	}
+	if got := DiffPermissions(nil, a); len(got) != 3 {
+		t.Errorf("DiffPermissions(nil, a) = %v, want 3 grants", got)
+	}
+}
+
+func TestMergePermissions(t *testing.T) {
+	a := &Permissions{ListAccounts: true, UseAccounts: true}
+	b := &Permissions{ListAccounts: true, RetrieveAccounts: true}
+
+	want := Permissions{ListAccounts: true, UseAccounts: true, RetrieveAccounts: true}
+	if got := MergePermissions(a, b); *got != want {
+		t.Errorf("MergePermissions() = %+v, want %+v", got, want)
+	}
+	if got := MergePermissions(nil, b); *got != *b || got == b {
+		t.Errorf("MergePermissions(nil, b) = %+v, want a copy of b", got)
+	}
+	if a.RetrieveAccounts {
+		t.Error("MergePermissions() modified its input")
+	}
+}
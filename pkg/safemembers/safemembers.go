@@ -7,9 +7,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -75,7 +78,7 @@ type ListOptions struct {
 // This is equivalent to Get-PASSafeMember in psPAS.
 func List(ctx context.Context, sess *session.Session, safeName string, opts ListOptions) (*SafeMembersResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if safeName == "" {
@@ -112,10 +115,24 @@ func List(ctx context.Context, sess *session.Session, safeName string, opts List
 	return &result, nil
 }
 
+// All returns an iterator over every member of safeName matching opts across all pages.
+func All(ctx context.Context, sess *session.Session, safeName string, opts ListOptions, pageOpts types.PageOptions) iter.Seq2[SafeMember, error] {
+	fetch := func(ctx context.Context, offset int) (*helpers.Page[SafeMember], error) {
+		pageQuery := opts
+		pageQuery.Offset = offset
+		result, err := List(ctx, sess, safeName, pageQuery)
+		if err != nil {
+			return nil, err
+		}
+		return &helpers.Page[SafeMember]{Items: result.Value, Total: result.Count, NextLink: result.NextLink}, nil
+	}
+	return helpers.Paginate(ctx, fetch, opts.Offset, pageOpts)
+}
+
 // Get retrieves a specific safe member.
 func Get(ctx context.Context, sess *session.Session, safeName string, memberName string) (*SafeMember, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if safeName == "" {
@@ -151,7 +168,7 @@ type AddOptions struct {
 // This is equivalent to Add-PASSafeMember in psPAS.
 func Add(ctx context.Context, sess *session.Session, safeName string, opts AddOptions) (*SafeMember, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if safeName == "" {
@@ -189,7 +206,7 @@ type UpdateOptions struct {
 // This is equivalent to Set-PASSafeMember in psPAS.
 func Update(ctx context.Context, sess *session.Session, safeName string, memberName string, opts UpdateOptions) (*SafeMember, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if safeName == "" {
@@ -217,7 +234,7 @@ func Update(ctx context.Context, sess *session.Session, safeName string, memberN
 // This is equivalent to Remove-PASSafeMember in psPAS.
 func Remove(ctx context.Context, sess *session.Session, safeName string, memberName string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if safeName == "" {
@@ -246,6 +263,25 @@ func DefaultUserPermissions() *Permissions {
 	}
 }
 
+// DefaultApplicationPermissions returns the permissions an application needs
+// to retrieve credentials from a safe through the Credential Provider.
+func DefaultApplicationPermissions() *Permissions {
+	return &Permissions{
+		ListAccounts:     true,
+		RetrieveAccounts: true,
+	}
+}
+
+// DefaultProviderPermissions returns the permissions a Credential Provider
+// user needs to serve an application's requests from a safe.
+func DefaultProviderPermissions() *Permissions {
+	return &Permissions{
+		ListAccounts:     true,
+		RetrieveAccounts: true,
+		ViewSafeMembers:  true,
+	}
+}
+
 // DefaultAdminPermissions returns the default permissions for an admin.
 func DefaultAdminPermissions() *Permissions {
 	return &Permissions{
@@ -405,6 +405,46 @@ func TestDefaultUserPermissions(t *testing.T) {
 	}
 }
 
+func TestDefaultApplicationPermissions(t *testing.T) {
+	perms := DefaultApplicationPermissions()
+	if perms == nil {
+		t.Fatal("DefaultApplicationPermissions() returned nil")
+	}
+
+	if !perms.ListAccounts {
+		t.Error("ListAccounts should be true")
+	}
+	if !perms.RetrieveAccounts {
+		t.Error("RetrieveAccounts should be true")
+	}
+	if perms.UseAccounts {
+		t.Error("UseAccounts should be false for application permissions")
+	}
+	if perms.ViewSafeMembers {
+		t.Error("ViewSafeMembers should be false for application permissions")
+	}
+}
+
+func TestDefaultProviderPermissions(t *testing.T) {
+	perms := DefaultProviderPermissions()
+	if perms == nil {
+		t.Fatal("DefaultProviderPermissions() returned nil")
+	}
+
+	if !perms.ListAccounts {
+		t.Error("ListAccounts should be true")
+	}
+	if !perms.RetrieveAccounts {
+		t.Error("RetrieveAccounts should be true")
+	}
+	if !perms.ViewSafeMembers {
+		t.Error("ViewSafeMembers should be true")
+	}
+	if perms.UseAccounts {
+		t.Error("UseAccounts should be false for provider permissions")
+	}
+}
+
 func TestDefaultAdminPermissions(t *testing.T) {
 	perms := DefaultAdminPermissions()
 	if perms == nil {
@@ -7,9 +7,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -60,7 +63,7 @@ type ListOptions struct {
 // This is equivalent to Get-PASSafe in psPAS.
 func List(ctx context.Context, sess *session.Session, opts ListOptions) (*SafesResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -96,11 +99,25 @@ func List(ctx context.Context, sess *session.Session, opts ListOptions) (*SafesR
 	return &result, nil
 }
 
+// All returns an iterator over every safe matching opts across all pages.
+func All(ctx context.Context, sess *session.Session, opts ListOptions, pageOpts types.PageOptions) iter.Seq2[Safe, error] {
+	fetch := func(ctx context.Context, offset int) (*helpers.Page[Safe], error) {
+		pageQuery := opts
+		pageQuery.Offset = offset
+		result, err := List(ctx, sess, pageQuery)
+		if err != nil {
+			return nil, err
+		}
+		return &helpers.Page[Safe]{Items: result.Value, Total: result.Count, NextLink: result.NextLink}, nil
+	}
+	return helpers.Paginate(ctx, fetch, opts.Offset, pageOpts)
+}
+
 // Get retrieves a specific safe by name.
 // This is equivalent to Get-PASSafe -SafeName in psPAS.
 func Get(ctx context.Context, sess *session.Session, safeName string) (*Safe, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if safeName == "" {
@@ -136,7 +153,7 @@ type CreateOptions struct {
 // This is equivalent to Add-PASSafe in psPAS.
 func Create(ctx context.Context, sess *session.Session, opts CreateOptions) (*Safe, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if opts.SafeName == "" {
@@ -177,7 +194,7 @@ type UpdateOptions struct {
 // This is equivalent to Set-PASSafe in psPAS.
 func Update(ctx context.Context, sess *session.Session, safeName string, opts UpdateOptions) (*Safe, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if safeName == "" {
@@ -201,7 +218,7 @@ func Update(ctx context.Context, sess *session.Session, safeName string, opts Up
 // This is equivalent to Remove-PASSafe in psPAS.
 func Delete(ctx context.Context, sess *session.Session, safeName string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if safeName == "" {
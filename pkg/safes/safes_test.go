@@ -4,12 +4,16 @@ package safes
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/types"
 )
 
 // createTestSession creates a test session with a mock server
@@ -475,8 +479,24 @@ func intPtr(i int) *int {
 
 func TestGet_InvalidSession(t *testing.T) {
 	_, err := Get(context.Background(), nil, "TestSafe")
-	if err == nil {
-		t.Error("Get() with nil session expected error, got nil")
+	if !errors.Is(err, client.ErrSessionInvalid) {
+		t.Errorf("Get() with nil session error = %v, want ErrSessionInvalid", err)
+	}
+}
+
+func TestGet_NotFound(t *testing.T) {
+	sess, server := createTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"ErrorCode":"SFWS0007","ErrorMessage":"Safe Missing does not exist."}`))
+	}))
+	defer server.Close()
+
+	_, err := Get(context.Background(), sess, "Missing")
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+	if apiErr, ok := client.AsAPIError(err); !ok || apiErr.ErrorCode != "SFWS0007" {
+		t.Errorf("AsAPIError() = %v, %v; want wrapped APIError", apiErr, ok)
 	}
 }
 
@@ -679,3 +699,61 @@ func TestUpdate_InvalidJSON(t *testing.T) {
 		t.Error("Update() expected error for invalid JSON")
 	}
 }
+
+func TestAll(t *testing.T) {
+	const totalItems = 5
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if limit := r.URL.Query().Get("limit"); limit != "2" {
+			t.Errorf("Expected limit=2, got %q", limit)
+		}
+
+		end := offset + 2
+		if end > totalItems {
+			end = totalItems
+		}
+		var page []json.RawMessage
+		for i := offset; i < end; i++ {
+			page = append(page, json.RawMessage(fmt.Sprintf(`{"safeUrlId": "safe%d", "safeName": "safe%d"}`, i, i)))
+		}
+
+		body := map[string]interface{}{"value": page}
+		body["count"] = totalItems
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+
+	sess, server := createTestSession(t, handler)
+	defer server.Close()
+
+	var got []Safe
+	for item, err := range All(context.Background(), sess, ListOptions{Limit: 2}, types.PageOptions{}) {
+		if err != nil {
+			t.Fatalf("All() unexpected error: %v", err)
+		}
+		got = append(got, item)
+	}
+	if len(got) != totalItems {
+		t.Fatalf("All() yielded %d items, want %d", len(got), totalItems)
+	}
+
+	var limited int
+	for _, err := range All(context.Background(), sess, ListOptions{Limit: 2}, types.PageOptions{MaxItems: 3}) {
+		if err != nil {
+			t.Fatalf("All() unexpected error: %v", err)
+		}
+		limited++
+	}
+	if limited != 3 {
+		t.Errorf("All() with MaxItems yielded %d items, want 3", limited)
+	}
+}
+
+func TestAll_InvalidSession(t *testing.T) {
+	for _, err := range All(context.Background(), nil, ListOptions{Limit: 2}, types.PageOptions{}) {
+		if err == nil {
+			t.Error("All() expected error for nil session")
+		}
+	}
+}
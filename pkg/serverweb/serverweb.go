@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 )
 
@@ -25,7 +26,7 @@ type ServerInfo struct {
 // This is equivalent to Get-PASServer in psPAS.
 func GetServer(ctx context.Context, sess *session.Session) (*ServerInfo, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/WebServices/PIMServices.svc/Server", nil)
@@ -51,7 +52,7 @@ type WebServiceStatus struct {
 // This is equivalent to Get-PASServerWebService in psPAS.
 func GetWebServiceStatus(ctx context.Context, sess *session.Session) (*WebServiceStatus, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/WebServices/PIMServices.svc/Verify", nil)
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 	"github.com/chrisranney/gopas/pkg/types"
 )
@@ -29,7 +30,7 @@ type PublicSSHKeysResponse struct {
 // This is equivalent to Get-PASPublicSSHKey in psPAS.
 func GetUserPublicSSHKeys(ctx context.Context, sess *session.Session, userID string) ([]PublicSSHKey, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if userID == "" {
@@ -53,7 +54,7 @@ func GetUserPublicSSHKeys(ctx context.Context, sess *session.Session, userID str
 // This is equivalent to Add-PASPublicSSHKey in psPAS.
 func AddUserPublicSSHKey(ctx context.Context, sess *session.Session, userID string, publicKey string) (*PublicSSHKey, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if userID == "" {
@@ -85,7 +86,7 @@ func AddUserPublicSSHKey(ctx context.Context, sess *session.Session, userID stri
 // This is equivalent to Remove-PASPublicSSHKey in psPAS.
 func RemoveUserPublicSSHKey(ctx context.Context, sess *session.Session, userID string, keyID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if userID == "" {
@@ -125,7 +126,7 @@ type GetAccountSSHKeyOptions struct {
 // This is equivalent to Get-PASAccountSSHKey in psPAS.
 func GetAccountSSHKey(ctx context.Context, sess *session.Session, accountID string, opts GetAccountSSHKeyOptions) (*AccountSSHKey, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if accountID == "" {
@@ -167,7 +168,7 @@ type GeneratePrivateSSHKeyOptions struct {
 // This is equivalent to New-PASPrivateSSHKey in psPAS.
 func GeneratePrivateSSHKey(ctx context.Context, sess *session.Session, userID string, opts GeneratePrivateSSHKeyOptions) (*PrivateSSHKey, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if userID == "" {
@@ -191,7 +192,7 @@ func GeneratePrivateSSHKey(ctx context.Context, sess *session.Session, userID st
 // This is equivalent to Remove-PASPrivateSSHKey in psPAS.
 func RemovePrivateSSHKey(ctx context.Context, sess *session.Session, userID string, keyID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if userID == "" {
@@ -214,7 +215,7 @@ func RemovePrivateSSHKey(ctx context.Context, sess *session.Session, userID stri
 // This is equivalent to Clear-PASPrivateSSHKey in psPAS.
 func ClearPrivateSSHKeys(ctx context.Context, sess *session.Session, userID string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if userID == "" {
@@ -239,7 +240,7 @@ type MFACachedSSHKey struct {
 // ListMFACachedSSHKeys retrieves MFA-cached SSH keys for a user.
 func ListMFACachedSSHKeys(ctx context.Context, sess *session.Session, userID string) ([]MFACachedSSHKey, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if userID == "" {
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
 )
 
@@ -47,7 +49,11 @@ type ComponentSummaryResponse struct {
 // This is equivalent to Get-PASComponentSummary in psPAS.
 func ListComponentSummary(ctx context.Context, sess *session.Session) ([]ComponentSummary, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "systemhealth.ListComponentSummary", MinVersion: "10.1", SelfHostedOnly: true}); err != nil {
+		return nil, err
 	}
 
 	resp, err := sess.Client.Get(ctx, "/ComponentsMonitoringSummary", nil)
@@ -67,7 +73,11 @@ func ListComponentSummary(ctx context.Context, sess *session.Session) ([]Compone
 // This is equivalent to Get-PASComponentDetail in psPAS.
 func GetComponentDetail(ctx context.Context, sess *session.Session, componentID string) (*ComponentDetail, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
+	}
+
+	if err := sess.CheckRequirement(helpers.Requirement{Operation: "systemhealth.GetComponentDetail", MinVersion: "10.1", SelfHostedOnly: true}); err != nil {
+		return nil, err
 	}
 
 	if componentID == "" {
@@ -96,7 +106,7 @@ type VaultHealth struct {
 // GetVaultHealth retrieves the overall vault health status.
 func GetVaultHealth(ctx context.Context, sess *session.Session) (*VaultHealth, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, "/ServerHealth", nil)
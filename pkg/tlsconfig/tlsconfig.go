@@ -0,0 +1,339 @@
+// Package tlsconfig builds TLS client configurations for CyberArk
+// connections. It loads client certificates from files, PEM bytes or
+// PKCS#12 bundles, trusts private CAs, pins server keys and picks up
+// rotated certificate files without a restart.
+package tlsconfig
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// Config describes the TLS settings for a client. At most one client
+// certificate source may be set.
+type Config struct {
+	// CertFile and KeyFile are PEM files holding the client certificate
+	// and its private key. They are reloaded when either file changes.
+	CertFile string
+	KeyFile  string
+
+	// CertPEM and KeyPEM hold the PEM-encoded client certificate and key,
+	// for example from environment variables
+	CertPEM []byte
+	KeyPEM  []byte
+
+	// Certificate is a client certificate that has already been loaded
+	Certificate *tls.Certificate
+
+	// PKCS12File or PKCS12 holds a PKCS#12 (.p12/.pfx) bundle with the
+	// client certificate, its key and optionally its CA chain. PKCS12File
+	// is reloaded when it changes.
+	PKCS12File     string
+	PKCS12         []byte
+	PKCS12Password string
+
+	// RootCAs are the CAs trusted to sign the server certificate. CAFile
+	// and CAPEM add PEM-encoded CAs to it. When none is set the system
+	// pool is used.
+	RootCAs *x509.CertPool
+	CAFile  string
+	CAPEM   []byte
+
+	// PinnedSPKIHashes restricts the server to certificates whose public
+	// key matches one of these SHA-256 hashes of the SubjectPublicKeyInfo.
+	// Hashes are base64 (optionally prefixed with "sha256/") or hex
+	// encoded, see SPKIHash.
+	PinnedSPKIHashes []string
+
+	// ServerName overrides the name used to verify the server certificate
+	ServerName string
+
+	// MinVersion is the minimum TLS version (default TLS 1.2)
+	MinVersion uint16
+
+	// InsecureSkipVerify disables server certificate verification (not
+	// recommended for production). Pins are still checked.
+	InsecureSkipVerify bool
+}
+
+// Build returns a tls.Config for the settings. A nil Config yields a
+// default configuration.
+func (c *Config) Build() (*tls.Config, error) {
+	if c == nil {
+		c = &Config{}
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.MinVersion != 0 {
+		tlsConfig.MinVersion = c.MinVersion
+	}
+
+	roots, err := c.rootCAs()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.RootCAs = roots
+
+	if err := c.configureClientCertificate(tlsConfig); err != nil {
+		return nil, err
+	}
+
+	if len(c.PinnedSPKIHashes) > 0 {
+		pins, err := parsePins(c.PinnedSPKIHashes)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPins(cs, pins)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// HasClientCertificate reports whether a client certificate source is set.
+func (c *Config) HasClientCertificate() bool {
+	return c != nil && (c.CertFile != "" || c.KeyFile != "" ||
+		len(c.CertPEM) > 0 || len(c.KeyPEM) > 0 || c.Certificate != nil ||
+		c.PKCS12File != "" || len(c.PKCS12) > 0)
+}
+
+func (c *Config) rootCAs() (*x509.CertPool, error) {
+	if c.CAFile == "" && len(c.CAPEM) == 0 {
+		return c.RootCAs, nil
+	}
+
+	pool := c.RootCAs
+	if pool == nil {
+		var err error
+		if pool, err = x509.SystemCertPool(); err != nil {
+			pool = x509.NewCertPool()
+		}
+	} else {
+		pool = pool.Clone()
+	}
+
+	if c.CAFile != "" {
+		data, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+		}
+	}
+	if len(c.CAPEM) > 0 && !pool.AppendCertsFromPEM(c.CAPEM) {
+		return nil, errors.New("no certificates found in CA PEM")
+	}
+	return pool, nil
+}
+
+func (c *Config) configureClientCertificate(tlsConfig *tls.Config) error {
+	sources := 0
+	for _, set := range []bool{
+		c.CertFile != "" || c.KeyFile != "",
+		len(c.CertPEM) > 0 || len(c.KeyPEM) > 0,
+		c.Certificate != nil,
+		c.PKCS12File != "",
+		len(c.PKCS12) > 0,
+	} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one client certificate source may be set")
+	}
+
+	switch {
+	case c.CertFile != "" || c.KeyFile != "":
+		if c.CertFile == "" || c.KeyFile == "" {
+			return errors.New("both the client certificate and key files are required")
+		}
+		certFile, keyFile := c.CertFile, c.KeyFile
+		r := &reloader{
+			files: []string{certFile, keyFile},
+			load: func() (*tls.Certificate, error) {
+				cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+				return &cert, err
+			},
+		}
+		if _, err := r.certificate(); err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.GetClientCertificate = r.getClientCertificate
+
+	case len(c.CertPEM) > 0 || len(c.KeyPEM) > 0:
+		cert, err := tls.X509KeyPair(c.CertPEM, c.KeyPEM)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+
+	case c.Certificate != nil:
+		tlsConfig.Certificates = []tls.Certificate{*c.Certificate}
+
+	case c.PKCS12File != "":
+		path, password := c.PKCS12File, c.PKCS12Password
+		r := &reloader{
+			files: []string{path},
+			load: func() (*tls.Certificate, error) {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return nil, err
+				}
+				return decodePKCS12(data, password)
+			},
+		}
+		if _, err := r.certificate(); err != nil {
+			return fmt.Errorf("failed to load PKCS#12 bundle: %w", err)
+		}
+		tlsConfig.GetClientCertificate = r.getClientCertificate
+
+	case len(c.PKCS12) > 0:
+		cert, err := decodePKCS12(c.PKCS12, c.PKCS12Password)
+		if err != nil {
+			return fmt.Errorf("failed to load PKCS#12 bundle: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	}
+	return nil
+}
+
+// decodePKCS12 returns the certificate, key and CA chain in a PKCS#12 bundle.
+func decodePKCS12(data []byte, password string) (*tls.Certificate, error) {
+	key, leaf, chain, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, err
+	}
+	cert := &tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+	for _, ca := range chain {
+		cert.Certificate = append(cert.Certificate, ca.Raw)
+	}
+	return cert, nil
+}
+
+// reloader loads a client certificate from files and loads it again when
+// their modification times change. If a reload fails, for example while
+// the files are half written, the previous certificate is kept.
+type reloader struct {
+	files []string
+	load  func() (*tls.Certificate, error)
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime []time.Time
+}
+
+func (r *reloader) certificate() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTime := make([]time.Time, len(r.files))
+	for i, file := range r.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			if r.cert != nil {
+				return r.cert, nil
+			}
+			return nil, err
+		}
+		modTime[i] = info.ModTime()
+	}
+	if r.cert != nil && equalTimes(modTime, r.modTime) {
+		return r.cert, nil
+	}
+
+	cert, err := r.load()
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, err
+	}
+	r.cert, r.modTime = cert, modTime
+	return cert, nil
+}
+
+func (r *reloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.certificate()
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SPKIHash returns the base64-encoded SHA-256 hash of the certificate's
+// SubjectPublicKeyInfo, the form used by PinnedSPKIHashes. It matches
+//
+//	openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func parsePins(hashes []string) (map[[sha256.Size]byte]bool, error) {
+	pins := make(map[[sha256.Size]byte]bool, len(hashes))
+	for _, hash := range hashes {
+		value := strings.TrimPrefix(strings.TrimSpace(hash), "sha256/")
+		var sum []byte
+		if decoded, err := hex.DecodeString(strings.ReplaceAll(value, ":", "")); err == nil && len(decoded) == sha256.Size {
+			sum = decoded
+		} else if decoded, err := base64.StdEncoding.DecodeString(value); err == nil && len(decoded) == sha256.Size {
+			sum = decoded
+		} else {
+			return nil, fmt.Errorf("invalid SPKI pin %q: expected a base64 or hex SHA-256 hash", hash)
+		}
+		pins[[sha256.Size]byte(sum)] = true
+	}
+	return pins, nil
+}
+
+// verifyPins accepts the connection if a certificate in a verified chain
+// has a pinned public key. When verification is skipped there are no
+// verified chains, and only the server's own certificate is checked; other
+// certificates the server presents are not trusted to satisfy a pin.
+func verifyPins(cs tls.ConnectionState, pins map[[sha256.Size]byte]bool) error {
+	var certs []*x509.Certificate
+	switch {
+	case len(cs.VerifiedChains) > 0:
+		for _, chain := range cs.VerifiedChains {
+			certs = append(certs, chain...)
+		}
+	case len(cs.PeerCertificates) > 0:
+		certs = cs.PeerCertificates[:1]
+	}
+	for _, cert := range certs {
+		if pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+			return nil
+		}
+	}
+	return errors.New("server certificate does not match any pinned public key")
+}
@@ -0,0 +1,341 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a leaf certificate signed by the CA.
+func (ca *testCA) issue(t *testing.T, cn string, server bool) (*x509.Certificate, *ecdsa.PrivateKey, tls.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}
+}
+
+func encodePEM(t *testing.T, cert *x509.Certificate, key *ecdsa.PrivateKey) (certPEM, keyPEM []byte) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// newTestServer starts an HTTPS server signed by ca that requires a client
+// certificate from ca and answers with the client certificate's common name.
+func newTestServer(t *testing.T, ca *testCA) (*httptest.Server, *x509.Certificate) {
+	t.Helper()
+	serverCert, _, tlsCert := ca.issue(t, "server", true)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{tlsCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv, serverCert
+}
+
+// get connects with cfg and returns the client common name the server saw.
+func get(t *testing.T, srv *httptest.Server, cfg *Config) (string, error) {
+	t.Helper()
+	tlsConfig, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestBuild_ClientCertificateSources(t *testing.T) {
+	ca := newTestCA(t)
+	srv, _ := newTestServer(t, ca)
+
+	cert, key, tlsCert := ca.issue(t, "client", false)
+	certPEM, keyPEM := encodePEM(t, cert, key)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	os.WriteFile(certFile, certPEM, 0o600)
+	os.WriteFile(keyFile, keyPEM, 0o600)
+
+	p12, err := pkcs12.Modern.Encode(key, cert, []*x509.Certificate{ca.cert}, "changeit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p12File := filepath.Join(dir, "client.p12")
+	os.WriteFile(p12File, p12, 0o600)
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"files", Config{CertFile: certFile, KeyFile: keyFile}},
+		{"PEM", Config{CertPEM: certPEM, KeyPEM: keyPEM}},
+		{"certificate", Config{Certificate: &tlsCert}},
+		{"PKCS12 bytes", Config{PKCS12: p12, PKCS12Password: "changeit"}},
+		{"PKCS12 file", Config{PKCS12File: p12File, PKCS12Password: "changeit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.CAPEM = ca.pem
+			cn, err := get(t, srv, &tt.cfg)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if cn != "client" {
+				t.Errorf("client certificate CN = %q, want client", cn)
+			}
+		})
+	}
+}
+
+func TestBuild_Errors(t *testing.T) {
+	ca := newTestCA(t)
+	cert, key, _ := ca.issue(t, "client", false)
+	certPEM, keyPEM := encodePEM(t, cert, key)
+	p12, _ := pkcs12.Modern.Encode(key, cert, nil, "changeit")
+
+	tests := []struct {
+		name   string
+		cfg    Config
+		errMsg string
+	}{
+		{"two sources", Config{CertPEM: certPEM, KeyPEM: keyPEM, PKCS12: p12}, "only one client certificate source"},
+		{"missing key file", Config{CertFile: "/nonexistent/client.crt"}, "both the client certificate and key files"},
+		{"unreadable files", Config{CertFile: "/nonexistent/client.crt", KeyFile: "/nonexistent/client.key"}, "failed to load client certificate"},
+		{"mismatched PEM", Config{CertPEM: certPEM, KeyPEM: []byte("junk")}, "failed to load client certificate"},
+		{"wrong PKCS12 password", Config{PKCS12: p12, PKCS12Password: "wrong"}, "failed to load PKCS#12 bundle"},
+		{"missing CA file", Config{CAFile: "/nonexistent/ca.pem"}, "failed to read CA file"},
+		{"empty CA PEM", Config{CAPEM: []byte("not a certificate")}, "no certificates found"},
+		{"invalid pin", Config{PinnedSPKIHashes: []string{"abc"}}, "invalid SPKI pin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cfg.Build()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Build() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestBuild_RootCAs(t *testing.T) {
+	ca := newTestCA(t)
+	srv, _ := newTestServer(t, ca)
+	cert, key, _ := ca.issue(t, "client", false)
+	certPEM, keyPEM := encodePEM(t, cert, key)
+
+	if _, err := get(t, srv, &Config{CertPEM: certPEM, KeyPEM: keyPEM}); err == nil {
+		t.Error("Get() without the private CA succeeded")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, ca.pem, 0o600)
+	if _, err := get(t, srv, &Config{CertPEM: certPEM, KeyPEM: keyPEM, CAFile: caFile}); err != nil {
+		t.Errorf("Get() with CAFile error = %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	if _, err := get(t, srv, &Config{CertPEM: certPEM, KeyPEM: keyPEM, RootCAs: pool}); err != nil {
+		t.Errorf("Get() with RootCAs error = %v", err)
+	}
+}
+
+func TestBuild_PinnedSPKIHashes(t *testing.T) {
+	ca := newTestCA(t)
+	srv, serverCert := newTestServer(t, ca)
+	cert, key, _ := ca.issue(t, "client", false)
+	certPEM, keyPEM := encodePEM(t, cert, key)
+	other, _, _ := ca.issue(t, "other", true)
+
+	base := Config{CertPEM: certPEM, KeyPEM: keyPEM, CAPEM: ca.pem}
+	tests := []struct {
+		name    string
+		pins    []string
+		skip    bool
+		wantErr bool
+	}{
+		{"leaf pin", []string{SPKIHash(serverCert)}, false, false},
+		{"prefixed pin", []string{"sha256/" + SPKIHash(serverCert)}, false, false},
+		{"CA pin", []string{SPKIHash(ca.cert)}, false, false},
+		{"other key", []string{SPKIHash(other)}, false, true},
+		{"other key without verification", []string{SPKIHash(other)}, true, true},
+		{"leaf pin without verification", []string{SPKIHash(serverCert)}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.PinnedSPKIHashes = tt.pins
+			cfg.InsecureSkipVerify = tt.skip
+			_, err := get(t, srv, &cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Hex-encoded pins are accepted too.
+	sum, _ := parsePins([]string{SPKIHash(serverCert)})
+	for k := range sum {
+		cfg := base
+		cfg.PinnedSPKIHashes = []string{hex.EncodeToString(k[:])}
+		if _, err := get(t, srv, &cfg); err != nil {
+			t.Errorf("Get() with hex pin error = %v", err)
+		}
+	}
+}
+
+func TestBuild_PinnedSPKIHashes_ForeignLeaf(t *testing.T) {
+	ca := newTestCA(t)
+	_, _, pinned := ca.issue(t, "server", true)
+	foreignCA := newTestCA(t)
+	_, _, foreign := foreignCA.issue(t, "server", true)
+
+	// The server presents a foreign leaf followed by the pinned certificate.
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	foreign.Certificate = append(foreign.Certificate, pinned.Certificate[0])
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{foreign}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	cfg := &Config{PinnedSPKIHashes: []string{SPKIHash(pinned.Leaf)}, InsecureSkipVerify: true}
+	if _, err := get(t, srv, cfg); err == nil {
+		t.Error("Get() succeeded with a pin matching only a non-leaf certificate")
+	}
+
+	cfg.PinnedSPKIHashes = []string{SPKIHash(foreign.Leaf)}
+	if _, err := get(t, srv, cfg); err != nil {
+		t.Errorf("Get() with the leaf pinned error = %v", err)
+	}
+}
+
+func TestBuild_ReloadsRotatedFiles(t *testing.T) {
+	ca := newTestCA(t)
+	srv, _ := newTestServer(t, ca)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+
+	write := func(cn string, modTime time.Time) {
+		cert, key, _ := ca.issue(t, cn, false)
+		certPEM, keyPEM := encodePEM(t, cert, key)
+		os.WriteFile(certFile, certPEM, 0o600)
+		os.WriteFile(keyFile, keyPEM, 0o600)
+		os.Chtimes(certFile, modTime, modTime)
+		os.Chtimes(keyFile, modTime, modTime)
+	}
+	write("first", time.Now().Add(-time.Minute))
+
+	cfg := &Config{CertFile: certFile, KeyFile: keyFile, CAPEM: ca.pem}
+	tlsConfig, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true}}
+	commonName := func() string {
+		t.Helper()
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if cn := commonName(); cn != "first" {
+		t.Errorf("CN = %q, want first", cn)
+	}
+	write("second", time.Now())
+	if cn := commonName(); cn != "second" {
+		t.Errorf("CN after rotation = %q, want second", cn)
+	}
+
+	// A broken rotation keeps the last good certificate.
+	os.WriteFile(keyFile, []byte("partial"), 0o600)
+	if cn := commonName(); cn != "second" {
+		t.Errorf("CN after broken rotation = %q, want second", cn)
+	}
+}
@@ -0,0 +1,12 @@
+// Package types provides shared types used across the gopas library.
+package types
+
+// PageOptions controls how list iterators walk through paged results.
+type PageOptions struct {
+	// MaxItems stops iteration after this many items (0 means no limit)
+	MaxItems int
+
+	// Prefetch fetches the next page in the background while the
+	// current page is being consumed
+	Prefetch bool
+}
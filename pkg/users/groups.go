@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"strconv"
 
+	"github.com/chrisranney/gopas/internal/client"
 	"github.com/chrisranney/gopas/internal/session"
 )
 
@@ -54,7 +55,7 @@ type ListGroupsOptions struct {
 // This is equivalent to Get-PASGroup in psPAS.
 func ListGroups(ctx context.Context, sess *session.Session, opts ListGroupsOptions) (*GroupsResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -93,7 +94,7 @@ func ListGroups(ctx context.Context, sess *session.Session, opts ListGroupsOptio
 // GetGroup retrieves a specific group by ID.
 func GetGroup(ctx context.Context, sess *session.Session, groupID int) (*Group, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, fmt.Sprintf("/UserGroups/%d", groupID), nil)
@@ -120,7 +121,7 @@ type CreateGroupOptions struct {
 // This is equivalent to New-PASGroup in psPAS.
 func CreateGroup(ctx context.Context, sess *session.Session, opts CreateGroupOptions) (*Group, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if opts.GroupName == "" {
@@ -144,7 +145,7 @@ func CreateGroup(ctx context.Context, sess *session.Session, opts CreateGroupOpt
 // This is equivalent to Remove-PASGroup in psPAS.
 func DeleteGroup(ctx context.Context, sess *session.Session, groupID int) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	_, err := sess.Client.Delete(ctx, fmt.Sprintf("/UserGroups/%d", groupID))
@@ -166,7 +167,7 @@ type AddGroupMemberOptions struct {
 // This is equivalent to Add-PASGroupMember in psPAS.
 func AddGroupMember(ctx context.Context, sess *session.Session, groupID int, opts AddGroupMemberOptions) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if opts.MemberID == 0 && opts.MemberName == "" {
@@ -185,7 +186,7 @@ func AddGroupMember(ctx context.Context, sess *session.Session, groupID int, opt
 // This is equivalent to Remove-PASGroupMember in psPAS.
 func RemoveGroupMember(ctx context.Context, sess *session.Session, groupID int, memberName string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if memberName == "" {
@@ -203,7 +204,7 @@ func RemoveGroupMember(ctx context.Context, sess *session.Session, groupID int,
 // ListGroupMembers retrieves the members of a group.
 func ListGroupMembers(ctx context.Context, sess *session.Session, groupID int) ([]GroupMemberDetail, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, fmt.Sprintf("/UserGroups/%d/Members", groupID), nil)
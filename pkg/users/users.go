@@ -7,10 +7,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 
+	"github.com/chrisranney/gopas/internal/client"
+	"github.com/chrisranney/gopas/internal/helpers"
 	"github.com/chrisranney/gopas/internal/session"
+	"github.com/chrisranney/gopas/pkg/types"
 )
 
 // User represents a CyberArk user.
@@ -109,7 +113,7 @@ type ListOptions struct {
 // This is equivalent to Get-PASUser in psPAS.
 func List(ctx context.Context, sess *session.Session, opts ListOptions) (*UsersResponse, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	params := url.Values{}
@@ -148,11 +152,25 @@ func List(ctx context.Context, sess *session.Session, opts ListOptions) (*UsersR
 	return &result, nil
 }
 
+// All returns an iterator over every user matching opts across all pages.
+func All(ctx context.Context, sess *session.Session, opts ListOptions, pageOpts types.PageOptions) iter.Seq2[User, error] {
+	fetch := func(ctx context.Context, offset int) (*helpers.Page[User], error) {
+		pageQuery := opts
+		pageQuery.Offset = offset
+		result, err := List(ctx, sess, pageQuery)
+		if err != nil {
+			return nil, err
+		}
+		return &helpers.Page[User]{Items: result.Users, Total: result.Total, NextLink: result.NextLink}, nil
+	}
+	return helpers.Paginate(ctx, fetch, opts.Offset, pageOpts)
+}
+
 // Get retrieves a specific user by ID.
 // This is equivalent to Get-PASUser -id in psPAS.
 func Get(ctx context.Context, sess *session.Session, userID int) (*User, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Get(ctx, fmt.Sprintf("/Users/%d", userID), nil)
@@ -192,7 +210,7 @@ type CreateOptions struct {
 // This is equivalent to New-PASUser in psPAS.
 func Create(ctx context.Context, sess *session.Session, opts CreateOptions) (*User, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	if opts.Username == "" {
@@ -234,7 +252,7 @@ type UpdateOptions struct {
 // This is equivalent to Set-PASUser in psPAS.
 func Update(ctx context.Context, sess *session.Session, userID int, opts UpdateOptions) (*User, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Put(ctx, fmt.Sprintf("/Users/%d", userID), opts)
@@ -254,7 +272,7 @@ func Update(ctx context.Context, sess *session.Session, userID int, opts UpdateO
 // This is equivalent to Remove-PASUser in psPAS.
 func Delete(ctx context.Context, sess *session.Session, userID int) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	_, err := sess.Client.Delete(ctx, fmt.Sprintf("/Users/%d", userID))
@@ -269,7 +287,7 @@ func Delete(ctx context.Context, sess *session.Session, userID int) error {
 // This is equivalent to Unblock-PASUser in psPAS.
 func ActivateUser(ctx context.Context, sess *session.Session, userID int) (*User, error) {
 	if sess == nil || !sess.IsValid() {
-		return nil, fmt.Errorf("valid session is required")
+		return nil, client.ErrSessionInvalid
 	}
 
 	resp, err := sess.Client.Post(ctx, fmt.Sprintf("/Users/%d/Activate", userID), nil)
@@ -289,7 +307,7 @@ func ActivateUser(ctx context.Context, sess *session.Session, userID int) (*User
 // This is equivalent to Set-PASUserPassword in psPAS.
 func ResetPassword(ctx context.Context, sess *session.Session, userID int, newPassword string) error {
 	if sess == nil || !sess.IsValid() {
-		return fmt.Errorf("valid session is required")
+		return client.ErrSessionInvalid
 	}
 
 	if newPassword == "" {